@@ -3,11 +3,45 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io"
+	"os"
 
 	"github.com/localvar/xuandb/pkg/config"
 	"github.com/localvar/xuandb/pkg/version"
 )
 
+var validateOnly bool
+
+func init() {
+	flag.BoolVar(&validateOnly, "validate", false, "validate the configuration file and exit")
+}
+
+// osExit exits the process with a status code.
+var osExit = os.Exit
+
+// validate loads the configuration file and writes a summary of it to 'w',
+// the configurations are normalized and validated in the same way as the
+// server does.
+func validate(w io.Writer) error {
+	if err := config.Load("", false); err != nil {
+		return err
+	}
+
+	nodes := config.Nodes()
+	numVoter := 0
+	for _, nc := range nodes {
+		if nc.Meta.RaftVoter {
+			numVoter++
+		}
+	}
+
+	fmt.Fprintln(w, "Configuration is valid.")
+	fmt.Fprintln(w, "Cluster name:", config.ClusterName())
+	fmt.Fprintln(w, "Nodes:", len(nodes))
+	fmt.Fprintln(w, "Voters:", numVoter)
+	return nil
+}
+
 func main() {
 	flag.Parse()
 
@@ -21,7 +55,15 @@ func main() {
 		return
 	}
 
-	if err := config.Load(""); err != nil {
+	if validateOnly {
+		if err := validate(os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, "Invalid configuration:", err)
+			osExit(1)
+		}
+		return
+	}
+
+	if err := config.Load("", false); err != nil {
 		fmt.Println("Failed to load configuration:", err)
 		return
 	}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	assert := assert.New(t)
+
+	path := filepath.Join(t.TempDir(), "xuandb.toml")
+	t.Setenv("XUANDB_CONFIG_PATH", path)
+
+	require.NoError(t, os.WriteFile(path, []byte(`
+cluster-name = "test"
+[[node]]
+	id = "1"
+	http-addr = "127.0.0.1:7001"
+	[node.meta]
+		raft-voter = true
+		raft-addr = "127.0.0.1:8001"
+		raft-store = "memory"
+		raft-snapshot-store = "memory"
+[[node]]
+	id = "2"
+	http-addr = "127.0.0.1:7002"
+	[node.meta]
+		raft-addr = "127.0.0.1:8002"
+`), 0600))
+
+	var sb strings.Builder
+	assert.NoError(validate(&sb))
+	assert.Equal("Configuration is valid.\nCluster name: test\nNodes: 2\nVoters: 1\n", sb.String())
+
+	// the error is the same as the one reported by the server.
+	require.NoError(t, os.WriteFile(path, []byte(`
+[[node]]
+	id = "1"
+	http-addr = "127.0.0.1:7001"
+	[node.meta]
+		raft-voter = true
+		raft-addr = "127.0.0.1:8001"
+`), 0600))
+
+	sb.Reset()
+	err := validate(&sb)
+	assert.ErrorContains(err, "'data-dir' is required")
+	assert.Empty(sb.String())
+}
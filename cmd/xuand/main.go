@@ -1,11 +1,13 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log/slog"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 
 	"github.com/localvar/xuandb/pkg/config"
@@ -13,6 +15,7 @@ import (
 	"github.com/localvar/xuandb/pkg/httpserver"
 	"github.com/localvar/xuandb/pkg/logger"
 	"github.com/localvar/xuandb/pkg/meta"
+	"github.com/localvar/xuandb/pkg/metrics"
 	"github.com/localvar/xuandb/pkg/query"
 	"github.com/localvar/xuandb/pkg/version"
 )
@@ -38,7 +41,7 @@ func main() {
 		}
 	}
 
-	if err := config.Load(nodeID); err != nil {
+	if err := config.Load(nodeID, true); err != nil {
 		fmt.Fprintln(os.Stderr, "failed to load configuration:", err.Error())
 		return
 	}
@@ -47,8 +50,19 @@ func main() {
 	debug.Init()
 
 	httpserver.Start()
+
+	// the http server must be shut down before the services, so that the
+	// in-flight requests are drained while the services are still up. It is
+	// called explicitly once a stop signal is received, the deferred call is
+	// for the failure of starting the services.
+	shutdownHTTP := sync.OnceFunc(func() {
+		timeout := config.CurrentNode().ShutdownTimeout
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		httpserver.ShutdownContext(ctx)
+	})
 	defer func() {
-		httpserver.Shutdown()
+		shutdownHTTP()
 		slog.Info("xuandb stopped.")
 	}()
 
@@ -61,6 +75,8 @@ func main() {
 	}
 	defer meta.ShutdownService()
 
+	metrics.Init()
+
 	if err := query.StartService(); err != nil {
 		slog.Error(
 			"failed to start query service.",
@@ -73,7 +89,62 @@ func main() {
 	slog.Info("xuandb started.")
 
 	signals := make(chan os.Signal, 1)
-	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
-	<-signals
-	slog.Info("xuandb stopping...")
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	sig := waitForStop(signals, reloadConfig)
+	slog.Info("xuandb stopping...", slog.String("signal", sig.String()))
+
+	// a second signal forces the process to exit without waiting for the
+	// graceful shutdown.
+	go forceExitOnSignal(signals)
+
+	// the services are shut down by the deferred calls after this.
+	shutdownHTTP()
+}
+
+// osExit exits the process without waiting for the graceful shutdown.
+var osExit = os.Exit
+
+// waitForStop waits until a SIGINT or SIGTERM is received from 'signals' and
+// returns the signal, SIGHUPs received before that reload the configuration
+// via 'reload'. It returns nil if 'signals' is closed.
+func waitForStop(signals <-chan os.Signal, reload func()) os.Signal {
+	for sig := range signals {
+		if sig != syscall.SIGHUP {
+			return sig
+		}
+		reload()
+	}
+	return nil
+}
+
+// forceExitOnSignal waits for another SIGINT or SIGTERM from 'signals' during
+// the graceful shutdown, and exits the process immediately when it arrives.
+// SIGHUPs are ignored as there's no point reloading the configuration now.
+func forceExitOnSignal(signals <-chan os.Signal) {
+	for sig := range signals {
+		if sig == syscall.SIGHUP {
+			continue
+		}
+		slog.Warn(
+			"signal received again during shutdown, exiting immediately.",
+			slog.String("signal", sig.String()),
+		)
+		osExit(1)
+		return
+	}
+}
+
+// reloadConfig reloads the configuration and applies the changes which are
+// safe to apply at run time.
+func reloadConfig() {
+	slog.Info("reloading configuration...")
+	if _, err := config.Reload(); err != nil {
+		slog.Error(
+			"failed to reload configuration.",
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+	logger.Reconfigure(config.CurrentNode().Logger)
+	slog.Info("configuration reloaded.")
 }
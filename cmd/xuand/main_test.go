@@ -0,0 +1,54 @@
+package main
+
+import (
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWaitForStop(t *testing.T) {
+	assert := assert.New(t)
+
+	reloads := 0
+	reload := func() { reloads++ }
+
+	signals := make(chan os.Signal, 3)
+	signals <- syscall.SIGHUP
+	signals <- syscall.SIGHUP
+	signals <- syscall.SIGTERM
+	assert.Equal(syscall.SIGTERM, waitForStop(signals, reload))
+	assert.Equal(2, reloads)
+
+	signals <- os.Interrupt
+	assert.Equal(os.Interrupt, waitForStop(signals, reload))
+
+	close(signals)
+	assert.Nil(waitForStop(signals, reload))
+}
+
+func TestForceExitOnSignal(t *testing.T) {
+	assert := assert.New(t)
+
+	defer func(fn func(int)) { osExit = fn }(osExit)
+	code := -1
+	osExit = func(c int) { code = c }
+
+	// SIGHUP does not force exit.
+	signals := make(chan os.Signal, 2)
+	signals <- syscall.SIGHUP
+	close(signals)
+	forceExitOnSignal(signals)
+	assert.Equal(-1, code)
+
+	// the second SIGTERM or SIGINT forces exit.
+	for _, sig := range []os.Signal{syscall.SIGTERM, os.Interrupt} {
+		code = -1
+		signals = make(chan os.Signal, 2)
+		signals <- syscall.SIGHUP
+		signals <- sig
+		forceExitOnSignal(signals)
+		assert.Equal(1, code)
+	}
+}
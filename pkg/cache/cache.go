@@ -0,0 +1,102 @@
+// Package cache maintains a registry of the node-local caches, so that
+// operators can flush them to force a refresh after manual interventions.
+//
+// Caches are node-local, flushing a cache only affects the node which
+// receives the request.
+package cache
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/localvar/xuandb/pkg/xerrors"
+)
+
+var (
+	lock    sync.Mutex
+	flushes = map[string]func(){}
+)
+
+// Register registers a cache with name 'name', 'flush' is called to clear
+// the cache when it is flushed. Names are case-insensitive, and it panics if
+// the name is already registered.
+func Register(name string, flush func()) {
+	key := strings.ToLower(name)
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	if _, ok := flushes[key]; ok {
+		panic("cache already registered: " + name)
+	}
+	flushes[key] = flush
+}
+
+// Names returns the names of the registered caches in ascending order.
+func Names() []string {
+	lock.Lock()
+	defer lock.Unlock()
+
+	names := make([]string, 0, len(flushes))
+	for name := range flushes {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Flush flushes cache 'name', or all caches if 'name' is empty. It returns
+// the names of the flushed caches.
+func Flush(name string) ([]string, error) {
+	if name == "" {
+		names := Names()
+		for _, n := range names {
+			flush(n)
+		}
+		slog.Info("all caches flushed", slog.Any("caches", names))
+		return names, nil
+	}
+
+	key := strings.ToLower(name)
+	if !flush(key) {
+		msg := "unknown cache: " + name
+		if names := Names(); len(names) > 0 {
+			msg += ", available caches are: " + strings.Join(names, ", ")
+		}
+		return nil, xerrors.New(http.StatusNotFound, msg)
+	}
+
+	slog.Info("cache flushed", slog.String("cache", key))
+	return []string{key}, nil
+}
+
+// flush calls the flush function of cache 'key', it returns false if the
+// cache does not exist.
+func flush(key string) bool {
+	lock.Lock()
+	fn, ok := flushes[key]
+	lock.Unlock()
+
+	if ok {
+		fn()
+	}
+	return ok
+}
+
+// HandleFlush is an http handler that flushes the cache specified by query
+// parameter 'name', or all caches if it is empty. It responds the names of
+// the flushed caches.
+func HandleFlush(w http.ResponseWriter, r *http.Request) {
+	names, err := Flush(r.FormValue("name"))
+	if err != nil {
+		xerrors.WriteJSON(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(names)
+}
@@ -0,0 +1,75 @@
+package cache
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// authCache is a cache of the authentication results for test cases, an
+// entry is recomputed after it is flushed.
+type authCache struct {
+	entries  map[string]bool
+	computes int
+}
+
+func (ac *authCache) auth(name string) bool {
+	if ok, hit := ac.entries[name]; hit {
+		return ok
+	}
+	ac.computes++
+	ac.entries[name] = name == "admin"
+	return ac.entries[name]
+}
+
+func (ac *authCache) flush() {
+	clear(ac.entries)
+}
+
+func TestFlush(t *testing.T) {
+	assert := assert.New(t)
+
+	defer func(m map[string]func()) { flushes = m }(flushes)
+	flushes = map[string]func(){}
+
+	ac := &authCache{entries: map[string]bool{}}
+	Register("Auth", ac.flush)
+	others := 0
+	Register("leader", func() { others++ })
+	assert.Panics(func() { Register("AUTH", func() {}) })
+	assert.Equal([]string{"auth", "leader"}, Names())
+
+	assert.True(ac.auth("admin"))
+	assert.True(ac.auth("admin"))
+	assert.Equal(1, ac.computes)
+
+	// the next auth is recomputed after the cache is flushed.
+	names, err := Flush("AUTH")
+	assert.NoError(err)
+	assert.Equal([]string{"auth"}, names)
+	assert.Zero(others)
+	assert.True(ac.auth("admin"))
+	assert.Equal(2, ac.computes)
+
+	// flush all caches.
+	names, err = Flush("")
+	assert.NoError(err)
+	assert.Equal([]string{"auth", "leader"}, names)
+	assert.Equal(1, others)
+	assert.Empty(ac.entries)
+
+	_, err = Flush("privilege")
+	assert.EqualError(err, "unknown cache: privilege, available caches are: auth, leader")
+
+	w := httptest.NewRecorder()
+	HandleFlush(w, httptest.NewRequest(http.MethodPost, "/debug/cache/flush?name=leader", nil))
+	assert.Equal(http.StatusOK, w.Code)
+	assert.JSONEq(`["leader"]`, w.Body.String())
+	assert.Equal(2, others)
+
+	w = httptest.NewRecorder()
+	HandleFlush(w, httptest.NewRequest(http.MethodPost, "/debug/cache/flush?name=x", nil))
+	assert.Equal(http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// dfltBackupCount is the default number of configuration backups to retain.
+const dfltBackupCount = 10
+
+// names of the configuration backup files are 'xuandb-<timestamp>.toml', the
+// timestamp is in UTC so that the names are sorted by time.
+const (
+	backupPrefix     = "xuandb-"
+	backupSuffix     = ".toml"
+	backupTimeLayout = "20060102T150405.000000000Z"
+)
+
+// backup writes configuration 'c', which must have been normalized, to a new
+// backup file in 'c.BackupDir', and removes the oldest backups if there are
+// more than 'c.BackupCount' backups.
+func (c *Config) backup() error {
+	if err := os.MkdirAll(c.BackupDir, 0700); err != nil {
+		return err
+	}
+
+	// write to a temporary file first, so that a partially written backup
+	// is never taken as a valid one.
+	f, err := os.CreateTemp(c.BackupDir, "*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	err = toml.NewEncoder(f).Encode(c)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+
+	name := backupPrefix + time.Now().UTC().Format(backupTimeLayout) + backupSuffix
+	if err = os.Rename(f.Name(), filepath.Join(c.BackupDir, name)); err != nil {
+		return err
+	}
+
+	return pruneBackups(c.BackupDir, c.BackupCount)
+}
+
+// pruneBackups removes the oldest configuration backups in 'dir' to keep at
+// most 'n' of them.
+func pruneBackups(dir string, n int) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	for _, e := range entries {
+		name := e.Name()
+		if !e.IsDir() && strings.HasPrefix(name, backupPrefix) && strings.HasSuffix(name, backupSuffix) {
+			names = append(names, name)
+		}
+	}
+	if len(names) <= n {
+		return nil
+	}
+
+	slices.Sort(names)
+	for _, name := range names[:len(names)-n] {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const backupCfg = `
+%s
+[[node]]
+	id = "1"
+	http-addr = "127.0.0.1:7001"
+	[node.meta]
+		raft-voter = true
+		raft-addr = "127.0.0.1:8001"
+		raft-store = "memory"
+		raft-snapshot-store = "memory"
+`
+
+func TestLoadBackup(t *testing.T) {
+	assert := assert.New(t)
+
+	defer func() {
+		confPath, loadedPath, allCfg, backupOnLoad = "", "", &Config{}, false
+		curNodeCfg.Store(nil)
+	}()
+
+	dir := filepath.Join(t.TempDir(), "backup")
+	confPath = filepath.Join(t.TempDir(), "xuandb.toml")
+	data := fmt.Sprintf(backupCfg, fmt.Sprintf("backup-dir = %q\nbackup-count = 3", dir))
+	require.NoError(t, os.WriteFile(confPath, []byte(data), 0600))
+
+	// backups of previous loads, and a file which is not a backup.
+	require.NoError(t, os.MkdirAll(dir, 0700))
+	old := []string{
+		"xuandb-20240101T000000.000000000Z.toml",
+		"xuandb-20240102T000000.000000000Z.toml",
+		"xuandb-20240103T000000.000000000Z.toml",
+	}
+	for _, name := range append(old, "other.toml") {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), nil, 0600))
+	}
+
+	// tools which only read the configuration do not back it up.
+	require.NoError(t, Load("1", false))
+	files, err := filepath.Glob(filepath.Join(dir, "xuandb-*.toml"))
+	require.NoError(t, err)
+	require.Len(t, files, 3)
+
+	require.NoError(t, Load("1", true))
+
+	// the content is the normalized configuration.
+	files, err = filepath.Glob(filepath.Join(dir, "xuandb-*.toml"))
+	require.NoError(t, err)
+	require.Len(t, files, 3)
+	content, err := os.ReadFile(files[2])
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	require.NoError(t, toml.NewEncoder(&buf).Encode(All()))
+	assert.Equal(buf.String(), string(content))
+	assert.Contains(string(content), `raft-store = "memory"`)
+	assert.Contains(string(content), "[node.logger]")
+
+	// the oldest backup is removed, other files are untouched.
+	assert.NoFileExists(filepath.Join(dir, old[0]))
+	assert.FileExists(filepath.Join(dir, old[1]))
+	assert.FileExists(filepath.Join(dir, old[2]))
+	assert.FileExists(filepath.Join(dir, "other.toml"))
+
+	// the environment variable overrides the directory.
+	envDir := t.TempDir()
+	t.Setenv("XUANDB_CONFIG_BACKUP_DIR", envDir)
+	require.NoError(t, Load("1", true))
+	files, err = filepath.Glob(filepath.Join(envDir, "xuandb-*.toml"))
+	require.NoError(t, err)
+	assert.Len(files, 1)
+
+	// and so is the reload.
+	_, err = Reload()
+	require.NoError(t, err)
+	files, err = filepath.Glob(filepath.Join(envDir, "xuandb-*.toml"))
+	require.NoError(t, err)
+	assert.Len(files, 2)
+}
+
+func TestTidyBackupCount(t *testing.T) {
+	assert := assert.New(t)
+
+	c, err := decodeAndTidy(fmt.Sprintf(backupCfg, ""))
+	assert.NoError(err)
+	assert.Equal(dfltBackupCount, c.BackupCount)
+
+	c, err = decodeAndTidy(fmt.Sprintf(backupCfg, "backup-count = 3"))
+	assert.NoError(err)
+	assert.Equal(3, c.BackupCount)
+
+	_, err = decodeAndTidy(fmt.Sprintf(backupCfg, "backup-count = 0"))
+	assert.EqualError(err, "'backup-count' must be positive")
+}
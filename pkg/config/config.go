@@ -6,8 +6,11 @@ import (
 	"log/slog"
 	"net"
 	"net/netip"
+	"regexp"
 	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/BurntSushi/toml"
 )
@@ -17,10 +20,10 @@ type hasKeyFunc func(string) bool
 
 // LoggerConfig contains logger configuration.
 type LoggerConfig struct {
-	Format    string     `toml:"format" json:"format"`
-	Level     slog.Level `toml:"level" json:"level"`
-	AddSource bool       `toml:"add-source" json:"addSource"`
-	OutputTo  string     `toml:"output-to" json:"outputTo"`
+	Format    string     `toml:"format" json:"format" yaml:"format"`
+	Level     slog.Level `toml:"level" json:"level" yaml:"level"`
+	AddSource bool       `toml:"add-source" json:"addSource" yaml:"add-source"`
+	OutputTo  string     `toml:"output-to" json:"outputTo" yaml:"output-to"`
 }
 
 // dfltLoggerCfg contains the default values for LoggerConfig.
@@ -53,8 +56,13 @@ func (lc *LoggerConfig) updateDefault(hasKey hasKeyFunc) error {
 		dflt.AddSource = lc.AddSource
 	}
 
-	if lc.OutputTo != "" {
-		dflt.OutputTo = lc.OutputTo
+	switch v := strings.ToLower(lc.OutputTo); v {
+	case "stderr", "stdout", "discard":
+		dflt.OutputTo = v
+	case "":
+		// do nothing
+	default:
+		return fmt.Errorf("unknown log output: %s", lc.OutputTo)
 	}
 
 	return nil
@@ -82,8 +90,13 @@ func (lc *LoggerConfig) tidy(hasKey hasKeyFunc) error {
 		lc.AddSource = dflt.AddSource
 	}
 
-	if lc.OutputTo == "" {
+	switch v := strings.ToLower(lc.OutputTo); v {
+	case "stderr", "stdout", "discard":
+		lc.OutputTo = v
+	case "":
 		lc.OutputTo = dflt.OutputTo
+	default:
+		return fmt.Errorf("unknown log output: %s", lc.OutputTo)
 	}
 
 	return nil
@@ -91,17 +104,194 @@ func (lc *LoggerConfig) tidy(hasKey hasKeyFunc) error {
 
 // MetaConfig contains configuration for the meta service.
 type MetaConfig struct {
-	RaftVoter         bool   `toml:"raft-voter" json:"raftVoter"`
-	RaftAddr          string `toml:"raft-addr" json:"raftAddr"`
-	RaftStore         string `toml:"raft-store" json:"raftStore"`
-	RaftSnapshotStore string `toml:"raft-snapshot-store" json:"raftSnapshotStore"`
-	DataDir           string `toml:"data-dir" json:"dataDir"`
+	RaftVoter         bool   `toml:"raft-voter" json:"raftVoter" yaml:"raft-voter"`
+	RaftAddr          string `toml:"raft-addr" json:"raftAddr" yaml:"raft-addr"`
+	RaftStore         string `toml:"raft-store" json:"raftStore" yaml:"raft-store"`
+	RaftSnapshotStore string `toml:"raft-snapshot-store" json:"raftSnapshotStore" yaml:"raft-snapshot-store"`
+	DataDir           string `toml:"data-dir" json:"dataDir" yaml:"data-dir"`
+
+	// MaxApplyFailures is the maximum number of consecutive raft log apply
+	// failures, the node halts when it is reached. 0 means never halt.
+	MaxApplyFailures int `toml:"max-apply-failures" json:"maxApplyFailures" yaml:"max-apply-failures"`
+
+	// MaxCommandSize is the maximum size in bytes of a command written to
+	// the raft log, larger commands are rejected. 0 means no limit.
+	MaxCommandSize int `toml:"max-command-size" json:"maxCommandSize" yaml:"max-command-size"`
+
+	// HeartbeatInterval is the interval of node heartbeats. A node is
+	// considered unknown if there's no heartbeat from it for
+	// NodeUnknownAfter, and dead for NodeDeadAfter.
+	HeartbeatInterval time.Duration `toml:"heartbeat-interval" json:"heartbeatInterval" yaml:"heartbeat-interval"`
+	NodeUnknownAfter  time.Duration `toml:"node-unknown-after" json:"nodeUnknownAfter" yaml:"node-unknown-after"`
+	NodeDeadAfter     time.Duration `toml:"node-dead-after" json:"nodeDeadAfter" yaml:"node-dead-after"`
+
+	// RejoinGraceWindow is how long a dropped node is allowed to rejoin the
+	// cluster automatically with its prior suffrage. After that, the node
+	// must be re-added manually. 0 means it must always be re-added manually.
+	RejoinGraceWindow time.Duration `toml:"rejoin-grace-window" json:"rejoinGraceWindow" yaml:"rejoin-grace-window"`
+
+	// PasswordDenyList is the path of a file which lists the disallowed
+	// passwords, one per line. Empty means no deny list. The passwords are
+	// compared case-insensitively if PasswordDenyListIgnoreCase is true.
+	PasswordDenyList           string `toml:"password-deny-list" json:"passwordDenyList" yaml:"password-deny-list"`
+	PasswordDenyListIgnoreCase bool   `toml:"password-deny-list-ignore-case" json:"passwordDenyListIgnoreCase" yaml:"password-deny-list-ignore-case"`
+
+	// PasswordPolicy is the strength requirements of new passwords, its
+	// items are at the same level as the other items.
+	PasswordPolicy `yaml:",inline"`
+
+	// ReadRepairAfter is how long the meta data of a follower can diverge
+	// from the leader before it is resynchronized from the leader. 0 means
+	// never resynchronize, the divergence is only logged.
+	ReadRepairAfter time.Duration `toml:"read-repair-after" json:"readRepairAfter" yaml:"read-repair-after"`
+
+	// RequestTimeout is the timeout of a request sent to other meta nodes,
+	// e.g. joining the cluster and heartbeats. 0 means no timeout.
+	RequestTimeout time.Duration `toml:"request-timeout" json:"requestTimeout" yaml:"request-timeout"`
+
+	// LeaderRetryAttempts is the maximum number of attempts of a request to
+	// the leader, and LeaderRetryDelay is the delay before the first retry,
+	// the delay doubles on every subsequent retry.
+	LeaderRetryAttempts int           `toml:"leader-retry-attempts" json:"leaderRetryAttempts" yaml:"leader-retry-attempts"`
+	LeaderRetryDelay    time.Duration `toml:"leader-retry-delay" json:"leaderRetryDelay" yaml:"leader-retry-delay"`
+
+	// NamePattern is the regular expression which the names of users and
+	// databases must match.
+	NamePattern string `toml:"name-pattern" json:"namePattern" yaml:"name-pattern"`
+}
+
+// PasswordPolicy contains the strength requirements of passwords, the zero
+// value allows any password.
+type PasswordPolicy struct {
+	// MinLength is the minimum number of characters, 0 means no limit.
+	MinLength int `toml:"password-min-length" json:"passwordMinLength" yaml:"password-min-length"`
+
+	// The RequireXXXXXX items require passwords to contain at least one
+	// character of the corresponding class, a symbol is a character which is
+	// neither a letter nor a digit.
+	RequireUpper  bool `toml:"password-require-upper" json:"passwordRequireUpper" yaml:"password-require-upper"`
+	RequireLower  bool `toml:"password-require-lower" json:"passwordRequireLower" yaml:"password-require-lower"`
+	RequireDigit  bool `toml:"password-require-digit" json:"passwordRequireDigit" yaml:"password-require-digit"`
+	RequireSymbol bool `toml:"password-require-symbol" json:"passwordRequireSymbol" yaml:"password-require-symbol"`
+}
+
+// updateDefault updates the default password policy 'dflt' with the values
+// from the current policy.
+func (pp *PasswordPolicy) updateDefault(dflt *PasswordPolicy, hasKey hasKeyFunc) error {
+	if hasKey("password-min-length") {
+		if pp.MinLength < 0 {
+			return errors.New("'password-min-length' cannot be negative")
+		}
+		dflt.MinLength = pp.MinLength
+	}
+	if hasKey("password-require-upper") {
+		dflt.RequireUpper = pp.RequireUpper
+	}
+	if hasKey("password-require-lower") {
+		dflt.RequireLower = pp.RequireLower
+	}
+	if hasKey("password-require-digit") {
+		dflt.RequireDigit = pp.RequireDigit
+	}
+	if hasKey("password-require-symbol") {
+		dflt.RequireSymbol = pp.RequireSymbol
+	}
+	return nil
+}
+
+// tidy fills missing items of the password policy with the values of
+// 'dflt', and validates the policy.
+func (pp *PasswordPolicy) tidy(dflt *PasswordPolicy, hasKey hasKeyFunc) error {
+	if !hasKey("password-min-length") {
+		pp.MinLength = dflt.MinLength
+	} else if pp.MinLength < 0 {
+		return errors.New("'password-min-length' cannot be negative")
+	}
+	if !hasKey("password-require-upper") {
+		pp.RequireUpper = dflt.RequireUpper
+	}
+	if !hasKey("password-require-lower") {
+		pp.RequireLower = dflt.RequireLower
+	}
+	if !hasKey("password-require-digit") {
+		pp.RequireDigit = dflt.RequireDigit
+	}
+	if !hasKey("password-require-symbol") {
+		pp.RequireSymbol = dflt.RequireSymbol
+	}
+	return nil
+}
+
+// DefaultNamePattern is the default value of MetaConfig.NamePattern.
+const DefaultNamePattern = `^[A-Za-z_][A-Za-z0-9_-]*$`
+
+// isHostName returns whether 's' is a valid host name, that is, dot separated
+// labels of letters, digits and hyphens, a label cannot start or end with a
+// hyphen.
+func isHostName(s string) bool {
+	if len(s) == 0 || len(s) > 253 {
+		return false
+	}
+
+	for _, label := range strings.Split(s, ".") {
+		if len(label) == 0 || len(label) > 63 {
+			return false
+		}
+		if label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for i := 0; i < len(label); i++ {
+			c := label[i]
+			if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '-') {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// parseHostPort validates address 'addr' of configuration item 'key', which
+// is in the form of 'host:port', the host can be an IP literal or a host name.
+// It returns the IP if the host is an IP literal, or an invalid [netip.Addr]
+// if it is a host name.
+func parseHostPort(key, addr string) (netip.Addr, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("invalid '%s': %w", key, err)
+	}
+
+	p, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("invalid port of '%s': %s", key, port)
+	}
+	if p == 0 {
+		return netip.Addr{}, fmt.Errorf("port of '%s' cannot be 0", key)
+	}
+
+	if ip, err := netip.ParseAddr(host); err == nil {
+		return ip, nil
+	}
+	if !isHostName(host) {
+		return netip.Addr{}, fmt.Errorf("invalid host of '%s': %q", key, host)
+	}
+	return netip.Addr{}, nil
 }
 
 // dfltMetaCfg contains the default values for MetaConfig.
 var dfltMetaCfg = &MetaConfig{
-	RaftStore:         "boltdb",
-	RaftSnapshotStore: "file",
+	RaftStore:           "boltdb",
+	RaftSnapshotStore:   "file",
+	MaxCommandSize:      1 << 20,
+	HeartbeatInterval:   time.Second,
+	NodeUnknownAfter:    10 * time.Second,
+	NodeDeadAfter:       30 * time.Second,
+	RejoinGraceWindow:   5 * time.Minute,
+	ReadRepairAfter:     time.Minute,
+	RequestTimeout:      10 * time.Second,
+	LeaderRetryAttempts: 3,
+	LeaderRetryDelay:    100 * time.Millisecond,
+	NamePattern:         DefaultNamePattern,
 }
 
 // updateDefault updates the default configuration with the values from the
@@ -114,16 +304,13 @@ func (mc *MetaConfig) updateDefault(hasKey hasKeyFunc) error {
 	}
 
 	if mc.RaftAddr != "" {
-		ap, err := netip.ParseAddrPort(mc.RaftAddr)
+		ip, err := parseHostPort("raft-addr", mc.RaftAddr)
 		if err != nil {
 			return err
 		}
-		if !ap.Addr().IsUnspecified() {
+		if ip.IsValid() && !ip.IsUnspecified() {
 			return errors.New("IP of 'raft-addr' of the default node can only be '0.0.0.0' or '[::]'")
 		}
-		if ap.Port() == 0 {
-			return errors.New("port of 'raft-addr' cannot be 0")
-		}
 		dflt.RaftAddr = mc.RaftAddr
 	}
 
@@ -155,6 +342,82 @@ func (mc *MetaConfig) updateDefault(hasKey hasKeyFunc) error {
 		dflt.DataDir = mc.DataDir
 	}
 
+	if hasKey("max-apply-failures") {
+		if mc.MaxApplyFailures < 0 {
+			return errors.New("'max-apply-failures' cannot be negative")
+		}
+		dflt.MaxApplyFailures = mc.MaxApplyFailures
+	}
+
+	if hasKey("max-command-size") {
+		if mc.MaxCommandSize < 0 {
+			return errors.New("'max-command-size' cannot be negative")
+		}
+		dflt.MaxCommandSize = mc.MaxCommandSize
+	}
+
+	if hasKey("heartbeat-interval") {
+		dflt.HeartbeatInterval = mc.HeartbeatInterval
+	}
+	if hasKey("node-unknown-after") {
+		dflt.NodeUnknownAfter = mc.NodeUnknownAfter
+	}
+	if hasKey("node-dead-after") {
+		dflt.NodeDeadAfter = mc.NodeDeadAfter
+	}
+
+	if hasKey("rejoin-grace-window") {
+		if mc.RejoinGraceWindow < 0 {
+			return errors.New("'rejoin-grace-window' cannot be negative")
+		}
+		dflt.RejoinGraceWindow = mc.RejoinGraceWindow
+	}
+
+	if mc.PasswordDenyList != "" {
+		dflt.PasswordDenyList = mc.PasswordDenyList
+	}
+	if hasKey("password-deny-list-ignore-case") {
+		dflt.PasswordDenyListIgnoreCase = mc.PasswordDenyListIgnoreCase
+	}
+
+	if err := mc.PasswordPolicy.updateDefault(&dflt.PasswordPolicy, hasKey); err != nil {
+		return err
+	}
+
+	if hasKey("read-repair-after") {
+		if mc.ReadRepairAfter < 0 {
+			return errors.New("'read-repair-after' cannot be negative")
+		}
+		dflt.ReadRepairAfter = mc.ReadRepairAfter
+	}
+
+	if hasKey("request-timeout") {
+		if mc.RequestTimeout < 0 {
+			return errors.New("'request-timeout' cannot be negative")
+		}
+		dflt.RequestTimeout = mc.RequestTimeout
+	}
+
+	if hasKey("leader-retry-attempts") {
+		if mc.LeaderRetryAttempts <= 0 {
+			return errors.New("'leader-retry-attempts' must be positive")
+		}
+		dflt.LeaderRetryAttempts = mc.LeaderRetryAttempts
+	}
+	if hasKey("leader-retry-delay") {
+		if mc.LeaderRetryDelay < 0 {
+			return errors.New("'leader-retry-delay' cannot be negative")
+		}
+		dflt.LeaderRetryDelay = mc.LeaderRetryDelay
+	}
+
+	if mc.NamePattern != "" {
+		if _, err := regexp.Compile(mc.NamePattern); err != nil {
+			return errors.New("invalid 'name-pattern': " + err.Error())
+		}
+		dflt.NamePattern = mc.NamePattern
+	}
+
 	return nil
 }
 
@@ -167,15 +430,90 @@ func (mc *MetaConfig) tidy(hasKey hasKeyFunc) error {
 		mc.RaftVoter = dflt.RaftVoter
 	}
 
+	if !hasKey("max-apply-failures") {
+		mc.MaxApplyFailures = dflt.MaxApplyFailures
+	} else if mc.MaxApplyFailures < 0 {
+		return errors.New("'max-apply-failures' cannot be negative")
+	}
+
+	if !hasKey("max-command-size") {
+		mc.MaxCommandSize = dflt.MaxCommandSize
+	} else if mc.MaxCommandSize < 0 {
+		return errors.New("'max-command-size' cannot be negative")
+	}
+
+	if !hasKey("heartbeat-interval") {
+		mc.HeartbeatInterval = dflt.HeartbeatInterval
+	}
+	if !hasKey("node-unknown-after") {
+		mc.NodeUnknownAfter = dflt.NodeUnknownAfter
+	}
+	if !hasKey("node-dead-after") {
+		mc.NodeDeadAfter = dflt.NodeDeadAfter
+	}
+	if mc.HeartbeatInterval <= 0 {
+		return errors.New("'heartbeat-interval' must be positive")
+	}
+	if mc.NodeUnknownAfter <= mc.HeartbeatInterval {
+		return errors.New("'node-unknown-after' must be greater than 'heartbeat-interval'")
+	}
+	if mc.NodeDeadAfter <= mc.NodeUnknownAfter {
+		return errors.New("'node-dead-after' must be greater than 'node-unknown-after'")
+	}
+
+	if !hasKey("rejoin-grace-window") {
+		mc.RejoinGraceWindow = dflt.RejoinGraceWindow
+	} else if mc.RejoinGraceWindow < 0 {
+		return errors.New("'rejoin-grace-window' cannot be negative")
+	}
+
+	if mc.PasswordDenyList == "" {
+		mc.PasswordDenyList = dflt.PasswordDenyList
+	}
+	if !hasKey("password-deny-list-ignore-case") {
+		mc.PasswordDenyListIgnoreCase = dflt.PasswordDenyListIgnoreCase
+	}
+
+	if err := mc.PasswordPolicy.tidy(&dflt.PasswordPolicy, hasKey); err != nil {
+		return err
+	}
+
+	if !hasKey("read-repair-after") {
+		mc.ReadRepairAfter = dflt.ReadRepairAfter
+	} else if mc.ReadRepairAfter < 0 {
+		return errors.New("'read-repair-after' cannot be negative")
+	}
+
+	if !hasKey("request-timeout") {
+		mc.RequestTimeout = dflt.RequestTimeout
+	} else if mc.RequestTimeout < 0 {
+		return errors.New("'request-timeout' cannot be negative")
+	}
+
+	if !hasKey("leader-retry-attempts") {
+		mc.LeaderRetryAttempts = dflt.LeaderRetryAttempts
+	} else if mc.LeaderRetryAttempts <= 0 {
+		return errors.New("'leader-retry-attempts' must be positive")
+	}
+	if !hasKey("leader-retry-delay") {
+		mc.LeaderRetryDelay = dflt.LeaderRetryDelay
+	} else if mc.LeaderRetryDelay < 0 {
+		return errors.New("'leader-retry-delay' cannot be negative")
+	}
+
+	if mc.NamePattern == "" {
+		mc.NamePattern = dflt.NamePattern
+	} else if _, err := regexp.Compile(mc.NamePattern); err != nil {
+		return errors.New("invalid 'name-pattern': " + err.Error())
+	}
+
 	if mc.RaftAddr == "" {
 		mc.RaftAddr = dflt.RaftAddr
 	}
 	if mc.RaftAddr == "" {
 		return fmt.Errorf("'raft-addr' is required")
-	} else if ap, err := netip.ParseAddrPort(mc.RaftAddr); err != nil {
+	} else if _, err := parseHostPort("raft-addr", mc.RaftAddr); err != nil {
 		return err
-	} else if ap.Port() == 0 {
-		return errors.New("port of 'raft-addr' cannot be 0")
 	}
 
 	if !mc.RaftVoter {
@@ -226,11 +564,17 @@ func (mc *MetaConfig) tidy(hasKey hasKeyFunc) error {
 
 // DataConfig contains configuration for the data service.
 type DataConfig struct {
-	DataDir string `toml:"data-dir" json:"dataDir"`
+	DataDir string `toml:"data-dir" json:"dataDir" yaml:"data-dir"`
+
+	// ExpiryCheckInterval is the interval to check the databases for the
+	// data which is out of the retention period.
+	ExpiryCheckInterval time.Duration `toml:"expiry-check-interval" json:"expiryCheckInterval" yaml:"expiry-check-interval"`
 }
 
 // dfltDataCfg contains the default values for DataConfig.
-var dfltDataCfg = &DataConfig{}
+var dfltDataCfg = &DataConfig{
+	ExpiryCheckInterval: time.Minute,
+}
 
 // updateDefault updates the default configuration with the values from the
 // current configuration.
@@ -241,6 +585,13 @@ func (dc *DataConfig) updateDefault(hasKey hasKeyFunc) error {
 		dflt.DataDir = dc.DataDir
 	}
 
+	if hasKey("expiry-check-interval") {
+		if dc.ExpiryCheckInterval <= 0 {
+			return errors.New("'expiry-check-interval' must be positive")
+		}
+		dflt.ExpiryCheckInterval = dc.ExpiryCheckInterval
+	}
+
 	return nil
 }
 
@@ -253,46 +604,210 @@ func (dc *DataConfig) tidy(hasKey hasKeyFunc) error {
 		dc.DataDir = dflt.DataDir
 	}
 
+	if !hasKey("expiry-check-interval") {
+		dc.ExpiryCheckInterval = dflt.ExpiryCheckInterval
+	} else if dc.ExpiryCheckInterval <= 0 {
+		return errors.New("'expiry-check-interval' must be positive")
+	}
+
 	return nil
 }
 
 // QueryConfig contains configuration for the query service.
 type QueryConfig struct {
+	// DevMode makes the query service panic on bugs of statement
+	// implementations, it is for development and testing only.
+	DevMode bool `toml:"dev-mode" json:"devMode" yaml:"dev-mode"`
+
+	// Notice is a message from the operators, like a message of the day,
+	// it is sent to clients in the 'X-Server-Notice' header of the query
+	// responses if not empty.
+	Notice string `toml:"notice" json:"notice" yaml:"notice"`
+
+	// AuditReads enables auditing of the read statements, like 'SHOW USER'.
+	// It is separated from the auditing of mutations because reads are
+	// much more frequent.
+	AuditReads bool `toml:"audit-reads" json:"auditReads" yaml:"audit-reads"`
+
+	// SlowThreshold is the execution time above which a query is logged as
+	// a slow query. 0 disables slow query logging.
+	SlowThreshold time.Duration `toml:"slow-threshold" json:"slowThreshold" yaml:"slow-threshold"`
+
+	// MaxQueryBytes is the maximum size in bytes of a query, larger queries
+	// are rejected before parsing.
+	MaxQueryBytes int `toml:"max-query-bytes" json:"maxQueryBytes" yaml:"max-query-bytes"`
+
+	// RateLimitPerSec is the number of query requests per second allowed
+	// for each client, 0 means no limit. RateLimitBurst is the maximum
+	// number of requests allowed in a burst.
+	RateLimitPerSec float64 `toml:"rate-limit-per-sec" json:"rateLimitPerSec" yaml:"rate-limit-per-sec"`
+	RateLimitBurst  int     `toml:"rate-limit-burst" json:"rateLimitBurst" yaml:"rate-limit-burst"`
 }
 
 // dfltQueryCfg contains the default values for QueryConfig.
-var dfltQueryCfg = &QueryConfig{}
+var dfltQueryCfg = &QueryConfig{
+	MaxQueryBytes:  1 << 20,
+	RateLimitBurst: 10,
+}
 
 // updateDefault updates the default configuration with the values from the
 // current configuration.
 func (qc *QueryConfig) updateDefault(hasKey hasKeyFunc) error {
+	dflt := dfltQueryCfg
+
+	if hasKey("dev-mode") {
+		dflt.DevMode = qc.DevMode
+	}
+	if hasKey("notice") {
+		dflt.Notice = qc.Notice
+	}
+	if hasKey("audit-reads") {
+		dflt.AuditReads = qc.AuditReads
+	}
+	if hasKey("slow-threshold") {
+		if qc.SlowThreshold < 0 {
+			return errors.New("'slow-threshold' cannot be negative")
+		}
+		dflt.SlowThreshold = qc.SlowThreshold
+	}
+	if hasKey("max-query-bytes") {
+		if qc.MaxQueryBytes <= 0 {
+			return errors.New("'max-query-bytes' must be positive")
+		}
+		dflt.MaxQueryBytes = qc.MaxQueryBytes
+	}
+	if hasKey("rate-limit-per-sec") {
+		if qc.RateLimitPerSec < 0 {
+			return errors.New("'rate-limit-per-sec' cannot be negative")
+		}
+		dflt.RateLimitPerSec = qc.RateLimitPerSec
+	}
+	if hasKey("rate-limit-burst") {
+		if qc.RateLimitBurst <= 0 {
+			return errors.New("'rate-limit-burst' must be positive")
+		}
+		dflt.RateLimitBurst = qc.RateLimitBurst
+	}
+
 	return nil
 }
 
 // tidy fills missing configuration items with default values, normalizes all
 // values and validates the configuration.
 func (qc *QueryConfig) tidy(hasKey hasKeyFunc) error {
+	dflt := dfltQueryCfg
+
+	if !hasKey("dev-mode") {
+		qc.DevMode = dflt.DevMode
+	}
+	if !hasKey("notice") {
+		qc.Notice = dflt.Notice
+	}
+	if !hasKey("audit-reads") {
+		qc.AuditReads = dflt.AuditReads
+	}
+	if !hasKey("slow-threshold") {
+		qc.SlowThreshold = dflt.SlowThreshold
+	} else if qc.SlowThreshold < 0 {
+		return errors.New("'slow-threshold' cannot be negative")
+	}
+	if !hasKey("max-query-bytes") {
+		qc.MaxQueryBytes = dflt.MaxQueryBytes
+	} else if qc.MaxQueryBytes <= 0 {
+		return errors.New("'max-query-bytes' must be positive")
+	}
+	if !hasKey("rate-limit-per-sec") {
+		qc.RateLimitPerSec = dflt.RateLimitPerSec
+	} else if qc.RateLimitPerSec < 0 {
+		return errors.New("'rate-limit-per-sec' cannot be negative")
+	}
+	if !hasKey("rate-limit-burst") {
+		qc.RateLimitBurst = dflt.RateLimitBurst
+	} else if qc.RateLimitBurst <= 0 {
+		return errors.New("'rate-limit-burst' must be positive")
+	}
+	qc.Notice = strings.TrimSpace(qc.Notice)
+	if strings.ContainsAny(qc.Notice, "\r\n") {
+		return errors.New("'notice' must be a single line")
+	}
+
 	return nil
 }
 
 // NodeConfig contains configuration for a node.
 type NodeConfig struct {
-	ID          string        `toml:"id" json:"id"`
-	DomainName  string        `toml:"domain-name" json:"domainName"`
-	HTTPAddr    string        `toml:"http-addr" json:"httpAddr"`
-	EnablePprof bool          `toml:"enable-pprof" json:"enablePprof"`
-	Logger      *LoggerConfig `toml:"logger,omitempty" json:"logger,omitempty"`
-	Meta        *MetaConfig   `toml:"meta,omitempty" json:"meta,omitempty"`
-	Data        *DataConfig   `toml:"data,omitempty" json:"data,omitempty"`
-	Query       *QueryConfig  `toml:"query,omitempty" json:"query,omitempty"`
+	ID          string        `toml:"id" json:"id" yaml:"id"`
+	DomainName  string        `toml:"domain-name" json:"domainName" yaml:"domain-name"`
+	HTTPAddr    string        `toml:"http-addr" json:"httpAddr" yaml:"http-addr"`
+	TLSCertFile string        `toml:"tls-cert-file" json:"tlsCertFile" yaml:"tls-cert-file"`
+	TLSKeyFile  string        `toml:"tls-key-file" json:"tlsKeyFile" yaml:"tls-key-file"`
+	TLSCAFile   string        `toml:"tls-ca-file" json:"tlsCAFile" yaml:"tls-ca-file"`
+	EnablePprof bool          `toml:"enable-pprof" json:"enablePprof" yaml:"enable-pprof"`
+	Logger      *LoggerConfig `toml:"logger,omitempty" json:"logger,omitempty" yaml:"logger,omitempty"`
+	Meta        *MetaConfig   `toml:"meta,omitempty" json:"meta,omitempty" yaml:"meta,omitempty"`
+	Data        *DataConfig   `toml:"data,omitempty" json:"data,omitempty" yaml:"data,omitempty"`
+	Query       *QueryConfig  `toml:"query,omitempty" json:"query,omitempty" yaml:"query,omitempty"`
+
+	// ProductionMode disables the sensitive debug endpoints regardless of
+	// individual toggles like EnablePprof, the endpoints in DebugEndpoints
+	// are the exceptions.
+	ProductionMode bool     `toml:"production-mode" json:"productionMode" yaml:"production-mode"`
+	DebugEndpoints []string `toml:"debug-endpoints" json:"debugEndpoints" yaml:"debug-endpoints,omitempty"`
+
+	// BindBeforeBootstrap controls whether the HTTP service listens on
+	// HTTPAddr before the first user is created. If it is false, the HTTP
+	// service only listens on the loopback address until then.
+	BindBeforeBootstrap bool `toml:"bind-before-bootstrap" json:"bindBeforeBootstrap" yaml:"bind-before-bootstrap"`
+
+	// ShutdownTimeout is the maximum time to wait for the in-flight requests
+	// to complete when shutting down gracefully.
+	ShutdownTimeout time.Duration `toml:"shutdown-timeout" json:"shutdownTimeout" yaml:"shutdown-timeout"`
+
+	// RequireTLSForAuth rejects the requests which carry credentials but
+	// arrive over a non-TLS connection, to prevent exposing the credentials.
+	RequireTLSForAuth bool `toml:"require-tls-for-auth" json:"requireTLSForAuth" yaml:"require-tls-for-auth"`
+
+	// AllowLoopbackPlaintextAuth exempts the requests from the loopback
+	// address from RequireTLSForAuth, for local administration.
+	AllowLoopbackPlaintextAuth bool `toml:"allow-loopback-plaintext-auth" json:"allowLoopbackPlaintextAuth" yaml:"allow-loopback-plaintext-auth"`
+
+	// GzipResponses compresses the large responses with gzip for the clients
+	// which accept it.
+	GzipResponses bool `toml:"gzip-responses" json:"gzipResponses" yaml:"gzip-responses"`
+
+	// Labels are arbitrary key/value pairs advertised by the node, like the
+	// rack or zone it is located in, they are used for routing and
+	// rack-awareness.
+	Labels map[string]string `toml:"labels,omitempty" json:"labels,omitempty" yaml:"labels,omitempty"`
 }
 
 // dfltNodeCfg contains the default values for NodeConfig.
 var dfltNodeCfg = &NodeConfig{
-	Logger: dfltLoggerCfg,
-	Meta:   dfltMetaCfg,
-	Data:   dfltDataCfg,
-	Query:  dfltQueryCfg,
+	Logger:              dfltLoggerCfg,
+	Meta:                dfltMetaCfg,
+	Data:                dfltDataCfg,
+	Query:               dfltQueryCfg,
+	BindBeforeBootstrap: true,
+	ShutdownTimeout:     10 * time.Second,
+}
+
+// built-in default values, they are used to reset the default values before
+// loading configurations.
+var (
+	builtinLoggerCfg = *dfltLoggerCfg
+	builtinMetaCfg   = *dfltMetaCfg
+	builtinDataCfg   = *dfltDataCfg
+	builtinQueryCfg  = *dfltQueryCfg
+	builtinNodeCfg   = *dfltNodeCfg
+)
+
+// resetDefaults resets the default values to the built-in ones.
+func resetDefaults() {
+	*dfltLoggerCfg = builtinLoggerCfg
+	*dfltMetaCfg = builtinMetaCfg
+	*dfltDataCfg = builtinDataCfg
+	*dfltQueryCfg = builtinQueryCfg
+	*dfltNodeCfg = builtinNodeCfg
 }
 
 // ToExternalAddress converts an internal address to an external address.
@@ -311,6 +826,48 @@ func (nc *NodeConfig) ToExternalAddress(addr string) string {
 	return net.JoinHostPort(nc.DomainName, port)
 }
 
+// TLSEnabled returns whether TLS is enabled for the HTTP service.
+func (nc *NodeConfig) TLSEnabled() bool {
+	return nc.TLSCertFile != ""
+}
+
+// sensitive debug endpoints which are disabled in production mode.
+const (
+	DebugEndpointPprof  = "pprof"
+	DebugEndpointConfig = "config"
+)
+
+// validateDebugEndpoints validates the value of 'debug-endpoints'.
+func validateDebugEndpoints(endpoints []string) error {
+	for _, ep := range endpoints {
+		if ep != DebugEndpointPprof && ep != DebugEndpointConfig {
+			return fmt.Errorf("invalid debug endpoint: %s", ep)
+		}
+	}
+	return nil
+}
+
+// DebugEndpointEnabled returns whether the sensitive debug endpoint 'name' is
+// enabled. In production mode, an endpoint is enabled only if it is listed in
+// 'debug-endpoints' explicitly, and the pprof endpoint also requires
+// 'enable-pprof' in all modes.
+func (nc *NodeConfig) DebugEndpointEnabled(name string) bool {
+	if name == DebugEndpointPprof && !nc.EnablePprof {
+		return false
+	}
+	return !nc.ProductionMode || slices.Contains(nc.DebugEndpoints, name)
+}
+
+// validateLabels validates node labels.
+func validateLabels(labels map[string]string) error {
+	for k := range labels {
+		if strings.TrimSpace(k) == "" {
+			return errors.New("label key cannot be empty")
+		}
+	}
+	return nil
+}
+
 // updateDefault updates the default configuration with the values from the
 // current configuration.
 func (nc *NodeConfig) updateDefault(hasKey hasKeyFunc) error {
@@ -319,23 +876,73 @@ func (nc *NodeConfig) updateDefault(hasKey hasKeyFunc) error {
 	// skip 'ID' and 'DomainName' as they should not have default values.
 
 	if nc.HTTPAddr != "" {
-		ap, err := netip.ParseAddrPort(nc.HTTPAddr)
+		ip, err := parseHostPort("http-addr", nc.HTTPAddr)
 		if err != nil {
 			return err
 		}
-		if !ap.Addr().IsUnspecified() {
+		if ip.IsValid() && !ip.IsUnspecified() {
 			return errors.New("IP of 'http-addr' of the default node can only be '0.0.0.0' or '[::]'")
 		}
-		if ap.Port() == 0 {
-			return errors.New("port of 'http-addr' cannot be 0")
-		}
 		dflt.HTTPAddr = nc.HTTPAddr
 	}
 
+	if nc.TLSCertFile != "" {
+		dflt.TLSCertFile = nc.TLSCertFile
+	}
+
+	if nc.TLSKeyFile != "" {
+		dflt.TLSKeyFile = nc.TLSKeyFile
+	}
+
+	if nc.TLSCAFile != "" {
+		dflt.TLSCAFile = nc.TLSCAFile
+	}
+
 	if hasKey("enable-pprof") {
 		dflt.EnablePprof = nc.EnablePprof
 	}
 
+	if hasKey("production-mode") {
+		dflt.ProductionMode = nc.ProductionMode
+	}
+
+	if hasKey("debug-endpoints") {
+		if err := validateDebugEndpoints(nc.DebugEndpoints); err != nil {
+			return err
+		}
+		dflt.DebugEndpoints = nc.DebugEndpoints
+	}
+
+	if hasKey("bind-before-bootstrap") {
+		dflt.BindBeforeBootstrap = nc.BindBeforeBootstrap
+	}
+
+	if hasKey("shutdown-timeout") {
+		if nc.ShutdownTimeout <= 0 {
+			return errors.New("'shutdown-timeout' must be positive")
+		}
+		dflt.ShutdownTimeout = nc.ShutdownTimeout
+	}
+
+	if hasKey("require-tls-for-auth") {
+		dflt.RequireTLSForAuth = nc.RequireTLSForAuth
+	}
+
+	if hasKey("allow-loopback-plaintext-auth") {
+		dflt.AllowLoopbackPlaintextAuth = nc.AllowLoopbackPlaintextAuth
+	}
+
+	if hasKey("gzip-responses") {
+		dflt.GzipResponses = nc.GzipResponses
+	}
+
+	if hasKey("labels") {
+		if err := validateLabels(nc.Labels); err != nil {
+			return err
+		}
+		dflt.Labels = nc.Labels
+	}
+
 	if nc.Logger != nil {
 		hasKey1 := func(key string) bool { return hasKey("logger." + key) }
 		if err := nc.Logger.updateDefault(hasKey1); err != nil {
@@ -381,23 +988,84 @@ func (nc *NodeConfig) tidy(hasKey hasKeyFunc) error {
 	}
 	if nc.HTTPAddr == "" {
 		return fmt.Errorf("'http-addr' is required for node '%s'", nc.ID)
-	} else if ap, err := netip.ParseAddrPort(nc.HTTPAddr); err != nil {
+	} else if _, err := parseHostPort("http-addr", nc.HTTPAddr); err != nil {
 		return err
-	} else if ap.Port() == 0 {
-		return errors.New("port of 'http-addr' cannot be 0")
+	}
+
+	if nc.TLSCertFile == "" {
+		nc.TLSCertFile = dflt.TLSCertFile
+	}
+	if nc.TLSKeyFile == "" {
+		nc.TLSKeyFile = dflt.TLSKeyFile
+	}
+	if (nc.TLSCertFile == "") != (nc.TLSKeyFile == "") {
+		return fmt.Errorf("'tls-cert-file' and 'tls-key-file' must be provided together for node '%s'", nc.ID)
+	}
+	if nc.TLSCAFile == "" {
+		nc.TLSCAFile = dflt.TLSCAFile
 	}
 
 	if !hasKey("enable-pprof") {
 		nc.EnablePprof = dfltNodeCfg.EnablePprof
 	}
 
+	if !hasKey("production-mode") {
+		nc.ProductionMode = dflt.ProductionMode
+	}
+
+	if !hasKey("debug-endpoints") {
+		nc.DebugEndpoints = dflt.DebugEndpoints
+	} else if err := validateDebugEndpoints(nc.DebugEndpoints); err != nil {
+		return err
+	}
+
+	if !hasKey("bind-before-bootstrap") {
+		nc.BindBeforeBootstrap = dflt.BindBeforeBootstrap
+	}
+
+	if !hasKey("shutdown-timeout") {
+		nc.ShutdownTimeout = dflt.ShutdownTimeout
+	} else if nc.ShutdownTimeout <= 0 {
+		return errors.New("'shutdown-timeout' must be positive")
+	}
+
+	if !hasKey("require-tls-for-auth") {
+		nc.RequireTLSForAuth = dflt.RequireTLSForAuth
+	}
+	if !hasKey("allow-loopback-plaintext-auth") {
+		nc.AllowLoopbackPlaintextAuth = dflt.AllowLoopbackPlaintextAuth
+	}
+	if !hasKey("gzip-responses") {
+		nc.GzipResponses = dflt.GzipResponses
+	}
+	if nc.RequireTLSForAuth && !nc.TLSEnabled() {
+		return fmt.Errorf("'require-tls-for-auth' requires TLS to be enabled for node '%s'", nc.ID)
+	}
+
+	// labels of the default node are inherited unless they are overridden.
+	if err := validateLabels(nc.Labels); err != nil {
+		return err
+	}
+	for k, v := range dflt.Labels {
+		if _, ok := nc.Labels[k]; ok {
+			continue
+		}
+		if nc.Labels == nil {
+			nc.Labels = make(map[string]string, len(dflt.Labels))
+		}
+		nc.Labels[k] = v
+	}
+
 	if nc.Logger != nil {
 		hasKey1 := func(key string) bool { return hasKey("logger." + key) }
 		if err := nc.Logger.tidy(hasKey1); err != nil {
 			return err
 		}
 	} else {
-		nc.Logger = dflt.Logger
+		// make a copy, so that changes to the default values do not affect
+		// this node.
+		lc := *dflt.Logger
+		nc.Logger = &lc
 	}
 
 	if nc.Meta != nil {
@@ -428,8 +1096,21 @@ func (nc *NodeConfig) tidy(hasKey hasKeyFunc) error {
 
 // Config contains all configurations.
 type Config struct {
-	ClusterName string        `toml:"cluster-name" json:"clusterName"`
-	Nodes       []*NodeConfig `toml:"node" json:"nodes"`
+	ClusterName string `toml:"cluster-name" json:"clusterName" yaml:"cluster-name"`
+
+	// AllowUnsafeQuorum downgrades the validation errors of the number of
+	// raft voters to warnings, it is for development and testing only.
+	AllowUnsafeQuorum bool `toml:"allow-unsafe-quorum" json:"allowUnsafeQuorum" yaml:"allow-unsafe-quorum"`
+
+	// BackupDir is the directory to write a backup of the normalized
+	// configuration on each successful load by the daemon, empty disables
+	// the backup.
+	BackupDir string `toml:"backup-dir" json:"backupDir" yaml:"backup-dir"`
+
+	// BackupCount is the number of backups to retain in BackupDir.
+	BackupCount int `toml:"backup-count" json:"backupCount" yaml:"backup-count"`
+
+	Nodes []*NodeConfig `toml:"node" json:"nodes" yaml:"nodes"`
 }
 
 // extracts the keys for each node.
@@ -470,7 +1151,7 @@ func (c *Config) tidyNodes(definedKeys []toml.Key) error {
 
 	// find the default node and remove it from the slice.
 	var dflt *NodeConfig
-	for i, nc := range allCfg.Nodes {
+	for i, nc := range c.Nodes {
 		if nc.ID == "#default#" {
 			if dflt != nil {
 				return errors.New("duplicated default node")
@@ -479,7 +1160,7 @@ func (c *Config) tidyNodes(definedKeys []toml.Key) error {
 			curNodeKeys = allNodeKeys[i]
 		} else if dflt != nil {
 			// left shift nodes after the default to remove the default.
-			allCfg.Nodes[i-1] = nc
+			c.Nodes[i-1] = nc
 			allNodeKeys[i-1] = allNodeKeys[i]
 		}
 	}
@@ -487,7 +1168,7 @@ func (c *Config) tidyNodes(definedKeys []toml.Key) error {
 	// if the default node is defined, shrink the slice as we have removed it,
 	// and apply its values to the internal default.
 	if dflt != nil {
-		allCfg.Nodes = allCfg.Nodes[:len(allCfg.Nodes)-1]
+		c.Nodes = c.Nodes[:len(c.Nodes)-1]
 		allNodeKeys = allNodeKeys[:len(allNodeKeys)-1]
 		if err := dflt.updateDefault(hasKey); err != nil {
 			return err
@@ -497,7 +1178,7 @@ func (c *Config) tidyNodes(definedKeys []toml.Key) error {
 	// tidy all other nodes.
 	numVoter := 0
 	ids := make(map[string]struct{})
-	for i, nc := range allCfg.Nodes {
+	for i, nc := range c.Nodes {
 		if _, ok := ids[nc.ID]; ok {
 			return fmt.Errorf("duplicated node id: %s", nc.ID)
 		}
@@ -513,19 +1194,38 @@ func (c *Config) tidyNodes(definedKeys []toml.Key) error {
 		}
 	}
 
+	var err error
 	if numVoter == 0 {
-		return errors.New("no raft voter node")
+		err = errors.New("no raft voter node")
+	} else if numVoter%2 == 0 {
+		err = errors.New("even number of raft voter nodes")
 	}
 
-	if numVoter%2 == 0 {
-		return errors.New("even number of raft voter nodes")
+	if err == nil || !c.AllowUnsafeQuorum {
+		return err
 	}
 
+	slog.Warn(
+		"unsafe quorum is allowed, ignore the error",
+		slog.String("error", err.Error()),
+	)
 	return nil
 }
 
 // tidy fills missing configuration items with default values, normalizes all
 // values and validates the configuration.
 func (c *Config) tidy(definedKeys []toml.Key) error {
+	hasKey := func(key string) bool {
+		return slices.ContainsFunc(definedKeys, func(k toml.Key) bool {
+			return len(k) == 1 && k[0] == key
+		})
+	}
+
+	if !hasKey("backup-count") {
+		c.BackupCount = dfltBackupCount
+	} else if c.BackupCount <= 0 {
+		return errors.New("'backup-count' must be positive")
+	}
+
 	return c.tidyNodes(definedKeys)
 }
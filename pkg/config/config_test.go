@@ -0,0 +1,828 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/stretchr/testify/assert"
+)
+
+// decodeAndTidy decodes 'data' as a configuration file and tidies it.
+func decodeAndTidy(data string) (*Config, error) {
+	c := &Config{}
+	md, err := toml.Decode(data, c)
+	if err != nil {
+		return nil, err
+	}
+	return c, c.tidy(md.Keys())
+}
+
+const twoVoters = `
+[[node]]
+	id = "1"
+	http-addr = "127.0.0.1:7001"
+	[node.meta]
+		raft-voter = true
+		raft-addr = "127.0.0.1:8001"
+		raft-store = "memory"
+		raft-snapshot-store = "memory"
+
+[[node]]
+	id = "2"
+	http-addr = "127.0.0.1:7002"
+	[node.meta]
+		raft-voter = true
+		raft-addr = "127.0.0.1:8002"
+		raft-store = "memory"
+		raft-snapshot-store = "memory"
+`
+
+func TestTidyNodesVoterCount(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := decodeAndTidy(twoVoters)
+	assert.EqualError(err, "even number of raft voter nodes")
+
+	_, err = decodeAndTidy("allow-unsafe-quorum = false\n" + twoVoters)
+	assert.EqualError(err, "even number of raft voter nodes")
+
+	// the error should be downgraded to a warning.
+	var buf bytes.Buffer
+	old := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+	defer slog.SetDefault(old)
+
+	c, err := decodeAndTidy("allow-unsafe-quorum = true\n" + twoVoters)
+	assert.NoError(err)
+	assert.True(c.AllowUnsafeQuorum)
+	assert.Len(c.Nodes, 2)
+	assert.Contains(buf.String(), "level=WARN")
+	assert.Contains(buf.String(), "even number of raft voter nodes")
+}
+
+func TestTidyNodesNoVoter(t *testing.T) {
+	assert := assert.New(t)
+
+	const noVoter = `
+[[node]]
+	id = "1"
+	http-addr = "127.0.0.1:7001"
+	[node.meta]
+		raft-addr = "127.0.0.1:8001"
+`
+
+	_, err := decodeAndTidy(noVoter)
+	assert.EqualError(err, "no raft voter node")
+
+	_, err = decodeAndTidy("allow-unsafe-quorum = true\n" + noVoter)
+	assert.NoError(err)
+}
+
+func TestTidyNodeTLS(t *testing.T) {
+	assert := assert.New(t)
+
+	const node = `
+[[node]]
+	id = "1"
+	http-addr = "127.0.0.1:7001"
+	%s
+	[node.meta]
+		raft-voter = true
+		raft-addr = "127.0.0.1:8001"
+		raft-store = "memory"
+		raft-snapshot-store = "memory"
+`
+
+	c, err := decodeAndTidy(fmt.Sprintf(node, ""))
+	assert.NoError(err)
+	assert.False(c.Nodes[0].TLSEnabled())
+
+	c, err = decodeAndTidy(fmt.Sprintf(node, `tls-cert-file = "cert.pem"
+	tls-key-file = "key.pem"`))
+	assert.NoError(err)
+	assert.True(c.Nodes[0].TLSEnabled())
+
+	_, err = decodeAndTidy(fmt.Sprintf(node, `tls-cert-file = "cert.pem"`))
+	assert.EqualError(err, "'tls-cert-file' and 'tls-key-file' must be provided together for node '1'")
+
+	_, err = decodeAndTidy(fmt.Sprintf(node, `tls-key-file = "key.pem"`))
+	assert.Error(err)
+}
+
+func TestTidyNodeProductionMode(t *testing.T) {
+	assert := assert.New(t)
+
+	const node = `
+[[node]]
+	id = "1"
+	http-addr = "127.0.0.1:7001"
+	enable-pprof = true
+	%s
+	[node.meta]
+		raft-voter = true
+		raft-addr = "127.0.0.1:8001"
+		raft-store = "memory"
+		raft-snapshot-store = "memory"
+`
+
+	c, err := decodeAndTidy(fmt.Sprintf(node, ""))
+	assert.NoError(err)
+	assert.True(c.Nodes[0].DebugEndpointEnabled(DebugEndpointPprof))
+	assert.True(c.Nodes[0].DebugEndpointEnabled(DebugEndpointConfig))
+
+	// production mode overrides 'enable-pprof'.
+	c, err = decodeAndTidy(fmt.Sprintf(node, "production-mode = true"))
+	assert.NoError(err)
+	assert.False(c.Nodes[0].DebugEndpointEnabled(DebugEndpointPprof))
+	assert.False(c.Nodes[0].DebugEndpointEnabled(DebugEndpointConfig))
+
+	// explicit opt-in.
+	c, err = decodeAndTidy(fmt.Sprintf(node, `production-mode = true
+	debug-endpoints = ["pprof"]`))
+	assert.NoError(err)
+	assert.True(c.Nodes[0].DebugEndpointEnabled(DebugEndpointPprof))
+	assert.False(c.Nodes[0].DebugEndpointEnabled(DebugEndpointConfig))
+
+	_, err = decodeAndTidy(fmt.Sprintf(node, `debug-endpoints = ["vars"]`))
+	assert.EqualError(err, "invalid debug endpoint: vars")
+}
+
+func TestTidyLoggerOutputTo(t *testing.T) {
+	assert := assert.New(t)
+
+	const node = `
+[[node]]
+	id = "1"
+	http-addr = "127.0.0.1:7001"
+	[node.meta]
+		raft-voter = true
+		raft-addr = "127.0.0.1:8001"
+		raft-store = "memory"
+		raft-snapshot-store = "memory"
+	[node.logger]
+		%s
+`
+
+	c, err := decodeAndTidy(fmt.Sprintf(node, ""))
+	assert.NoError(err)
+	assert.Equal("stderr", c.Nodes[0].Logger.OutputTo)
+
+	c, err = decodeAndTidy(fmt.Sprintf(node, `output-to = "StdOut"`))
+	assert.NoError(err)
+	assert.Equal("stdout", c.Nodes[0].Logger.OutputTo)
+
+	_, err = decodeAndTidy(fmt.Sprintf(node, `output-to = "/var/log/xuand.log"`))
+	assert.EqualError(err, "unknown log output: /var/log/xuand.log")
+}
+
+func TestTidyQueryNotice(t *testing.T) {
+	assert := assert.New(t)
+
+	const node = `
+[[node]]
+	id = "1"
+	http-addr = "127.0.0.1:7001"
+	[node.meta]
+		raft-voter = true
+		raft-addr = "127.0.0.1:8001"
+		raft-store = "memory"
+		raft-snapshot-store = "memory"
+	[node.query]
+		notice = %q
+`
+
+	c, err := decodeAndTidy(fmt.Sprintf(node, " maintenance at 02:00 UTC "))
+	assert.NoError(err)
+	assert.Equal("maintenance at 02:00 UTC", c.Nodes[0].Query.Notice)
+
+	_, err = decodeAndTidy(fmt.Sprintf(node, "line 1\nline 2"))
+	assert.ErrorContains(err, "'notice' must be a single line")
+}
+
+func TestTidyQuerySlowThreshold(t *testing.T) {
+	assert := assert.New(t)
+
+	const node = `
+[[node]]
+	id = "1"
+	http-addr = "127.0.0.1:7001"
+	[node.meta]
+		raft-voter = true
+		raft-addr = "127.0.0.1:8001"
+		raft-store = "memory"
+		raft-snapshot-store = "memory"
+	[node.query]
+		%s
+`
+
+	c, err := decodeAndTidy(fmt.Sprintf(node, ""))
+	assert.NoError(err)
+	assert.Zero(c.Nodes[0].Query.SlowThreshold)
+
+	c, err = decodeAndTidy(fmt.Sprintf(node, `slow-threshold = "500ms"`))
+	assert.NoError(err)
+	assert.Equal(500*time.Millisecond, c.Nodes[0].Query.SlowThreshold)
+
+	_, err = decodeAndTidy(fmt.Sprintf(node, `slow-threshold = "-1s"`))
+	assert.EqualError(err, "'slow-threshold' cannot be negative")
+}
+
+func TestTidyQueryMaxQueryBytes(t *testing.T) {
+	assert := assert.New(t)
+
+	const node = `
+[[node]]
+	id = "1"
+	http-addr = "127.0.0.1:7001"
+	[node.meta]
+		raft-voter = true
+		raft-addr = "127.0.0.1:8001"
+		raft-store = "memory"
+		raft-snapshot-store = "memory"
+	[node.query]
+		%s
+`
+
+	c, err := decodeAndTidy(fmt.Sprintf(node, ""))
+	assert.NoError(err)
+	assert.Equal(1<<20, c.Nodes[0].Query.MaxQueryBytes)
+
+	c, err = decodeAndTidy(fmt.Sprintf(node, `max-query-bytes = 4096`))
+	assert.NoError(err)
+	assert.Equal(4096, c.Nodes[0].Query.MaxQueryBytes)
+
+	_, err = decodeAndTidy(fmt.Sprintf(node, `max-query-bytes = 0`))
+	assert.EqualError(err, "'max-query-bytes' must be positive")
+}
+
+func TestTidyQueryRateLimit(t *testing.T) {
+	assert := assert.New(t)
+
+	const node = `
+[[node]]
+	id = "1"
+	http-addr = "127.0.0.1:7001"
+	[node.meta]
+		raft-voter = true
+		raft-addr = "127.0.0.1:8001"
+		raft-store = "memory"
+		raft-snapshot-store = "memory"
+	[node.query]
+		%s
+`
+
+	c, err := decodeAndTidy(fmt.Sprintf(node, ""))
+	assert.NoError(err)
+	assert.Zero(c.Nodes[0].Query.RateLimitPerSec)
+	assert.Equal(10, c.Nodes[0].Query.RateLimitBurst)
+
+	c, err = decodeAndTidy(fmt.Sprintf(node, "rate-limit-per-sec = 2.5\n\t\trate-limit-burst = 5"))
+	assert.NoError(err)
+	assert.Equal(2.5, c.Nodes[0].Query.RateLimitPerSec)
+	assert.Equal(5, c.Nodes[0].Query.RateLimitBurst)
+
+	_, err = decodeAndTidy(fmt.Sprintf(node, `rate-limit-per-sec = -1.0`))
+	assert.EqualError(err, "'rate-limit-per-sec' cannot be negative")
+
+	_, err = decodeAndTidy(fmt.Sprintf(node, `rate-limit-burst = 0`))
+	assert.EqualError(err, "'rate-limit-burst' must be positive")
+}
+
+func TestTidyNodeLabels(t *testing.T) {
+	assert := assert.New(t)
+	defer resetDefaults()
+
+	c, err := decodeAndTidy(`
+[[node]]
+	id = "#default#"
+	[node.labels]
+		zone = "z1"
+		rack = "r1"
+
+[[node]]
+	id = "1"
+	http-addr = "127.0.0.1:7001"
+	[node.labels]
+		rack = "r2"
+	[node.meta]
+		raft-voter = true
+		raft-addr = "127.0.0.1:8001"
+		raft-store = "memory"
+		raft-snapshot-store = "memory"
+
+[[node]]
+	id = "2"
+	http-addr = "127.0.0.1:7002"
+	[node.meta]
+		raft-addr = "127.0.0.1:8002"
+		raft-store = "memory"
+		raft-snapshot-store = "memory"
+`)
+	assert.NoError(err)
+	assert.Equal(map[string]string{"zone": "z1", "rack": "r2"}, c.Nodes[0].Labels)
+	assert.Equal(map[string]string{"zone": "z1", "rack": "r1"}, c.Nodes[1].Labels)
+
+	_, err = decodeAndTidy(`
+[[node]]
+	id = "1"
+	http-addr = "127.0.0.1:7001"
+	labels = { "" = "v" }
+	[node.meta]
+		raft-voter = true
+		raft-addr = "127.0.0.1:8001"
+		raft-store = "memory"
+		raft-snapshot-store = "memory"
+`)
+	assert.EqualError(err, "label key cannot be empty")
+}
+
+func TestTidyHeartbeat(t *testing.T) {
+	assert := assert.New(t)
+
+	const cfg = `
+[[node]]
+	id = "1"
+	http-addr = "127.0.0.1:7001"
+	[node.meta]
+		raft-voter = true
+		raft-addr = "127.0.0.1:8001"
+		raft-store = "memory"
+		raft-snapshot-store = "memory"
+		%s
+`
+
+	c, err := decodeAndTidy(fmt.Sprintf(cfg, ""))
+	assert.NoError(err)
+	assert.Equal(time.Second, c.Nodes[0].Meta.HeartbeatInterval)
+	assert.Equal(10*time.Second, c.Nodes[0].Meta.NodeUnknownAfter)
+	assert.Equal(30*time.Second, c.Nodes[0].Meta.NodeDeadAfter)
+
+	c, err = decodeAndTidy(fmt.Sprintf(cfg, `heartbeat-interval = "5s"
+		node-unknown-after = "1m"
+		node-dead-after = "3m"`))
+	assert.NoError(err)
+	assert.Equal(5*time.Second, c.Nodes[0].Meta.HeartbeatInterval)
+	assert.Equal(time.Minute, c.Nodes[0].Meta.NodeUnknownAfter)
+	assert.Equal(3*time.Minute, c.Nodes[0].Meta.NodeDeadAfter)
+
+	_, err = decodeAndTidy(fmt.Sprintf(cfg, `heartbeat-interval = "0s"`))
+	assert.EqualError(err, "'heartbeat-interval' must be positive")
+
+	_, err = decodeAndTidy(fmt.Sprintf(cfg, `heartbeat-interval = "10s"`))
+	assert.EqualError(err, "'node-unknown-after' must be greater than 'heartbeat-interval'")
+
+	_, err = decodeAndTidy(fmt.Sprintf(cfg, `node-dead-after = "10s"`))
+	assert.EqualError(err, "'node-dead-after' must be greater than 'node-unknown-after'")
+}
+
+func TestTidyRejoinGraceWindow(t *testing.T) {
+	assert := assert.New(t)
+
+	const cfg = `
+[[node]]
+	id = "1"
+	http-addr = "127.0.0.1:7001"
+	[node.meta]
+		raft-voter = true
+		raft-addr = "127.0.0.1:8001"
+		raft-store = "memory"
+		raft-snapshot-store = "memory"
+		%s
+`
+
+	c, err := decodeAndTidy(fmt.Sprintf(cfg, ""))
+	assert.NoError(err)
+	assert.Equal(5*time.Minute, c.Nodes[0].Meta.RejoinGraceWindow)
+
+	c, err = decodeAndTidy(fmt.Sprintf(cfg, `rejoin-grace-window = "0s"`))
+	assert.NoError(err)
+	assert.Zero(c.Nodes[0].Meta.RejoinGraceWindow)
+
+	_, err = decodeAndTidy(fmt.Sprintf(cfg, `rejoin-grace-window = "-1s"`))
+	assert.EqualError(err, "'rejoin-grace-window' cannot be negative")
+}
+
+func TestTidyReadRepairAfter(t *testing.T) {
+	assert := assert.New(t)
+
+	const cfg = `
+[[node]]
+	id = "1"
+	http-addr = "127.0.0.1:7001"
+	[node.meta]
+		raft-voter = true
+		raft-addr = "127.0.0.1:8001"
+		raft-store = "memory"
+		raft-snapshot-store = "memory"
+		%s
+`
+
+	c, err := decodeAndTidy(fmt.Sprintf(cfg, ""))
+	assert.NoError(err)
+	assert.Equal(time.Minute, c.Nodes[0].Meta.ReadRepairAfter)
+
+	c, err = decodeAndTidy(fmt.Sprintf(cfg, `read-repair-after = "0s"`))
+	assert.NoError(err)
+	assert.Zero(c.Nodes[0].Meta.ReadRepairAfter)
+
+	_, err = decodeAndTidy(fmt.Sprintf(cfg, `read-repair-after = "-1m"`))
+	assert.EqualError(err, "'read-repair-after' cannot be negative")
+}
+
+func TestTidyRequestTimeout(t *testing.T) {
+	assert := assert.New(t)
+
+	const cfg = `
+[[node]]
+	id = "1"
+	http-addr = "127.0.0.1:7001"
+	[node.meta]
+		raft-voter = true
+		raft-addr = "127.0.0.1:8001"
+		raft-store = "memory"
+		raft-snapshot-store = "memory"
+		%s
+`
+
+	c, err := decodeAndTidy(fmt.Sprintf(cfg, ""))
+	assert.NoError(err)
+	assert.Equal(10*time.Second, c.Nodes[0].Meta.RequestTimeout)
+
+	c, err = decodeAndTidy(fmt.Sprintf(cfg, `request-timeout = "3s"`))
+	assert.NoError(err)
+	assert.Equal(3*time.Second, c.Nodes[0].Meta.RequestTimeout)
+
+	_, err = decodeAndTidy(fmt.Sprintf(cfg, `request-timeout = "-1s"`))
+	assert.EqualError(err, "'request-timeout' cannot be negative")
+}
+
+func TestTidyLeaderRetry(t *testing.T) {
+	assert := assert.New(t)
+
+	const cfg = `
+[[node]]
+	id = "1"
+	http-addr = "127.0.0.1:7001"
+	[node.meta]
+		raft-voter = true
+		raft-addr = "127.0.0.1:8001"
+		raft-store = "memory"
+		raft-snapshot-store = "memory"
+		%s
+`
+
+	c, err := decodeAndTidy(fmt.Sprintf(cfg, ""))
+	assert.NoError(err)
+	assert.Equal(3, c.Nodes[0].Meta.LeaderRetryAttempts)
+	assert.Equal(100*time.Millisecond, c.Nodes[0].Meta.LeaderRetryDelay)
+
+	c, err = decodeAndTidy(fmt.Sprintf(cfg, `leader-retry-attempts = 1
+		leader-retry-delay = "0s"`))
+	assert.NoError(err)
+	assert.Equal(1, c.Nodes[0].Meta.LeaderRetryAttempts)
+	assert.Zero(c.Nodes[0].Meta.LeaderRetryDelay)
+
+	_, err = decodeAndTidy(fmt.Sprintf(cfg, `leader-retry-attempts = 0`))
+	assert.EqualError(err, "'leader-retry-attempts' must be positive")
+
+	_, err = decodeAndTidy(fmt.Sprintf(cfg, `leader-retry-delay = "-1s"`))
+	assert.EqualError(err, "'leader-retry-delay' cannot be negative")
+}
+
+func TestTidyNamePattern(t *testing.T) {
+	assert := assert.New(t)
+
+	const cfg = `
+[[node]]
+	id = "1"
+	http-addr = "127.0.0.1:7001"
+	[node.meta]
+		raft-voter = true
+		raft-addr = "127.0.0.1:8001"
+		raft-store = "memory"
+		raft-snapshot-store = "memory"
+		%s
+`
+
+	c, err := decodeAndTidy(fmt.Sprintf(cfg, ""))
+	assert.NoError(err)
+	assert.Equal(DefaultNamePattern, c.Nodes[0].Meta.NamePattern)
+
+	c, err = decodeAndTidy(fmt.Sprintf(cfg, `name-pattern = '^[a-z]+$'`))
+	assert.NoError(err)
+	assert.Equal(`^[a-z]+$`, c.Nodes[0].Meta.NamePattern)
+
+	_, err = decodeAndTidy(fmt.Sprintf(cfg, `name-pattern = '^[a-z+$'`))
+	assert.ErrorContains(err, "invalid 'name-pattern': ")
+}
+
+func TestTidyPasswordDenyList(t *testing.T) {
+	assert := assert.New(t)
+	defer resetDefaults()
+
+	c, err := decodeAndTidy(`
+[[node]]
+	id = "#default#"
+	[node.meta]
+		password-deny-list = "/etc/xuandb/deny.txt"
+		password-deny-list-ignore-case = true
+
+[[node]]
+	id = "1"
+	http-addr = "127.0.0.1:7001"
+	[node.meta]
+		raft-voter = true
+		raft-addr = "127.0.0.1:8001"
+		raft-store = "memory"
+		raft-snapshot-store = "memory"
+
+[[node]]
+	id = "2"
+	http-addr = "127.0.0.1:7002"
+	[node.meta]
+		raft-addr = "127.0.0.1:8002"
+		password-deny-list = "/tmp/deny.txt"
+		password-deny-list-ignore-case = false
+`)
+	assert.NoError(err)
+	assert.Equal("/etc/xuandb/deny.txt", c.Nodes[0].Meta.PasswordDenyList)
+	assert.True(c.Nodes[0].Meta.PasswordDenyListIgnoreCase)
+	assert.Equal("/tmp/deny.txt", c.Nodes[1].Meta.PasswordDenyList)
+	assert.False(c.Nodes[1].Meta.PasswordDenyListIgnoreCase)
+}
+
+func TestTidyPasswordPolicy(t *testing.T) {
+	assert := assert.New(t)
+	defer resetDefaults()
+
+	c, err := decodeAndTidy(`
+[[node]]
+	id = "#default#"
+	[node.meta]
+		password-min-length = 8
+		password-require-digit = true
+
+[[node]]
+	id = "1"
+	http-addr = "127.0.0.1:7001"
+	[node.meta]
+		raft-voter = true
+		raft-addr = "127.0.0.1:8001"
+		raft-store = "memory"
+		raft-snapshot-store = "memory"
+
+[[node]]
+	id = "2"
+	http-addr = "127.0.0.1:7002"
+	[node.meta]
+		raft-addr = "127.0.0.1:8002"
+		password-require-digit = false
+		password-require-symbol = true
+`)
+	assert.NoError(err)
+	assert.Equal(PasswordPolicy{MinLength: 8, RequireDigit: true}, c.Nodes[0].Meta.PasswordPolicy)
+	assert.Equal(PasswordPolicy{MinLength: 8, RequireSymbol: true}, c.Nodes[1].Meta.PasswordPolicy)
+
+	_, err = decodeAndTidy(`
+[[node]]
+	id = "1"
+	http-addr = "127.0.0.1:7001"
+	[node.meta]
+		raft-voter = true
+		raft-addr = "127.0.0.1:8001"
+		password-min-length = -1
+`)
+	assert.EqualError(err, "'password-min-length' cannot be negative")
+}
+
+func TestTidyShutdownTimeout(t *testing.T) {
+	assert := assert.New(t)
+
+	const cfg = `
+[[node]]
+	id = "1"
+	http-addr = "127.0.0.1:7001"
+	%s
+	[node.meta]
+		raft-voter = true
+		raft-addr = "127.0.0.1:8001"
+		raft-store = "memory"
+		raft-snapshot-store = "memory"
+`
+
+	c, err := decodeAndTidy(fmt.Sprintf(cfg, ""))
+	assert.NoError(err)
+	assert.Equal(10*time.Second, c.Nodes[0].ShutdownTimeout)
+
+	c, err = decodeAndTidy(fmt.Sprintf(cfg, `shutdown-timeout = "1m"`))
+	assert.NoError(err)
+	assert.Equal(time.Minute, c.Nodes[0].ShutdownTimeout)
+
+	_, err = decodeAndTidy(fmt.Sprintf(cfg, `shutdown-timeout = "0s"`))
+	assert.EqualError(err, "'shutdown-timeout' must be positive")
+}
+
+func TestTidyRequireTLSForAuth(t *testing.T) {
+	assert := assert.New(t)
+	defer resetDefaults()
+
+	const cfg = `
+[[node]]
+	id = "#default#"
+	%s
+[[node]]
+	id = "1"
+	http-addr = "127.0.0.1:7001"
+	%s
+	[node.meta]
+		raft-voter = true
+		raft-addr = "127.0.0.1:8001"
+		raft-store = "memory"
+		raft-snapshot-store = "memory"
+`
+
+	c, err := decodeAndTidy(fmt.Sprintf(cfg, "", ""))
+	assert.NoError(err)
+	assert.False(c.Nodes[0].RequireTLSForAuth)
+	assert.False(c.Nodes[0].AllowLoopbackPlaintextAuth)
+
+	c, err = decodeAndTidy(fmt.Sprintf(cfg,
+		"require-tls-for-auth = true\n\tallow-loopback-plaintext-auth = true",
+		"tls-cert-file = \"cert.pem\"\n\ttls-key-file = \"key.pem\"",
+	))
+	assert.NoError(err)
+	assert.True(c.Nodes[0].RequireTLSForAuth)
+	assert.True(c.Nodes[0].AllowLoopbackPlaintextAuth)
+
+	_, err = decodeAndTidy(fmt.Sprintf(cfg, "", "require-tls-for-auth = true"))
+	assert.EqualError(err, "'require-tls-for-auth' requires TLS to be enabled for node '1'")
+}
+
+func TestTidyGzipResponses(t *testing.T) {
+	assert := assert.New(t)
+	defer resetDefaults()
+
+	const cfg = `
+[[node]]
+	id = "#default#"
+	%s
+[[node]]
+	id = "1"
+	http-addr = "127.0.0.1:7001"
+	%s
+	[node.meta]
+		raft-voter = true
+		raft-addr = "127.0.0.1:8001"
+		raft-store = "memory"
+		raft-snapshot-store = "memory"
+`
+
+	c, err := decodeAndTidy(fmt.Sprintf(cfg, "", ""))
+	assert.NoError(err)
+	assert.False(c.Nodes[0].GzipResponses)
+
+	// inherited from the default node.
+	c, err = decodeAndTidy(fmt.Sprintf(cfg, "gzip-responses = true", ""))
+	assert.NoError(err)
+	assert.True(c.Nodes[0].GzipResponses)
+
+	resetDefaults()
+	c, err = decodeAndTidy(fmt.Sprintf(cfg, "gzip-responses = true", "gzip-responses = false"))
+	assert.NoError(err)
+	assert.False(c.Nodes[0].GzipResponses)
+}
+
+func TestTidyExpiryCheckInterval(t *testing.T) {
+	assert := assert.New(t)
+	defer resetDefaults()
+
+	const cfg = `
+[[node]]
+	id = "1"
+	http-addr = "127.0.0.1:7001"
+	[node.meta]
+		raft-voter = true
+		raft-addr = "127.0.0.1:8001"
+		raft-store = "memory"
+		raft-snapshot-store = "memory"
+	[node.data]
+		%s
+`
+
+	c, err := decodeAndTidy(fmt.Sprintf(cfg, ""))
+	assert.NoError(err)
+	assert.Equal(time.Minute, c.Nodes[0].Data.ExpiryCheckInterval)
+
+	c, err = decodeAndTidy(fmt.Sprintf(cfg, `expiry-check-interval = "1h"`))
+	assert.NoError(err)
+	assert.Equal(time.Hour, c.Nodes[0].Data.ExpiryCheckInterval)
+
+	_, err = decodeAndTidy(fmt.Sprintf(cfg, `expiry-check-interval = "-1s"`))
+	assert.EqualError(err, "'expiry-check-interval' must be positive")
+}
+
+func TestTidyNodeAddr(t *testing.T) {
+	assert := assert.New(t)
+	defer resetDefaults()
+
+	const cfg = `
+[[node]]
+	id = "1"
+	http-addr = "%s"
+	[node.meta]
+		raft-voter = true
+		raft-addr = "%s"
+		raft-store = "memory"
+		raft-snapshot-store = "memory"
+`
+
+	cases := []struct {
+		httpAddr string
+		raftAddr string
+		err      string
+	}{
+		{"[::]:8088", "[::1]:8089", ""},
+		{"example.com:8088", "node-1.example.com:8089", ""},
+		{"127.0.0.1:8088", "127.0.0.1:8089", ""},
+		{"example.com", "127.0.0.1:8089", "invalid 'http-addr': address example.com: missing port in address"},
+		{"127.0.0.1:8088", "example.com", "invalid 'raft-addr': address example.com: missing port in address"},
+		{"example.com:0", "127.0.0.1:8089", "port of 'http-addr' cannot be 0"},
+		{"example.com:65536", "127.0.0.1:8089", "invalid port of 'http-addr': 65536"},
+		{"-bad-.com:8088", "127.0.0.1:8089", `invalid host of 'http-addr': "-bad-.com"`},
+		{":8088", "127.0.0.1:8089", `invalid host of 'http-addr': ""`},
+	}
+
+	for _, c := range cases {
+		_, err := decodeAndTidy(fmt.Sprintf(cfg, c.httpAddr, c.raftAddr))
+		if c.err == "" {
+			assert.NoError(err, c.httpAddr)
+		} else {
+			assert.EqualError(err, c.err, c.httpAddr)
+		}
+	}
+
+	// the default node can only use unspecified IPs, but host names are
+	// allowed.
+	const dflt = `
+[[node]]
+	id = "#default#"
+	http-addr = "%s"
+` + cfg
+
+	_, err := decodeAndTidy(fmt.Sprintf(dflt, "10.0.0.1:8088", "127.0.0.1:8088", "127.0.0.1:8089"))
+	assert.EqualError(err, "IP of 'http-addr' of the default node can only be '0.0.0.0' or '[::]'")
+
+	_, err = decodeAndTidy(fmt.Sprintf(dflt, "[::]:8088", "127.0.0.1:8088", "127.0.0.1:8089"))
+	assert.NoError(err)
+
+	_, err = decodeAndTidy(fmt.Sprintf(dflt, "localhost:8088", "127.0.0.1:8088", "127.0.0.1:8089"))
+	assert.NoError(err)
+}
+
+func TestNodeConfigItems(t *testing.T) {
+	assert := assert.New(t)
+
+	nc := &NodeConfig{
+		ID:         "1",
+		TLSKeyFile: "/etc/xuandb/node.key",
+		Logger:     &LoggerConfig{Level: slog.LevelDebug},
+		Meta: &MetaConfig{
+			RaftAddr:          "127.0.0.1:8001",
+			HeartbeatInterval: 2 * time.Second,
+			PasswordPolicy:    PasswordPolicy{MinLength: 8},
+		},
+		DebugEndpoints: []string{"pprof", "config"},
+		Labels:         map[string]string{"zone": "a", "rack": "r1"},
+	}
+
+	items := make(map[string]string)
+	var keys []string
+	for _, item := range nc.Items() {
+		items[item.Key] = item.Value
+		keys = append(keys, item.Key)
+	}
+
+	assert.Equal("1", items["id"])
+	assert.Equal(redactedValue, items["tls-key-file"])
+	assert.Equal("", items["tls-cert-file"])
+	assert.Equal("DEBUG", items["logger.level"])
+	assert.Equal("127.0.0.1:8001", items["meta.raft-addr"])
+	assert.Equal("2s", items["meta.heartbeat-interval"])
+	assert.Equal("8", items["meta.password-min-length"])
+	assert.Equal("pprof,config", items["debug-endpoints"])
+	assert.Equal("r1", items["labels.rack"])
+	assert.Equal("a", items["labels.zone"])
+	assert.NotContains(keys, "data.data-dir")
+
+	// the items are in the order they are defined.
+	assert.Equal("id", keys[0])
+	assert.Less(slices.Index(keys, "labels.rack"), slices.Index(keys, "labels.zone"))
+
+	// the configuration is not modified.
+	assert.Equal("/etc/xuandb/node.key", nc.TLSKeyFile)
+}
@@ -0,0 +1,246 @@
+package config
+
+import (
+	"encoding"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/localvar/xuandb/pkg/utils"
+)
+
+// envPrefix is the prefix of environment variables that override
+// configuration items.
+const envPrefix = "XUANDB_"
+
+// envName returns the name of the environment variable for configuration
+// item 'key' in 'section', e.g. 'XUANDB_META_RAFT_VOTER' for 'raft-voter' in
+// section 'meta'. 'section' is empty for items of the node itself.
+func envName(section, key string) string {
+	name := strings.ToUpper(strings.ReplaceAll(key, "-", "_"))
+	if section != "" {
+		name = strings.ToUpper(section) + "_" + name
+	}
+	return envPrefix + name
+}
+
+// tomlName returns the toml key of a struct field, or an empty string if the
+// field has no toml key.
+func tomlName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("toml"), ",")
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+var (
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+	durationType        = reflect.TypeFor[time.Duration]()
+)
+
+// setFieldFromEnv sets the value of field 'v' from string 's'.
+func setFieldFromEnv(v reflect.Value, s string) error {
+	if v.Addr().Type().Implements(textUnmarshalerType) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+
+	if v.Type() == durationType {
+		d, err := utils.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 0, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(s, 0, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(u)
+	default:
+		return fmt.Errorf("unsupported type: %s", v.Type())
+	}
+
+	return nil
+}
+
+// isEmbeddedStruct returns whether 'f' is an embedded struct without a toml
+// key, its fields are at the same level as the other fields.
+func isEmbeddedStruct(f reflect.StructField) bool {
+	return f.Anonymous && f.Type.Kind() == reflect.Struct && tomlName(f) == ""
+}
+
+// applyEnvToStruct applies environment variables to the fields of struct 'v'
+// in 'section', and returns the keys of the fields which are overridden.
+func applyEnvToStruct(v reflect.Value, section string) ([]string, error) {
+	var keys []string
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if isEmbeddedStruct(f) {
+			ks, err := applyEnvToStruct(v.Field(i), section)
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, ks...)
+			continue
+		}
+
+		name := tomlName(f)
+		if name == "" || f.Type.Kind() == reflect.Pointer {
+			continue
+		}
+
+		// the 'id' of a node cannot be overridden.
+		if section == "" && name == "id" {
+			continue
+		}
+
+		s, ok := os.LookupEnv(envName(section, name))
+		if !ok {
+			continue
+		}
+
+		if err := setFieldFromEnv(v.Field(i), s); err != nil {
+			return nil, fmt.Errorf("invalid value of environment variable %s: %w", envName(section, name), err)
+		}
+
+		if section != "" {
+			name = section + "." + name
+		}
+		keys = append(keys, name)
+	}
+
+	return keys, nil
+}
+
+// hasEnvForSection returns whether there is any environment variable for the
+// fields of struct type 't' in 'section'.
+func hasEnvForSection(t reflect.Type, section string) bool {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if isEmbeddedStruct(f) {
+			if hasEnvForSection(f.Type, section) {
+				return true
+			}
+		} else if name := tomlName(f); name != "" {
+			if _, ok := os.LookupEnv(envName(section, name)); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// applyEnv overrides the configuration items of node 'nc' with environment
+// variables named 'XUANDB_<SECTION>_<KEY>', where <SECTION> is the name of
+// the section in upper case, e.g. 'META', and is omitted for items of the
+// node itself; <KEY> is the name of the item in upper case with dashes
+// replaced by underscores. For example, 'XUANDB_META_RAFT_VOTER' overrides
+// 'raft-voter' in the 'meta' section, and 'XUANDB_HTTP_ADDR' overrides
+// 'http-addr' of the node.
+//
+// The 'id' of the node cannot be overridden. A section is created if it does
+// not exist in the configuration file but there is an environment variable
+// for it.
+//
+// It returns the keys of the overridden items.
+func applyEnv(nc *NodeConfig) ([]string, error) {
+	v := reflect.ValueOf(nc).Elem()
+	t := v.Type()
+
+	keys, err := applyEnvToStruct(v, "")
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		section := tomlName(f)
+		if section == "" || f.Type.Kind() != reflect.Pointer {
+			continue
+		}
+
+		fv := v.Field(i)
+		if fv.IsNil() {
+			if !hasEnvForSection(f.Type.Elem(), section) {
+				continue
+			}
+			fv.Set(reflect.New(f.Type.Elem()))
+			keys = append(keys, section)
+		}
+
+		ks, err := applyEnvToStruct(fv.Elem(), section)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, ks...)
+	}
+
+	return keys, nil
+}
+
+// applyEnvToNode applies environment variables to the configuration of node
+// 'nodeID' in 'c', and returns 'definedKeys' with the overridden keys added,
+// so that they are treated as defined in the configuration file.
+func (c *Config) applyEnvToNode(nodeID string, definedKeys []toml.Key) ([]toml.Key, error) {
+	idx := -1
+	for i, nc := range c.Nodes {
+		if nc.ID == nodeID {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		return definedKeys, nil
+	}
+
+	keys, err := applyEnv(c.Nodes[idx])
+	if err != nil || len(keys) == 0 {
+		return definedKeys, err
+	}
+
+	// the keys of a node follow the '[[node]]' key of the node, so insert the
+	// overridden keys right after the 'idx'th '[[node]]' key.
+	pos := 0
+	for n := -1; pos < len(definedKeys); pos++ {
+		if k := definedKeys[pos]; len(k) == 1 && k[0] == "node" {
+			if n++; n == idx {
+				pos++
+				break
+			}
+		}
+	}
+
+	result := make([]toml.Key, 0, len(definedKeys)+len(keys))
+	result = append(result, definedKeys[:pos]...)
+	for _, k := range keys {
+		result = append(result, append(toml.Key{"node"}, strings.Split(k, ".")...))
+	}
+	result = append(result, definedKeys[pos:]...)
+
+	return result, nil
+}
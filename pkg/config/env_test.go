@@ -0,0 +1,89 @@
+package config
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const envCfg = `
+[[node]]
+	id = "1"
+	http-addr = "127.0.0.1:7001"
+	[node.meta]
+		raft-voter = false
+		raft-addr = "127.0.0.1:8001"
+		raft-store = "memory"
+		raft-snapshot-store = "memory"
+[[node]]
+	id = "2"
+	http-addr = "127.0.0.1:7002"
+	[node.meta]
+		raft-voter = true
+		raft-addr = "127.0.0.1:8002"
+		raft-store = "memory"
+		raft-snapshot-store = "memory"
+[[node]]
+	id = "3"
+	http-addr = "127.0.0.1:7003"
+	[node.meta]
+		raft-voter = true
+		raft-addr = "127.0.0.1:8003"
+		raft-store = "memory"
+		raft-snapshot-store = "memory"
+`
+
+func TestEnvOverride(t *testing.T) {
+	assert := assert.New(t)
+
+	defer func() {
+		confPath, loadedPath, allCfg = "", "", &Config{}
+		curNodeCfg.Store(nil)
+	}()
+
+	confPath = filepath.Join(t.TempDir(), "xuandb.toml")
+	require.NoError(t, os.WriteFile(confPath, []byte(envCfg), 0600))
+
+	// 2 voters, the configuration is invalid.
+	assert.Error(Load("1", false))
+
+	// environment variables take precedence over the configuration file.
+	t.Setenv("XUANDB_META_RAFT_VOTER", "true")
+	t.Setenv("XUANDB_LOGGER_LEVEL", "debug")
+	t.Setenv("XUANDB_HTTP_ADDR", "127.0.0.1:9001")
+	t.Setenv("XUANDB_ID", "4")
+	t.Setenv("XUANDB_META_PASSWORD_MIN_LENGTH", "12")
+	t.Setenv("XUANDB_META_PASSWORD_REQUIRE_DIGIT", "true")
+	require.NoError(t, Load("1", false))
+
+	nc := CurrentNode()
+	assert.Equal("1", nc.ID)
+	assert.True(nc.Meta.RaftVoter)
+	assert.Equal("127.0.0.1:9001", nc.HTTPAddr)
+	assert.Equal(slog.LevelDebug, nc.Logger.Level)
+
+	// the items of the embedded password policy.
+	assert.Equal(12, nc.Meta.MinLength)
+	assert.True(nc.Meta.RequireDigit)
+
+	// only the current node is overridden.
+	for _, n := range Nodes() {
+		if n.ID != "1" {
+			assert.NotEqual(slog.LevelDebug, n.Logger.Level)
+			assert.NotEqual("127.0.0.1:9001", n.HTTPAddr)
+		}
+	}
+
+	// the overridden items are validated.
+	t.Setenv("XUANDB_META_PASSWORD_MIN_LENGTH", "-1")
+	assert.EqualError(Load("1", false), "'password-min-length' cannot be negative")
+	t.Setenv("XUANDB_META_PASSWORD_MIN_LENGTH", "12")
+
+	// invalid value.
+	t.Setenv("XUANDB_META_RAFT_VOTER", "yes")
+	assert.Error(Load("1", false))
+}
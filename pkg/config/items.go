@@ -0,0 +1,96 @@
+package config
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Item is a configuration item of a node, Key is the toml key of the item
+// qualified by its section, like 'meta.raft-addr'.
+type Item struct {
+	Key   string
+	Value string
+}
+
+var textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+
+// formatValue formats the value of a configuration item.
+func formatValue(v reflect.Value) string {
+	if v.Type().Implements(textMarshalerType) {
+		data, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		if err == nil {
+			return string(data)
+		}
+	}
+	if v.Type() == durationType {
+		return v.Interface().(fmt.Stringer).String()
+	}
+	if v.Kind() == reflect.Slice {
+		ss := make([]string, v.Len())
+		for i := range ss {
+			ss[i] = formatValue(v.Index(i))
+		}
+		return strings.Join(ss, ",")
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// collectItems appends the items of struct 'v' to 'items', the keys are
+// prefixed with 'prefix'.
+func collectItems(prefix string, v reflect.Value, items []Item) []Item {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		fv := v.Field(i)
+		if f.Anonymous && fv.Kind() == reflect.Struct {
+			items = collectItems(prefix, fv, items)
+			continue
+		}
+
+		name := tomlName(f)
+		if name == "" {
+			continue
+		}
+		key := prefix + name
+
+		switch fv.Kind() {
+		case reflect.Pointer:
+			if !fv.IsNil() && fv.Elem().Kind() == reflect.Struct {
+				items = collectItems(key+".", fv.Elem(), items)
+			}
+		case reflect.Map:
+			keys := make([]string, 0, fv.Len())
+			for _, k := range fv.MapKeys() {
+				keys = append(keys, k.String())
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				val := formatValue(fv.MapIndex(reflect.ValueOf(k)))
+				items = append(items, Item{Key: key + "." + k, Value: val})
+			}
+		case reflect.String:
+			val := fv.String()
+			if isSecret(name) && val != "" {
+				val = redactedValue
+			}
+			items = append(items, Item{Key: key, Value: val})
+		default:
+			items = append(items, Item{Key: key, Value: formatValue(fv)})
+		}
+	}
+	return items
+}
+
+// Items returns the configuration items of the node in the order they are
+// defined, the items of the sections are prefixed with the section names,
+// like 'meta.raft-addr'. Sensitive items are redacted.
+func (nc *NodeConfig) Items() []Item {
+	return collectItems("", reflect.ValueOf(nc).Elem(), nil)
+}
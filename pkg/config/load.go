@@ -1,17 +1,24 @@
 package config
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"os"
 	"path/filepath"
+	"reflect"
 	"runtime"
+	"strconv"
 	"strings"
+	"sync/atomic"
 
 	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
 )
 
 // common command line arguments for both client and server.
@@ -34,8 +41,16 @@ func ShowVersion() bool {
 var allCfg = &Config{}
 
 // curNodeCfg contains configuration for current node. It will be nil if
-// current node is not specified.
-var curNodeCfg *NodeConfig
+// current node is not specified. It is an atomic pointer because it is
+// replaced when the configuration is reloaded.
+var curNodeCfg atomic.Pointer[NodeConfig]
+
+// loadedPath is the path of the loaded configuration file.
+var loadedPath string
+
+// backupOnLoad is whether the configurations are backed up when they are
+// loaded by Load and Reload, see Load.
+var backupOnLoad bool
 
 // ClusterName returns the name of the cluster.
 func ClusterName() string {
@@ -72,12 +87,12 @@ func NodeByID(id string) *NodeConfig {
 // NodeID returns the ID of current node. It panics if current node is not
 // specified.
 func NodeID() string {
-	return curNodeCfg.ID
+	return curNodeCfg.Load().ID
 }
 
 // CurrentNode returns configuration of the current node.
 func CurrentNode() *NodeConfig {
-	return curNodeCfg
+	return curNodeCfg.Load()
 }
 
 // getConfigPath returns the path of the configuration file.
@@ -125,48 +140,266 @@ func getConfigPath() string {
 	return ""
 }
 
+// load loads configurations from file 'path', overrides the configuration of
+// node 'nodeID' with environment variables, set missing items with default
+// values, and makes necessary normalization and validation.
+func load(path, nodeID string) (*Config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	c := &Config{}
+	md, err := toml.NewDecoder(f).Decode(c)
+	if err != nil {
+		return nil, err
+	}
+
+	if dir := os.Getenv("XUANDB_CONFIG_BACKUP_DIR"); dir != "" {
+		c.BackupDir = dir
+	}
+
+	keys, err := c.applyEnvToNode(nodeID, md.Keys())
+	if err != nil {
+		return nil, err
+	}
+
+	resetDefaults()
+	if err := c.tidy(keys); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
 // Load loads configurations from file, set missing items with default values,
-// and makes necessary normalization and validation.
+// and makes necessary normalization and validation. If 'backup' is true and
+// 'backup-dir' is set, or overridden by environment variable
+// 'XUANDB_CONFIG_BACKUP_DIR', the normalized configurations are written to a
+// new backup file in it, and so are they on every Reload. Only the daemon
+// should back up the configurations, not tools which just read them.
 //
 // If nodeID is specified (i.e. not empty), it set the corresponding node
-// configuration as the current node configuration.
-func Load(nodeID string) error {
+// configuration as the current node configuration, and configuration items of
+// the node can be overridden by environment variables, which take precedence
+// over the configuration file. Refer 'applyEnv' for the names of the
+// environment variables.
+func Load(nodeID string, backup bool) error {
 	path := getConfigPath()
 	if path == "" {
 		return errors.New("no available configuration file")
 	}
 
-	f, err := os.Open(path)
+	c, err := load(path, nodeID)
 	if err != nil {
 		return err
 	}
-	defer f.Close()
 
-	md, err := toml.NewDecoder(f).Decode(allCfg)
+	allCfg, loadedPath, backupOnLoad = c, path, backup
+	c.backupIfEnabled()
+
+	if nodeID != "" {
+		nc := NodeByID(nodeID)
+		if nc == nil {
+			return fmt.Errorf("missing configuration for node %q", nodeID)
+		}
+		curNodeCfg.Store(nc)
+	}
+
+	return nil
+}
+
+// backupIfEnabled backs up configurations 'c' if it is enabled by Load, a
+// failure is logged but not returned, as it does not affect the service.
+func (c *Config) backupIfEnabled() {
+	if !backupOnLoad || c.BackupDir == "" {
+		return
+	}
+	if err := c.backup(); err != nil {
+		slog.Warn(
+			"failed to backup configuration",
+			slog.String("dir", c.BackupDir),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// warnImmutableChanges logs a warning for each immutable configuration item
+// which is different between 'old' and 'nc'.
+func warnImmutableChanges(old, nc *NodeConfig) {
+	warn := func(key string) {
+		slog.Warn(
+			"configuration item cannot be changed at run time, ignored",
+			slog.String("key", key),
+		)
+	}
+
+	if old.DomainName != nc.DomainName {
+		warn("domain-name")
+	}
+	if old.HTTPAddr != nc.HTTPAddr {
+		warn("http-addr")
+	}
+	if old.TLSCertFile != nc.TLSCertFile ||
+		old.TLSKeyFile != nc.TLSKeyFile ||
+		old.TLSCAFile != nc.TLSCAFile {
+		warn("tls-cert-file/tls-key-file/tls-ca-file")
+	}
+	if !reflect.DeepEqual(old.Labels, nc.Labels) {
+		warn("labels")
+	}
+	if old.BindBeforeBootstrap != nc.BindBeforeBootstrap {
+		warn("bind-before-bootstrap")
+	}
+	if !reflect.DeepEqual(old.Meta, nc.Meta) {
+		warn("meta")
+	}
+	if !reflect.DeepEqual(old.Data, nc.Data) {
+		warn("data")
+	}
+	if !reflect.DeepEqual(old.Query, nc.Query) {
+		warn("query")
+	}
+}
+
+// Reload reloads configurations from the file loaded by Load, and applies the
+// items which are safe to change at run time, that is, 'logger',
+// 'enable-pprof', 'production-mode', 'debug-endpoints', 'shutdown-timeout',
+// 'require-tls-for-auth', 'allow-loopback-plaintext-auth' and
+// 'gzip-responses', to the current node. Changes to other items of the
+// current node are ignored with a warning.
+//
+// It returns the newly loaded configurations, note the return value of All,
+// Nodes and NodeByID does not change.
+func Reload() (*Config, error) {
+	if loadedPath == "" {
+		return nil, errors.New("configuration has not been loaded")
+	}
+
+	old := CurrentNode()
+	nodeID := ""
+	if old != nil {
+		nodeID = old.ID
+	}
+
+	c, err := load(loadedPath, nodeID)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	c.backupIfEnabled()
 
-	if err := allCfg.tidy(md.Keys()); err != nil {
-		return err
+	if old == nil {
+		return c, nil
 	}
 
-	if nodeID != "" {
-		if curNodeCfg = NodeByID(nodeID); curNodeCfg == nil {
-			return fmt.Errorf("missing configuration for node %q", nodeID)
+	var nc *NodeConfig
+	for _, n := range c.Nodes {
+		if n.ID == old.ID {
+			nc = n
+			break
 		}
 	}
+	if nc == nil {
+		return nil, fmt.Errorf("missing configuration for node %q", old.ID)
+	}
 
-	return nil
+	warnImmutableChanges(old, nc)
+
+	cur := *old
+	cur.Logger = nc.Logger
+	cur.EnablePprof = nc.EnablePprof
+	cur.ProductionMode = nc.ProductionMode
+	cur.DebugEndpoints = nc.DebugEndpoints
+	cur.ShutdownTimeout = nc.ShutdownTimeout
+	cur.RequireTLSForAuth = nc.RequireTLSForAuth
+	cur.AllowLoopbackPlaintextAuth = nc.AllowLoopbackPlaintextAuth
+	cur.GzipResponses = nc.GzipResponses
+	curNodeCfg.Store(&cur)
+
+	return c, nil
+}
+
+// etag returns the entity tag of configurations 'c' in 'format', it is based
+// on the hash of the JSON encoding of 'c', which is streamed to the hasher to
+// avoid buffering the whole encoding.
+func etag(c *Config, format string) (string, error) {
+	h := sha256.New()
+	if err := json.NewEncoder(h).Encode(c); err != nil {
+		return "", err
+	}
+	return `"` + hex.EncodeToString(h.Sum(nil)[:16]) + "-" + format + `"`, nil
+}
+
+// etagMatch returns whether 'tag' matches the 'If-None-Match' header 'inm'.
+func etagMatch(inm, tag string) bool {
+	for _, t := range strings.Split(inm, ",") {
+		t = strings.TrimSpace(t)
+		if t == "*" || strings.TrimPrefix(t, "W/") == tag {
+			return true
+		}
+	}
+	return false
 }
 
-// HandleList is an http handler to expose configurations.
+// HandleList is an http handler to expose configurations in TOML, or in JSON
+// or YAML according to the 'Accept' header. The response is written by the
+// encoders directly, its length is unknown in advance, so 'Content-Length' is
+// not set. It sets the 'ETag' header so that clients can cache the result and
+// detect changes, and responds 304 if the 'If-None-Match' header matches.
+// Sensitive items are redacted unless the 'redact' query parameter is false.
 func HandleList(w http.ResponseWriter, r *http.Request) {
-	if strings.EqualFold(r.Header.Get("Accept"), "application/json") {
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(allCfg)
-	} else {
-		w.Header().Set("Content-Type", "application/toml")
-		toml.NewEncoder(w).Encode(allCfg)
+	c := allCfg
+
+	redact := true
+	if v := r.URL.Query().Get("redact"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			http.Error(w, "invalid value of 'redact': "+v, http.StatusBadRequest)
+			return
+		}
+		redact = b
+	}
+
+	if redact {
+		rc, err := redacted(c)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		c = rc
+	}
+
+	format, contentType := "toml", "application/toml"
+	switch accept := r.Header.Get("Accept"); {
+	case strings.EqualFold(accept, "application/json"):
+		format, contentType = "json", "application/json"
+	case strings.EqualFold(accept, "application/yaml"):
+		format, contentType = "yaml", "application/yaml"
+	}
+
+	tag, err := etag(c, format)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("ETag", tag)
+	w.Header().Set("Vary", "Accept")
+	if inm := r.Header.Get("If-None-Match"); inm != "" && etagMatch(inm, tag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	switch format {
+	case "json":
+		json.NewEncoder(w).Encode(c)
+	case "yaml":
+		enc := yaml.NewEncoder(w)
+		enc.Encode(c)
+		enc.Close()
+	default:
+		toml.NewEncoder(w).Encode(c)
 	}
 }
@@ -0,0 +1,236 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+const reloadCfg = `
+[[node]]
+	id = "1"
+	http-addr = "127.0.0.1:7001"
+	enable-pprof = %v
+	[node.logger]
+		level = "%s"
+	[node.meta]
+		raft-voter = true
+		raft-addr = "%s"
+		raft-store = "memory"
+		raft-snapshot-store = "memory"
+`
+
+func TestReload(t *testing.T) {
+	assert := assert.New(t)
+
+	defer func() {
+		confPath, loadedPath, allCfg = "", "", &Config{}
+		curNodeCfg.Store(nil)
+	}()
+
+	confPath = filepath.Join(t.TempDir(), "xuandb.toml")
+	data := fmt.Sprintf(reloadCfg, false, "INFO", "127.0.0.1:8001")
+	require.NoError(t, os.WriteFile(confPath, []byte(data), 0600))
+	require.NoError(t, Load("1", false))
+
+	old := CurrentNode()
+	assert.Equal(slog.LevelInfo, old.Logger.Level)
+	assert.False(old.EnablePprof)
+
+	// change the log level, pprof and the raft address.
+	data = fmt.Sprintf(reloadCfg, true, "DEBUG", "127.0.0.1:8002")
+	require.NoError(t, os.WriteFile(confPath, []byte(data), 0600))
+
+	var buf bytes.Buffer
+	oldLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+	defer slog.SetDefault(oldLogger)
+
+	c, err := Reload()
+	require.NoError(t, err)
+	assert.Equal("127.0.0.1:8002", c.Nodes[0].Meta.RaftAddr)
+
+	nc := CurrentNode()
+	assert.Equal(slog.LevelDebug, nc.Logger.Level)
+	assert.True(nc.EnablePprof)
+	assert.Equal("127.0.0.1:8001", nc.Meta.RaftAddr)
+	assert.Contains(buf.String(), "key=meta")
+
+	// the old configuration is not modified.
+	assert.Equal(slog.LevelInfo, old.Logger.Level)
+	assert.False(old.EnablePprof)
+
+	// invalid configuration.
+	require.NoError(t, os.WriteFile(confPath, []byte("invalid"), 0600))
+	_, err = Reload()
+	assert.Error(err)
+	assert.Equal(slog.LevelDebug, CurrentNode().Logger.Level)
+}
+
+func TestHandleListETag(t *testing.T) {
+	assert := assert.New(t)
+
+	defer func() {
+		confPath, loadedPath, allCfg = "", "", &Config{}
+		curNodeCfg.Store(nil)
+	}()
+
+	confPath = filepath.Join(t.TempDir(), "xuandb.toml")
+	data := fmt.Sprintf(reloadCfg, false, "INFO", "127.0.0.1:8001")
+	require.NoError(t, os.WriteFile(confPath, []byte(data), 0600))
+	require.NoError(t, Load("1", false))
+
+	get := func(accept, inm string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/debug/config", nil)
+		if accept != "" {
+			r.Header.Set("Accept", accept)
+		}
+		if inm != "" {
+			r.Header.Set("If-None-Match", inm)
+		}
+		w := httptest.NewRecorder()
+		HandleList(w, r)
+		return w
+	}
+
+	// 200 then 304.
+	w := get("application/json", "")
+	assert.Equal(http.StatusOK, w.Code)
+	assert.Empty(w.Header().Get("Content-Length"))
+	jsonTag := w.Header().Get("ETag")
+	assert.NotEmpty(jsonTag)
+
+	var fromJSON Config
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &fromJSON))
+
+	w = get("application/json", jsonTag)
+	assert.Equal(http.StatusNotModified, w.Code)
+	assert.Empty(w.Body.Bytes())
+	assert.Equal(jsonTag, w.Header().Get("ETag"))
+
+	w = get("application/json", `"other", W/`+jsonTag)
+	assert.Equal(http.StatusNotModified, w.Code)
+
+	// the body in TOML matches the one in JSON, but the tag is different.
+	w = get("", jsonTag)
+	assert.Equal(http.StatusOK, w.Code)
+	tomlTag := w.Header().Get("ETag")
+	assert.NotEqual(jsonTag, tomlTag)
+
+	var fromTOML Config
+	_, err := toml.Decode(w.Body.String(), &fromTOML)
+	require.NoError(t, err)
+	assert.Equal(fromJSON, fromTOML)
+
+	w = get("", tomlTag)
+	assert.Equal(http.StatusNotModified, w.Code)
+
+	// the tag changes with the configuration.
+	data = fmt.Sprintf(reloadCfg, false, "INFO", "127.0.0.1:8002")
+	require.NoError(t, os.WriteFile(confPath, []byte(data), 0600))
+	require.NoError(t, Load("1", false))
+	w = get("application/json", jsonTag)
+	assert.Equal(http.StatusOK, w.Code)
+	assert.NotEqual(jsonTag, w.Header().Get("ETag"))
+}
+
+func TestHandleListFormats(t *testing.T) {
+	assert := assert.New(t)
+
+	defer func() {
+		confPath, loadedPath, allCfg = "", "", &Config{}
+		curNodeCfg.Store(nil)
+	}()
+
+	confPath = filepath.Join(t.TempDir(), "xuandb.toml")
+	data := fmt.Sprintf(reloadCfg, true, "DEBUG", "127.0.0.1:8001")
+	require.NoError(t, os.WriteFile(confPath, []byte(data), 0600))
+	require.NoError(t, Load("1", false))
+
+	cases := []struct {
+		accept      string
+		contentType string
+		decode      func([]byte, any) error
+	}{
+		{"application/json", "application/json", json.Unmarshal},
+		{"application/toml", "application/toml", toml.Unmarshal},
+		{"application/yaml", "application/yaml", yaml.Unmarshal},
+		{"APPLICATION/YAML", "application/yaml", yaml.Unmarshal},
+	}
+
+	for _, c := range cases {
+		r := httptest.NewRequest(http.MethodGet, "/debug/config", nil)
+		r.Header.Set("Accept", c.accept)
+		w := httptest.NewRecorder()
+		HandleList(w, r)
+
+		assert.Equal(http.StatusOK, w.Code, c.accept)
+		assert.Equal(c.contentType, w.Header().Get("Content-Type"), c.accept)
+
+		var cfg Config
+		require.NoError(t, c.decode(w.Body.Bytes(), &cfg), c.accept)
+		assert.Equal(*allCfg, cfg, c.accept)
+		assert.Equal(slog.LevelDebug, cfg.Nodes[0].Logger.Level, c.accept)
+	}
+}
+
+func TestHandleListRedact(t *testing.T) {
+	assert := assert.New(t)
+
+	defer func() {
+		confPath, loadedPath, allCfg = "", "", &Config{}
+		curNodeCfg.Store(nil)
+	}()
+
+	const keyFile = "/etc/xuandb/secret-node.key"
+	confPath = filepath.Join(t.TempDir(), "xuandb.toml")
+	data := fmt.Sprintf(reloadCfg, false, "INFO", "127.0.0.1:8001")
+	data = strings.Replace(data, "enable-pprof", `tls-cert-file = "/etc/xuandb/node.crt"
+	tls-key-file = "`+keyFile+`"
+	enable-pprof`, 1)
+	require.NoError(t, os.WriteFile(confPath, []byte(data), 0600))
+	require.NoError(t, Load("1", false))
+
+	get := func(query string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/debug/config"+query, nil)
+		r.Header.Set("Accept", "application/json")
+		w := httptest.NewRecorder()
+		HandleList(w, r)
+		return w
+	}
+
+	// redacted by default.
+	for _, query := range []string{"", "?redact=true"} {
+		w := get(query)
+		assert.Equal(http.StatusOK, w.Code)
+		assert.NotContains(w.Body.String(), keyFile)
+		var cfg Config
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &cfg))
+		assert.Equal(redactedValue, cfg.Nodes[0].TLSKeyFile)
+		assert.Equal("/etc/xuandb/node.crt", cfg.Nodes[0].TLSCertFile)
+	}
+
+	// the loaded configuration is not modified.
+	assert.Equal(keyFile, allCfg.Nodes[0].TLSKeyFile)
+
+	// raw values on request.
+	w := get("?redact=false")
+	assert.Equal(http.StatusOK, w.Code)
+	assert.Contains(w.Body.String(), keyFile)
+	assert.NotEqual(get("").Header().Get("ETag"), w.Header().Get("ETag"))
+
+	w = get("?redact=maybe")
+	assert.Equal(http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,66 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// redactedValue replaces the values of sensitive configuration items.
+const redactedValue = "***"
+
+// isSecret returns whether the configuration item with toml key 'name' is
+// sensitive, that is, the path of a private key or a password.
+func isSecret(name string) bool {
+	return strings.HasSuffix(name, "-key-file") ||
+		name == "password" ||
+		strings.HasSuffix(name, "-password")
+}
+
+// redactValue replaces the non-empty sensitive string items in 'v', which is
+// a struct, a pointer to a struct or a slice of them, with redactedValue.
+func redactValue(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if !v.IsNil() {
+			redactValue(v.Elem())
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			redactValue(v.Index(i))
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			fv := v.Field(i)
+			if fv.Kind() != reflect.String {
+				redactValue(fv)
+			} else if isSecret(tomlName(f)) && fv.Len() > 0 {
+				fv.SetString(redactedValue)
+			}
+		}
+	}
+}
+
+// redacted returns a copy of 'c' with the sensitive items redacted, 'c' is
+// not modified.
+func redacted(c *Config) (*Config, error) {
+	// the copy is made by a JSON round trip, which is deep and keeps all the
+	// exported items.
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+
+	var rc Config
+	if err := json.Unmarshal(data, &rc); err != nil {
+		return nil, err
+	}
+
+	redactValue(reflect.ValueOf(&rc))
+	return &rc, nil
+}
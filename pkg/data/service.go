@@ -2,24 +2,205 @@ package data
 
 import (
 	"log/slog"
+	"strings"
 	"sync"
+	"time"
 
+	"github.com/localvar/xuandb/pkg/config"
 	"github.com/localvar/xuandb/pkg/meta"
 )
 
+// Options are the options of a database, they are derived from the meta data
+// of the database.
+type Options struct {
+	// Duration is the retention period of the data, 0 means forever.
+	Duration time.Duration
+}
+
+// Database is a database of the data service, storage resources are attached
+// to it when it is opened and released when it is closed.
 type Database struct {
-	Name string
+	Name    string
+	Options Options
+
+	mu     sync.Mutex
+	opened bool
+}
+
+// newDatabase creates a database from its meta data, the database must be
+// opened before use.
+func newDatabase(db *meta.Database) *Database {
+	return &Database{
+		Name:    db.Name,
+		Options: Options{Duration: db.Duration},
+	}
+}
+
+// Open opens the database, it is a no-op if the database is already opened.
+func (db *Database) Open() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if db.opened {
+		return nil
+	}
+	db.opened = true
+
+	slog.Debug("database opened", slog.String("name", db.Name))
+	return nil
 }
 
+// Close closes the database and releases its resources, it is a no-op if the
+// database is not opened.
+func (db *Database) Close() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if !db.opened {
+		return nil
+	}
+	db.opened = false
+
+	slog.Debug("database closed", slog.String("name", db.Name))
+	return nil
+}
+
+// Opened returns whether the database is opened.
+func (db *Database) Opened() bool {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.opened
+}
+
+// databases maps the lower case names of the databases to the databases,
+// database names are case-insensitive, the same as the meta service.
 var databases = sync.Map{}
 
+// databaseByName returns the database with name 'name', or nil if not found.
+func databaseByName(name string) *Database {
+	if v, ok := databases.Load(strings.ToLower(name)); ok {
+		return v.(*Database)
+	}
+	return nil
+}
+
 func handleCreateDatabase(db *meta.Database) {
-	db1 := &Database{Name: db.Name}
-	databases.LoadOrStore(db.Name, db1)
+	key := strings.ToLower(db.Name)
+	db1 := newDatabase(db)
+	if _, loaded := databases.LoadOrStore(key, db1); loaded {
+		return
+	}
+
+	if err := db1.Open(); err != nil {
+		databases.CompareAndDelete(key, db1)
+		slog.Error(
+			"failed to open database",
+			slog.String("name", db.Name),
+			slog.String("error", err.Error()),
+		)
+	}
 }
 
 func handleDropDatabase(name string) {
-	databases.Delete(name)
+	v, ok := databases.LoadAndDelete(strings.ToLower(name))
+	if !ok {
+		return
+	}
+
+	if err := v.(*Database).Close(); err != nil {
+		slog.Error(
+			"failed to close database",
+			slog.String("name", name),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// reconcileDatabases makes the databases the same as 'dbs', the databases of
+// the meta data. It is called when database events were dropped.
+func reconcileDatabases(dbs []*meta.Database) {
+	keys := make(map[string]struct{}, len(dbs))
+	for _, db := range dbs {
+		keys[strings.ToLower(db.Name)] = struct{}{}
+		handleCreateDatabase(db)
+	}
+
+	databases.Range(func(key, value any) bool {
+		if _, ok := keys[key.(string)]; !ok {
+			handleDropDatabase(key.(string))
+		}
+		return true
+	})
+}
+
+// the clock, the ticker and the deletion of the data expiry.
+var (
+	now       = time.Now
+	newTicker = func(d time.Duration) (<-chan time.Time, func()) {
+		t := time.NewTicker(d)
+		return t.C, t.Stop
+	}
+
+	// expireBefore expires the data of 'db' which is older than 'cutoff'.
+	// It only logs the cutoff for now, as a placeholder of the deletion.
+	expireBefore = func(db *Database, cutoff time.Time) {
+		slog.Info(
+			"expiring data",
+			slog.String("database", db.Name),
+			slog.Time("cutoff", cutoff),
+		)
+	}
+)
+
+// expireData expires the data which is out of the retention period of each
+// opened database, databases without a retention period are skipped.
+func expireData() {
+	t := now()
+	databases.Range(func(key, value any) bool {
+		db := value.(*Database)
+		if db.Options.Duration > 0 && db.Opened() {
+			expireBefore(db, t.Add(-db.Options.Duration))
+		}
+		return true
+	})
+}
+
+// expiry controls the background data expiry.
+var expiry struct {
+	wg   sync.WaitGroup
+	stop chan struct{}
+}
+
+// startExpiry starts a background goroutine which expires data every
+// 'interval'.
+func startExpiry(interval time.Duration) {
+	expiry.stop = make(chan struct{})
+	tick, stopTicker := newTicker(interval)
+
+	expiry.wg.Add(1)
+	go func() {
+		defer expiry.wg.Done()
+		defer stopTicker()
+
+		for {
+			select {
+			case <-expiry.stop:
+				return
+			case <-tick:
+				expireData()
+			}
+		}
+	}()
+}
+
+// stopExpiry stops the background data expiry and waits for it to exit.
+func stopExpiry() {
+	if expiry.stop == nil {
+		return
+	}
+	close(expiry.stop)
+	expiry.wg.Wait()
+	expiry.stop = nil
 }
 
 // StartService starts the data service.
@@ -30,11 +211,26 @@ func StartService() error {
 
 	meta.DatabaseInformer().AddCreateHandler(handleCreateDatabase)
 	meta.DatabaseInformer().AddDropHandler(handleDropDatabase)
+	meta.DatabaseInformer().AddResyncHandler(func() {
+		reconcileDatabases(meta.Databases())
+	})
+
+	interval := time.Minute
+	if nc := config.CurrentNode(); nc != nil && nc.Data != nil {
+		interval = nc.Data.ExpiryCheckInterval
+	}
+	startExpiry(interval)
+
 	slog.Info("data service started")
 	return nil
 }
 
 // ShutdownService shuts down the data service.
 func ShutdownService() {
+	stopExpiry()
+	databases.Range(func(key, value any) bool {
+		handleDropDatabase(key.(string))
+		return true
+	})
 	slog.Info("data service stopped")
 }
@@ -0,0 +1,104 @@
+package data
+
+import (
+	"testing"
+	"time"
+
+	"github.com/localvar/xuandb/pkg/meta"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDatabaseLifecycle(t *testing.T) {
+	assert := assert.New(t)
+
+	handleCreateDatabase(&meta.Database{Name: "Foo", Duration: time.Hour})
+	db := databaseByName("foo")
+	if !assert.NotNil(db) {
+		return
+	}
+	assert.Equal("Foo", db.Name)
+	assert.Equal(Options{Duration: time.Hour}, db.Options)
+	assert.True(db.Opened())
+
+	// creating it again is idempotent, the existing database is kept.
+	handleCreateDatabase(&meta.Database{Name: "foo"})
+	assert.Same(db, databaseByName("FOO"))
+	assert.True(db.Opened())
+
+	handleDropDatabase("FOO")
+	assert.Nil(databaseByName("foo"))
+	assert.False(db.Opened())
+
+	// dropping a database which does not exist does nothing.
+	handleDropDatabase("foo")
+
+	handleCreateDatabase(&meta.Database{Name: "bar"})
+	db = databaseByName("bar")
+	ShutdownService()
+	assert.Nil(databaseByName("bar"))
+	assert.False(db.Opened())
+}
+
+func TestReconcileDatabases(t *testing.T) {
+	assert := assert.New(t)
+	defer ShutdownService()
+
+	// 'foo' was dropped and 'baz' was created while the events were lost.
+	handleCreateDatabase(&meta.Database{Name: "foo"})
+	handleCreateDatabase(&meta.Database{Name: "bar"})
+	bar := databaseByName("bar")
+
+	reconcileDatabases([]*meta.Database{{Name: "Bar"}, {Name: "baz"}})
+	assert.Nil(databaseByName("foo"))
+	assert.Same(bar, databaseByName("bar"))
+	if db := databaseByName("baz"); assert.NotNil(db) {
+		assert.True(db.Opened())
+	}
+}
+
+func TestExpiry(t *testing.T) {
+	assert := assert.New(t)
+
+	defer func(fn func() time.Time) { now = fn }(now)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := start
+	now = func() time.Time { return clock }
+
+	tick := make(chan time.Time)
+	stopped := false
+	defer func(fn func(time.Duration) (<-chan time.Time, func())) { newTicker = fn }(newTicker)
+	newTicker = func(d time.Duration) (<-chan time.Time, func()) {
+		assert.Equal(time.Second, d)
+		return tick, func() { stopped = true }
+	}
+
+	type expired struct {
+		name   string
+		cutoff time.Time
+	}
+	ch := make(chan expired, 10)
+	defer func(fn func(*Database, time.Time)) { expireBefore = fn }(expireBefore)
+	expireBefore = func(db *Database, cutoff time.Time) {
+		ch <- expired{db.Name, cutoff}
+	}
+
+	handleCreateDatabase(&meta.Database{Name: "hour", Duration: time.Hour})
+	handleCreateDatabase(&meta.Database{Name: "forever"})
+	startExpiry(time.Second)
+
+	tick <- clock
+	assert.Equal(expired{"hour", start.Add(-time.Hour)}, <-ch)
+
+	// a new database is expired on the next tick, and a dropped one is not.
+	clock = start.Add(time.Minute)
+	handleCreateDatabase(&meta.Database{Name: "day", Duration: 24 * time.Hour})
+	handleDropDatabase("hour")
+	tick <- clock
+	assert.Equal(expired{"day", clock.Add(-24 * time.Hour)}, <-ch)
+
+	stopExpiry()
+	assert.True(stopped)
+	assert.Empty(ch)
+
+	ShutdownService()
+}
@@ -1,47 +1,88 @@
 package debug
 
 import (
+	"log/slog"
 	"net/http"
 	"net/http/pprof"
 
+	"github.com/localvar/xuandb/pkg/cache"
 	"github.com/localvar/xuandb/pkg/config"
+	"github.com/localvar/xuandb/pkg/httpauth"
 	"github.com/localvar/xuandb/pkg/httpserver"
 	"github.com/localvar/xuandb/pkg/logger"
 	"github.com/localvar/xuandb/pkg/meta"
-	"github.com/localvar/xuandb/pkg/xerrors"
+	"github.com/localvar/xuandb/pkg/version"
 )
 
 // auth wraps the input http.HandlerFunc to a new http.HandlerFunc which
 // authenticates and authorizes the request for debug operations.
 func auth(handler http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		name, pwd, _ := r.BasicAuth()
+	rp := meta.RequiredPrivileges{Global: meta.PrivilegeDebug}
+	return httpauth.Require(rp, handler)
+}
 
-		rp := meta.RequiredPrivileges{Global: meta.PrivilegeDebug}
-		if err := meta.Auth(name, pwd, rp); err != nil {
-			se := err.(*xerrors.StatusError)
-			http.Error(w, se.Msg, se.StatusCode)
+// endpointGuard wraps the input http.HandlerFunc to a new http.HandlerFunc
+// which responds 404 if the sensitive debug endpoint 'name' is not enabled for
+// the current node. The check is done for every request because the
+// configuration can be reloaded.
+func endpointGuard(name string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !config.CurrentNode().DebugEndpointEnabled(name) {
+			http.NotFound(w, r)
 			return
 		}
-
 		handler(w, r)
 	}
 }
 
+// handleLiveness responds 204 to indicate the node is alive, it does not
+// require authentication and is available in all modes.
+func handleLiveness(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// logEndpoints logs the debug endpoints which are active at startup.
+func logEndpoints() {
+	nc := config.CurrentNode()
+	active := []string{"liveness", "version", "logger"}
+	for _, name := range []string{config.DebugEndpointPprof, config.DebugEndpointConfig} {
+		if nc.DebugEndpointEnabled(name) {
+			active = append(active, name)
+		}
+	}
+	slog.Info(
+		"debug endpoints initialized",
+		slog.Bool("productionMode", nc.ProductionMode),
+		slog.Any("active", active),
+	)
+}
+
 // Init initializes the debug package.
 func Init() {
+	httpserver.HandleFunc("GET /debug/liveness", handleLiveness)
+
 	// add an http handler to expose configurations.
-	httpserver.HandleFunc("GET /debug/config", auth(config.HandleList))
+	httpserver.HandleFunc("GET /debug/config", auth(endpointGuard(config.DebugEndpointConfig, config.HandleList)))
+
+	// the version and build information, for auditing the builds of nodes.
+	httpserver.HandleFunc("GET /debug/version", auth(version.HandleGet))
 
 	httpserver.HandleFunc("GET /debug/logger/level", auth(logger.HandleGetLevel))
 	httpserver.HandleFunc("POST /debug/logger/level", auth(logger.HandleSetLevel))
 
-	// registers the pprof handlers.
-	if config.CurrentNode().EnablePprof {
-		httpserver.HandleFunc("GET /debug/pprof/", auth(pprof.Index))
-		httpserver.HandleFunc("GET /debug/pprof/cmdline", auth(pprof.Cmdline))
-		httpserver.HandleFunc("GET /debug/pprof/profile", auth(pprof.Profile))
-		httpserver.HandleFunc("GET /debug/pprof/symbol", auth(pprof.Symbol))
-		httpserver.HandleFunc("GET /debug/pprof/trace", auth(pprof.Trace))
+	// caches are node-local, only the caches of this node are flushed.
+	httpserver.HandleFunc("POST /debug/cache/flush", auth(cache.HandleFlush))
+
+	// registers the pprof handlers, they are always registered because pprof
+	// can be enabled or disabled by reloading the configuration.
+	pprofOnly := func(handler http.HandlerFunc) http.HandlerFunc {
+		return auth(endpointGuard(config.DebugEndpointPprof, handler))
 	}
+	httpserver.HandleFunc("GET /debug/pprof/", pprofOnly(pprof.Index))
+	httpserver.HandleFunc("GET /debug/pprof/cmdline", pprofOnly(pprof.Cmdline))
+	httpserver.HandleFunc("GET /debug/pprof/profile", pprofOnly(pprof.Profile))
+	httpserver.HandleFunc("GET /debug/pprof/symbol", pprofOnly(pprof.Symbol))
+	httpserver.HandleFunc("GET /debug/pprof/trace", pprofOnly(pprof.Trace))
+
+	logEndpoints()
 }
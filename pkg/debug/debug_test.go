@@ -0,0 +1,64 @@
+package debug
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/http/pprof"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/localvar/xuandb/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const nodeCfg = `
+[[node]]
+	id = "1"
+	http-addr = "127.0.0.1:7001"
+	enable-pprof = true
+	production-mode = %v
+	[node.meta]
+		raft-voter = true
+		raft-addr = "127.0.0.1:8001"
+		raft-store = "memory"
+		raft-snapshot-store = "memory"
+`
+
+// loadConfig loads the node configuration with production mode set to 'pm'.
+func loadConfig(t *testing.T, pm bool) {
+	path := filepath.Join(t.TempDir(), "xuandb.toml")
+	data := fmt.Sprintf(nodeCfg, pm)
+	require.NoError(t, os.WriteFile(path, []byte(data), 0600))
+	require.NoError(t, flag.Set("config", path))
+	require.NoError(t, config.Load("1", false))
+}
+
+func TestProductionMode(t *testing.T) {
+	assert := assert.New(t)
+
+	// same as the handlers registered by Init, but without authentication.
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /debug/liveness", handleLiveness)
+	mux.HandleFunc("GET /debug/config", endpointGuard(config.DebugEndpointConfig, config.HandleList))
+	mux.HandleFunc("GET /debug/pprof/", endpointGuard(config.DebugEndpointPprof, pprof.Index))
+
+	get := func(path string) int {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w.Code
+	}
+
+	loadConfig(t, false)
+	assert.Equal(http.StatusNoContent, get("/debug/liveness"))
+	assert.Equal(http.StatusOK, get("/debug/config"))
+	assert.Equal(http.StatusOK, get("/debug/pprof/"))
+
+	loadConfig(t, true)
+	assert.Equal(http.StatusNoContent, get("/debug/liveness"))
+	assert.Equal(http.StatusNotFound, get("/debug/config"))
+	assert.Equal(http.StatusNotFound, get("/debug/pprof/"))
+}
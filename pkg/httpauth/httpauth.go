@@ -0,0 +1,53 @@
+// Package httpauth authenticates and authorizes HTTP requests with the
+// credentials of the basic authentication scheme.
+package httpauth
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/localvar/xuandb/pkg/meta"
+	"github.com/localvar/xuandb/pkg/xerrors"
+)
+
+// contextKey is the type of the keys of the values stored in the request
+// context by this package.
+type contextKey struct{}
+
+// Error responds the authentication or authorization error 'err' with its
+// status code. External authenticators may return errors other than status
+// errors, which are responded with 401.
+func Error(w http.ResponseWriter, err error) {
+	se := xerrors.Wrap(err, http.StatusUnauthorized).(*xerrors.StatusError)
+	http.Error(w, se.Msg, se.StatusCode)
+}
+
+// Require wraps 'handler' to a new http.HandlerFunc which authenticates the
+// request and checks it has privileges 'rp' before calling 'handler'. The
+// request is refused with the status code of the error if it fails.
+//
+// If no user has been created, all requests are allowed without credentials,
+// so that the first user can be created.
+//
+// The handler can get the authenticated user by User.
+func Require(rp meta.RequiredPrivileges, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name, pwd, _ := r.BasicAuth()
+		u, err := meta.AuthUser(name, pwd, rp)
+		if err != nil {
+			Error(w, err)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), contextKey{}, u)
+		handler(w, r.WithContext(ctx))
+	}
+}
+
+// User returns the user authenticated by Require from the request context
+// 'ctx'. It returns nil if no user has been created, or if the request is not
+// authenticated by Require.
+func User(ctx context.Context) *meta.User {
+	u, _ := ctx.Value(contextKey{}).(*meta.User)
+	return u
+}
@@ -0,0 +1,98 @@
+package httpauth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/localvar/xuandb/pkg/meta"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeAuthenticator authenticates the users in it, authentication is
+// disabled if there's no user, like the local authenticator.
+type fakeAuthenticator map[string]*meta.User
+
+func (fa fakeAuthenticator) Authenticate(name, pwd string) (*meta.User, error) {
+	if len(fa) == 0 {
+		return nil, nil
+	}
+	if name == "" {
+		return nil, meta.ErrAuthRequired
+	}
+	if u := fa[name]; u != nil && u.Password == pwd {
+		return u, nil
+	}
+	return nil, meta.ErrPasswordMismatch
+}
+
+// errAuthenticator always fails with an error which is not a status error.
+type errAuthenticator struct{}
+
+func (errAuthenticator) Authenticate(name, pwd string) (*meta.User, error) {
+	return nil, errors.New("directory unavailable")
+}
+
+func TestRequire(t *testing.T) {
+	assert := assert.New(t)
+	defer meta.SetAuthenticator(nil)
+
+	var called bool
+	var user *meta.User
+	handler := Require(meta.RequiredPrivileges{Global: meta.PrivilegeDebug}, func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		user = User(r.Context())
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	serve := func(auth ...string) *httptest.ResponseRecorder {
+		called, user = false, nil
+		r := httptest.NewRequest(http.MethodGet, "/debug/test", nil)
+		if len(auth) == 2 {
+			r.SetBasicAuth(auth[0], auth[1])
+		}
+		w := httptest.NewRecorder()
+		handler(w, r)
+		return w
+	}
+
+	// bootstrap: no user has been created, all requests are allowed.
+	meta.SetAuthenticator(fakeAuthenticator{})
+	assert.Equal(http.StatusNoContent, serve().Code)
+	assert.True(called)
+	assert.Nil(user)
+
+	meta.SetAuthenticator(fakeAuthenticator{
+		"alice": {Name: "alice", Password: "pwd", Priv: meta.PrivilegeRead},
+		"bob":   {Name: "bob", Password: "pwd", Priv: meta.PrivilegeDebug},
+	})
+
+	// insufficient privileges.
+	w := serve("alice", "pwd")
+	assert.Equal(http.StatusForbidden, w.Code)
+	assert.False(called)
+
+	// authentication failures.
+	assert.Equal(http.StatusUnauthorized, serve().Code)
+	assert.Equal(http.StatusUnauthorized, serve("bob", "bad").Code)
+	assert.False(called)
+
+	// the user is in the request context.
+	assert.Equal(http.StatusNoContent, serve("bob", "pwd").Code)
+	assert.True(called)
+	if assert.NotNil(user) {
+		assert.Equal("bob", user.Name)
+	}
+
+	// errors of external authenticators.
+	meta.SetAuthenticator(errAuthenticator{})
+	w = serve("bob", "pwd")
+	assert.Equal(http.StatusUnauthorized, w.Code)
+	assert.Contains(w.Body.String(), "directory unavailable")
+	assert.False(called)
+
+	// not authenticated by Require.
+	assert.Nil(User(context.Background()))
+}
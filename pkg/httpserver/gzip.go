@@ -0,0 +1,241 @@
+package httpserver
+
+import (
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/localvar/xuandb/pkg/config"
+)
+
+// gzipMinSize is the minimum size of a response body to be compressed, the
+// small responses are not worth the cost of compression.
+const gzipMinSize = 1024
+
+// gzipWriterPool is a pool of gzip writers, a gzip writer allocates a lot of
+// memory for its internal state.
+var gzipWriterPool = sync.Pool{
+	New: func() any { return gzip.NewWriter(io.Discard) },
+}
+
+// compressedTypes are the content types which are already compressed, the
+// types which start with 'image/', 'video/' and 'audio/' are also treated as
+// compressed.
+var compressedTypes = map[string]bool{
+	"application/gzip":             true,
+	"application/x-gzip":           true,
+	"application/zip":              true,
+	"application/zstd":             true,
+	"application/x-bzip2":          true,
+	"application/x-xz":             true,
+	"application/x-7z-compressed":  true,
+	"application/x-rar-compressed": true,
+}
+
+// isCompressedType returns whether content type 'ct' is already compressed.
+func isCompressedType(ct string) bool {
+	mt, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return false
+	}
+	if compressedTypes[mt] {
+		return true
+	}
+	if mt == "image/svg+xml" {
+		return false
+	}
+	return strings.HasPrefix(mt, "image/") ||
+		strings.HasPrefix(mt, "video/") ||
+		strings.HasPrefix(mt, "audio/")
+}
+
+// acceptsGzip returns whether the client of request 'r' accepts the gzip
+// encoding according to its 'Accept-Encoding' header.
+func acceptsGzip(r *http.Request) bool {
+	for _, v := range r.Header.Values("Accept-Encoding") {
+		for _, enc := range strings.Split(v, ",") {
+			name, params, _ := strings.Cut(enc, ";")
+			name = strings.TrimSpace(name)
+			if !strings.EqualFold(name, "gzip") && name != "*" {
+				continue
+			}
+
+			// a quality value of 0 means 'not acceptable'.
+			q := 1.0
+			if k, v, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(k) == "q" {
+				if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+					q = f
+				}
+			}
+			if q > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// gzipResponseWriter wraps an http.ResponseWriter to compress the response
+// body with gzip. The status code and the beginning of the body are held
+// until gzipMinSize bytes are written, the body is flushed or the response
+// is completed, and then it decides whether to compress the body.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	status  int
+	buf     []byte
+	decided bool
+
+	// gz is the gzip writer of the body, nil if the body is not compressed.
+	gz *gzip.Writer
+}
+
+// WriteHeader implements [http.ResponseWriter].
+func (gw *gzipResponseWriter) WriteHeader(code int) {
+	// informational responses are sent as is, they do not have a body.
+	if gw.decided || (code >= 100 && code < 200) {
+		gw.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if gw.status == 0 {
+		gw.status = code
+	}
+}
+
+// Write implements [http.ResponseWriter].
+func (gw *gzipResponseWriter) Write(b []byte) (int, error) {
+	if gw.decided {
+		if gw.gz != nil {
+			return gw.gz.Write(b)
+		}
+		return gw.ResponseWriter.Write(b)
+	}
+
+	if gw.status == 0 {
+		gw.status = http.StatusOK
+	}
+	gw.buf = append(gw.buf, b...)
+	if len(gw.buf) < gzipMinSize {
+		return len(b), nil
+	}
+	if err := gw.decide(); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// shouldCompress returns whether the body should be compressed, it must be
+// called after the content type is known.
+func (gw *gzipResponseWriter) shouldCompress() bool {
+	h := gw.Header()
+	switch {
+	case len(gw.buf) < gzipMinSize:
+		return false
+	case gw.status == http.StatusNoContent || gw.status == http.StatusNotModified:
+		return false
+	case h.Get("Content-Encoding") != "":
+		return false
+	default:
+		return !isCompressedType(h.Get("Content-Type"))
+	}
+}
+
+// decide decides whether to compress the body, sends the held status code
+// and writes the held beginning of the body.
+func (gw *gzipResponseWriter) decide() error {
+	gw.decided = true
+
+	// the content type must be detected from the uncompressed data, or the
+	// server detects it as gzip.
+	h := gw.Header()
+	if len(gw.buf) > 0 && h.Get("Content-Type") == "" {
+		h.Set("Content-Type", http.DetectContentType(gw.buf))
+	}
+
+	if gw.shouldCompress() {
+		h.Del("Content-Length")
+		h.Set("Content-Encoding", "gzip")
+		gw.gz = gzipWriterPool.Get().(*gzip.Writer)
+		gw.gz.Reset(gw.ResponseWriter)
+	}
+
+	if gw.status != 0 {
+		gw.ResponseWriter.WriteHeader(gw.status)
+	}
+	if len(gw.buf) == 0 {
+		return nil
+	}
+
+	var err error
+	if gw.gz != nil {
+		_, err = gw.gz.Write(gw.buf)
+	} else {
+		_, err = gw.ResponseWriter.Write(gw.buf)
+	}
+	gw.buf = nil
+	return err
+}
+
+// Flush implements [http.Flusher], the data written so far is compressed
+// and sent to the client.
+func (gw *gzipResponseWriter) Flush() {
+	if !gw.decided {
+		gw.decide()
+	}
+	if gw.gz != nil {
+		gw.gz.Flush()
+	}
+	if f, ok := gw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying http.ResponseWriter, it is used by
+// [http.ResponseController].
+func (gw *gzipResponseWriter) Unwrap() http.ResponseWriter {
+	return gw.ResponseWriter
+}
+
+// close completes the response, it must be called before the handler
+// returns so that the trailers, if any, are sent after the body.
+func (gw *gzipResponseWriter) close() error {
+	if !gw.decided {
+		if err := gw.decide(); err != nil {
+			return err
+		}
+	}
+	if gw.gz == nil {
+		return nil
+	}
+
+	err := gw.gz.Close()
+	gw.gz.Reset(io.Discard)
+	gzipWriterPool.Put(gw.gz)
+	gw.gz = nil
+	return err
+}
+
+// gzipResponse is a middleware that compresses the responses with gzip if
+// 'gzip-responses' is enabled, see gzipResponseWriter for the details.
+func gzipResponse(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		nc := config.CurrentNode()
+		if nc == nil || !nc.GzipResponses {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Accept-Encoding")
+		if r.Method == http.MethodHead || !acceptsGzip(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		gw := &gzipResponseWriter{ResponseWriter: w}
+		defer gw.close()
+		next.ServeHTTP(gw, r)
+	})
+}
@@ -0,0 +1,169 @@
+package httpserver
+
+import (
+	"compress/gzip"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/localvar/xuandb/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const gzipCfg = `
+[[node]]
+	id = "1"
+	http-addr = "127.0.0.1:7001"
+	gzip-responses = %v
+	[node.meta]
+		raft-voter = true
+		raft-addr = "127.0.0.1:8001"
+		raft-store = "memory"
+		raft-snapshot-store = "memory"
+`
+
+func TestAcceptsGzip(t *testing.T) {
+	assert := assert.New(t)
+
+	accepts := func(ae ...string) bool {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		for _, v := range ae {
+			r.Header.Add("Accept-Encoding", v)
+		}
+		return acceptsGzip(r)
+	}
+
+	assert.True(accepts("gzip"))
+	assert.True(accepts("deflate, GZIP;q=0.5"))
+	assert.True(accepts("br", "gzip"))
+	assert.True(accepts("*"))
+	assert.False(accepts())
+	assert.False(accepts("br, deflate"))
+	assert.False(accepts("gzip;q=0"))
+}
+
+func TestIsCompressedType(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True(isCompressedType("image/png"))
+	assert.True(isCompressedType("application/zip"))
+	assert.True(isCompressedType("application/gzip; charset=binary"))
+	assert.False(isCompressedType("image/svg+xml"))
+	assert.False(isCompressedType("application/json"))
+	assert.False(isCompressedType(""))
+}
+
+func TestGzipResponse(t *testing.T) {
+	assert := assert.New(t)
+
+	load := func(enabled bool) {
+		path := filepath.Join(t.TempDir(), "xuandb.toml")
+		data := fmt.Sprintf(gzipCfg, enabled)
+		require.NoError(t, os.WriteFile(path, []byte(data), 0600))
+		require.NoError(t, flag.Set("config", path))
+		require.NoError(t, config.Load("1", false))
+	}
+
+	large := strings.Repeat(`{"name":"alice","age":18},`, 1000)
+	HandleFunc("GET /test/gzip/large", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(large))
+	})
+	HandleFunc("GET /test/gzip/small", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+	})
+	HandleFunc("GET /test/gzip/image", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte(large))
+	})
+	HandleFunc("GET /test/gzip/empty", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	HandleFunc("GET /test/gzip/stream", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Trailer", "X-Has-More")
+		for i := 0; i < 3; i++ {
+			w.Write([]byte(large))
+			w.(http.Flusher).Flush()
+		}
+		w.Header().Set("X-Has-More", "false")
+	})
+
+	srv := httptest.NewServer(buildHandler())
+	defer srv.Close()
+
+	// the transport is not allowed to decompress the responses, so that the
+	// encoding of the responses can be verified.
+	c := &http.Client{Transport: &http.Transport{DisableCompression: true}}
+	get := func(path string, gzipped bool) (*http.Response, string) {
+		req, err := http.NewRequest(http.MethodGet, srv.URL+path, nil)
+		require.NoError(t, err)
+		if gzipped {
+			req.Header.Set("Accept-Encoding", "gzip")
+		}
+		resp, err := c.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var body io.Reader = resp.Body
+		if resp.Header.Get("Content-Encoding") == "gzip" {
+			gr, err := gzip.NewReader(resp.Body)
+			require.NoError(t, err)
+			body = gr
+		}
+		data, err := io.ReadAll(body)
+		require.NoError(t, err)
+		return resp, string(data)
+	}
+
+	load(true)
+
+	// a gzip client gets a compressed body.
+	resp, body := get("/test/gzip/large", true)
+	assert.Equal(http.StatusOK, resp.StatusCode)
+	assert.Equal("gzip", resp.Header.Get("Content-Encoding"))
+	assert.Equal("application/json", resp.Header.Get("Content-Type"))
+	assert.Equal("Accept-Encoding", resp.Header.Get("Vary"))
+	assert.Equal(large, body)
+
+	// a non-gzip client gets the plaintext.
+	resp, body = get("/test/gzip/large", false)
+	assert.Empty(resp.Header.Get("Content-Encoding"))
+	assert.Equal(large, body)
+
+	// small responses and compressed content types are not compressed.
+	resp, body = get("/test/gzip/small", true)
+	assert.Empty(resp.Header.Get("Content-Encoding"))
+	assert.Equal("text/plain; charset=utf-8", resp.Header.Get("Content-Type"))
+	assert.Equal("hello", body)
+
+	resp, body = get("/test/gzip/image", true)
+	assert.Empty(resp.Header.Get("Content-Encoding"))
+	assert.Equal(large, body)
+
+	resp, body = get("/test/gzip/empty", true)
+	assert.Equal(http.StatusNoContent, resp.StatusCode)
+	assert.Empty(resp.Header.Get("Content-Encoding"))
+	assert.Empty(body)
+
+	// a streamed response is compressed chunk by chunk, and the trailers
+	// are sent after the body.
+	resp, body = get("/test/gzip/stream", true)
+	assert.Equal("gzip", resp.Header.Get("Content-Encoding"))
+	assert.Equal(strings.Repeat(large, 3), body)
+	assert.Equal("false", resp.Trailer.Get("X-Has-More"))
+
+	// disabled.
+	load(false)
+	resp, body = get("/test/gzip/large", true)
+	assert.Empty(resp.Header.Get("Content-Encoding"))
+	assert.Empty(resp.Header.Get("Vary"))
+	assert.Equal(large, body)
+}
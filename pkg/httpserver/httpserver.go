@@ -2,10 +2,16 @@ package httpserver
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"log/slog"
+	"net"
 	"net/http"
+	"net/netip"
 	"os"
-	"time"
+	"strings"
+	"sync"
 
 	"github.com/localvar/xuandb/pkg/config"
 )
@@ -13,16 +19,77 @@ import (
 var (
 	mux = http.NewServeMux()
 	svr = &http.Server{}
+
+	// scheme and client are used to send requests to other nodes.
+	scheme = "http"
+	client = http.DefaultClient
+
+	// loopbackLn is the loopback listener when listening on the configured
+	// address is withheld until the first user is created.
+	lnLock     sync.Mutex
+	loopbackLn net.Listener
 )
 
-// Start starts the http server.
-func Start() {
-	svr.Addr = config.CurrentNode().HTTPAddr
-	svr.Handler = mux
+// newClient creates an http client for inter-node communication, the client
+// uses the CA certificates in 'caFile' to verify servers, or the system CA
+// pool if 'caFile' is empty.
+func newClient(caFile string) (*http.Client, error) {
+	if caFile == "" {
+		return http.DefaultClient, nil
+	}
+
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errors.New("no valid certificate in CA file: " + caFile)
+	}
+
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	tr.TLSClientConfig = &tls.Config{RootCAs: pool}
+	return &http.Client{Transport: tr}, nil
+}
+
+// loopbackAddr returns the loopback address with the same port as 'addr',
+// and whether it is different from 'addr'. The IPv6 loopback address is used
+// if the IP of 'addr' is a specified IPv6 address, and the IPv4 one is used
+// if the host of 'addr' is a host name other than 'localhost'.
+func loopbackAddr(addr string) (string, bool) {
+	ap, err := netip.ParseAddrPort(addr)
+	if err != nil {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil || strings.EqualFold(host, "localhost") {
+			return addr, false
+		}
+		return net.JoinHostPort("127.0.0.1", port), true
+	}
+	if ap.Addr().IsLoopback() {
+		return addr, false
+	}
+
+	ip := netip.AddrFrom4([4]byte{127, 0, 0, 1})
+	if a := ap.Addr(); a.Is6() && !a.Is4In6() && !a.IsUnspecified() {
+		ip = netip.IPv6Loopback()
+	}
+	return netip.AddrPortFrom(ip, ap.Port()).String(), true
+}
 
+// serve serves http requests on 'ln' in a new goroutine.
+func serve(ln net.Listener) {
+	nc := config.CurrentNode()
 	go func() {
-		err := svr.ListenAndServe()
-		if err == nil || err == http.ErrServerClosed {
+		var err error
+		if nc.TLSEnabled() {
+			err = svr.ServeTLS(ln, nc.TLSCertFile, nc.TLSKeyFile)
+		} else {
+			err = svr.Serve(ln)
+		}
+
+		// 'net.ErrClosed' means the listener is closed by BindPublic.
+		if err == nil || err == http.ErrServerClosed || errors.Is(err, net.ErrClosed) {
 			return
 		}
 
@@ -32,16 +99,115 @@ func Start() {
 		)
 		os.Exit(1)
 	}()
+}
+
+// Start starts the http server. If 'bind-before-bootstrap' of the current
+// node is false, the server only listens on the loopback address until
+// BindPublic is called.
+func Start() {
+	nc := config.CurrentNode()
+	svr.Handler = buildHandler()
+
+	if nc.TLSEnabled() {
+		c, err := newClient(nc.TLSCAFile)
+		if err != nil {
+			slog.Error(
+				"failed to create http client",
+				slog.String("error", err.Error()),
+			)
+			os.Exit(1)
+		}
+		scheme, client = "https", c
+	}
+
+	addr, withheld := nc.HTTPAddr, false
+	if !nc.BindBeforeBootstrap {
+		addr, withheld = loopbackAddr(addr)
+	}
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		slog.Error(
+			"failed to listen",
+			slog.String("address", addr),
+			slog.String("error", err.Error()),
+		)
+		os.Exit(1)
+	}
+
+	if withheld {
+		lnLock.Lock()
+		loopbackLn = ln
+		lnLock.Unlock()
+	}
+
+	serve(ln)
+
+	slog.Info(
+		"http server started",
+		slog.String("address", addr),
+		slog.Bool("tls", nc.TLSEnabled()),
+		slog.Bool("withheld", withheld),
+	)
+}
+
+// BindPublic makes the http server listen on the configured address if it
+// only listens on the loopback address currently, it does nothing otherwise.
+// It is called once the first user is created.
+func BindPublic() {
+	lnLock.Lock()
+	defer lnLock.Unlock()
+
+	if loopbackLn == nil {
+		return
+	}
+
+	// the loopback listener must be closed first because it may conflict
+	// with the configured address, e.g. '0.0.0.0'.
+	loopbackLn.Close()
+	loopbackLn = nil
+
+	addr := config.CurrentNode().HTTPAddr
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		slog.Error(
+			"failed to listen",
+			slog.String("address", addr),
+			slog.String("error", err.Error()),
+		)
+		os.Exit(1)
+	}
 
-	slog.Info("http server started", slog.String("address", svr.Addr))
+	serve(ln)
+	slog.Info("http server listens on public address", slog.String("address", addr))
 }
 
-// Shutdown stops the http server.
+// Shutdown stops the http server, it waits for the in-flight requests to
+// complete until the configured 'shutdown-timeout' expires.
 func Shutdown() {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	svr.Shutdown(ctx)
+	timeout := config.CurrentNode().ShutdownTimeout
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	ShutdownContext(ctx)
 	cancel()
+}
+
+// ShutdownContext stops the http server, it waits for the in-flight requests
+// to complete until 'ctx' is done. The connections of the requests which are
+// still in flight are closed at that time, and the error of 'ctx' is returned.
+//
+// If it returns nil, all the handlers have completed, so it is safe to shut
+// down the services used by them.
+func ShutdownContext(ctx context.Context) error {
+	err := svr.Shutdown(ctx)
+	if err != nil {
+		slog.Warn(
+			"in-flight requests are not drained in time",
+			slog.String("error", err.Error()),
+		)
+		svr.Close()
+	}
 	slog.Info("http server stopped")
+	return err
 }
 
 // Handle registers the handler for the given pattern in [mux].
@@ -53,3 +219,14 @@ func Handle(pattern string, handler http.Handler) {
 func HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request)) {
 	mux.HandleFunc(pattern, handler)
 }
+
+// Client returns the http client for sending requests to other nodes.
+func Client() *http.Client {
+	return client
+}
+
+// URL returns the URL of 'pathAndQuery' at 'addr' with the scheme used by
+// the cluster.
+func URL(addr, pathAndQuery string) string {
+	return scheme + "://" + addr + pathAndQuery
+}
@@ -0,0 +1,253 @@
+package httpserver
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/localvar/xuandb/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeSelfSignedCert generates a self-signed certificate for 127.0.0.1 and
+// writes the certificate and the private key to 'dir'.
+func writeSelfSignedCert(t *testing.T, dir string) (certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "xuandb test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile = filepath.Join(dir, "cert.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	require.NoError(t, os.WriteFile(certFile, data, 0600))
+
+	keyFile = filepath.Join(dir, "key.pem")
+	data = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+	require.NoError(t, os.WriteFile(keyFile, data, 0600))
+
+	return certFile, keyFile
+}
+
+func TestTLS(t *testing.T) {
+	assert := assert.New(t)
+
+	certFile, keyFile := writeSelfSignedCert(t, t.TempDir())
+
+	HandleFunc("GET /meta/node", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &http.Server{Handler: buildHandler()}
+	go s.ServeTLS(ln, certFile, keyFile)
+	defer s.Close()
+
+	defer func(s string, c *http.Client) { scheme, client = s, c }(scheme, client)
+	scheme = "https"
+
+	// the client trusts the CA.
+	client, err = newClient(certFile)
+	require.NoError(t, err)
+	resp, err := Client().Get(URL(ln.Addr().String(), "/meta/node"))
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(http.StatusOK, resp.StatusCode)
+	assert.Equal("ok", string(body))
+
+	// the client does not trust the CA.
+	client, err = newClient("")
+	require.NoError(t, err)
+	_, err = Client().Get(URL(ln.Addr().String(), "/meta/node"))
+	assert.Error(err)
+
+	// invalid CA file.
+	_, err = newClient(keyFile)
+	assert.Error(err)
+}
+
+func TestLoopbackAddr(t *testing.T) {
+	assert := assert.New(t)
+
+	cases := []struct {
+		addr     string
+		want     string
+		withheld bool
+	}{
+		{"0.0.0.0:8086", "127.0.0.1:8086", true},
+		{"192.168.1.1:8086", "127.0.0.1:8086", true},
+		{"[::]:8086", "127.0.0.1:8086", true},
+		{"[fe80::1]:8086", "[::1]:8086", true},
+		{"127.0.0.1:8086", "127.0.0.1:8086", false},
+		{"[::1]:8086", "[::1]:8086", false},
+		{"node1.internal:8086", "127.0.0.1:8086", true},
+		{"localhost:8086", "localhost:8086", false},
+	}
+
+	for _, c := range cases {
+		addr, withheld := loopbackAddr(c.addr)
+		assert.Equal(c.want, addr, c.addr)
+		assert.Equal(c.withheld, withheld, c.addr)
+	}
+}
+
+// nonLoopbackIP returns a non-loopback IPv4 address of this host, or an empty
+// string if there is none.
+func nonLoopbackIP() string {
+	addrs, _ := net.InterfaceAddrs()
+	for _, a := range addrs {
+		if n, ok := a.(*net.IPNet); ok && !n.IP.IsLoopback() && n.IP.To4() != nil {
+			return n.IP.String()
+		}
+	}
+	return ""
+}
+
+const bindCfg = `
+[[node]]
+	id = "1"
+	http-addr = "0.0.0.0:%d"
+	bind-before-bootstrap = false
+	[node.meta]
+		raft-voter = true
+		raft-addr = "127.0.0.1:8001"
+		raft-store = "memory"
+		raft-snapshot-store = "memory"
+`
+
+func TestBindBeforeBootstrap(t *testing.T) {
+	assert := assert.New(t)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+
+	path := filepath.Join(t.TempDir(), "xuandb.toml")
+	data := fmt.Sprintf(bindCfg, port)
+	require.NoError(t, os.WriteFile(path, []byte(data), 0600))
+	require.NoError(t, flag.Set("config", path))
+	require.NoError(t, config.Load("1", false))
+
+	HandleFunc("GET /bootstrap", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	get := func(ip string) error {
+		resp, err := http.Get(fmt.Sprintf("http://%s:%d/bootstrap", ip, port))
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	Start()
+	defer Shutdown()
+
+	// the public listener is withheld, only loopback is available.
+	assert.Equal(fmt.Sprintf("127.0.0.1:%d", port), loopbackLn.Addr().String())
+	assert.NoError(get("127.0.0.1"))
+	ip := nonLoopbackIP()
+	if ip != "" {
+		assert.Error(get(ip))
+	}
+
+	// a user exists.
+	BindPublic()
+	assert.Nil(loopbackLn)
+	assert.NoError(get("127.0.0.1"))
+	if ip != "" {
+		assert.NoError(get(ip))
+	}
+
+	// calling it again does nothing.
+	BindPublic()
+	assert.NoError(get("127.0.0.1"))
+}
+
+func TestShutdownContext(t *testing.T) {
+	assert := assert.New(t)
+
+	defer func(s *http.Server) { svr = s }(svr)
+
+	// start serves a handler which blocks until 'release' is closed, and
+	// sends a request to it in the background.
+	start := func() (release chan struct{}, result chan error) {
+		started := make(chan struct{})
+		release, result = make(chan struct{}), make(chan error, 1)
+		svr = &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			<-release
+			w.Write([]byte("done"))
+		})}
+
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		go svr.Serve(ln)
+
+		go func() {
+			resp, err := http.Get("http://" + ln.Addr().String() + "/slow")
+			if err == nil {
+				var body []byte
+				body, err = io.ReadAll(resp.Body)
+				resp.Body.Close()
+				if err == nil && string(body) != "done" {
+					err = fmt.Errorf("unexpected body: %q", body)
+				}
+			}
+			result <- err
+		}()
+
+		<-started
+		return release, result
+	}
+
+	// the slow handler completes during shutdown.
+	release, result := start()
+	time.AfterFunc(100*time.Millisecond, func() { close(release) })
+	begin := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	assert.NoError(ShutdownContext(ctx))
+	cancel()
+	assert.GreaterOrEqual(time.Since(begin), 100*time.Millisecond)
+	assert.NoError(<-result)
+
+	// the connection is closed if the handler does not complete in time.
+	release, result = start()
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	assert.ErrorIs(ShutdownContext(ctx), context.DeadlineExceeded)
+	cancel()
+	assert.Error(<-result)
+	close(release)
+}
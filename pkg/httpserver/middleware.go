@@ -0,0 +1,136 @@
+package httpserver
+
+import (
+	"bufio"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"time"
+
+	"github.com/localvar/xuandb/pkg/config"
+)
+
+// Middleware wraps an http.Handler to a new http.Handler.
+type Middleware func(http.Handler) http.Handler
+
+// middlewares is the middleware chain applied to [mux] in Start, the first
+// one is the outermost.
+var middlewares = []Middleware{logRequest, requireTLSForAuth, gzipResponse}
+
+// Use appends a middleware to the middleware chain, it must be called before
+// Start.
+func Use(middleware func(http.Handler) http.Handler) {
+	middlewares = append(middlewares, middleware)
+}
+
+// buildHandler applies the middleware chain to [mux] and returns the result.
+func buildHandler() http.Handler {
+	var h http.Handler = mux
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		h = middlewares[i](h)
+	}
+	return h
+}
+
+// responseWriter wraps an http.ResponseWriter to capture the status code and
+// the number of bytes written.
+type responseWriter struct {
+	http.ResponseWriter
+	status int
+	size   int
+}
+
+// WriteHeader implements [http.ResponseWriter].
+func (rw *responseWriter) WriteHeader(code int) {
+	if rw.status == 0 {
+		rw.status = code
+	}
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+// Write implements [http.ResponseWriter].
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	if rw.status == 0 {
+		rw.status = http.StatusOK
+	}
+	n, err := rw.ResponseWriter.Write(b)
+	rw.size += n
+	return n, err
+}
+
+// Flush implements [http.Flusher].
+func (rw *responseWriter) Flush() {
+	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
+		if rw.status == 0 {
+			rw.status = http.StatusOK
+		}
+		f.Flush()
+	}
+}
+
+// Hijack implements [http.Hijacker].
+func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := rw.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, errors.New("http.Hijacker is not supported")
+}
+
+// Unwrap returns the underlying http.ResponseWriter, it is used by
+// [http.ResponseController].
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// logRequest is a middleware that logs method, path, status code, number of
+// bytes written and latency of each request at debug level.
+func logRequest(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rw := &responseWriter{ResponseWriter: w}
+
+		next.ServeHTTP(rw, r)
+
+		if rw.status == 0 {
+			rw.status = http.StatusOK
+		}
+		slog.Debug(
+			"http request handled",
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", rw.status),
+			slog.Int("bytes", rw.size),
+			slog.Duration("latency", time.Since(start)),
+		)
+	})
+}
+
+// isLoopbackRequest returns whether request 'r' is from the loopback address.
+func isLoopbackRequest(r *http.Request) bool {
+	ap, err := netip.ParseAddrPort(r.RemoteAddr)
+	return err == nil && ap.Addr().IsLoopback()
+}
+
+// requireTLSForAuth is a middleware that rejects the requests which carry
+// credentials but arrive over a non-TLS connection if 'require-tls-for-auth'
+// is enabled.
+func requireTLSForAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		nc := config.CurrentNode()
+		if nc == nil || !nc.RequireTLSForAuth || r.TLS != nil || r.Header.Get("Authorization") == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if nc.AllowLoopbackPlaintextAuth && isLoopbackRequest(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Upgrade", "TLS/1.2, HTTP/1.1")
+		w.Header().Set("Connection", "Upgrade")
+		http.Error(w, "credentials must be sent over a TLS connection", http.StatusUpgradeRequired)
+	})
+}
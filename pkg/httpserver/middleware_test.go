@@ -0,0 +1,135 @@
+package httpserver
+
+import (
+	"bytes"
+	"crypto/tls"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/localvar/xuandb/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogRequest(t *testing.T) {
+	assert := assert.New(t)
+
+	var buf bytes.Buffer
+	old := slog.Default()
+	opts := &slog.HandlerOptions{Level: slog.LevelDebug}
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, opts)))
+	defer slog.SetDefault(old)
+
+	HandleFunc("GET /test/log-request", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("hello"))
+		assert.Implements((*http.Flusher)(nil), w)
+		assert.Implements((*http.Hijacker)(nil), w)
+	})
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/test/log-request", nil)
+	buildHandler().ServeHTTP(w, r)
+
+	assert.Equal(http.StatusAccepted, w.Code)
+	assert.Equal("hello", w.Body.String())
+
+	log := buf.String()
+	assert.Contains(log, "level=DEBUG")
+	assert.Contains(log, `msg="http request handled"`)
+	assert.Contains(log, "method=GET")
+	assert.Contains(log, "path=/test/log-request")
+	assert.Contains(log, "status=202")
+	assert.Contains(log, "bytes=5")
+	assert.Contains(log, "latency=")
+}
+
+func TestUse(t *testing.T) {
+	defer func(m []Middleware) { middlewares = m }(middlewares)
+
+	var order []string
+	for _, name := range []string{"a", "b"} {
+		Use(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		})
+	}
+
+	HandleFunc("GET /test/use", func(w http.ResponseWriter, r *http.Request) {})
+	r := httptest.NewRequest(http.MethodGet, "/test/use", nil)
+	buildHandler().ServeHTTP(httptest.NewRecorder(), r)
+	assert.Equal(t, []string{"a", "b"}, order)
+}
+
+const requireTLSCfg = `
+[[node]]
+	id = "1"
+	http-addr = "127.0.0.1:7001"
+	tls-cert-file = "cert.pem"
+	tls-key-file = "key.pem"
+	require-tls-for-auth = true
+	allow-loopback-plaintext-auth = %v
+	[node.meta]
+		raft-voter = true
+		raft-addr = "127.0.0.1:8001"
+		raft-store = "memory"
+		raft-snapshot-store = "memory"
+`
+
+func TestRequireTLSForAuth(t *testing.T) {
+	assert := assert.New(t)
+
+	load := func(allowLoopback bool) {
+		path := filepath.Join(t.TempDir(), "xuandb.toml")
+		data := fmt.Sprintf(requireTLSCfg, allowLoopback)
+		require.NoError(t, os.WriteFile(path, []byte(data), 0600))
+		require.NoError(t, flag.Set("config", path))
+		require.NoError(t, config.Load("1", false))
+	}
+
+	HandleFunc("GET /test/require-tls", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	serve := func(auth, secure bool, remoteAddr string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/test/require-tls", nil)
+		if auth {
+			r.SetBasicAuth("alice", "secret")
+		}
+		if secure {
+			r.TLS = &tls.ConnectionState{}
+		}
+		if remoteAddr != "" {
+			r.RemoteAddr = remoteAddr
+		}
+		w := httptest.NewRecorder()
+		buildHandler().ServeHTTP(w, r)
+		return w
+	}
+
+	load(false)
+
+	// credentials over plaintext are rejected.
+	w := serve(true, false, "")
+	assert.Equal(http.StatusUpgradeRequired, w.Code)
+	assert.Equal("TLS/1.2, HTTP/1.1", w.Header().Get("Upgrade"))
+	assert.Equal(http.StatusUpgradeRequired, serve(true, false, "127.0.0.1:1234").Code)
+
+	// credentials over TLS, and requests without credentials are allowed.
+	assert.Equal(http.StatusNoContent, serve(true, true, "").Code)
+	assert.Equal(http.StatusNoContent, serve(false, false, "").Code)
+
+	// loopback is exempted.
+	load(true)
+	assert.Equal(http.StatusNoContent, serve(true, false, "127.0.0.1:1234").Code)
+	assert.Equal(http.StatusNoContent, serve(true, false, "[::1]:1234").Code)
+	assert.Equal(http.StatusUpgradeRequired, serve(true, false, "").Code)
+}
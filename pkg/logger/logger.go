@@ -2,6 +2,7 @@ package logger
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
@@ -19,9 +20,24 @@ var lvlVar = &slog.LevelVar{}
 // Init initialize a logger according to the configuration and set it as the
 // slog.Default().
 func Init() {
-	lc := config.CurrentNode().Logger
+	Reconfigure(config.CurrentNode().Logger)
+}
+
+// Reconfigure rebuilds the logger according to 'lc' and set it as the
+// slog.Default(). The current logger is kept if 'lc' is invalid.
+func Reconfigure(lc *config.LoggerConfig) {
+	handler, err := newHandler(lc)
+	if err != nil {
+		slog.Error("failed to reconfigure logger", slog.String("error", err.Error()))
+		return
+	}
 
 	lvlVar.Set(lc.Level)
+	slog.SetDefault(slog.New(handler))
+}
+
+// newHandler builds a slog.Handler according to 'lc'.
+func newHandler(lc *config.LoggerConfig) (slog.Handler, error) {
 	opts := &slog.HandlerOptions{
 		AddSource: lc.AddSource,
 		Level:     lvlVar,
@@ -37,19 +53,13 @@ func Init() {
 		w = io.Discard
 	default:
 		// TODO: add file handler
-		panic("not implemented")
+		return nil, fmt.Errorf("unknown log output: %s", lc.OutputTo)
 	}
 
-	var handler slog.Handler
 	if lc.Format == "json" {
-		handler = slog.NewJSONHandler(w, opts)
-	} else {
-		handler = slog.NewTextHandler(w, opts)
+		return slog.NewJSONHandler(w, opts), nil
 	}
-
-	logger := slog.New(handler)
-	slog.SetDefault(logger)
-
+	return slog.NewTextHandler(w, opts), nil
 }
 
 // HandleGetLevel is an http handler that returns the current minimal log level.
@@ -0,0 +1,69 @@
+package meta
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/localvar/xuandb/pkg/xerrors"
+)
+
+// Operations of the meta data mutations.
+const (
+	MutationCreateUser     = opCreateUser
+	MutationDropUser       = opDropUser
+	MutationSetPassword    = opSetPassword
+	MutationSetUserEnabled = opSetUserEnabled
+	MutationCreateDatabase = opCreateDatabase
+	MutationDropDatabase   = opDropDatabase
+	MutationAddNode        = "add-node"
+	MutationDropNode       = "drop-node"
+	MutationDrainNode      = "drain-node"
+)
+
+// Mutation describes a meta data mutation which is going to be applied.
+type Mutation struct {
+	// Op is the operation, one of the MutationXXXXXX constants.
+	Op string
+
+	// Name is the name of the user or database, or the ID of the node.
+	Name string
+
+	// Object is the object to be created or updated, that is, a *User for
+	// MutationCreateUser and MutationSetPassword, and a *Database for
+	// MutationCreateDatabase. It is nil for other operations. Hooks must not
+	// modify it.
+	Object any
+}
+
+// AdmissionHook is called on the leader before a meta data mutation is
+// applied, the mutation is rejected if it returns an error. The error is
+// returned to the client, its status code is 403 if it is not an
+// [xerrors.StatusError].
+type AdmissionHook func(m *Mutation) error
+
+// admissionHooks are the registered admission hooks.
+var admissionHooks []AdmissionHook
+
+// AddAdmissionHook registers admission hook 'hook', hooks are called in the
+// order of registration. It is not safe for concurrent use and must be
+// called before the meta service is started.
+func AddAdmissionHook(hook AdmissionHook) {
+	admissionHooks = append(admissionHooks, hook)
+}
+
+// admit calls the admission hooks for mutation 'm', and returns the error of
+// the first hook which rejects it.
+func admit(m *Mutation) error {
+	for _, hook := range admissionHooks {
+		if err := hook(m); err != nil {
+			slog.Debug(
+				"mutation rejected by admission hook",
+				slog.String("op", m.Op),
+				slog.String("name", m.Name),
+				slog.String("error", err.Error()),
+			)
+			return xerrors.Wrap(err, http.StatusForbidden)
+		}
+	}
+	return nil
+}
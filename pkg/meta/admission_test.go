@@ -0,0 +1,75 @@
+package meta
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/localvar/xuandb/pkg/xerrors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdmissionHook(t *testing.T) {
+	assert := assert.New(t)
+
+	s := newService()
+	defer func(inst *service) { svcInst = inst }(svcInst)
+	svcInst = s
+	startTestRaft(t, s, "1")
+
+	dbInit()
+	defer dbUninit()
+
+	defer func(hooks []AdmissionHook) { admissionHooks = hooks }(admissionHooks)
+	admissionHooks = nil
+
+	// database names must be in lower case.
+	pattern := regexp.MustCompile(`^[a-z_]+$`)
+	var ops []string
+	AddAdmissionHook(func(m *Mutation) error {
+		ops = append(ops, m.Op)
+		return nil
+	})
+	AddAdmissionHook(func(m *Mutation) error {
+		if m.Op == MutationCreateDatabase && !pattern.MatchString(m.Name) {
+			return errors.New("database name must match " + pattern.String())
+		}
+		if m.Op == MutationDropDatabase && m.Name == "keep" {
+			return xerrors.New(http.StatusConflict, "database 'keep' cannot be dropped")
+		}
+		return nil
+	})
+
+	create := func(name string) *httptest.ResponseRecorder {
+		body := bytes.NewReader([]byte(`{"name":"` + name + `"}`))
+		req := httptest.NewRequest(http.MethodPost, "/meta/databases", body)
+		w := httptest.NewRecorder()
+		handleCreateDatabase(w, req)
+		return w
+	}
+
+	w := create("Foo")
+	assert.Equal(http.StatusForbidden, w.Code)
+	assert.Contains(w.Body.String(), "database name must match")
+	assert.Nil(DatabaseByName("Foo"))
+
+	w = create("foo")
+	assert.Equal(http.StatusNoContent, w.Code)
+	assert.NotNil(DatabaseByName("foo"))
+
+	assert.Equal(http.StatusNoContent, create("keep").Code)
+	err := leaderDropDatabase("keep", false)
+	assert.Equal(http.StatusConflict, err.(*xerrors.StatusError).StatusCode)
+	assert.NotNil(DatabaseByName("keep"))
+
+	// the first hook sees all mutations, including the rejected ones.
+	assert.Equal([]string{
+		MutationCreateDatabase,
+		MutationCreateDatabase,
+		MutationCreateDatabase,
+		MutationDropDatabase,
+	}, ops)
+}
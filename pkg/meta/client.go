@@ -2,46 +2,216 @@ package meta
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"io"
+	"log/slog"
+	"net"
 	"net/http"
+	"time"
 
+	"github.com/localvar/xuandb/pkg/config"
+	"github.com/localvar/xuandb/pkg/httpserver"
 	"github.com/localvar/xuandb/pkg/xerrors"
 )
 
 // ErrMetaServiceUnavailable means there's no available meta serice.
 var ErrMetaServiceUnavailable = xerrors.New(http.StatusServiceUnavailable, "meta service is unavailable")
 
-// sendRequestToLeader sends an HTTP post request to the leader node of the
-// meta service.
-func sendRequestToLeader(method, pathAndQuery string, data any) error {
-	addr := LeaderHTTPAddr()
-	if addr == "" {
-		return ErrMetaServiceUnavailable
+// leaderHTTPAddr returns the HTTP address of the leader node.
+var leaderHTTPAddr = LeaderHTTPAddr
+
+// LeaderHintHeader is the header of the server error responses of the meta
+// API, its value is the HTTP address of the leader known by the responding
+// node, clients can send the request to it directly.
+const LeaderHintHeader = "X-Meta-Leader-Hint"
+
+// ClusterNameHeader is the header carrying the cluster name of the sender of
+// a meta API request, requests from other clusters are refused.
+const ClusterNameHeader = "X-Meta-Cluster-Name"
+
+// errWrongCluster is returned when a request is from another cluster.
+var errWrongCluster = xerrors.New(http.StatusForbidden, "wrong cluster name")
+
+// leaderHintWriter is an http.ResponseWriter which sets LeaderHintHeader on
+// server error responses.
+type leaderHintWriter struct {
+	http.ResponseWriter
+}
+
+func (w leaderHintWriter) WriteHeader(code int) {
+	if code >= 500 {
+		if addr := leaderHTTPAddr(); addr != "" {
+			w.Header().Set(LeaderHintHeader, addr)
+		}
 	}
+	w.ResponseWriter.WriteHeader(code)
+}
 
-	var body io.Reader
+// metaHandler wraps the handler of a meta API, the wrapped handler refuses
+// requests from other clusters and adds the leader hint to the server error
+// responses.
+func metaHandler(handler func(http.ResponseWriter, *http.Request)) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w = leaderHintWriter{w}
+		if name := r.Header.Get(ClusterNameHeader); name != config.ClusterName() {
+			slog.Warn(
+				"request from another cluster refused",
+				slog.String("path", r.URL.Path),
+				slog.String("clusterName", name),
+				slog.String("remoteAddr", r.RemoteAddr),
+			)
+			xerrors.WriteJSON(w, errWrongCluster)
+			return
+		}
+		handler(w, r)
+	}
+}
+
+// handleFunc registers the handler of a meta API, see metaHandler for the
+// details.
+func handleFunc(pattern string, handler func(http.ResponseWriter, *http.Request)) {
+	httpserver.HandleFunc(pattern, metaHandler(handler))
+}
+
+// operatorHandler wraps the handler of a meta API which is called by the
+// operators instead of other nodes, so the cluster name is not required, the
+// wrapped handler only adds the leader hint to the server error responses.
+func operatorHandler(handler func(http.ResponseWriter, *http.Request)) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handler(leaderHintWriter{w}, r)
+	}
+}
+
+// handleOperatorFunc registers the handler of a meta API for the operators,
+// see operatorHandler for the details.
+func handleOperatorFunc(pattern string, handler func(http.ResponseWriter, *http.Request)) {
+	httpserver.HandleFunc(pattern, operatorHandler(handler))
+}
+
+// connection pool settings of the client.
+const (
+	maxIdleConnsPerHost = 16
+	idleConnTimeout     = 90 * time.Second
+)
+
+// client is the http client for sending requests to other meta nodes. It is
+// shared by all requests so that idle connections are reused instead of
+// setting up a new one for every request.
+var client = &http.Client{}
+
+// initClient configures the client with the transport settings of the
+// cluster, e.g. TLS, and the request timeout.
+func initClient(timeout time.Duration) {
+	tr, ok := httpserver.Client().Transport.(*http.Transport)
+	if !ok {
+		tr = http.DefaultTransport.(*http.Transport)
+	}
+	tr = tr.Clone()
+	tr.MaxIdleConnsPerHost = maxIdleConnsPerHost
+	tr.IdleConnTimeout = idleConnTimeout
+
+	client.Transport = tr
+	client.Timeout = timeout
+}
+
+// retry settings of requests to the leader, the delay before the n-th retry
+// is retryBaseDelay * 2^(n-1). They are replaced by the configured ones when
+// the service starts.
+var (
+	retryAttempts  = 3
+	retryBaseDelay = 100 * time.Millisecond
+)
+
+// sendRequestToLeader sends an HTTP request to the leader node of the meta
+// service, the response body is decoded to 'result' if it is not nil. The
+// request is retried with exponential backoff if there's no leader or the
+// leader is unreachable or unavailable, for example, during an election,
+// and the leader address is resolved again before every retry. If a failed
+// response carries a leader hint, the request is sent to the hinted address
+// immediately.
+//
+// A request with a non-idempotent method, e.g. POST, may have been applied
+// even if it failed, so it is only retried if it was not sent, that is, the
+// leader is unknown or cannot be connected, or if it was refused by a node
+// which knows another leader.
+func sendRequestToLeader(ctx context.Context, method, pathAndQuery string, data, result any) error {
+	var body []byte
+	var err error
 	if data != nil {
-		d, err := json.Marshal(data)
-		if err != nil {
+		if body, err = json.Marshal(data); err != nil {
 			return xerrors.Wrap(err, http.StatusInternalServerError)
 		}
-		body = bytes.NewReader(d)
 	}
 
-	url := "http://" + addr + pathAndQuery
-	req, err := http.NewRequest(method, url, body)
+	var hint string
+	for i := range retryAttempts {
+		addr := hint
+		if addr != "" {
+			slog.Debug(
+				"follow leader hint",
+				slog.String("path", pathAndQuery),
+				slog.String("leaderAddr", addr),
+			)
+		} else if i > 0 {
+			delay := retryBaseDelay << (i - 1)
+			slog.Debug(
+				"retry request to leader",
+				slog.String("path", pathAndQuery),
+				slog.Int("attempt", i+1),
+				slog.Duration("delay", delay),
+				slog.String("error", err.Error()),
+			)
+			select {
+			case <-ctx.Done():
+				return err
+			case <-time.After(delay):
+			}
+		}
+
+		if addr == "" {
+			addr = leaderHTTPAddr()
+		}
+
+		var retry bool
+		retry, hint, err = sendRequestToLeaderOnce(ctx, addr, method, pathAndQuery, body, result)
+		if !retry {
+			break
+		}
+	}
+
+	return err
+}
+
+// sendRequestToLeaderOnce makes a single attempt of sendRequestToLeader to
+// the leader at 'addr'. If the request failed, it returns whether it should
+// be retried and the leader hint of the response, if any.
+func sendRequestToLeaderOnce(ctx context.Context, addr, method, pathAndQuery string, data []byte, result any) (bool, string, error) {
+	if addr == "" {
+		return true, "", ErrMetaServiceUnavailable
+	}
+
+	var body io.Reader
+	if data != nil {
+		body = bytes.NewReader(data)
+	}
+
+	url := httpserver.URL(addr, pathAndQuery)
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
 	if err != nil {
-		return xerrors.Wrap(err, http.StatusInternalServerError)
+		return false, "", xerrors.Wrap(err, http.StatusInternalServerError)
 	}
 
+	req.Header.Set(ClusterNameHeader, config.ClusterName())
 	if body != nil {
 		req.Header.Set("Content-Type", "application/json")
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
-		return xerrors.Wrap(err, http.StatusInternalServerError)
+		retry := ctx.Err() == nil && (isIdempotent(method) || isConnectError(err))
+		return retry, "", xerrors.Wrap(err, http.StatusInternalServerError)
 	}
 
 	defer func() {
@@ -49,21 +219,53 @@ func sendRequestToLeader(method, pathAndQuery string, data any) error {
 		resp.Body.Close()
 	}()
 
-	if resp.StatusCode < 300 {
-		return nil
+	if resp.StatusCode >= 300 {
+		hint := resp.Header.Get(LeaderHintHeader)
+		if hint == addr {
+			hint = ""
+		}
+		retry := hint != "" || (resp.StatusCode == http.StatusServiceUnavailable && isIdempotent(method))
+		return retry, hint, xerrors.FromHTTPResponse(resp)
+	}
+
+	if result != nil {
+		if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+			return false, "", xerrors.Wrap(err, http.StatusInternalServerError)
+		}
+	}
+
+	return false, "", nil
+}
+
+// isIdempotent returns whether requests with 'method' are idempotent, so that
+// they can be retried safely even if they may have been applied.
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
 	}
+	return false
+}
+
+// isConnectError returns whether 'err' is an error of connecting to the
+// server, which means the request was not sent.
+func isConnectError(err error) bool {
+	var oe *net.OpError
+	return errors.As(err, &oe) && oe.Op == "dial"
+}
 
-	return xerrors.FromHTTPResponse(resp)
+func sendGetRequestToLeader(ctx context.Context, pathAndQuery string, result any) error {
+	return sendRequestToLeader(ctx, http.MethodGet, pathAndQuery, nil, result)
 }
 
-func sendPostRequestToLeader(pathAndQuery string, data any) error {
-	return sendRequestToLeader(http.MethodPost, pathAndQuery, data)
+func sendPostRequestToLeader(ctx context.Context, pathAndQuery string, data any) error {
+	return sendRequestToLeader(ctx, http.MethodPost, pathAndQuery, data, nil)
 }
 
-func sendPutRequestToLeader(pathAndQuery string, data any) error {
-	return sendRequestToLeader(http.MethodPut, pathAndQuery, data)
+func sendPutRequestToLeader(ctx context.Context, pathAndQuery string, data any) error {
+	return sendRequestToLeader(ctx, http.MethodPut, pathAndQuery, data, nil)
 }
 
-func sendDeleteRequestToLeader(pathAndQuery string) error {
-	return sendRequestToLeader(http.MethodDelete, pathAndQuery, nil)
+func sendDeleteRequestToLeader(ctx context.Context, pathAndQuery string) error {
+	return sendRequestToLeader(ctx, http.MethodDelete, pathAndQuery, nil, nil)
 }
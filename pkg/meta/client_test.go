@@ -0,0 +1,210 @@
+package meta
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/raft"
+	"github.com/localvar/xuandb/pkg/xerrors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient(t *testing.T) {
+	assert := assert.New(t)
+
+	defer func(tr http.RoundTripper, timeout time.Duration) {
+		client.Transport, client.Timeout = tr, timeout
+	}(client.Transport, client.Timeout)
+
+	c := client
+	initClient(100 * time.Millisecond)
+	assert.Same(c, client)
+	assert.Equal(100*time.Millisecond, client.Timeout)
+	tr := client.Transport.(*http.Transport)
+	assert.Equal(maxIdleConnsPerHost, tr.MaxIdleConnsPerHost)
+	assert.Equal(idleConnTimeout, tr.IdleConnTimeout)
+
+	var conns atomic.Int32
+	leader := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(time.Second)
+		}
+		w.Write([]byte(`{}`))
+	}))
+	leader.Config.ConnState = func(_ net.Conn, cs http.ConnState) {
+		if cs == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	leader.Start()
+	defer leader.Close()
+
+	defer func(fn func() string) { leaderHTTPAddr = fn }(leaderHTTPAddr)
+	leaderHTTPAddr = func() string { return strings.TrimPrefix(leader.URL, "http://") }
+
+	// the connection is reused by subsequent requests.
+	for range 3 {
+		assert.NoError(sendGetRequestToLeader(context.Background(), "/fast", nil))
+	}
+	assert.EqualValues(1, conns.Load())
+
+	// the request is canceled after the timeout.
+	start := time.Now()
+	assert.Error(sendGetRequestToLeader(context.Background(), "/slow", nil))
+	assert.Less(time.Since(start), time.Second)
+}
+
+// roundTripFunc is an http.RoundTripper implemented by a function.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestSendRequestToLeaderRetry(t *testing.T) {
+	assert := assert.New(t)
+
+	defer func(tr http.RoundTripper) { client.Transport = tr }(client.Transport)
+	defer func(n int, d time.Duration) { retryAttempts, retryBaseDelay = n, d }(retryAttempts, retryBaseDelay)
+	retryAttempts, retryBaseDelay = 3, time.Millisecond
+
+	// the leader address is resolved on every attempt.
+	var resolved int
+	defer func(fn func() string) { leaderHTTPAddr = fn }(leaderHTTPAddr)
+	leaderHTTPAddr = func() string {
+		resolved++
+		return "leader:7001"
+	}
+
+	// the connection fails twice and then succeeds.
+	var attempts int
+	var bodies []string
+	client.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		attempts++
+		data, _ := io.ReadAll(req.Body)
+		bodies = append(bodies, string(data))
+		if attempts <= 2 {
+			return nil, &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(`{"name":"foo"}`)),
+		}, nil
+	})
+
+	var db Database
+	err := sendRequestToLeader(context.Background(), http.MethodPost, "/meta/databases", &Database{Name: "foo"}, &db)
+	assert.NoError(err)
+	assert.Equal("foo", db.Name)
+	assert.Equal(3, attempts)
+	assert.Equal(3, resolved)
+	for _, b := range bodies {
+		assert.JSONEq(`{"name":"foo","duration":0,"createdAt":"0001-01-01T00:00:00Z","updatedAt":"0001-01-01T00:00:00Z"}`, b)
+	}
+
+	// give up after the maximum attempts.
+	attempts = 0
+	client.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		attempts++
+		return &http.Response{
+			StatusCode: http.StatusServiceUnavailable,
+			Header:     http.Header{"Content-Type": {"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(`{"error":"meta service is unavailable","code":503}`)),
+		}, nil
+	})
+	assert.Equal(ErrMetaServiceUnavailable, sendGetRequestToLeader(context.Background(), "/meta/databases", nil))
+	assert.Equal(3, attempts)
+
+	// but non-idempotent requests may have been applied, they are not
+	// retried.
+	attempts = 0
+	assert.Equal(ErrMetaServiceUnavailable, sendPostRequestToLeader(context.Background(), "/meta/databases", &Database{Name: "foo"}))
+	assert.Equal(1, attempts)
+
+	// neither are they on errors after the connection is established.
+	attempts = 0
+	client.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		attempts++
+		return nil, errors.New("connection reset by peer")
+	})
+	assert.Error(sendPostRequestToLeader(context.Background(), "/meta/databases", &Database{Name: "foo"}))
+	assert.Equal(1, attempts)
+	attempts = 0
+	assert.Error(sendGetRequestToLeader(context.Background(), "/meta/databases", nil))
+	assert.Equal(3, attempts)
+
+	// client errors are not retried.
+	attempts = 0
+	client.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		attempts++
+		return &http.Response{
+			StatusCode: http.StatusConflict,
+			Header:     http.Header{"Content-Type": {"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(`{"error":"database already exists","code":409}`)),
+		}, nil
+	})
+	assert.Equal(ErrDatabaseExists, sendPostRequestToLeader(context.Background(), "/meta/databases", &Database{Name: "foo"}))
+	assert.Equal(1, attempts)
+
+	// no retry after the deadline of the context.
+	leaderHTTPAddr = func() string { return "" }
+	retryBaseDelay = time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err = sendRequestToLeader(ctx, http.MethodGet, "/meta/databases", nil, nil)
+	assert.Equal(ErrMetaServiceUnavailable, err)
+	assert.Less(time.Since(start), time.Second)
+}
+
+func TestLeaderHint(t *testing.T) {
+	assert := assert.New(t)
+
+	var leaderCalls, followerCalls atomic.Int32
+	leader := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		leaderCalls.Add(1)
+		w.Write([]byte(`{"name":"foo"}`))
+	}))
+	defer leader.Close()
+	leaderAddr := strings.TrimPrefix(leader.URL, "http://")
+
+	// the local view of the client is stale, it believes the follower is
+	// the leader.
+	follower := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		followerCalls.Add(1)
+		w.Header().Set(LeaderHintHeader, leaderAddr)
+		xerrors.WriteJSON(w, xerrors.Wrap(raft.ErrNotLeader, http.StatusServiceUnavailable))
+	}))
+	defer follower.Close()
+	followerAddr := strings.TrimPrefix(follower.URL, "http://")
+
+	defer func(fn func() string) { leaderHTTPAddr = fn }(leaderHTTPAddr)
+	defer func(n int, d time.Duration) { retryAttempts, retryBaseDelay = n, d }(retryAttempts, retryBaseDelay)
+	retryAttempts, retryBaseDelay = 3, time.Hour
+
+	// the hint is set on server errors only.
+	leaderHTTPAddr = func() string { return leaderAddr }
+	w := httptest.NewRecorder()
+	leaderHintWriter{w}.WriteHeader(http.StatusInternalServerError)
+	assert.Equal(leaderAddr, w.Header().Get(LeaderHintHeader))
+	w = httptest.NewRecorder()
+	leaderHintWriter{w}.WriteHeader(http.StatusConflict)
+	assert.Empty(w.Header().Get(LeaderHintHeader))
+
+	// the client follows the hint immediately, the huge retry delay would
+	// fail the test otherwise.
+	var db Database
+	leaderHTTPAddr = func() string { return followerAddr }
+	assert.NoError(sendGetRequestToLeader(context.Background(), "/meta/databases/foo", &db))
+	assert.Equal("foo", db.Name)
+	assert.EqualValues(1, followerCalls.Load())
+	assert.EqualValues(1, leaderCalls.Load())
+}
@@ -0,0 +1,185 @@
+package meta
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"hash/fnv"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/raft"
+	"github.com/localvar/xuandb/pkg/xerrors"
+)
+
+// opResync is the raft operation to resynchronize the meta data of the
+// diverged nodes from the leader.
+const opResync = "resync"
+
+// resyncRegisterAPIHandlers registers the API handlers for resynchronization.
+func resyncRegisterAPIHandlers() {
+	handleFunc("POST /meta/resync", handleResync)
+}
+
+// indexAndHash returns the index and hash of the data, the hash covers the
+// users and databases, which are encoded in JSON with sorted keys, so that
+// it is the same on all nodes which have the same data.
+func (d *Data) indexAndHash() (uint64, string) {
+	d.lock()
+	defer d.unlock()
+
+	data, err := json.Marshal(struct {
+		Users     map[string]*User     `json:"users"`
+		Databases map[string]*Database `json:"databases"`
+	}{d.Users, d.Databases})
+	if err != nil {
+		// should not happen, and an empty hash disables the check.
+		slog.Error("failed to marshal meta data", slog.String("error", err.Error()))
+		return d.Index, ""
+	}
+
+	h := fnv.New64a()
+	h.Write(data)
+	return d.Index, strconv.FormatUint(h.Sum64(), 16)
+}
+
+// requestResync requests the leader to resynchronize the meta data.
+var requestResync = Resync
+
+// checkConsistency compares the data of the current node with the one of the
+// leader, which has index 'index' and hash 'hash'. The data can only be
+// compared if no data modification is applied between them, that is, the
+// indexes are the same. If the data diverges for longer than the read repair
+// period, a resynchronization is requested.
+func (s *service) checkConsistency(index uint64, hash string) {
+	idx, h := s.md.indexAndHash()
+	if idx != index {
+		return
+	}
+
+	if h == hash {
+		if !s.divergedSince.IsZero() {
+			slog.Info("meta data is consistent with the leader again")
+			s.divergedSince = time.Time{}
+		}
+		return
+	}
+
+	now := time.Now()
+	if s.divergedSince.IsZero() {
+		s.divergedSince = now
+		slog.Error(
+			"meta data diverges from the leader",
+			slog.Uint64("index", index),
+			slog.String("hash", h),
+			slog.String("leaderHash", hash),
+		)
+		return
+	}
+
+	if s.readRepairAfter <= 0 || now.Sub(s.divergedSince) < s.readRepairAfter {
+		return
+	}
+
+	// restart the period, so that the request is not sent too frequently if
+	// the resynchronization does not fix the divergence.
+	s.divergedSince = now
+	slog.Error(
+		"meta data diverges from the leader for too long, requesting resynchronization",
+		slog.Uint64("index", index),
+	)
+
+	// must not block applying raft logs.
+	go func() {
+		if err := requestResync(); err != nil {
+			slog.Error("failed to request resynchronization", slog.String("error", err.Error()))
+		}
+	}()
+}
+
+// resyncCommand is the raft FSM command to resynchronize the meta data.
+type resyncCommand struct {
+	baseCommand
+	Data *Data  `json:"data"`
+	Hash string `json:"hash"`
+}
+
+// applyResync applies a resyncCommand to the current node, the data is only
+// replaced if the current node diverges from the leader, and no data
+// modification is applied after the command was created.
+func applyResync(l *raft.Log) any {
+	var cmd resyncCommand
+	if err := json.Unmarshal(l.Data, &cmd); err != nil {
+		return err
+	}
+	if cmd.Data == nil {
+		return errors.New("invalid resync command: no data")
+	}
+
+	s := svcInst
+	idx, h := s.md.indexAndHash()
+	if idx != cmd.Data.Index || h == cmd.Hash {
+		return nil
+	}
+
+	s.md = cmd.Data
+	s.divergedSince = time.Time{}
+	slog.Warn(
+		"meta data resynchronized from the leader",
+		slog.Uint64("index", idx),
+		slog.String("hash", cmd.Hash),
+	)
+
+	if len(cmd.Data.Users) > 0 {
+		s.notifyUserExists()
+	}
+	return nil
+}
+
+// leaderResync replicates the meta data of the leader to all nodes. The
+// command contains all the data, so it is exempted from the command size
+// limit, see checkCommandSize.
+func leaderResync() error {
+	d := svcInst.md.clone()
+	_, h := d.indexAndHash()
+	cmd := &resyncCommand{
+		baseCommand: baseCommand{Op: opResync},
+		Data:        d,
+		Hash:        h,
+	}
+
+	if err := svcInst.raftApply(cmd); err != nil {
+		slog.Error("failed to resynchronize meta data", slog.String("error", err.Error()))
+		return err
+	}
+
+	slog.Info("meta data resynchronization replicated", slog.Uint64("index", d.Index))
+	return nil
+}
+
+// handleResync handles the resynchronization request.
+func handleResync(w http.ResponseWriter, r *http.Request) {
+	if !svcInst.isLeader() {
+		xerrors.WriteJSON(w, xerrors.New(http.StatusServiceUnavailable, "not leader"))
+		return
+	}
+
+	slog.Info("resynchronization request received")
+	if err := leaderResync(); err != nil {
+		xerrors.WriteJSON(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Resync resynchronizes the meta data of the nodes which diverge from the
+// leader.
+func Resync() error {
+	if svcInst.isLeader() {
+		return leaderResync()
+	}
+	return sendPostRequestToLeader(context.Background(), "/meta/resync", nil)
+}
@@ -0,0 +1,125 @@
+package meta
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/raft"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDataIndexAndHash(t *testing.T) {
+	assert := assert.New(t)
+
+	d1, d2 := newData(), newData()
+	d1.Users["alice"] = &User{Name: "alice", DbPriv: map[string]Privilege{"a": PrivilegeRead, "b": PrivilegeDDL}}
+	d2.Users["alice"] = &User{Name: "alice", DbPriv: map[string]Privilege{"b": PrivilegeDDL, "a": PrivilegeRead}}
+	d1.Index, d2.Index = 10, 10
+
+	idx1, h1 := d1.indexAndHash()
+	idx2, h2 := d2.indexAndHash()
+	assert.Equal(uint64(10), idx1)
+	assert.Equal(idx1, idx2)
+	assert.NotEmpty(h1)
+	assert.Equal(h1, h2)
+
+	d2.Databases["db"] = &Database{Name: "db"}
+	_, h2 = d2.indexAndHash()
+	assert.NotEqual(h1, h2)
+}
+
+func TestReadRepair(t *testing.T) {
+	assert := assert.New(t)
+
+	dbInit()
+	defer dbUninit()
+
+	// the leader.
+	s := newService()
+	defer func(inst *service) { svcInst = inst }(svcInst)
+	svcInst = s
+	startTestRaft(t, s, "1")
+
+	require.NoError(t, CreateDatabase(&Database{Name: "db1"}, false))
+	require.NoError(t, CreateDatabase(&Database{Name: "db2"}, false))
+	assert.Equal(s.raft.LastIndex(), s.md.Index)
+	index, hash := s.md.indexAndHash()
+
+	// a follower which missed the creation of 'db2'.
+	f := newService()
+	f.md = s.md.clone()
+	delete(f.md.Databases, "db2")
+	f.readRepairAfter = time.Hour
+
+	resyncs := make(chan struct{}, 1)
+	defer func(fn func() error) { requestResync = fn }(requestResync)
+	requestResync = func() error {
+		resyncs <- struct{}{}
+		return nil
+	}
+
+	applyNodeList := func(index uint64, hash string) {
+		data, _ := json.Marshal(&updateNodeListCommand{
+			baseCommand: baseCommand{Op: opUpdateNodeList},
+			Nodes:       map[string]*NodeInfo{},
+			DataIndex:   index,
+			DataHash:    hash,
+		})
+		svcInst = f
+		defer func() { svcInst = s }()
+		assert.Nil(f.Apply(&raft.Log{Index: index + 1, Data: data}))
+	}
+
+	// the divergence is detected, but not repaired within the period.
+	applyNodeList(index, hash)
+	assert.False(f.divergedSince.IsZero())
+	assert.Equal(index, f.md.Index, "node list updates must not change the data index")
+
+	// a different index cannot be compared.
+	since := f.divergedSince
+	applyNodeList(index+1, "different")
+	assert.Equal(since, f.divergedSince)
+
+	// the divergence lasts beyond the period, the follower requests a
+	// resynchronization.
+	f.divergedSince = f.divergedSince.Add(-time.Hour)
+	applyNodeList(index, hash)
+	select {
+	case <-resyncs:
+	case <-time.After(5 * time.Second):
+		assert.Fail("resynchronization is not requested")
+	}
+
+	// the leader replicates its data, it is not changed on the leader.
+	lastIndex := s.raft.LastIndex()
+	assert.NoError(Resync())
+	assert.Equal(lastIndex+1, s.raft.LastIndex())
+	assert.Equal(index, s.md.Index)
+	assert.Len(s.md.Databases, 2)
+
+	// the follower applies the same log and is repaired.
+	entry := &raft.Log{}
+	require.NoError(t, s.logs.GetLog(s.raft.LastIndex(), entry))
+	svcInst = f
+	assert.Nil(f.Apply(entry))
+	svcInst = s
+	assert.Len(f.md.Databases, 2)
+	assert.True(f.divergedSince.IsZero())
+	_, h := f.md.indexAndHash()
+	assert.Equal(hash, h)
+
+	// the data is consistent now.
+	applyNodeList(index, hash)
+	assert.True(f.divergedSince.IsZero())
+
+	// the data is not replaced if it was modified after the resync command
+	// was created.
+	f.md.Index++
+	delete(f.md.Databases, "db2")
+	svcInst = f
+	assert.Nil(f.Apply(entry))
+	svcInst = s
+	assert.Len(f.md.Databases, 1)
+}
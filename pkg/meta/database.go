@@ -1,6 +1,7 @@
 package meta
 
 import (
+	"context"
 	"encoding/json"
 	"log/slog"
 	"net/http"
@@ -8,11 +9,11 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/hashicorp/raft"
 	"github.com/localvar/xuandb/pkg/config"
-	"github.com/localvar/xuandb/pkg/httpserver"
 	"github.com/localvar/xuandb/pkg/xerrors"
 )
 
@@ -28,11 +29,22 @@ const (
 	opDropDatabase   = "drop-database"
 )
 
+// dbEventBufferSize is the number of database events buffered for the
+// handlers of the database informer.
+const dbEventBufferSize = 10
+
+// dbInformer calls the handlers of database events in a separate goroutine,
+// events are dropped if the buffer is full, so that slow handlers never
+// block the apply of raft logs. The resync handlers are called after events
+// were dropped, so that the subscribers can reconcile with Databases.
 type dbInformer struct {
 	wg             sync.WaitGroup
 	ch             chan any
+	dropped        atomic.Uint64
+	needResync     atomic.Bool
 	createHandlers []func(*Database)
 	dropHandlers   []func(string)
+	resyncHandlers []func()
 }
 
 func (di *dbInformer) AddCreateHandler(handler func(*Database)) {
@@ -43,8 +55,21 @@ func (di *dbInformer) AddDropHandler(handler func(string)) {
 	di.dropHandlers = append(di.dropHandlers, handler)
 }
 
+func (di *dbInformer) AddResyncHandler(handler func()) {
+	di.resyncHandlers = append(di.resyncHandlers, handler)
+}
+
 func (di *dbInformer) inform(evt any) {
-	di.ch <- evt
+	select {
+	case di.ch <- evt:
+	default:
+		n := di.dropped.Add(1)
+		di.needResync.Store(true)
+		slog.Error(
+			"database event dropped due to slow handlers",
+			slog.Uint64("dropped", n),
+		)
+	}
 }
 
 func (di *dbInformer) run() {
@@ -66,6 +91,15 @@ func (di *dbInformer) run() {
 			default:
 				panic("unknown database event")
 			}
+
+			// the buffer was full when an event was dropped, so there is
+			// always an event after the drop to get here.
+			if di.needResync.Swap(false) {
+				slog.Warn("resynchronizing database event handlers")
+				for _, handler := range di.resyncHandlers {
+					handler()
+				}
+			}
 		}
 	}()
 }
@@ -78,7 +112,7 @@ func (di *dbInformer) close() {
 var databaseInformer *dbInformer
 
 func dbInit() {
-	databaseInformer = &dbInformer{ch: make(chan any, 10)}
+	databaseInformer = &dbInformer{ch: make(chan any, dbEventBufferSize)}
 	databaseInformer.run()
 }
 
@@ -88,8 +122,9 @@ func databaseRegisterAPIHandlers() {
 	if !config.CurrentNode().Meta.RaftVoter {
 		return
 	}
-	httpserver.HandleFunc("POST /meta/databases", handleCreateDatabase)
-	httpserver.HandleFunc("DELETE /meta/databases", handleDropDatabase)
+	handleFunc("GET /meta/databases", handleListDatabases)
+	handleFunc("POST /meta/databases", handleCreateDatabase)
+	handleFunc("DELETE /meta/databases", handleDropDatabase)
 }
 
 func dbUninit() {
@@ -100,6 +135,36 @@ func dbUninit() {
 type Database struct {
 	Name     string        `json:"name"`
 	Duration time.Duration `json:"duration"`
+
+	// CreatedAt and UpdatedAt are set by the leader when the database is
+	// created and changed respectively.
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// reservedDatabaseNames are the names, in lower case, which are reserved for
+// the databases managed by xuandb itself.
+var reservedDatabaseNames = map[string]bool{
+	"_internal": true,
+	"_system":   true,
+}
+
+// ValidateDatabase validates database 'db' which is going to be created, a
+// zero 'Duration' means the data is retained forever.
+func ValidateDatabase(db *Database) error {
+	if db.Name == "" {
+		return xerrors.New(http.StatusBadRequest, "name is required")
+	}
+	if !namePattern.MatchString(db.Name) {
+		return xerrors.New(http.StatusBadRequest, "invalid database name: "+db.Name)
+	}
+	if reservedDatabaseNames[strings.ToLower(db.Name)] {
+		return xerrors.New(http.StatusBadRequest, "database name is reserved: "+db.Name)
+	}
+	if db.Duration < 0 {
+		return xerrors.New(http.StatusBadRequest, "duration must not be negative")
+	}
+	return nil
 }
 
 // handlers for the create database command.
@@ -123,19 +188,36 @@ func applyCreateDatabase(log *raft.Log) any {
 
 	if u := md.Databases[key]; u == nil {
 		md.Databases[key] = cmd.Database
+		databaseInformer.inform(cmd)
+		informer.publish(DatabaseCreated{Database: cmd.Database})
 		return nil
 	}
 
-	databaseInformer.inform(cmd)
 	return ErrDatabaseExists
 }
 
-func leaderCreateDatabase(db *Database) error {
+// leaderCreateDatabase creates database 'db', if 'ifNotExists' is true, it is
+// not an error if the database already exists.
+func leaderCreateDatabase(db *Database, ifNotExists bool) error {
+	if err := ValidateDatabase(db); err != nil {
+		return err
+	}
+
 	if DatabaseByName(db.Name) != nil {
 		slog.Debug("database already exists", slog.String("name", db.Name))
+		if ifNotExists {
+			return nil
+		}
 		return ErrDatabaseExists
 	}
 
+	m := &Mutation{Op: MutationCreateDatabase, Name: db.Name, Object: db}
+	if err := admit(m); err != nil {
+		return err
+	}
+
+	db.CreatedAt = time.Now()
+	db.UpdatedAt = db.CreatedAt
 	cmd := createDatabaseCommand{
 		baseCommand: baseCommand{Op: opCreateDatabase},
 		Database:    db,
@@ -146,6 +228,11 @@ func leaderCreateDatabase(db *Database) error {
 		return nil
 	}
 
+	if err == ErrDatabaseExists && ifNotExists {
+		slog.Debug("database already exists", slog.String("name", db.Name))
+		return nil
+	}
+
 	slog.Debug("create database failed", slog.String("error", err.Error()))
 	return err
 }
@@ -154,30 +241,32 @@ func handleCreateDatabase(w http.ResponseWriter, r *http.Request) {
 	db := &Database{}
 
 	if err := json.NewDecoder(r.Body).Decode(db); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		xerrors.WriteJSON(w, xerrors.New(http.StatusBadRequest, err.Error()))
 		return
 	}
 
-	if db.Name == "" {
-		http.Error(w, "name is required", http.StatusBadRequest)
-		return
-	}
+	ifNotExists := r.FormValue("ifNotExists") == "true"
 
 	slog.Debug("create database command received", slog.String("name", db.Name))
-	if err := leaderCreateDatabase(db); err != nil {
-		se := err.(*xerrors.StatusError)
-		http.Error(w, se.Msg, se.StatusCode)
+	if err := leaderCreateDatabase(db, ifNotExists); err != nil {
+		xerrors.WriteJSON(w, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
-func CreateDatabase(db *Database) error {
+// CreateDatabase creates a database, if 'ifNotExists' is true, it is not an
+// error if the database already exists.
+func CreateDatabase(db *Database, ifNotExists bool) error {
 	if svcInst.isLeader() {
-		return leaderCreateDatabase(db)
+		return leaderCreateDatabase(db, ifNotExists)
+	}
+	pathAndQuery := "/meta/databases"
+	if ifNotExists {
+		pathAndQuery += "?ifNotExists=true"
 	}
-	return sendPostRequestToLeader("/meta/databases", db)
+	return sendPostRequestToLeader(context.Background(), pathAndQuery, db)
 }
 
 // handlers for the drop database command.
@@ -196,19 +285,32 @@ func applyDropDatabase(log *raft.Log) any {
 	key := strings.ToLower(cmd.Name)
 
 	md.lock()
+	_, existed := md.Databases[key]
 	delete(md.Databases, key)
 	md.unlock()
 
 	databaseInformer.inform(cmd)
+	if existed {
+		informer.publish(DatabaseDropped{Name: cmd.Name})
+	}
 	return nil
 }
 
-func leaderDropDatabase(name string) error {
+// leaderDropDatabase drops database 'name', if 'ifExists' is true, it is not
+// an error if the database does not exist.
+func leaderDropDatabase(name string, ifExists bool) error {
 	if DatabaseByName(name) == nil {
 		slog.Debug("database does not exist", slog.String("name", name))
+		if ifExists {
+			return nil
+		}
 		return ErrDatabaseNotExists
 	}
 
+	if err := admit(&Mutation{Op: MutationDropDatabase, Name: name}); err != nil {
+		return err
+	}
+
 	cmd := dropDatabaseCommand{
 		baseCommand: baseCommand{Op: opDropDatabase},
 		Name:        name,
@@ -226,29 +328,37 @@ func leaderDropDatabase(name string) error {
 func handleDropDatabase(w http.ResponseWriter, r *http.Request) {
 	name := r.FormValue("name")
 	if name == "" {
-		http.Error(w, "name is required", http.StatusBadRequest)
+		xerrors.WriteJSON(w, xerrors.New(http.StatusBadRequest, "name is required"))
 		return
 	}
 
+	ifExists := r.FormValue("ifExists") == "true"
+
 	slog.Debug("drop database command received", slog.String("name", name))
-	if err := leaderDropDatabase(name); err != nil {
-		se := err.(*xerrors.StatusError)
-		http.Error(w, se.Msg, se.StatusCode)
+	if err := leaderDropDatabase(name, ifExists); err != nil {
+		xerrors.WriteJSON(w, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
-// DropDatabase drops a database.
-func DropDatabase(name string) error {
+// DropDatabase drops a database, if 'ifExists' is true, it is not an error if
+// the database does not exist.
+func DropDatabase(name string, ifExists bool) error {
 	if svcInst.isLeader() {
-		return leaderDropDatabase(name)
+		return leaderDropDatabase(name, ifExists)
+	}
+	pathAndQuery := "/meta/databases?name=" + url.QueryEscape(name)
+	if ifExists {
+		pathAndQuery += "&ifExists=true"
 	}
-	return sendDeleteRequestToLeader("/meta/databases?name=" + url.QueryEscape(name))
+	return sendDeleteRequestToLeader(context.Background(), pathAndQuery)
 }
 
-// Databases returns all databases. The result is sorted by name.
+// Databases returns all databases. The result is sorted by name. It reads the
+// local state, which may be stale on a follower, use DatabasesWithOptions for
+// a consistent read.
 func Databases() []*Database {
 	md := svcInst.md
 
@@ -276,7 +386,9 @@ func DatabaseByName(name string) *Database {
 	return md.Databases[key]
 }
 
-// DatabaseInformer returns the database informer.
+// DatabaseInformer returns the database informer. Its handlers are called
+// in order, but events are dropped and logged if the handlers fall behind,
+// and then the resync handlers are called.
 func DatabaseInformer() *dbInformer {
 	return databaseInformer
 }
@@ -0,0 +1,151 @@
+package meta
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDatabaseIfExists(t *testing.T) {
+	assert := assert.New(t)
+
+	s := newService()
+	defer func(inst *service) { svcInst = inst }(svcInst)
+	svcInst = s
+	startTestRaft(t, s, "1")
+
+	dbInit()
+	defer dbUninit()
+
+	created := make(chan string, 10)
+	DatabaseInformer().AddCreateHandler(func(db *Database) { created <- db.Name })
+
+	create := func(query string) int {
+		body := bytes.NewReader([]byte(`{"name":"foo"}`))
+		req := httptest.NewRequest(http.MethodPost, "/meta/databases"+query, body)
+		w := httptest.NewRecorder()
+		handleCreateDatabase(w, req)
+		return w.Code
+	}
+
+	drop := func(query string) int {
+		req := httptest.NewRequest(http.MethodDelete, "/meta/databases"+query, nil)
+		w := httptest.NewRecorder()
+		handleDropDatabase(w, req)
+		return w.Code
+	}
+
+	// CREATE DATABASE foo IF NOT EXISTS, twice.
+	before := time.Now()
+	assert.Equal(http.StatusNoContent, create("?ifNotExists=true"))
+	assert.Equal(http.StatusNoContent, create("?ifNotExists=true"))
+	db := DatabaseByName("foo")
+	if assert.NotNil(db) {
+		assert.False(db.CreatedAt.Before(before))
+		assert.Equal(db.CreatedAt, db.UpdatedAt)
+	}
+	assert.Equal(http.StatusConflict, create(""))
+
+	// only the successful creation is informed.
+	assert.Equal("foo", <-created)
+	assert.Empty(created)
+
+	// DROP DATABASE bar IF EXISTS.
+	assert.Equal(http.StatusNoContent, drop("?name=bar&ifExists=true"))
+	assert.Equal(http.StatusNotFound, drop("?name=bar"))
+
+	assert.Equal(http.StatusNoContent, drop("?name=foo"))
+	assert.Nil(DatabaseByName("foo"))
+}
+
+func TestDatabaseInformerBlockedHandler(t *testing.T) {
+	assert := assert.New(t)
+
+	s := newService()
+	defer func(inst *service) { svcInst = inst }(svcInst)
+	svcInst = s
+	startTestRaft(t, s, "1")
+
+	dbInit()
+	defer dbUninit()
+
+	// the handler is stuck until it is unblocked.
+	unblock := make(chan struct{})
+	var unblockOnce sync.Once
+	unblockHandler := func() { unblockOnce.Do(func() { close(unblock) }) }
+	defer unblockHandler()
+	DatabaseInformer().AddCreateHandler(func(db *Database) { <-unblock })
+	resynced := make(chan struct{}, 1)
+	DatabaseInformer().AddResyncHandler(func() { resynced <- struct{}{} })
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := range dbEventBufferSize + 5 {
+			assert.NoError(CreateDatabase(&Database{Name: fmt.Sprintf("db%d", i)}, false))
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("raft apply is blocked by the database event handler")
+	}
+
+	assert.Len(Databases(), dbEventBufferSize+5)
+	assert.NotZero(DatabaseInformer().dropped.Load())
+
+	// the handlers are asked to resynchronize once they catch up.
+	unblockHandler()
+	select {
+	case <-resynced:
+	case <-time.After(5 * time.Second):
+		t.Fatal("resync handler is not called after events were dropped")
+	}
+}
+
+func TestValidateDatabase(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.NoError(ValidateDatabase(&Database{Name: "foo"}))
+	assert.NoError(ValidateDatabase(&Database{Name: "_Foo_1", Duration: time.Hour}))
+	assert.EqualError(ValidateDatabase(&Database{}), "name is required")
+	assert.EqualError(ValidateDatabase(&Database{Name: "1foo"}), "invalid database name: 1foo")
+	assert.EqualError(ValidateDatabase(&Database{Name: "foo bar"}), "invalid database name: foo bar")
+	assert.EqualError(ValidateDatabase(&Database{Name: "_INTERNAL"}), "database name is reserved: _INTERNAL")
+	assert.EqualError(ValidateDatabase(&Database{Name: "foo", Duration: -time.Hour}), "duration must not be negative")
+
+	s := newService()
+	defer func(inst *service) { svcInst = inst }(svcInst)
+	svcInst = s
+	startTestRaft(t, s, "1")
+
+	dbInit()
+	defer dbUninit()
+
+	create := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/meta/databases", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		handleCreateDatabase(w, req)
+		return w
+	}
+
+	w := create(`{"name":"foo","duration":-1}`)
+	assert.Equal(http.StatusBadRequest, w.Code)
+	assert.JSONEq(`{"error":"duration must not be negative","code":400}`, w.Body.String())
+
+	w = create(`{"name":"foo\tbar"}`)
+	assert.Equal(http.StatusBadRequest, w.Code)
+	assert.JSONEq(`{"error":"invalid database name: foo\tbar","code":400}`, w.Body.String())
+	assert.Nil(DatabaseByName("foo\tbar"))
+
+	assert.Equal(http.StatusNoContent, create(`{"name":"foo","duration":0}`).Code)
+	assert.Zero(DatabaseByName("foo").Duration)
+}
@@ -1,11 +1,15 @@
 package meta
 
 import (
+	"bufio"
 	"encoding/json"
+	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/hashicorp/raft"
 	"github.com/localvar/xuandb/pkg/xerrors"
@@ -26,6 +30,10 @@ type Data struct {
 	l         sync.Mutex           `json:"-"`
 	Users     map[string]*User     `json:"users"`
 	Databases map[string]*Database `json:"databases"`
+
+	// Index is the index of the last applied raft log which may modify the
+	// data, it is used to check the consistency of the data across nodes.
+	Index uint64 `json:"index"`
 }
 
 // newData creates a new Data.
@@ -46,6 +54,10 @@ func (d *Data) clone() *Data {
 	for k, v := range d.Users {
 		r.Users[k] = v
 	}
+	for k, v := range d.Databases {
+		r.Databases[k] = v
+	}
+	r.Index = d.Index
 
 	return r
 }
@@ -60,19 +72,86 @@ func (d *Data) unlock() {
 	d.l.Unlock()
 }
 
+// snapshotPeakMemory is the peak memory in bytes used to buffer the data when
+// persisting the last snapshot.
+var snapshotPeakMemory atomic.Uint64
+
+// SnapshotPeakMemory returns the peak memory in bytes used to buffer the data
+// when persisting the last snapshot.
+func SnapshotPeakMemory() uint64 {
+	return snapshotPeakMemory.Load()
+}
+
+// snapshotWriter encodes the values of a snapshot one by one, so that only
+// one value need to be buffered in memory at a time. It also records the size
+// of the largest value.
+type snapshotWriter struct {
+	w    *bufio.Writer
+	enc  *json.Encoder
+	n    int // size of the value being encoded
+	peak int // size of the largest value
+}
+
+func newSnapshotWriter(w io.Writer) *snapshotWriter {
+	sw := &snapshotWriter{w: bufio.NewWriter(w)}
+	sw.enc = json.NewEncoder(sw)
+	return sw
+}
+
+// Write implements [io.Writer], it is called by the encoder.
+func (sw *snapshotWriter) Write(p []byte) (int, error) {
+	sw.n += len(p)
+	return sw.w.Write(p)
+}
+
+// encode encodes 'v' to the underlying writer.
+func (sw *snapshotWriter) encode(v any) error {
+	sw.n = 0
+	err := sw.enc.Encode(v)
+	sw.peak = max(sw.peak, sw.n)
+	return err
+}
+
+// writeSnapshotMap writes map 'm' as a JSON object, entry by entry.
+func writeSnapshotMap[V any](sw *snapshotWriter, m map[string]V) error {
+	sw.w.WriteByte('{')
+	first := true
+	for k, v := range m {
+		if !first {
+			sw.w.WriteByte(',')
+		}
+		first = false
+		if err := sw.encode(k); err != nil {
+			return err
+		}
+		sw.w.WriteByte(':')
+		if err := sw.encode(v); err != nil {
+			return err
+		}
+	}
+	sw.w.WriteByte('}')
+	return nil
+}
+
 // Persist implements [raft.FSMSnapshot]
 func (d *Data) Persist(sink raft.SnapshotSink) error {
 	err := func() error {
-		// Encode data.
-		b, err := json.Marshal(d)
-		if err != nil {
+		// Encode data to sink, errors of the bufio.Writer are sticky and
+		// reported by Flush.
+		sw := newSnapshotWriter(sink)
+		sw.w.WriteString(`{"users":`)
+		if err := writeSnapshotMap(sw, d.Users); err != nil {
 			return err
 		}
-
-		// Write data to sink.
-		if _, err := sink.Write(b); err != nil {
+		sw.w.WriteString(`,"databases":`)
+		if err := writeSnapshotMap(sw, d.Databases); err != nil {
+			return err
+		}
+		fmt.Fprintf(sw.w, `,"index":%d}`, d.Index)
+		if err := sw.w.Flush(); err != nil {
 			return err
 		}
+		snapshotPeakMemory.Store(uint64(sw.w.Size() + sw.peak))
 
 		// Close the sink.
 		return sink.Close()
@@ -85,6 +164,68 @@ func (d *Data) Persist(sink raft.SnapshotSink) error {
 	return err
 }
 
+// readSnapshotMap reads a JSON object to map 'm', entry by entry.
+func readSnapshotMap[V any](dec *json.Decoder, m map[string]V) error {
+	t, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if t == nil {
+		return nil // null
+	}
+	if t != json.Delim('{') {
+		return fmt.Errorf("invalid snapshot: unexpected %v", t)
+	}
+
+	for dec.More() {
+		if t, err = dec.Token(); err != nil {
+			return err
+		}
+		var v V
+		if err = dec.Decode(&v); err != nil {
+			return err
+		}
+		m[t.(string)] = v
+	}
+
+	_, err = dec.Token() // '}'
+	return err
+}
+
+// readSnapshot reads the data from snapshot 'r'.
+func (d *Data) readSnapshot(r io.Reader) error {
+	dec := json.NewDecoder(r)
+	if t, err := dec.Token(); err != nil {
+		return err
+	} else if t != json.Delim('{') {
+		return fmt.Errorf("invalid snapshot: unexpected %v", t)
+	}
+
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch t {
+		case "users":
+			err = readSnapshotMap(dec, d.Users)
+		case "databases":
+			err = readSnapshotMap(dec, d.Databases)
+		case "index":
+			err = dec.Decode(&d.Index)
+		default:
+			var v json.RawMessage
+			err = dec.Decode(&v) // skip unknown fields
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := dec.Token() // '}'
+	return err
+}
+
 // Release implements [raft.FSMSnapshot]
 func (d *Data) Release() {
 }
@@ -106,18 +247,67 @@ func (d *Data) Release() {
 //     leaderXXXXXX directly if called from the leader.
 var raftApplyFuncs = map[string]func(*raft.Log) any{
 	opUpdateNodeList: applyUpdateNodeList,
+	opResync:         applyResync,
 
 	opCreateDatabase: applyCreateDatabase,
 	opDropDatabase:   applyDropDatabase,
 
-	opCreateUser:  applyCreateUser,
-	opDropUser:    applyDropUser,
-	opSetPassword: applySetPassword,
+	opCreateUser:     applyCreateUser,
+	opBulkCreateUser: applyBulkCreateUser,
+	opDropUser:       applyDropUser,
+	opSetPassword:    applySetPassword,
+	opSetUserEnabled: applySetUserEnabled,
 }
 
+// commandVersion is the latest version of the commands which the current
+// node understands. It must be increased when the JSON shape of a command
+// changes, so that nodes running an older version refuse to apply commands
+// they would misparse.
+const commandVersion = 1
+
 // baseCommand is the base of all data operation commands.
 type baseCommand struct {
 	Op string `json:"op"`
+
+	// Version is the version of the command, 0 means 1 for the commands
+	// written before the version was introduced.
+	Version int `json:"version,omitempty"`
+}
+
+// version returns the version of the command.
+func (c *baseCommand) version() int {
+	if c.Version == 0 {
+		return 1
+	}
+	return c.Version
+}
+
+// batchCommand is implemented by commands which operate on a batch of
+// objects.
+type batchCommand interface {
+	batchSize() int
+}
+
+// checkCommandSize checks the size of command 'v', which is 'size' bytes
+// after marshaling, against the configured limit. A too large raft log entry
+// could destabilize the cluster, so it is rejected before being applied.
+func (s *service) checkCommandSize(v any, size int) error {
+	if s.maxCommandSize <= 0 || size <= s.maxCommandSize {
+		return nil
+	}
+
+	// the data can be resynchronized only as a whole, and the node list is
+	// built by the leader, the limit is for the commands of clients.
+	switch v.(type) {
+	case *resyncCommand, *updateNodeListCommand:
+		return nil
+	}
+
+	msg := fmt.Sprintf("command size %d exceeds the limit of %d bytes", size, s.maxCommandSize)
+	if bc, ok := v.(batchCommand); ok {
+		msg += fmt.Sprintf(", please split the batch of %d items into smaller ones", bc.batchSize())
+	}
+	return xerrors.New(http.StatusRequestEntityTooLarge, msg)
 }
 
 // raftApply is a helper function to apply a command to the Raft log.
@@ -127,8 +317,15 @@ func (s *service) raftApply(v any) error {
 		return xerrors.Wrap(err, http.StatusInternalServerError)
 	}
 
+	if err := s.checkCommandSize(v, len(data)); err != nil {
+		return err
+	}
+
 	future := s.raft.Apply(data, 0)
 	if err := future.Error(); err != nil {
+		if err == raft.ErrNotLeader || err == raft.ErrLeadershipLost {
+			return xerrors.Wrap(err, http.StatusServiceUnavailable)
+		}
 		return xerrors.Wrap(err, http.StatusInternalServerError)
 	}
 
@@ -144,6 +341,57 @@ func (s *service) raftApply(v any) error {
 	}
 }
 
+// ApplyFailureStats records the failures of applying raft logs to the FSM of the
+// current node. Note errors like 'user already exists' are the expected
+// results of the commands and are not counted as failures, only errors like
+// unmarshal failures, which mean the FSM of the current node may diverge from
+// others, are counted.
+type ApplyFailureStats struct {
+	Count       uint64    `json:"count"`       // total number of failures
+	Consecutive uint64    `json:"consecutive"` // number of consecutive failures
+	LastIndex   uint64    `json:"lastIndex"`   // index of the last failed log
+	LastError   string    `json:"lastError"`   // error of the last failure
+	LastTime    time.Time `json:"lastTime"`    // time of the last failure
+}
+
+// recordApplyResult records the result of applying raft log 'l'.
+func (s *service) recordApplyResult(l *raft.Log, result any) {
+	err, _ := result.(error)
+	if _, ok := err.(*xerrors.StatusError); ok {
+		err = nil
+	}
+
+	s.applyFailuresLock.Lock()
+	defer s.applyFailuresLock.Unlock()
+
+	af := &s.applyFailures
+	if err == nil {
+		af.Consecutive = 0
+		return
+	}
+
+	af.Count++
+	af.Consecutive++
+	af.LastIndex = l.Index
+	af.LastError = err.Error()
+	af.LastTime = time.Now()
+
+	slog.Warn(
+		"failed to apply raft log, meta data may diverge from other nodes",
+		slog.Uint64("index", l.Index),
+		slog.Uint64("consecutiveFailures", af.Consecutive),
+		slog.String("error", err.Error()),
+	)
+
+	if s.maxApplyFailures > 0 && af.Consecutive >= uint64(s.maxApplyFailures) {
+		slog.Error(
+			"too many consecutive raft log apply failures, halting",
+			slog.Uint64("consecutiveFailures", af.Consecutive),
+		)
+		panic("too many consecutive raft log apply failures")
+	}
+}
+
 // Apply implements [raft.FSM]
 func (s *service) Apply(l *raft.Log) any {
 	var cmd baseCommand
@@ -152,6 +400,7 @@ func (s *service) Apply(l *raft.Log) any {
 			"failed to unmarshal raft log to data operation command",
 			slog.String("error", err.Error()),
 		)
+		s.recordApplyResult(l, err)
 		return err
 	}
 
@@ -160,7 +409,35 @@ func (s *service) Apply(l *raft.Log) any {
 		panic("unknown operation: " + cmd.Op)
 	}
 
-	return fn(l)
+	if v := cmd.version(); v > commandVersion {
+		err := fmt.Errorf("unsupported version %d of command %s, upgrade the node", v, cmd.Op)
+		slog.Error(
+			"unsupported version of data operation command",
+			slog.String("error", err.Error()),
+		)
+		s.recordApplyResult(l, err)
+		return err
+	}
+
+	result := fn(l)
+	s.recordApplyResult(l, result)
+
+	// node operations do not modify the data.
+	if cmd.Op != opUpdateNodeList && cmd.Op != opResync {
+		s.md.lock()
+		s.md.Index = l.Index
+		s.md.unlock()
+	}
+
+	return result
+}
+
+// ApplyFailures returns the raft log apply failures of the current node.
+func ApplyFailures() ApplyFailureStats {
+	s := svcInst
+	s.applyFailuresLock.Lock()
+	defer s.applyFailuresLock.Unlock()
+	return s.applyFailures
 }
 
 // Snapshot implements [raft.FSM]
@@ -171,9 +448,13 @@ func (s *service) Snapshot() (raft.FSMSnapshot, error) {
 // Restore implements [raft.FSM]
 func (s *service) Restore(rc io.ReadCloser) error {
 	d := newData()
-	if err := json.NewDecoder(rc).Decode(d); err != nil {
+	if err := d.readSnapshot(rc); err != nil {
 		return err
 	}
 	s.md = d
+
+	if len(d.Users) > 0 {
+		s.notifyUserExists()
+	}
 	return nil
 }
@@ -0,0 +1,221 @@
+package meta
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/raft"
+	"github.com/localvar/xuandb/pkg/xerrors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyFailures(t *testing.T) {
+	assert := assert.New(t)
+
+	s := newService()
+	defer func(inst *service) { svcInst = inst }(svcInst)
+	svcInst = s
+
+	// invalid command.
+	result := s.Apply(&raft.Log{Index: 3, Data: []byte("not json")})
+	assert.Error(result.(error))
+
+	af := ApplyFailures()
+	assert.EqualValues(1, af.Count)
+	assert.EqualValues(1, af.Consecutive)
+	assert.EqualValues(3, af.LastIndex)
+	assert.NotEmpty(af.LastError)
+	assert.False(af.LastTime.IsZero())
+
+	// a command which cannot be unmarshaled, e.g. due to version mismatch.
+	result = s.Apply(&raft.Log{Index: 5, Data: []byte(`{"op":"create-user","name":1}`)})
+	assert.Error(result.(error))
+
+	af = ApplyFailures()
+	assert.EqualValues(2, af.Count)
+	assert.EqualValues(2, af.Consecutive)
+	assert.EqualValues(5, af.LastIndex)
+
+	// a successful apply resets the consecutive count only.
+	result = s.Apply(&raft.Log{Index: 6, Data: []byte(`{"op":"create-user","name":"alice"}`)})
+	assert.Nil(result)
+
+	af = ApplyFailures()
+	assert.EqualValues(2, af.Count)
+	assert.EqualValues(0, af.Consecutive)
+	assert.EqualValues(5, af.LastIndex)
+
+	// an expected error result is not a failure.
+	result = s.Apply(&raft.Log{Index: 7, Data: []byte(`{"op":"create-user","name":"alice"}`)})
+	assert.Equal(ErrUserExists, result)
+	assert.EqualValues(2, ApplyFailures().Count)
+}
+
+func TestApplyFailuresHalt(t *testing.T) {
+	s := newService()
+	s.maxApplyFailures = 2
+
+	s.Apply(&raft.Log{Index: 1, Data: []byte("not json")})
+	assert.Panics(t, func() {
+		s.Apply(&raft.Log{Index: 2, Data: []byte("not json")})
+	})
+}
+
+func TestApplyCommandVersion(t *testing.T) {
+	assert := assert.New(t)
+
+	s := newService()
+	defer func(inst *service) { svcInst = inst }(svcInst)
+	svcInst = s
+
+	// commands without a version are version 1.
+	result := s.Apply(&raft.Log{Index: 1, Data: []byte(`{"op":"create-user","name":"alice"}`)})
+	assert.Nil(result)
+	result = s.Apply(&raft.Log{Index: 2, Data: []byte(`{"op":"create-user","version":1,"name":"bob"}`)})
+	assert.Nil(result)
+	assert.EqualValues(2, s.md.Index)
+
+	// a command of a newer version is refused without touching the data.
+	data := `{"op":"set-password","version":2,"name":"alice","password":{"hash":"x"}}`
+	result = s.Apply(&raft.Log{Index: 3, Data: []byte(data)})
+	assert.EqualError(result.(error), "unsupported version 2 of command set-password, upgrade the node")
+	assert.Empty(s.md.Users["alice"].Password)
+	assert.EqualValues(2, s.md.Index)
+
+	af := ApplyFailures()
+	assert.EqualValues(1, af.Count)
+	assert.EqualValues(3, af.LastIndex)
+}
+
+// testSnapshotSink is an in-memory [raft.SnapshotSink].
+func TestRaftApplyCommandSize(t *testing.T) {
+	assert := assert.New(t)
+
+	s := newService()
+	defer func(inst *service) { svcInst = inst }(svcInst)
+	svcInst = s
+	startTestRaft(t, s, "1")
+	s.maxCommandSize = 512
+
+	// a command under the limit applies.
+	lastIndex := s.raft.LastIndex()
+	assert.NoError(CreateUser(&User{Name: "alice", Password: "pwd"}, false))
+	assert.Equal(lastIndex+1, s.raft.LastIndex())
+	assert.NotNil(UserByName("alice"))
+
+	// an over-size command is rejected before being applied.
+	lastIndex = s.raft.LastIndex()
+	err := CreateUser(&User{Name: "bob", Password: strings.Repeat("x", 1024)}, false)
+	se, ok := err.(*xerrors.StatusError)
+	if assert.True(ok) {
+		assert.Equal(http.StatusRequestEntityTooLarge, se.StatusCode)
+		assert.NotContains(se.Msg, "split")
+	}
+	assert.Equal(lastIndex, s.raft.LastIndex())
+	assert.Nil(UserByName("bob"))
+
+	// the error of a batch suggests splitting it.
+	var us []*User
+	for i := range 20 {
+		us = append(us, &User{Name: fmt.Sprintf("user%d", i), Password: "pwd"})
+	}
+	err = CreateUsers(us)
+	if assert.Error(err) {
+		assert.Contains(err.Error(), "exceeds the limit of 512 bytes")
+		assert.Contains(err.Error(), "split the batch of 20 items")
+	}
+	assert.Equal(lastIndex, s.raft.LastIndex())
+
+	// 0 means no limit.
+	s.maxCommandSize = 0
+	assert.NoError(CreateUsers(us))
+
+	// resynchronization replicates all the data, it is not limited.
+	s.maxCommandSize = 512
+	lastIndex = s.raft.LastIndex()
+	assert.NoError(Resync())
+	assert.Equal(lastIndex+1, s.raft.LastIndex())
+}
+
+type testSnapshotSink struct {
+	bytes.Buffer
+	closed, canceled bool
+}
+
+func (s *testSnapshotSink) ID() string    { return "test" }
+func (s *testSnapshotSink) Close() error  { s.closed = true; return nil }
+func (s *testSnapshotSink) Cancel() error { s.canceled = true; return nil }
+
+func newTestData(users int) *Data {
+	d := newData()
+	for i := range users {
+		name := fmt.Sprintf("user%d", i)
+		d.Users[name] = &User{Name: name, Password: "password", CreatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	}
+	d.Databases["db"] = &Database{
+		Name:      "db",
+		Duration:  time.Hour,
+		CreatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		UpdatedAt: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+	}
+	return d
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	assert := assert.New(t)
+
+	d := newTestData(100)
+	d.Users["alice"] = &User{Name: "alice", Priv: PrivilegeAdmin, DbPriv: map[string]Privilege{"db": PrivilegeAdmin}}
+	d.Index = 42
+
+	sink := &testSnapshotSink{}
+	assert.NoError(d.clone().Persist(sink))
+	assert.True(sink.closed)
+	assert.False(sink.canceled)
+	assert.NotZero(SnapshotPeakMemory())
+
+	s := newService()
+	assert.NoError(s.Restore(io.NopCloser(&sink.Buffer)))
+	assert.Len(s.userExists, 1)
+	assert.Equal(d.Users, s.md.Users)
+	assert.Equal(d.Databases, s.md.Databases)
+	assert.Equal(uint64(42), s.md.Index)
+
+	// null maps and unknown fields.
+	s = newService()
+	assert.NoError(s.Restore(io.NopCloser(bytes.NewBufferString(`{"users":null,"foo":[1,2],"databases":{}}`))))
+	assert.Empty(s.md.Users)
+
+	assert.Error(s.Restore(io.NopCloser(bytes.NewBufferString(`[]`))))
+	assert.Error(s.Restore(io.NopCloser(bytes.NewBufferString(`{"users":[]}`))))
+}
+
+func TestPersistAllocation(t *testing.T) {
+	d := newTestData(10000)
+
+	sink := &testSnapshotSink{}
+	sink.Grow(4 << 20)
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	if err := d.Persist(sink); err != nil {
+		t.Fatal(err)
+	}
+	runtime.ReadMemStats(&after)
+
+	// the data is encoded value by value, the allocated memory should be far
+	// less than the size of the snapshot.
+	allocated := after.TotalAlloc - before.TotalAlloc
+	if size := uint64(sink.Len()); allocated > size/2 {
+		t.Errorf("persist allocated %d bytes for a snapshot of %d bytes", allocated, size)
+	}
+	if peak := SnapshotPeakMemory(); peak > 8192 {
+		t.Errorf("peak snapshot memory = %d, want <= 8192", peak)
+	}
+}
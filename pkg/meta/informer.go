@@ -0,0 +1,126 @@
+package meta
+
+import (
+	"log/slog"
+	"sync"
+)
+
+// Event is an event of a meta data change, it is one of UserCreated,
+// UserDropped, NodeJoined, NodeLeft, DatabaseCreated and DatabaseDropped.
+// The objects carried by events must not be modified.
+type Event interface {
+	metaEvent()
+}
+
+// UserCreated is the event of a user is created.
+type UserCreated struct {
+	User *User
+}
+
+// UserDropped is the event of a user is dropped.
+type UserDropped struct {
+	Name string
+}
+
+// NodeJoined is the event of a node is added to the node list.
+type NodeJoined struct {
+	Node *NodeInfo
+}
+
+// NodeLeft is the event of a node is removed from the node list.
+type NodeLeft struct {
+	ID string
+}
+
+// DatabaseCreated is the event of a database is created.
+type DatabaseCreated struct {
+	Database *Database
+}
+
+// DatabaseDropped is the event of a database is dropped.
+type DatabaseDropped struct {
+	Name string
+}
+
+func (UserCreated) metaEvent()     {}
+func (UserDropped) metaEvent()     {}
+func (NodeJoined) metaEvent()      {}
+func (NodeLeft) metaEvent()        {}
+func (DatabaseCreated) metaEvent() {}
+func (DatabaseDropped) metaEvent() {}
+
+// eventBufferSize is the number of events buffered for each subscriber.
+const eventBufferSize = 64
+
+// metaInformer delivers meta data events to subscribers. Events are
+// published when raft logs are applied, which must not be blocked by slow
+// subscribers, so if the buffer of a subscriber is full, its oldest event is
+// dropped to make room for the new one.
+type metaInformer struct {
+	lock sync.Mutex
+	subs map[chan Event]struct{}
+}
+
+// subscribe adds a subscriber, see Subscribe.
+func (mi *metaInformer) subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, eventBufferSize)
+
+	mi.lock.Lock()
+	if mi.subs == nil {
+		mi.subs = make(map[chan Event]struct{})
+	}
+	mi.subs[ch] = struct{}{}
+	mi.lock.Unlock()
+
+	unsubscribe := func() {
+		mi.lock.Lock()
+		defer mi.lock.Unlock()
+		if _, ok := mi.subs[ch]; ok {
+			delete(mi.subs, ch)
+			close(ch)
+		}
+	}
+
+	return ch, unsubscribe
+}
+
+// publish sends 'evt' to all subscribers.
+func (mi *metaInformer) publish(evt Event) {
+	mi.lock.Lock()
+	defer mi.lock.Unlock()
+
+	for ch := range mi.subs {
+		select {
+		case ch <- evt:
+			continue
+		default:
+		}
+
+		// the buffer is full, drop the oldest event. Only the publisher,
+		// which holds the lock, sends to the channel, so there's room for
+		// the new event after this, but the subscriber may also receive
+		// concurrently, so don't block on receiving.
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- evt
+		slog.Warn("meta event dropped due to slow subscriber")
+	}
+}
+
+// informer is the singleton of the meta informer.
+var informer = &metaInformer{}
+
+// Subscribe subscribes to the meta data events of this node, that is, the
+// changes applied to its local copy of the meta data. Events are delivered
+// in order, but a subscriber which falls more than 64 events behind loses
+// the oldest ones. The returned function unsubscribes and closes the
+// channel, it can be called more than once.
+//
+// Events are not generated for the changes caused by restoring a snapshot or
+// a resynchronization, subscribers which need an accurate view should read
+// the meta data again when they are not sure.
+func Subscribe() (<-chan Event, func()) {
+	return informer.subscribe()
+}
@@ -0,0 +1,103 @@
+package meta
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/raft"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetaInformer(t *testing.T) {
+	assert := assert.New(t)
+
+	mi := &metaInformer{}
+	ch1, unsubscribe1 := mi.subscribe()
+	ch2, unsubscribe2 := mi.subscribe()
+	defer unsubscribe2()
+
+	mi.publish(UserDropped{Name: "alice"})
+	assert.Equal(UserDropped{Name: "alice"}, <-ch1)
+	assert.Equal(UserDropped{Name: "alice"}, <-ch2)
+
+	// the oldest events are dropped for a slow subscriber.
+	for i := range eventBufferSize + 2 {
+		mi.publish(UserDropped{Name: fmt.Sprint(i)})
+	}
+	assert.Len(ch1, eventBufferSize)
+	assert.Equal(UserDropped{Name: "2"}, <-ch1)
+
+	// the channel is closed on unsubscribe, which can be called again.
+	unsubscribe1()
+	unsubscribe1()
+	for range ch1 {
+	}
+	mi.publish(UserDropped{Name: "bob"})
+	assert.Len(ch2, eventBufferSize)
+	assert.Len(mi.subs, 1)
+}
+
+func TestSubscribe(t *testing.T) {
+	assert := assert.New(t)
+
+	s := newService()
+	defer func(inst *service) { svcInst = inst }(svcInst)
+	svcInst = s
+	startTestRaft(t, s, "1")
+
+	dbInit()
+	defer dbUninit()
+
+	events, unsubscribe := Subscribe()
+	defer unsubscribe()
+
+	next := func() Event {
+		select {
+		case evt := <-events:
+			return evt
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "no event received")
+			return nil
+		}
+	}
+
+	require.NoError(t, CreateUser(&User{Name: "admin", Password: "pwd"}, false))
+	if evt, ok := next().(UserCreated); assert.True(ok) {
+		assert.Equal("admin", evt.User.Name)
+	}
+	require.NoError(t, CreateUsers([]*User{{Name: "alice", Password: "pwd"}}))
+	if evt, ok := next().(UserCreated); assert.True(ok) {
+		assert.Equal("alice", evt.User.Name)
+	}
+	require.NoError(t, DropUser("alice", false))
+	assert.Equal(UserDropped{Name: "alice"}, next())
+
+	require.NoError(t, CreateDatabase(&Database{Name: "db1"}, false))
+	if evt, ok := next().(DatabaseCreated); assert.True(ok) {
+		assert.Equal("db1", evt.Database.Name)
+	}
+	require.NoError(t, DropDatabase("db1", false))
+	assert.Equal(DatabaseDropped{Name: "db1"}, next())
+
+	// a node joins and then leaves.
+	apply := func(nodes map[string]*NodeInfo) {
+		data, _ := json.Marshal(&updateNodeListCommand{
+			baseCommand: baseCommand{Op: opUpdateNodeList},
+			Nodes:       nodes,
+		})
+		assert.Nil(s.Apply(&raft.Log{Index: 100, Data: data}))
+	}
+	apply(map[string]*NodeInfo{"2": {ID: "2", Addr: "node2"}})
+	if evt, ok := next().(NodeJoined); assert.True(ok) {
+		assert.Equal("2", evt.Node.ID)
+	}
+	apply(map[string]*NodeInfo{})
+	assert.Equal(NodeLeft{ID: "2"}, next())
+
+	// nothing more, and dropping a missing object is not an event.
+	require.NoError(t, DropUser("bob", true))
+	assert.Empty(events)
+}
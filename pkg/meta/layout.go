@@ -0,0 +1,108 @@
+package meta
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+// dataDirPerm is the permission of the directories created in the data
+// directory, the meta data contains password hashes, so they are only
+// accessible by the owner.
+const dataDirPerm = 0o700
+
+// dataLayout is the layout of the meta data directory:
+//
+//	<data-dir>/raft/raft.db		the boltdb raft store
+//	<data-dir>/snapshots/		the raft snapshots
+type dataLayout struct {
+	dir string
+}
+
+// raftDir returns the directory of the raft store.
+func (dl dataLayout) raftDir() string {
+	return filepath.Join(dl.dir, "raft")
+}
+
+// raftStorePath returns the path of the boltdb raft store.
+func (dl dataLayout) raftStorePath() string {
+	return filepath.Join(dl.raftDir(), "raft.db")
+}
+
+// legacyRaftStorePath returns the path of the boltdb raft store used by the
+// old versions, which put it directly in the data directory.
+func (dl dataLayout) legacyRaftStorePath() string {
+	return filepath.Join(dl.dir, "raft.db")
+}
+
+// snapshotBase returns the base directory of the file snapshot store, the
+// store puts the snapshots in its 'snapshots' sub directory.
+func (dl dataLayout) snapshotBase() string {
+	return dl.dir
+}
+
+// snapshotDir returns the directory of the raft snapshots.
+func (dl dataLayout) snapshotDir() string {
+	return filepath.Join(dl.snapshotBase(), "snapshots")
+}
+
+// prepare creates the directories of the layout, checks they are writable,
+// and moves the raft store of the old layout to its new path.
+func (dl dataLayout) prepare() error {
+	for _, dir := range []string{dl.raftDir(), dl.snapshotDir()} {
+		if err := os.MkdirAll(dir, dataDirPerm); err != nil {
+			return fmt.Errorf("failed to create directory '%s': %w", dir, err)
+		}
+		if err := checkWritable(dir); err != nil {
+			return err
+		}
+	}
+	return dl.migrate()
+}
+
+// migrate moves the raft store of the old layout to its new path, it does
+// nothing if there is no old store or the new store already exists.
+func (dl dataLayout) migrate() error {
+	oldPath, newPath := dl.legacyRaftStorePath(), dl.raftStorePath()
+
+	if _, err := os.Stat(oldPath); errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to check raft store '%s': %w", oldPath, err)
+	}
+
+	if _, err := os.Stat(newPath); err == nil {
+		slog.Warn(
+			"raft store of the old layout is ignored",
+			slog.String("path", oldPath),
+		)
+		return nil
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to check raft store '%s': %w", newPath, err)
+	}
+
+	if err := os.Rename(oldPath, newPath); err != nil {
+		return fmt.Errorf("failed to move raft store '%s' to '%s': %w", oldPath, newPath, err)
+	}
+
+	slog.Info(
+		"raft store moved to the new layout",
+		slog.String("from", oldPath),
+		slog.String("to", newPath),
+	)
+	return nil
+}
+
+// checkWritable checks whether files can be created in 'dir'.
+func checkWritable(dir string) error {
+	f, err := os.CreateTemp(dir, ".write-check-*")
+	if err != nil {
+		return fmt.Errorf("directory '%s' is not writable: %w", dir, err)
+	}
+	name := f.Name()
+	f.Close()
+	return os.Remove(name)
+}
@@ -0,0 +1,69 @@
+package meta
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDataLayout(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := filepath.Join(t.TempDir(), "meta")
+	dl := dataLayout{dir: dir}
+	assert.Equal(filepath.Join(dir, "raft", "raft.db"), dl.raftStorePath())
+	assert.Equal(filepath.Join(dir, "snapshots"), dl.snapshotDir())
+
+	// directories are created with the expected permission.
+	require.NoError(t, dl.prepare())
+	for _, d := range []string{dl.raftDir(), dl.snapshotDir()} {
+		fi, err := os.Stat(d)
+		require.NoError(t, err)
+		assert.True(fi.IsDir())
+		assert.Equal(os.FileMode(dataDirPerm), fi.Mode().Perm())
+	}
+
+	// preparing again is fine, and no temporary file is left.
+	require.NoError(t, dl.prepare())
+	entries, err := os.ReadDir(dl.raftDir())
+	require.NoError(t, err)
+	assert.Empty(entries)
+}
+
+func TestDataLayoutMigrate(t *testing.T) {
+	assert := assert.New(t)
+
+	dl := dataLayout{dir: t.TempDir()}
+	require.NoError(t, os.WriteFile(dl.legacyRaftStorePath(), []byte("old"), 0o600))
+
+	// the old store is moved.
+	require.NoError(t, dl.prepare())
+	data, err := os.ReadFile(dl.raftStorePath())
+	require.NoError(t, err)
+	assert.Equal("old", string(data))
+	_, err = os.Stat(dl.legacyRaftStorePath())
+	assert.ErrorIs(err, os.ErrNotExist)
+
+	// the new store is never overwritten.
+	require.NoError(t, os.WriteFile(dl.legacyRaftStorePath(), []byte("older"), 0o600))
+	require.NoError(t, dl.prepare())
+	data, err = os.ReadFile(dl.raftStorePath())
+	require.NoError(t, err)
+	assert.Equal("old", string(data))
+}
+
+func TestDataLayoutNotWritable(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("permissions are not enforced for root")
+	}
+
+	dl := dataLayout{dir: t.TempDir()}
+	require.NoError(t, os.MkdirAll(dl.raftDir(), 0o500))
+	defer os.Chmod(dl.raftDir(), 0o700)
+
+	err := dl.prepare()
+	assert.ErrorContains(t, err, "is not writable")
+}
@@ -0,0 +1,11 @@
+package meta
+
+import (
+	"regexp"
+
+	"github.com/localvar/xuandb/pkg/config"
+)
+
+// namePattern is the pattern which the names of users and databases must
+// match, it is replaced by the configured one when the service starts.
+var namePattern = regexp.MustCompile(config.DefaultNamePattern)
@@ -2,12 +2,15 @@ package meta
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"io"
 	"log/slog"
+	"maps"
 	"net/http"
 	"net/url"
 	"sort"
+	"strconv"
 	"time"
 
 	"github.com/hashicorp/raft"
@@ -23,14 +26,22 @@ const (
 
 // nodeRegisterAPIHandlers registers API handlers for node operations.
 func nodeRegisterAPIHandlers() {
+	// every node has its own raft instance, so it can report its stats and
+	// logs, and maintain its store. These are for the operators, not other
+	// nodes.
+	handleOperatorFunc("GET /meta/raft/stats", handleRaftStats)
+	handleOperatorFunc("GET /meta/raft/log", handleRaftLogs)
+	handleOperatorFunc("POST /meta/raft/compact", handleCompactRaftStore)
+
 	// only voters need to register API handlers.
 	if !config.CurrentNode().Meta.RaftVoter {
 		return
 	}
 
-	httpserver.HandleFunc("POST /meta/nodes", handleAddNode)
-	httpserver.HandleFunc("DELETE /meta/nodes", handleDropNode)
-	httpserver.HandleFunc("POST /meta/node/heartbeat", handleNodeHeartbeat)
+	handleFunc("POST /meta/nodes", handleAddNode)
+	handleFunc("DELETE /meta/nodes", handleDropNode)
+	handleFunc("POST /meta/node/heartbeat", handleNodeHeartbeat)
+	handleFunc("POST /meta/node/drain", handleDrainNode)
 }
 
 // joinRequest is the request to join a raft cluster.
@@ -39,6 +50,10 @@ type joinRequest struct {
 	ID          string `json:"id"`
 	Addr        string `json:"addr"`
 	Voter       bool   `json:"voter"`
+
+	// Manual is true if the node is added by an administrator, a manually
+	// added node is always admitted even if it was dropped recently.
+	Manual bool `json:"manual,omitempty"`
 }
 
 // join joins the current node to the raft cluster via addr.
@@ -52,8 +67,15 @@ func join(addr string) error {
 		Voter:       mc.RaftVoter,
 	})
 
-	urlJoin := "http://" + addr + "/meta/nodes"
-	resp, err := http.Post(urlJoin, "application/json", bytes.NewReader(jr))
+	urlJoin := httpserver.URL(addr, "/meta/nodes")
+	req, err := http.NewRequest(http.MethodPost, urlJoin, bytes.NewReader(jr))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(ClusterNameHeader, config.ClusterName())
+
+	resp, err := client.Do(req)
 	if err != nil {
 		slog.Error(
 			"failed to join cluster",
@@ -84,6 +106,10 @@ func join(addr string) error {
 }
 
 func leaderAddNode(id, addr string, voter bool) error {
+	if err := admit(&Mutation{Op: MutationAddNode, Name: id}); err != nil {
+		return err
+	}
+
 	var err error
 
 	sid, saddr := raft.ServerID(id), raft.ServerAddress(addr)
@@ -94,6 +120,9 @@ func leaderAddNode(id, addr string, voter bool) error {
 	}
 
 	if err == nil {
+		svcInst.departedLock.Lock()
+		delete(svcInst.departed, id)
+		svcInst.departedLock.Unlock()
 		slog.Info("node added", slog.String("nodeId", id))
 		return nil
 	}
@@ -111,15 +140,15 @@ func handleAddNode(w http.ResponseWriter, r *http.Request) {
 	var jr joinRequest
 	err := json.NewDecoder(r.Body).Decode(&jr)
 	if err != nil {
-		http.Error(w, "failed to decode join request", http.StatusBadRequest)
+		xerrors.WriteJSON(w, xerrors.New(http.StatusBadRequest, "failed to decode join request"))
 		return
 	}
 	if jr.ID == "" || jr.Addr == "" {
-		http.Error(w, "invalid join request", http.StatusBadRequest)
+		xerrors.WriteJSON(w, xerrors.New(http.StatusBadRequest, "invalid join request"))
 		return
 	}
 	if jr.ClusterName != config.ClusterName() {
-		http.Error(w, "wrong cluster name", http.StatusForbidden)
+		xerrors.WriteJSON(w, xerrors.New(http.StatusForbidden, "wrong cluster name"))
 		return
 	}
 
@@ -131,13 +160,20 @@ func handleAddNode(w http.ResponseWriter, r *http.Request) {
 
 	if !svcInst.isLeader() {
 		slog.Debug("refuse due to not leader", slog.String("nodeId", jr.ID))
-		http.Error(w, "not leader", http.StatusServiceUnavailable)
+		xerrors.WriteJSON(w, xerrors.New(http.StatusServiceUnavailable, "not leader"))
 		return
 	}
 
-	if err = leaderAddNode(jr.ID, jr.Addr, jr.Voter); err != nil {
-		se := err.(*xerrors.StatusError)
-		http.Error(w, se.Msg, se.StatusCode)
+	voter := jr.Voter
+	if !jr.Manual {
+		if voter, err = checkRejoin(jr.ID, voter); err != nil {
+			xerrors.WriteJSON(w, err)
+			return
+		}
+	}
+
+	if err = leaderAddNode(jr.ID, jr.Addr, voter); err != nil {
+		xerrors.WriteJSON(w, err)
 		return
 	}
 
@@ -155,13 +191,75 @@ func AddNode(id, addr string, voter bool) error {
 		ID:          id,
 		Addr:        addr,
 		Voter:       voter,
+		Manual:      true,
 	}
-	return sendPostRequestToLeader("/meta/nodes", jr)
+	return sendPostRequestToLeader(context.Background(), "/meta/nodes", jr)
+}
+
+// departedNode records a node dropped from the cluster.
+type departedNode struct {
+	Voter     bool
+	DroppedAt time.Time
+}
+
+// checkRejoin checks whether node 'id', which requests to join the cluster
+// as a voter or not according to 'voter', was dropped recently. If it was
+// dropped within the rejoin grace window, it is readmitted with its prior
+// suffrage, which is returned; otherwise, an error is returned as it must be
+// re-added manually.
+func checkRejoin(id string, voter bool) (bool, error) {
+	s := svcInst
+	s.departedLock.Lock()
+	dn, ok := s.departed[id]
+	s.departedLock.Unlock()
+
+	if !ok {
+		return voter, nil
+	}
+
+	if elapsed := time.Since(dn.DroppedAt); elapsed < s.rejoinGraceWindow {
+		slog.Warn(
+			"readmitting recently dropped node",
+			slog.String("nodeId", id),
+			slog.Bool("voter", dn.Voter),
+			slog.Duration("droppedFor", elapsed),
+		)
+		return dn.Voter, nil
+	}
+
+	slog.Warn(
+		"refuse to readmit dropped node outside the rejoin grace window",
+		slog.String("nodeId", id),
+	)
+	return false, xerrors.New(
+		http.StatusForbidden,
+		"node was dropped from the cluster and must be re-added manually: "+id,
+	)
 }
 
 func leaderDropNode(id string) error {
+	if err := admit(&Mutation{Op: MutationDropNode, Name: id}); err != nil {
+		return err
+	}
+
+	// find the suffrage of the node before removing it, so that it can be
+	// readmitted with the same suffrage.
+	dn := departedNode{}
+	if f := svcInst.raft.GetConfiguration(); f.Error() == nil {
+		for _, svr := range f.Configuration().Servers {
+			if svr.ID == raft.ServerID(id) {
+				dn.Voter = svr.Suffrage == raft.Voter
+				break
+			}
+		}
+	}
+
 	err := svcInst.raft.RemoveServer(raft.ServerID(id), 0, 0).Error()
 	if err == nil {
+		dn.DroppedAt = time.Now()
+		svcInst.departedLock.Lock()
+		svcInst.departed[id] = dn
+		svcInst.departedLock.Unlock()
 		slog.Info("node dropped", slog.String("nodeId", id))
 		return nil
 	}
@@ -178,7 +276,7 @@ func leaderDropNode(id string) error {
 func handleDropNode(w http.ResponseWriter, r *http.Request) {
 	id := r.FormValue("id")
 	if id == "" {
-		http.Error(w, "invalid request", http.StatusBadRequest)
+		xerrors.WriteJSON(w, xerrors.New(http.StatusBadRequest, "invalid request"))
 		return
 	}
 
@@ -187,13 +285,12 @@ func handleDropNode(w http.ResponseWriter, r *http.Request) {
 	s := svcInst
 	if !s.isLeader() {
 		slog.Debug("refuse due to not leader", slog.String("nodeId", id))
-		http.Error(w, "not leader", http.StatusServiceUnavailable)
+		xerrors.WriteJSON(w, xerrors.New(http.StatusServiceUnavailable, "not leader"))
 		return
 	}
 
 	if err := leaderDropNode(id); err != nil {
-		se := err.(*xerrors.StatusError)
-		http.Error(w, se.Msg, se.StatusCode)
+		xerrors.WriteJSON(w, err)
 		return
 	}
 
@@ -203,9 +300,90 @@ func handleDropNode(w http.ResponseWriter, r *http.Request) {
 // DropNode removes the current node from the cluster.
 func DropNode(id string) error {
 	if svcInst.isLeader() {
-		leaderDropNode(id)
+		return leaderDropNode(id)
 	}
-	return sendDeleteRequestToLeader("/meta/nodes?id=" + url.QueryEscape(id))
+	return sendDeleteRequestToLeader(context.Background(), "/meta/nodes?id="+url.QueryEscape(id))
+}
+
+// errNodeNotExists returns the error for a non-existing node 'id'.
+func errNodeNotExists(id string) error {
+	return xerrors.New(http.StatusNotFound, "node does not exist: "+id)
+}
+
+// leaderDrainNode marks node 'id' as draining via an updateNodeListCommand,
+// the flag is kept by all later node lists.
+func leaderDrainNode(id string) error {
+	if NodeByID(id) == nil {
+		slog.Debug("node does not exist", slog.String("nodeId", id))
+		return errNodeNotExists(id)
+	}
+
+	if err := admit(&Mutation{Op: MutationDrainNode, Name: id}); err != nil {
+		return err
+	}
+
+	s := svcInst
+	cmd, err := s.nodeListCommand()
+	if err != nil {
+		return xerrors.Wrap(err, http.StatusInternalServerError)
+	}
+
+	ni := cmd.Nodes[id]
+	if ni == nil {
+		// the node is not a raft server.
+		return errNodeNotExists(id)
+	}
+	ni = ni.clone()
+	ni.Draining = true
+	cmd.Nodes[id] = ni
+
+	err = s.raftApply(cmd)
+	if err == nil {
+		slog.Info("node draining", slog.String("nodeId", id))
+		return nil
+	}
+
+	slog.Debug(
+		"failed to drain node",
+		slog.String("nodeId", id),
+		slog.String("error", err.Error()),
+	)
+	return err
+}
+
+// handleDrainNode handles the drain node request.
+func handleDrainNode(w http.ResponseWriter, r *http.Request) {
+	id := r.FormValue("id")
+	if id == "" {
+		xerrors.WriteJSON(w, xerrors.New(http.StatusBadRequest, "invalid request"))
+		return
+	}
+
+	slog.Debug("drain node request received", slog.String("nodeId", id))
+
+	if !svcInst.isLeader() {
+		slog.Debug("refuse due to not leader", slog.String("nodeId", id))
+		xerrors.WriteJSON(w, xerrors.New(http.StatusServiceUnavailable, "not leader"))
+		return
+	}
+
+	if err := leaderDrainNode(id); err != nil {
+		xerrors.WriteJSON(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DrainNode marks node 'id' as draining, it is the first step to remove a
+// node from the cluster gracefully. A draining node stays in the cluster, but
+// the leader no longer tracks its heartbeats, and its state is reported as
+// 'draining' instead of being judged by its last heartbeat.
+func DrainNode(id string) error {
+	if svcInst.isLeader() {
+		return leaderDrainNode(id)
+	}
+	return sendPostRequestToLeader(context.Background(), "/meta/node/drain?id="+url.QueryEscape(id), nil)
 }
 
 // NodeRole represents the role of a node in the cluster.
@@ -246,6 +424,14 @@ type NodeInfo struct {
 	Addr              string    `json:"addr"` // HTTP address of the node
 	Role              NodeRole  `json:"role"`
 	LastHeartbeatTime time.Time `json:"lastHeartbeatTime"`
+
+	// Labels are the key/value pairs advertised by the node.
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Draining is set by DrainNode, it is kept when the info is overwritten
+	// by node lists, as the node itself does not know it is draining. The
+	// leader ignores the heartbeats of a draining node.
+	Draining bool `json:"draining,omitempty"`
 }
 
 // init initializes the NodeInfo according to configuration of the current node.
@@ -253,6 +439,7 @@ func (ni *NodeInfo) init() {
 	nc := config.CurrentNode()
 	ni.ID = nc.ID
 	ni.Addr = nc.ToExternalAddress(nc.HTTPAddr)
+	ni.Labels = maps.Clone(nc.Labels)
 
 	if nc.Meta.RaftVoter {
 		ni.Role |= NodeRoleMeta
@@ -268,6 +455,7 @@ func (ni *NodeInfo) init() {
 // clone returns a copy of the NodeInfo.
 func (ni *NodeInfo) clone() *NodeInfo {
 	ni1 := *ni
+	ni1.Labels = maps.Clone(ni.Labels)
 	return &ni1
 }
 
@@ -281,7 +469,7 @@ type NodeStatus struct {
 
 // sendHeartbeatToLeader sends a heartbeat of the current node to the leader.
 func (s *service) sendHeartbeatToLeader(ni *NodeInfo) {
-	err := sendPostRequestToLeader("/meta/node/heartbeat", ni)
+	err := sendPostRequestToLeader(context.Background(), "/meta/node/heartbeat", ni)
 	if err != nil {
 		slog.Error(
 			"failed to send heartbeat to leader",
@@ -300,14 +488,14 @@ func handleNodeHeartbeat(w http.ResponseWriter, r *http.Request) {
 			"failed to decode node info",
 			slog.String("error", err.Error()),
 		)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		xerrors.WriteJSON(w, xerrors.New(http.StatusBadRequest, err.Error()))
 		return
 	}
 
 	if time.Until(hb.LastHeartbeatTime) > 10*time.Second {
 		const msg = "heartbeat time is in the distant future"
 		slog.Debug(msg, slog.String("nodeID", hb.ID))
-		http.Error(w, msg, http.StatusBadRequest)
+		xerrors.WriteJSON(w, xerrors.New(http.StatusBadRequest, msg))
 		return
 	}
 
@@ -316,16 +504,19 @@ func handleNodeHeartbeat(w http.ResponseWriter, r *http.Request) {
 	s := svcInst
 	s.lockNodes()
 
-	// save the node status only if it is already in the map.
+	// save the node status only if it is already in the map, and is not
+	// draining, a draining node is no longer a heartbeat target.
 	ni := s.nodes[hb.ID]
-	if ni != nil {
-		*ni = hb
+	if ni != nil && !ni.Draining {
+		s.nodes[hb.ID] = &hb
 	}
 
 	s.unlockNodes()
 
 	if ni == nil {
 		slog.Debug("node does not exist", slog.String("nodeID", hb.ID))
+	} else if ni.Draining {
+		slog.Debug("heartbeat of draining node ignored", slog.String("nodeID", hb.ID))
 	}
 }
 
@@ -333,6 +524,11 @@ func handleNodeHeartbeat(w http.ResponseWriter, r *http.Request) {
 type updateNodeListCommand struct {
 	baseCommand
 	Nodes map[string]*NodeInfo `json:"nodes"`
+
+	// DataIndex and DataHash are the index and hash of the meta data of the
+	// leader, followers compare them with their own to detect divergence.
+	DataIndex uint64 `json:"dataIndex,omitempty"`
+	DataHash  string `json:"dataHash,omitempty"`
 }
 
 // applyUpdateNodeList applies a updateNodeListCommand to current node.
@@ -354,6 +550,7 @@ func applyUpdateNodeList(l *raft.Log) any {
 	for id := range s.nodes {
 		if _, ok := cmd.Nodes[id]; !ok {
 			delete(s.nodes, id)
+			informer.publish(NodeLeft{ID: id})
 		}
 	}
 
@@ -361,26 +558,39 @@ func applyUpdateNodeList(l *raft.Log) any {
 	for id, ni := range cmd.Nodes {
 		ni1 := s.nodes[id]
 		if ni1 == nil || ni1.LastHeartbeatTime.Before(ni.LastHeartbeatTime) {
+			// the node list may be built before a node is marked as
+			// draining, don't lose the flag.
+			if ni1 != nil && ni1.Draining {
+				ni.Draining = true
+			}
 			s.nodes[id] = ni
+			if ni1 == nil {
+				informer.publish(NodeJoined{Node: ni})
+			}
+		} else if ni.Draining && !ni1.Draining {
+			// the node is marked as draining by DrainNode.
+			ni1 = ni1.clone()
+			ni1.Draining = true
+			s.nodes[id] = ni1
 		}
 	}
 
 	s.unlockNodes()
 
 	slog.Debug("node list updated")
+
+	if cmd.DataHash != "" && !s.isLeader() {
+		s.checkConsistency(cmd.DataIndex, cmd.DataHash)
+	}
 	return nil
 }
 
-// sendNodeListToFollower sends the info of all nodes to all servers of the
-// raft cluster via an updateNodeListCommand.
-func (s *service) sendNodeListToFollower() {
+// nodeListCommand builds an updateNodeListCommand with the info of all
+// servers of the raft cluster.
+func (s *service) nodeListCommand() (*updateNodeListCommand, error) {
 	fGet := s.raft.GetConfiguration()
 	if err := fGet.Error(); err != nil {
-		slog.Error(
-			"failed to get raft configuration",
-			slog.String("error", err.Error()),
-		)
-		return
+		return nil, err
 	}
 
 	svrs := fGet.Configuration().Servers
@@ -388,6 +598,7 @@ func (s *service) sendNodeListToFollower() {
 		baseCommand: baseCommand{Op: opUpdateNodeList},
 		Nodes:       make(map[string]*NodeInfo, len(svrs)),
 	}
+	cmd.DataIndex, cmd.DataHash = s.md.indexAndHash()
 
 	s.lockNodes()
 	for _, svr := range svrs {
@@ -399,10 +610,26 @@ func (s *service) sendNodeListToFollower() {
 		}
 	}
 
-	// must marshal before unlock, because we are reusing the NodeInfos.
-	data, err := json.Marshal(cmd)
 	s.unlockNodes()
 
+	return cmd, nil
+}
+
+// sendNodeListToFollower sends the info of all nodes to all servers of the
+// raft cluster via an updateNodeListCommand.
+func (s *service) sendNodeListToFollower() {
+	cmd, err := s.nodeListCommand()
+	if err != nil {
+		slog.Error(
+			"failed to get raft configuration",
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+
+	// the NodeInfos are reused, but they are never updated in place.
+	data, err := json.Marshal(cmd)
+
 	if err != nil {
 		slog.Error(
 			"failed to marshal update node list command",
@@ -433,6 +660,33 @@ func (s *service) sendNodeListToFollower() {
 	slog.Debug("send update node list command to follower succeeded")
 }
 
+// heartbeat updates the info of the current node locally, and then sends it
+// to the leader if this node is a follower, or sends the node list to all
+// servers of the raft cluster every 5 ticks if this node is the leader.
+func (s *service) heartbeat(ni *NodeInfo, ticks uint) {
+	ni.LastHeartbeatTime = time.Now()
+
+	// update current node info locally, the whole info is overwritten
+	// because the existing one may be restored from the raft state and
+	// become stale, e.g. the address of this node changed after restart.
+	s.lockNodes()
+	ni1 := s.nodes[ni.ID]
+	if ni1 != nil {
+		ni.Draining = ni1.Draining
+	}
+	s.nodes[ni.ID] = ni.clone()
+	if ni1 == nil {
+		informer.publish(NodeJoined{Node: s.nodes[ni.ID]})
+	}
+	s.unlockNodes()
+
+	if !s.isLeader() {
+		s.sendHeartbeatToLeader(ni)
+	} else if ticks%5 == 0 {
+		s.sendNodeListToFollower()
+	}
+}
+
 // updateNodeInfo updates the info of the current node periodically.
 // If this node is a follower, it will also send its info to the leader as a
 // heartbeat; if this node is the leader, it will send its node list to all
@@ -443,34 +697,27 @@ func (s *service) updateNodeInfo() {
 	go func() {
 		defer s.wg.Done()
 
-		t := time.NewTicker(1 * time.Second)
+		t := time.NewTicker(s.heartbeatInterval)
 		defer t.Stop()
 
 		ni := &NodeInfo{}
 		ni.init()
 
-		for ticks := uint(0); ; ticks++ {
+		// the first tick fires right away, so that the cluster gets the
+		// latest info of this node as soon as possible after it starts.
+		ticks := uint(0)
+		for {
+			s.heartbeat(ni, ticks)
+
 			select {
 			case <-s.stop:
 				return
 			case <-t.C:
-			}
-
-			ni.LastHeartbeatTime = time.Now()
-
-			// update current node info locally.
-			s.lockNodes()
-			if ni1 := s.nodes[ni.ID]; ni1 != nil {
-				ni1.LastHeartbeatTime = ni.LastHeartbeatTime
-			} else {
-				s.nodes[ni.ID] = ni.clone()
-			}
-			s.unlockNodes()
-
-			if !s.isLeader() {
-				s.sendHeartbeatToLeader(ni)
-			} else if ticks%5 == 0 {
-				s.sendNodeListToFollower()
+				ticks++
+			case <-s.leaderAcquired:
+				// restart counting, so that the new leader sends the node
+				// list right away instead of on the 5th tick.
+				ticks = 0
 			}
 		}
 	}()
@@ -482,7 +729,7 @@ func Nodes() []NodeInfo {
 
 	svcInst.lockNodes()
 	for _, ni := range svcInst.nodes {
-		result = append(result, *ni)
+		result = append(result, *ni.clone())
 	}
 	svcInst.unlockNodes()
 
@@ -494,6 +741,39 @@ func Nodes() []NodeInfo {
 	return result
 }
 
+// NodesLeaderFirst returns a list of all nodes in the cluster with the leader
+// at index 0, the other nodes are sorted by ID. It is the same as Nodes if
+// there's no leader.
+func NodesLeaderFirst() []NodeInfo {
+	nodes := Nodes()
+	leader := LeaderNode()
+	if leader == nil {
+		return nodes
+	}
+
+	result := make([]NodeInfo, 1, len(nodes)+1)
+	result[0] = *leader
+	for _, ni := range nodes {
+		if ni.ID != leader.ID {
+			result = append(result, ni)
+		}
+	}
+	return result
+}
+
+// NodesWithLabel returns the nodes which have label 'key' with 'value', the
+// result is sorted by node ID.
+func NodesWithLabel(key, value string) []NodeInfo {
+	nodes := Nodes()
+	result := nodes[:0]
+	for _, ni := range nodes {
+		if v, ok := ni.Labels[key]; ok && v == value {
+			result = append(result, ni)
+		}
+	}
+	return result
+}
+
 // NodeByID returns the node info by ID. It returns nil if not found.
 func NodeByID(id string) *NodeInfo {
 	svcInst.lockNodes()
@@ -504,6 +784,11 @@ func NodeByID(id string) *NodeInfo {
 	return nil
 }
 
+// IsLeader returns whether the current node is the leader.
+func IsLeader() bool {
+	return svcInst != nil && svcInst.isLeader()
+}
+
 // LeaderNode returns the info of the leader node.
 // It returns nil if there's no leader.
 func LeaderNode() *NodeInfo {
@@ -535,13 +820,35 @@ func LeaderHTTPAddr() string {
 	return NodeHTTPAddr(string(id))
 }
 
+// RaftStats returns the statistics of the raft instance of the current node,
+// like its state, term, commit index and last contact to the leader, and the
+// failures of applying raft logs to the FSM, see ApplyFailures.
+func RaftStats() map[string]string {
+	stats := svcInst.raft.Stats()
+
+	af := ApplyFailures()
+	stats["apply_failures"] = strconv.FormatUint(af.Count, 10)
+	stats["consecutive_apply_failures"] = strconv.FormatUint(af.Consecutive, 10)
+	if af.Count > 0 {
+		stats["last_apply_failure_index"] = strconv.FormatUint(af.LastIndex, 10)
+		stats["last_apply_failure_error"] = af.LastError
+		stats["last_apply_failure_time"] = af.LastTime.Format(time.RFC3339)
+	}
+
+	return stats
+}
+
+func handleRaftStats(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, RaftStats())
+}
+
 // NodeStatuses returns the status of all nodes in the cluster.
 func NodeStatuses() []NodeStatus {
 	result := make([]NodeStatus, 0, len(config.Nodes()))
 
 	svcInst.lockNodes()
 	for _, ni := range svcInst.nodes {
-		result = append(result, NodeStatus{NodeInfo: *ni})
+		result = append(result, NodeStatus{NodeInfo: *ni.clone()})
 	}
 	svcInst.unlockNodes()
 
@@ -555,9 +862,11 @@ func NodeStatuses() []NodeStatus {
 	for i := 0; i < len(result); i++ {
 		ns := &result[i]
 		ns.Leader = ns.ID == string(leaderID)
-		if d := now.Sub(ns.LastHeartbeatTime); d >= 30*time.Second {
+		if ns.Draining {
+			ns.State = "draining"
+		} else if d := now.Sub(ns.LastHeartbeatTime); d >= svcInst.nodeDeadAfter {
 			ns.State = "down"
-		} else if d >= 10*time.Second {
+		} else if d >= svcInst.nodeUnknownAfter {
 			ns.State = "unknown"
 		} else {
 			ns.State = "up"
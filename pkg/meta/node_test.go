@@ -0,0 +1,490 @@
+package meta
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/raft"
+	"github.com/localvar/xuandb/pkg/config"
+	"github.com/localvar/xuandb/pkg/xerrors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startTestRaft starts a single node raft cluster for service 's' and waits
+// until the node becomes the leader.
+func startTestRaft(t *testing.T, s *service, id string) {
+	cfg := raft.DefaultConfig()
+	cfg.LocalID = raft.ServerID(id)
+	cfg.Logger = hclog.NewNullLogger()
+	cfg.HeartbeatTimeout = 50 * time.Millisecond
+	cfg.ElectionTimeout = 50 * time.Millisecond
+	cfg.LeaderLeaseTimeout = 50 * time.Millisecond
+	cfg.CommitTimeout = 5 * time.Millisecond
+
+	store := raft.NewInmemStore()
+	addr, trans := raft.NewInmemTransport("")
+	ra, err := raft.NewRaft(cfg, s, store, store, raft.NewInmemSnapshotStore(), trans)
+	require.NoError(t, err)
+	t.Cleanup(func() { ra.Shutdown().Error() })
+
+	svrs := []raft.Server{{ID: cfg.LocalID, Address: addr}}
+	require.NoError(t, ra.BootstrapCluster(raft.Configuration{Servers: svrs}).Error())
+
+	s.raft = ra
+	s.logs = store
+	require.Eventually(t, s.isLeader, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestNodeRejoinWithNewAddr(t *testing.T) {
+	assert := assert.New(t)
+
+	s := newService()
+	defer func(inst *service) { svcInst = inst }(svcInst)
+	svcInst = s
+	startTestRaft(t, s, "1")
+	require.NoError(t, s.raft.AddNonvoter("2", "node2", 0, 0).Error())
+
+	// the node info restored from the raft state.
+	past := time.Now().Add(-time.Minute)
+	s.nodes["1"] = &NodeInfo{ID: "1", Addr: "10.0.0.1:8086", Role: NodeRoleMeta, LastHeartbeatTime: past}
+	s.nodes["2"] = &NodeInfo{ID: "2", Addr: "10.0.0.2:8086", Role: NodeRoleData, LastHeartbeatTime: past}
+
+	// the leader restarted with a new address.
+	ni := &NodeInfo{ID: "1", Addr: "10.0.1.1:8086", Role: NodeRoleMeta}
+	s.heartbeat(ni, 0)
+	assert.Equal("10.0.1.1:8086", LeaderHTTPAddr())
+	assert.Equal(NodeRole(NodeRoleMeta), NodeByID("1").Role)
+
+	// node 2 restarted with a new address and re-announces its info.
+	hb, _ := json.Marshal(&NodeInfo{
+		ID:                "2",
+		Addr:              "10.0.1.2:8086",
+		Role:              NodeRoleData,
+		LastHeartbeatTime: time.Now(),
+	})
+	req := httptest.NewRequest(http.MethodPost, "/meta/node/heartbeat", bytes.NewReader(hb))
+	w := httptest.NewRecorder()
+	handleNodeHeartbeat(w, req)
+	assert.Equal(http.StatusOK, w.Code)
+	assert.Equal("10.0.1.2:8086", NodeHTTPAddr("2"))
+
+	// the stale info in an old node list does not overwrite the new one.
+	data, _ := json.Marshal(&updateNodeListCommand{
+		baseCommand: baseCommand{Op: opUpdateNodeList},
+		Nodes: map[string]*NodeInfo{
+			"1": {ID: "1", Addr: "10.0.0.1:8086", LastHeartbeatTime: past},
+			"2": {ID: "2", Addr: "10.0.0.2:8086", LastHeartbeatTime: past},
+		},
+	})
+	assert.Nil(s.Apply(&raft.Log{Index: 100, Data: data}))
+	assert.Equal("10.0.1.1:8086", NodeHTTPAddr("1"))
+	assert.Equal("10.0.1.2:8086", NodeHTTPAddr("2"))
+}
+
+func TestRaftStats(t *testing.T) {
+	assert := assert.New(t)
+
+	s := newService()
+	defer func(inst *service) { svcInst = inst }(svcInst)
+	svcInst = s
+	startTestRaft(t, s, "1")
+
+	stats := RaftStats()
+	assert.Equal("Leader", stats["state"])
+	assert.Contains(stats, "last_log_index")
+	assert.Equal("0", stats["apply_failures"])
+	assert.NotContains(stats, "last_apply_failure_index")
+
+	s.recordApplyResult(&raft.Log{Index: 7}, errors.New("bad command"))
+	stats = RaftStats()
+	assert.Equal("1", stats["apply_failures"])
+	assert.Equal("1", stats["consecutive_apply_failures"])
+	assert.Equal("7", stats["last_apply_failure_index"])
+	assert.Equal("bad command", stats["last_apply_failure_error"])
+
+	w := httptest.NewRecorder()
+	handleRaftStats(w, httptest.NewRequest(http.MethodGet, "/meta/raft/stats", nil))
+	assert.Equal(http.StatusOK, w.Code)
+	var got map[string]string
+	assert.NoError(json.Unmarshal(w.Body.Bytes(), &got))
+	assert.Equal("Leader", got["state"])
+}
+
+func TestNodeLabels(t *testing.T) {
+	assert := assert.New(t)
+
+	path := filepath.Join(t.TempDir(), "xuandb.toml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+[[node]]
+	id = "1"
+	http-addr = "127.0.0.1:7001"
+	[node.labels]
+		zone = "z1"
+	[node.meta]
+		raft-voter = true
+		raft-addr = "127.0.0.1:8001"
+		raft-store = "memory"
+		raft-snapshot-store = "memory"
+`), 0600))
+	t.Setenv("XUANDB_CONFIG_PATH", path)
+	require.NoError(t, config.Load("1", false))
+
+	s := newService()
+	defer func(inst *service) { svcInst = inst }(svcInst)
+	svcInst = s
+	startTestRaft(t, s, "1")
+
+	ni := &NodeInfo{}
+	ni.init()
+	s.heartbeat(ni, 0)
+	s.nodes["2"] = &NodeInfo{ID: "2", Labels: map[string]string{"zone": "z2"}}
+
+	nodes := Nodes()
+	require.Len(t, nodes, 2)
+	assert.Equal(map[string]string{"zone": "z1"}, nodes[0].Labels)
+
+	// labels survive the round trip of the update node list command.
+	data, _ := json.Marshal(&updateNodeListCommand{
+		baseCommand: baseCommand{Op: opUpdateNodeList},
+		Nodes:       s.nodes,
+	})
+	s.nodes = make(map[string]*NodeInfo)
+	assert.Nil(s.Apply(&raft.Log{Index: 100, Data: data}))
+	assert.Equal(map[string]string{"zone": "z1"}, NodeByID("1").Labels)
+
+	nodes = NodesWithLabel("zone", "z2")
+	require.Len(t, nodes, 1)
+	assert.Equal("2", nodes[0].ID)
+	assert.Empty(NodesWithLabel("zone", "z3"))
+}
+
+func TestNodesLeaderFirst(t *testing.T) {
+	assert := assert.New(t)
+
+	s := newService()
+	defer func(inst *service) { svcInst = inst }(svcInst)
+	svcInst = s
+	startTestRaft(t, s, "1")
+
+	s.nodes["0"] = &NodeInfo{ID: "0"}
+	s.nodes["1"] = &NodeInfo{ID: "1"}
+	s.nodes["2"] = &NodeInfo{ID: "2"}
+	s.nodes["3"] = &NodeInfo{ID: "3"}
+
+	ids := func(nodes []NodeInfo) []string {
+		var result []string
+		for _, ni := range nodes {
+			result = append(result, ni.ID)
+		}
+		return result
+	}
+
+	assert.Equal([]string{"0", "1", "2", "3"}, ids(Nodes()))
+	assert.Equal([]string{"1", "0", "2", "3"}, ids(NodesLeaderFirst()))
+
+	// the leader is not in the node list yet.
+	delete(s.nodes, "1")
+	assert.Equal([]string{"0", "2", "3"}, ids(NodesLeaderFirst()))
+}
+
+func TestNodeStatusesThresholds(t *testing.T) {
+	assert := assert.New(t)
+
+	s := newService()
+	defer func(inst *service) { svcInst = inst }(svcInst)
+	svcInst = s
+	startTestRaft(t, s, "1")
+
+	now := time.Now()
+	s.nodes["1"] = &NodeInfo{ID: "1", LastHeartbeatTime: now}
+	s.nodes["2"] = &NodeInfo{ID: "2", LastHeartbeatTime: now.Add(-15 * time.Second)}
+	s.nodes["3"] = &NodeInfo{ID: "3", LastHeartbeatTime: now.Add(-45 * time.Second)}
+	s.nodes["4"] = &NodeInfo{ID: "4", LastHeartbeatTime: now.Add(-2 * time.Minute)}
+
+	states := func() []string {
+		var result []string
+		for _, ns := range NodeStatuses() {
+			result = append(result, ns.State)
+		}
+		return result
+	}
+
+	// the default thresholds.
+	assert.Equal([]string{"up", "unknown", "down", "down"}, states())
+
+	// thresholds for a slow network.
+	s.nodeUnknownAfter = 30 * time.Second
+	s.nodeDeadAfter = 90 * time.Second
+	assert.Equal([]string{"up", "up", "unknown", "down"}, states())
+}
+
+func TestDrainNode(t *testing.T) {
+	assert := assert.New(t)
+
+	s := newService()
+	defer func(inst *service) { svcInst = inst }(svcInst)
+	svcInst = s
+	startTestRaft(t, s, "1")
+	require.NoError(t, s.raft.AddNonvoter("2", "node2", 0, 0).Error())
+
+	now := time.Now()
+	s.nodes["1"] = &NodeInfo{ID: "1", LastHeartbeatTime: now}
+	s.nodes["2"] = &NodeInfo{ID: "2", LastHeartbeatTime: now}
+
+	err := DrainNode("3")
+	if se, ok := err.(*xerrors.StatusError); assert.True(ok) {
+		assert.Equal(http.StatusNotFound, se.StatusCode)
+	}
+
+	assert.NoError(DrainNode("2"))
+	nss := NodeStatuses()
+	assert.Equal("up", nss[0].State)
+	assert.Equal("draining", nss[1].State)
+
+	// the leader ignores the heartbeats from a draining node.
+	hb, _ := json.Marshal(&NodeInfo{ID: "2", Addr: "new", LastHeartbeatTime: time.Now()})
+	w := httptest.NewRecorder()
+	handleNodeHeartbeat(w, httptest.NewRequest(http.MethodPost, "/meta/node/heartbeat", bytes.NewReader(hb)))
+	assert.Equal(http.StatusOK, w.Code)
+	ni := NodeByID("2")
+	assert.True(ni.Draining)
+	assert.Empty(ni.Addr)
+	assert.True(ni.LastHeartbeatTime.Equal(now))
+
+	// the liveness of a draining node is not judged by its heartbeats.
+	s.nodeDeadAfter = time.Nanosecond
+	s.nodeUnknownAfter = time.Nanosecond
+	assert.Equal("draining", NodeStatuses()[1].State)
+	assert.Equal("down", NodeStatuses()[0].State)
+
+	// an update node list command built before draining does not reset the
+	// draining state.
+	data, _ := json.Marshal(&updateNodeListCommand{
+		baseCommand: baseCommand{Op: opUpdateNodeList},
+		Nodes: map[string]*NodeInfo{
+			"1": {ID: "1", LastHeartbeatTime: now},
+			"2": {ID: "2", LastHeartbeatTime: time.Now()},
+		},
+	})
+	assert.Nil(s.Apply(&raft.Log{Index: 100, Data: data}))
+	assert.Equal("draining", NodeStatuses()[1].State)
+
+	// the node list sent to the followers carries the flag.
+	cmd, err := s.nodeListCommand()
+	if assert.NoError(err) {
+		assert.False(cmd.Nodes["1"].Draining)
+		assert.True(cmd.Nodes["2"].Draining)
+	}
+
+	// the HTTP API.
+	w = httptest.NewRecorder()
+	handleDrainNode(w, httptest.NewRequest(http.MethodPost, "/meta/node/drain?id=1", nil))
+	assert.Equal(http.StatusNoContent, w.Code)
+	assert.Equal("draining", NodeStatuses()[0].State)
+}
+
+func TestNodeRejoinGraceWindow(t *testing.T) {
+	assert := assert.New(t)
+
+	s := newService()
+	defer func(inst *service) { svcInst = inst }(svcInst)
+	svcInst = s
+	startTestRaft(t, s, "1")
+	require.NoError(t, s.raft.AddNonvoter("2", "node2", 0, 0).Error())
+
+	suffrage := func(id string) (raft.ServerSuffrage, bool) {
+		f := s.raft.GetConfiguration()
+		require.NoError(t, f.Error())
+		for _, svr := range f.Configuration().Servers {
+			if svr.ID == raft.ServerID(id) {
+				return svr.Suffrage, true
+			}
+		}
+		return 0, false
+	}
+
+	rejoin := func() *httptest.ResponseRecorder {
+		jr, _ := json.Marshal(&joinRequest{
+			ClusterName: config.ClusterName(),
+			ID:          "2",
+			Addr:        "node2",
+			Voter:       true,
+		})
+		req := httptest.NewRequest(http.MethodPost, "/meta/nodes", bytes.NewReader(jr))
+		w := httptest.NewRecorder()
+		handleAddNode(w, req)
+		return w
+	}
+
+	// readmitted with its prior suffrage within the grace window.
+	require.NoError(t, leaderDropNode("2"))
+	_, ok := suffrage("2")
+	assert.False(ok)
+
+	w := rejoin()
+	assert.Equal(http.StatusNoContent, w.Code)
+	sf, ok := suffrage("2")
+	assert.True(ok)
+	assert.Equal(raft.Nonvoter, sf)
+	assert.NotContains(s.departed, "2")
+
+	// rejected outside the grace window.
+	require.NoError(t, leaderDropNode("2"))
+	dn := s.departed["2"]
+	dn.DroppedAt = dn.DroppedAt.Add(-s.rejoinGraceWindow)
+	s.departed["2"] = dn
+
+	w = rejoin()
+	assert.Equal(http.StatusForbidden, w.Code)
+	assert.Contains(w.Body.String(), "must be re-added manually")
+	_, ok = suffrage("2")
+	assert.False(ok)
+
+	// a manual re-add is always admitted.
+	require.NoError(t, AddNode("2", "node2", true))
+	sf, ok = suffrage("2")
+	assert.True(ok)
+	assert.Equal(raft.Voter, sf)
+	assert.NotContains(s.departed, "2")
+
+	// a window of 0 disables automatic readmission.
+	s.rejoinGraceWindow = 0
+	require.NoError(t, leaderDropNode("2"))
+	w = rejoin()
+	assert.Equal(http.StatusForbidden, w.Code)
+}
+
+func TestClusterNameCheck(t *testing.T) {
+	assert := assert.New(t)
+
+	s := newService()
+	defer func(inst *service) { svcInst = inst }(svcInst)
+	svcInst = s
+	startTestRaft(t, s, "1")
+
+	before := time.Now().Add(-time.Minute)
+	s.nodes["2"] = &NodeInfo{ID: "2", LastHeartbeatTime: before}
+
+	handler := metaHandler(handleNodeHeartbeat)
+	heartbeat := func(clusterName string) *httptest.ResponseRecorder {
+		hb, _ := json.Marshal(&NodeInfo{ID: "2", LastHeartbeatTime: time.Now()})
+		req := httptest.NewRequest(http.MethodPost, "/meta/node/heartbeat", bytes.NewReader(hb))
+		req.Header.Set(ClusterNameHeader, clusterName)
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w
+	}
+
+	// a heartbeat from another cluster is refused.
+	w := heartbeat("other-" + config.ClusterName())
+	assert.Equal(http.StatusForbidden, w.Code)
+	assert.JSONEq(`{"error":"wrong cluster name","code":403}`, w.Body.String())
+	assert.Equal(before, NodeByID("2").LastHeartbeatTime)
+
+	w = heartbeat(config.ClusterName())
+	assert.Equal(http.StatusOK, w.Code)
+	assert.True(NodeByID("2").LastHeartbeatTime.After(before))
+
+	// the operator APIs do not require the cluster name.
+	w = httptest.NewRecorder()
+	operatorHandler(handleRaftStats)(w, httptest.NewRequest(http.MethodGet, "/meta/raft/stats", nil))
+	assert.Equal(http.StatusOK, w.Code)
+
+	// the client sends the cluster name with every request.
+	var got []string
+	leader := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Values(ClusterNameHeader)
+	}))
+	defer leader.Close()
+	defer func(fn func() string) { leaderHTTPAddr = fn }(leaderHTTPAddr)
+	leaderHTTPAddr = func() string { return strings.TrimPrefix(leader.URL, "http://") }
+
+	assert.NoError(sendPostRequestToLeader(context.Background(), "/meta/node/heartbeat", NodeByID("2")))
+	assert.Equal([]string{config.ClusterName()}, got)
+}
+
+func TestNodesConcurrentAccess(t *testing.T) {
+	s := newService()
+	defer func(inst *service) { svcInst = inst }(svcInst)
+	svcInst = s
+	startTestRaft(t, s, "1")
+
+	s.nodes["2"] = &NodeInfo{ID: "2", LastHeartbeatTime: time.Now()}
+
+	const n = 200
+	var wg sync.WaitGroup
+	run := func(fn func(i int)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range n {
+				fn(i)
+			}
+		}()
+	}
+
+	// writers.
+	run(func(i int) {
+		hb, _ := json.Marshal(&NodeInfo{
+			ID:                "2",
+			Labels:            map[string]string{"zone": "z1"},
+			LastHeartbeatTime: time.Now(),
+		})
+		req := httptest.NewRequest(http.MethodPost, "/meta/node/heartbeat", bytes.NewReader(hb))
+		handleNodeHeartbeat(httptest.NewRecorder(), req)
+	})
+	run(func(i int) {
+		ni := &NodeInfo{ID: "1", Labels: map[string]string{"zone": "z2"}}
+		s.heartbeat(ni, 1)
+	})
+	run(func(i int) {
+		if i%20 == 0 {
+			s.sendNodeListToFollower()
+		}
+	})
+
+	// readers, which may modify the returned copies freely.
+	run(func(i int) {
+		for _, ni := range Nodes() {
+			if ni.Labels != nil {
+				ni.Labels["zone"] = "x"
+			}
+		}
+	})
+	run(func(i int) {
+		for _, ns := range NodeStatuses() {
+			if ns.Labels != nil {
+				ns.Labels["zone"] = "x"
+			}
+		}
+	})
+	run(func(i int) {
+		if ni := NodeByID("1"); ni != nil {
+			ni.Labels["zone"] = "x"
+			ni.Draining = !ni.Draining
+		}
+		LeaderNode()
+		LeaderHTTPAddr()
+		NodesWithLabel("zone", "z1")
+	})
+
+	wg.Wait()
+
+	// the copies returned to readers are not shared with the node list, node
+	// 2 is removed by the node list as it is not a raft server.
+	ni := NodeByID("1")
+	assert.Equal(t, "z2", ni.Labels["zone"])
+	assert.False(t, ni.Draining)
+}
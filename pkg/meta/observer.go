@@ -0,0 +1,70 @@
+package meta
+
+import (
+	"log/slog"
+
+	"github.com/hashicorp/raft"
+)
+
+// observeRaft registers a raft observer which logs leadership and peer
+// changes, and notifies updateNodeInfo when this node becomes the leader.
+// It must be called after the raft is created and before it starts an
+// election, so that no leadership change is missed.
+func (s *service) observeRaft() {
+	ch := make(chan raft.Observation, 16)
+	s.observer = raft.NewObserver(ch, false, func(o *raft.Observation) bool {
+		switch o.Data.(type) {
+		case raft.LeaderObservation, raft.PeerObservation:
+			return true
+		}
+		return false
+	})
+	s.raft.RegisterObserver(s.observer)
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case o := <-ch:
+				s.handleObservation(o)
+			}
+		}
+	}()
+}
+
+// handleObservation handles a raft observation.
+func (s *service) handleObservation(o raft.Observation) {
+	switch data := o.Data.(type) {
+	case raft.LeaderObservation:
+		if data.LeaderID == "" {
+			slog.Info("raft leader lost")
+			return
+		}
+
+		slog.Info(
+			"raft leader changed",
+			slog.String("leaderId", string(data.LeaderID)),
+			slog.String("leaderAddr", string(data.LeaderAddr)),
+		)
+
+		if !s.isLeader() {
+			return
+		}
+		select {
+		case s.leaderAcquired <- struct{}{}:
+		default:
+		}
+
+	case raft.PeerObservation:
+		slog.Info(
+			"raft peer changed",
+			slog.String("peerId", string(data.Peer.ID)),
+			slog.String("peerAddr", string(data.Peer.Address)),
+			slog.String("suffrage", data.Peer.Suffrage.String()),
+			slog.Bool("removed", data.Removed),
+		)
+	}
+}
@@ -0,0 +1,52 @@
+package meta
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/raft"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestObserveLeadership(t *testing.T) {
+	assert := assert.New(t)
+
+	s := newService()
+	defer func(inst *service) { svcInst = inst }(svcInst)
+	svcInst = s
+
+	cfg := raft.DefaultConfig()
+	cfg.LocalID = "1"
+	cfg.Logger = hclog.NewNullLogger()
+	cfg.HeartbeatTimeout = 50 * time.Millisecond
+	cfg.ElectionTimeout = 50 * time.Millisecond
+	cfg.LeaderLeaseTimeout = 50 * time.Millisecond
+	cfg.CommitTimeout = 5 * time.Millisecond
+
+	store := raft.NewInmemStore()
+	addr, trans := raft.NewInmemTransport("")
+	ra, err := raft.NewRaft(cfg, s, store, store, raft.NewInmemSnapshotStore(), trans)
+	require.NoError(t, err)
+	t.Cleanup(func() { ra.Shutdown().Error() })
+	s.raft = ra
+
+	// the observer is registered before the bootstrap, so it observes the
+	// leadership acquisition.
+	s.observeRaft()
+	svrs := []raft.Server{{ID: cfg.LocalID, Address: addr}}
+	require.NoError(t, ra.BootstrapCluster(raft.Configuration{Servers: svrs}).Error())
+
+	select {
+	case <-s.leaderAcquired:
+		assert.True(s.isLeader())
+	case <-time.After(5 * time.Second):
+		t.Fatal("leadership acquisition is not observed")
+	}
+
+	// the observer goroutine exits on shutdown.
+	s.raft.DeregisterObserver(s.observer)
+	close(s.stop)
+	s.wg.Wait()
+}
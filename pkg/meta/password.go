@@ -0,0 +1,140 @@
+package meta
+
+import (
+	"bufio"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/localvar/xuandb/pkg/config"
+	"github.com/localvar/xuandb/pkg/xerrors"
+)
+
+// ErrWeakPassword is returned when a password is not allowed to be used.
+var ErrWeakPassword = xerrors.New(http.StatusBadRequest, "password is too weak or has been compromised")
+
+// passwordDenyList is a set of passwords which are not allowed to be used.
+type passwordDenyList struct {
+	passwords  map[string]struct{}
+	ignoreCase bool
+}
+
+// loadPasswordDenyList loads the deny list from file 'path', which contains
+// one password per line, empty lines and lines starting with '#' are ignored.
+// It returns nil if 'path' is empty.
+func loadPasswordDenyList(path string, ignoreCase bool) (*passwordDenyList, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	dl := &passwordDenyList{
+		passwords:  make(map[string]struct{}),
+		ignoreCase: ignoreCase,
+	}
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		if ignoreCase {
+			line = strings.ToLower(line)
+		}
+		dl.passwords[line] = struct{}{}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return dl, nil
+}
+
+// passwordDenyListCache is the name of the password deny list in the cache
+// registry, flushing it reloads the deny list from the file.
+const passwordDenyListCache = "password_deny_list"
+
+// reloadPasswordDenyList reloads the password deny list from the configured
+// file, the current deny list is kept if it fails.
+func (s *service) reloadPasswordDenyList() {
+	mc := config.CurrentNode().Meta
+	dl, err := loadPasswordDenyList(mc.PasswordDenyList, mc.PasswordDenyListIgnoreCase)
+	if err != nil {
+		slog.Error("failed to reload password deny list", slog.String("error", err.Error()))
+		return
+	}
+	s.pwdDenyList.Store(dl)
+}
+
+// contains returns whether 'pwd' is in the deny list, a nil deny list
+// contains nothing.
+func (dl *passwordDenyList) contains(pwd string) bool {
+	if dl == nil {
+		return false
+	}
+	if dl.ignoreCase {
+		pwd = strings.ToLower(pwd)
+	}
+	_, ok := dl.passwords[pwd]
+	return ok
+}
+
+// CheckPasswordStrength checks 'pwd' against 'policy', it returns a 400
+// [xerrors.StatusError] describing the first rule which is not satisfied.
+func CheckPasswordStrength(pwd string, policy config.PasswordPolicy) error {
+	if n := policy.MinLength; n > 0 && utf8.RuneCountInString(pwd) < n {
+		msg := "password must contain at least " + strconv.Itoa(n) + " characters"
+		return xerrors.New(http.StatusBadRequest, msg)
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range pwd {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case !unicode.IsLetter(r):
+			symbol = true
+		}
+	}
+
+	if policy.RequireUpper && !upper {
+		return xerrors.New(http.StatusBadRequest, "password must contain an upper case letter")
+	}
+	if policy.RequireLower && !lower {
+		return xerrors.New(http.StatusBadRequest, "password must contain a lower case letter")
+	}
+	if policy.RequireDigit && !digit {
+		return xerrors.New(http.StatusBadRequest, "password must contain a digit")
+	}
+	if policy.RequireSymbol && !symbol {
+		return xerrors.New(http.StatusBadRequest, "password must contain a symbol")
+	}
+
+	return nil
+}
+
+// checkPassword returns an error if 'pwd' does not satisfy the password
+// policy, or ErrWeakPassword if it is in the deny list.
+func checkPassword(pwd string) error {
+	if err := CheckPasswordStrength(pwd, svcInst.pwdPolicy); err != nil {
+		return err
+	}
+	if svcInst.pwdDenyList.Load().contains(pwd) {
+		return ErrWeakPassword
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package meta
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/localvar/xuandb/pkg/config"
+	"github.com/localvar/xuandb/pkg/xerrors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeDenyList writes 'content' to a deny list file and returns its path.
+func writeDenyList(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "deny.txt")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoadPasswordDenyList(t *testing.T) {
+	assert := assert.New(t)
+
+	dl, err := loadPasswordDenyList("", false)
+	assert.NoError(err)
+	assert.Nil(dl)
+	assert.False(dl.contains("123456"))
+
+	_, err = loadPasswordDenyList(filepath.Join(t.TempDir(), "none.txt"), false)
+	assert.Error(err)
+
+	// an empty list denies nothing.
+	dl, err = loadPasswordDenyList(writeDenyList(t, "# comment only\n\n"), false)
+	assert.NoError(err)
+	assert.NotNil(dl)
+	assert.False(dl.contains(""))
+	assert.False(dl.contains("# comment only"))
+
+	path := writeDenyList(t, "123456\n  Password  \n# qwerty\n")
+	dl, err = loadPasswordDenyList(path, false)
+	assert.NoError(err)
+	assert.True(dl.contains("123456"))
+	assert.True(dl.contains("Password"))
+	assert.False(dl.contains("password"))
+	assert.False(dl.contains("qwerty"))
+
+	dl, err = loadPasswordDenyList(path, true)
+	assert.NoError(err)
+	assert.True(dl.contains("password"))
+	assert.True(dl.contains("PASSWORD"))
+}
+
+func TestPasswordDenyList(t *testing.T) {
+	assert := assert.New(t)
+
+	s := newService()
+	defer func(inst *service) { svcInst = inst }(svcInst)
+	svcInst = s
+	startTestRaft(t, s, "1")
+
+	// no deny list.
+	assert.NoError(CreateUser(&User{Name: "admin", Password: "123456"}, false))
+
+	dl, err := loadPasswordDenyList(writeDenyList(t, "123456\npassword\n"), false)
+	require.NoError(t, err)
+	s.pwdDenyList.Store(dl)
+
+	assert.Equal(ErrWeakPassword, CreateUser(&User{Name: "alice", Password: "password"}, false))
+	assert.Nil(UserByName("alice"))
+	assert.NoError(CreateUser(&User{Name: "alice", Password: "s3cret!"}, false))
+
+	assert.Equal(ErrWeakPassword, SetPassword("alice", "123456"))
+	assert.Equal("s3cret!", UserByName("alice").Password)
+	assert.NoError(SetPassword("alice", "an0ther!"))
+	assert.Equal("an0ther!", UserByName("alice").Password)
+
+	err = CreateUsers([]*User{
+		{Name: "bob", Password: "b0b!"},
+		{Name: "carol", Password: "password"},
+	})
+	assert.Equal(ErrWeakPassword, err)
+	assert.Nil(UserByName("bob"))
+}
+
+func TestReloadPasswordDenyList(t *testing.T) {
+	assert := assert.New(t)
+
+	denyList := writeDenyList(t, "123456\n")
+	path := filepath.Join(t.TempDir(), "xuandb.toml")
+	require.NoError(t, os.WriteFile(path, []byte(fmt.Sprintf(`
+[[node]]
+	id = "1"
+	http-addr = "127.0.0.1:7001"
+	[node.meta]
+		raft-voter = true
+		raft-addr = "127.0.0.1:8001"
+		raft-store = "memory"
+		raft-snapshot-store = "memory"
+		password-deny-list = %q
+`, denyList)), 0600))
+	t.Setenv("XUANDB_CONFIG_PATH", path)
+	require.NoError(t, config.Load("1", false))
+
+	s := newService()
+	s.reloadPasswordDenyList()
+	assert.True(s.pwdDenyList.Load().contains("123456"))
+
+	// the changes of the file take effect after reloading.
+	require.NoError(t, os.WriteFile(denyList, []byte("password\n"), 0600))
+	assert.True(s.pwdDenyList.Load().contains("123456"))
+	s.reloadPasswordDenyList()
+	assert.False(s.pwdDenyList.Load().contains("123456"))
+	assert.True(s.pwdDenyList.Load().contains("password"))
+
+	// the current deny list is kept if the file cannot be loaded.
+	require.NoError(t, os.Remove(denyList))
+	s.reloadPasswordDenyList()
+	assert.True(s.pwdDenyList.Load().contains("password"))
+}
+
+func TestCheckPasswordStrength(t *testing.T) {
+	assert := assert.New(t)
+
+	// the zero policy allows anything.
+	assert.NoError(CheckPasswordStrength("", config.PasswordPolicy{}))
+	assert.NoError(CheckPasswordStrength("a", config.PasswordPolicy{}))
+
+	p := config.PasswordPolicy{MinLength: 6}
+	assert.EqualError(CheckPasswordStrength("abcde", p), "password must contain at least 6 characters")
+	assert.NoError(CheckPasswordStrength("abcdef", p))
+	// the length is measured in characters rather than bytes.
+	assert.Error(CheckPasswordStrength("密码密码密", p))
+	assert.NoError(CheckPasswordStrength("密码密码密码", p))
+
+	p = config.PasswordPolicy{RequireUpper: true}
+	assert.EqualError(CheckPasswordStrength("abc1!", p), "password must contain an upper case letter")
+	assert.NoError(CheckPasswordStrength("aBc", p))
+
+	p = config.PasswordPolicy{RequireLower: true}
+	assert.EqualError(CheckPasswordStrength("ABC1!", p), "password must contain a lower case letter")
+	assert.NoError(CheckPasswordStrength("ABc", p))
+
+	p = config.PasswordPolicy{RequireDigit: true}
+	assert.EqualError(CheckPasswordStrength("abC!", p), "password must contain a digit")
+	assert.NoError(CheckPasswordStrength("ab3", p))
+
+	p = config.PasswordPolicy{RequireSymbol: true}
+	assert.EqualError(CheckPasswordStrength("abC1", p), "password must contain a symbol")
+	assert.NoError(CheckPasswordStrength("ab c", p))
+	assert.NoError(CheckPasswordStrength("ab#", p))
+
+	// the error is a 400 status error.
+	err := CheckPasswordStrength("", config.PasswordPolicy{MinLength: 1})
+	var se *xerrors.StatusError
+	assert.ErrorAs(err, &se)
+	assert.Equal(http.StatusBadRequest, se.StatusCode)
+}
+
+func TestPasswordPolicy(t *testing.T) {
+	assert := assert.New(t)
+
+	s := newService()
+	defer func(inst *service) { svcInst = inst }(svcInst)
+	svcInst = s
+	startTestRaft(t, s, "1")
+	s.pwdPolicy = config.PasswordPolicy{MinLength: 8, RequireDigit: true}
+
+	err := CreateUser(&User{Name: "admin", Password: "short1"}, false)
+	assert.EqualError(err, "password must contain at least 8 characters")
+	assert.Nil(UserByName("admin"))
+	assert.NoError(CreateUser(&User{Name: "admin", Password: "longer-pwd-1"}, false))
+
+	assert.EqualError(SetPassword("admin", "no-digits-here"), "password must contain a digit")
+	assert.Equal("longer-pwd-1", UserByName("admin").Password)
+	assert.NoError(SetPassword("admin", "digits-here-2"))
+	assert.Equal("digits-here-2", UserByName("admin").Password)
+}
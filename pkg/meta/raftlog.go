@@ -0,0 +1,137 @@
+package meta
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/hashicorp/raft"
+	"github.com/localvar/xuandb/pkg/xerrors"
+)
+
+// maxRaftLogRange is the maximum number of raft log entries can be read by
+// RaftLogs in a single call.
+const maxRaftLogRange = 1000
+
+// RaftLogEntry is the metadata of a raft log entry, the payload is not
+// included.
+type RaftLogEntry struct {
+	Index uint64 `json:"index"`
+	Term  uint64 `json:"term"`
+	Type  string `json:"type"`
+	Size  int    `json:"size"`
+
+	// Op is the operation name of a command, it is only set if requested.
+	Op string `json:"op,omitempty"`
+}
+
+// RaftLogs returns the metadata of the raft log entries with index in
+// [from, to] from the log store of the current node. If they are 0, the range
+// defaults to the latest maxRaftLogRange entries, or at most maxRaftLogRange
+// entries from 'from' if only 'to' is 0. Entries which have been compacted are
+// skipped. If 'withOp' is true, the operation names of the commands are
+// decoded.
+func RaftLogs(from, to uint64, withOp bool) ([]RaftLogEntry, error) {
+	ls := svcInst.logs
+
+	if from == 0 || to == 0 {
+		first, err := ls.FirstIndex()
+		if err != nil {
+			return nil, xerrors.Wrap(err, http.StatusInternalServerError)
+		}
+		last, err := ls.LastIndex()
+		if err != nil {
+			return nil, xerrors.Wrap(err, http.StatusInternalServerError)
+		}
+
+		if to == 0 {
+			to = last
+			if from != 0 {
+				to = min(last, from+maxRaftLogRange-1)
+			}
+		}
+		if from == 0 {
+			from = first
+			if to >= maxRaftLogRange {
+				from = max(first, to-maxRaftLogRange+1)
+			}
+		}
+	}
+
+	if from > to {
+		return nil, xerrors.New(http.StatusBadRequest, "invalid raft log range")
+	}
+	if to-from >= maxRaftLogRange {
+		msg := fmt.Sprintf("raft log range is too large, the limit is %d entries", maxRaftLogRange)
+		return nil, xerrors.New(http.StatusBadRequest, msg)
+	}
+
+	result := make([]RaftLogEntry, 0, to-from+1)
+	for idx := from; idx <= to; idx++ {
+		var l raft.Log
+		if err := ls.GetLog(idx, &l); errors.Is(err, raft.ErrLogNotFound) {
+			continue
+		} else if err != nil {
+			return nil, xerrors.Wrap(err, http.StatusInternalServerError)
+		}
+
+		e := RaftLogEntry{
+			Index: l.Index,
+			Term:  l.Term,
+			Type:  l.Type.String(),
+			Size:  len(l.Data),
+		}
+		if withOp && l.Type == raft.LogCommand {
+			var cmd baseCommand
+			if json.Unmarshal(l.Data, &cmd) == nil {
+				e.Op = cmd.Op
+			}
+		}
+		result = append(result, e)
+	}
+
+	return result, nil
+}
+
+// parseIndex parses raft log index 'name' from the request, 0 is returned if
+// it is not provided.
+func parseIndex(r *http.Request, name string) (uint64, error) {
+	s := r.FormValue(name)
+	if s == "" {
+		return 0, nil
+	}
+	idx, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, xerrors.New(http.StatusBadRequest, "invalid raft log index: "+s)
+	}
+	return idx, nil
+}
+
+// handleRaftLogs handles the request to read raft log entries, it is for
+// debugging and requires the admin privilege.
+func handleRaftLogs(w http.ResponseWriter, r *http.Request) {
+	name, pwd, _ := r.BasicAuth()
+	err := Auth(name, pwd, RequiredPrivileges{Global: PrivilegeAdmin})
+
+	var from, to uint64
+	if err == nil {
+		from, err = parseIndex(r, "from")
+	}
+	if err == nil {
+		to, err = parseIndex(r, "to")
+	}
+
+	var entries []RaftLogEntry
+	if err == nil {
+		entries, err = RaftLogs(from, to, r.FormValue("ops") == "true")
+	}
+
+	if err != nil {
+		xerrors.WriteJSON(w, err)
+		return
+	}
+
+	writeJSON(w, entries)
+}
@@ -0,0 +1,90 @@
+package meta
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/raft"
+	"github.com/localvar/xuandb/pkg/xerrors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRaftLogs(t *testing.T) {
+	assert := assert.New(t)
+
+	s := newService()
+	defer func(inst *service) { svcInst = inst }(svcInst)
+	svcInst = s
+	startTestRaft(t, s, "1")
+
+	require.NoError(t, CreateUser(&User{Name: "admin", Password: "pwd"}, false))
+	last := s.raft.LastIndex()
+
+	entries, err := RaftLogs(0, 0, false)
+	require.NoError(t, err)
+	require.NotEmpty(t, entries)
+	assert.EqualValues(1, entries[0].Index)
+	assert.Equal(raft.LogConfiguration.String(), entries[0].Type)
+
+	e := entries[len(entries)-1]
+	assert.Equal(last, e.Index)
+	assert.Equal(raft.LogCommand.String(), e.Type)
+	assert.Positive(e.Term)
+	assert.Positive(e.Size)
+	assert.Empty(e.Op)
+
+	entries, err = RaftLogs(last, last, true)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(opCreateUser, entries[0].Op)
+
+	// invalid and too large ranges.
+	_, err = RaftLogs(last, last-1, false)
+	assert.EqualError(err, "invalid raft log range")
+	_, err = RaftLogs(1, maxRaftLogRange+1, false)
+	if se, ok := err.(*xerrors.StatusError); assert.True(ok) {
+		assert.Equal(http.StatusBadRequest, se.StatusCode)
+	}
+
+	// the HTTP API requires the admin privilege.
+	r := httptest.NewRequest(http.MethodGet, "/meta/raft/log?from=1&to=1", nil)
+	w := httptest.NewRecorder()
+	handleRaftLogs(w, r)
+	assert.Equal(http.StatusUnauthorized, w.Code)
+
+	r.SetBasicAuth("admin", "pwd")
+	w = httptest.NewRecorder()
+	handleRaftLogs(w, r)
+	assert.Equal(http.StatusOK, w.Code)
+	var got []RaftLogEntry
+	assert.NoError(json.Unmarshal(w.Body.Bytes(), &got))
+	assert.Equal([]RaftLogEntry{{Index: 1, Term: 1, Type: raft.LogConfiguration.String(), Size: got[0].Size}}, got)
+
+	r = httptest.NewRequest(http.MethodGet, "/meta/raft/log?from=x", nil)
+	r.SetBasicAuth("admin", "pwd")
+	w = httptest.NewRecorder()
+	handleRaftLogs(w, r)
+	assert.Equal(http.StatusBadRequest, w.Code)
+
+	// the default range is limited if there are too many entries.
+	var logs []*raft.Log
+	for i := range uint64(maxRaftLogRange + 500) {
+		logs = append(logs, &raft.Log{Index: last + 1 + i, Term: 1, Type: raft.LogNoop})
+	}
+	require.NoError(t, s.logs.StoreLogs(logs))
+	newLast := last + maxRaftLogRange + 500
+
+	entries, err = RaftLogs(0, 0, false)
+	require.NoError(t, err)
+	require.Len(t, entries, maxRaftLogRange)
+	assert.Equal(newLast-maxRaftLogRange+1, entries[0].Index)
+	assert.Equal(newLast, entries[len(entries)-1].Index)
+
+	entries, err = RaftLogs(1, 0, false)
+	require.NoError(t, err)
+	require.Len(t, entries, maxRaftLogRange)
+	assert.EqualValues(1, entries[0].Index)
+}
@@ -0,0 +1,88 @@
+package meta
+
+import (
+	"log/slog"
+	"net/http"
+	"os"
+
+	raftboltdb "github.com/hashicorp/raft-boltdb/v2"
+	"github.com/localvar/xuandb/pkg/xerrors"
+)
+
+// errCompactNotSupported is returned when compacting a raft store which is
+// not a boltdb store.
+var errCompactNotSupported = xerrors.New(http.StatusNotImplemented, "compaction is only supported by the boltdb raft store")
+
+// raftStoreStats is the space usage of the boltdb raft store.
+type raftStoreStats struct {
+	FileSize     int64 `json:"fileSize"`
+	FreePages    int   `json:"freePages"`
+	PendingPages int   `json:"pendingPages"`
+	FreeBytes    int   `json:"freeBytes"`
+}
+
+// compactRaftStore runs the maintenance of the boltdb raft store of this
+// node and returns its space usage.
+//
+// A boltdb file never shrinks, the pages of the deleted logs are reused by
+// new logs. The file can only be rewritten offline, because the store does
+// not expose its database, so this flushes the store to disk and reports
+// the space usage, the free pages show how much the file would shrink.
+func (s *service) compactRaftStore() (*raftStoreStats, error) {
+	db, ok := s.logs.(*raftboltdb.BoltStore)
+	if !ok || s.raftStorePath == "" {
+		return nil, errCompactNotSupported
+	}
+
+	if err := db.Sync(); err != nil {
+		return nil, xerrors.Wrap(err, http.StatusInternalServerError)
+	}
+
+	fi, err := os.Stat(s.raftStorePath)
+	if err != nil {
+		return nil, xerrors.Wrap(err, http.StatusInternalServerError)
+	}
+
+	bs := db.Stats()
+	stats := &raftStoreStats{
+		FileSize:     fi.Size(),
+		FreePages:    bs.FreePageN,
+		PendingPages: bs.PendingPageN,
+		FreeBytes:    bs.FreeAlloc,
+	}
+
+	slog.Info(
+		"raft store maintenance done",
+		slog.Int64("fileSize", stats.FileSize),
+		slog.Int("freePages", stats.FreePages),
+		slog.Int("pendingPages", stats.PendingPages),
+		slog.Int("freeBytes", stats.FreeBytes),
+	)
+	return stats, nil
+}
+
+// CompactRaftStore runs the maintenance of the raft store of the current
+// node, the usage of the store is logged. It is only supported by the
+// boltdb store.
+func CompactRaftStore() error {
+	_, err := svcInst.compactRaftStore()
+	return err
+}
+
+// handleCompactRaftStore handles the request to compact the raft store of
+// the current node, it requires the admin privilege.
+func handleCompactRaftStore(w http.ResponseWriter, r *http.Request) {
+	name, pwd, _ := r.BasicAuth()
+	if err := Auth(name, pwd, RequiredPrivileges{Global: PrivilegeAdmin}); err != nil {
+		xerrors.WriteJSON(w, err)
+		return
+	}
+
+	stats, err := svcInst.compactRaftStore()
+	if err != nil {
+		xerrors.WriteJSON(w, err)
+		return
+	}
+
+	writeJSON(w, stats)
+}
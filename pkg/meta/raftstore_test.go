@@ -0,0 +1,71 @@
+package meta
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/raft"
+	raftboltdb "github.com/hashicorp/raft-boltdb/v2"
+	"github.com/localvar/xuandb/pkg/xerrors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompactRaftStore(t *testing.T) {
+	assert := assert.New(t)
+
+	compact := func() *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/meta/raft/compact", nil)
+		r.SetBasicAuth("admin", "pwd")
+		w := httptest.NewRecorder()
+		handleCompactRaftStore(w, r)
+		return w
+	}
+
+	// a boltdb store, with some free pages from the deleted logs.
+	s := newService()
+	defer func(inst *service) { svcInst = inst }(svcInst)
+	svcInst = s
+
+	path := dataLayout{dir: t.TempDir()}.legacyRaftStorePath()
+	db, err := raftboltdb.New(raftboltdb.Options{Path: path})
+	require.NoError(t, err)
+	defer db.Close()
+
+	logs := make([]*raft.Log, 100)
+	for i := range logs {
+		logs[i] = &raft.Log{Index: uint64(i + 1), Term: 1, Data: []byte(strings.Repeat("x", 1024))}
+	}
+	require.NoError(t, db.StoreLogs(logs))
+	require.NoError(t, db.DeleteRange(1, 90))
+
+	s.logs = db
+	s.raftStorePath = path
+	assert.NoError(CompactRaftStore())
+
+	w := compact()
+	assert.Equal(http.StatusOK, w.Code)
+	var stats raftStoreStats
+	assert.NoError(json.Unmarshal(w.Body.Bytes(), &stats))
+	assert.Positive(stats.FileSize)
+
+	// an in-memory store.
+	s = newService()
+	svcInst = s
+	startTestRaft(t, s, "1")
+	require.NoError(t, CreateUser(&User{Name: "admin", Password: "pwd"}, false))
+
+	assert.Equal(errCompactNotSupported, CompactRaftStore())
+	w = compact()
+	assert.Equal(http.StatusNotImplemented, w.Code)
+	assert.Equal(errCompactNotSupported, xerrors.FromHTTPResponse(w.Result()))
+
+	// the admin privilege is required.
+	r := httptest.NewRequest(http.MethodPost, "/meta/raft/compact", nil)
+	w = httptest.NewRecorder()
+	handleCompactRaftStore(w, r)
+	assert.Equal(http.StatusUnauthorized, w.Code)
+}
@@ -0,0 +1,122 @@
+package meta
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/localvar/xuandb/pkg/xerrors"
+)
+
+// ReadOptions are the options of the meta data read functions.
+type ReadOptions struct {
+	// Consistent requests a read which reflects all changes committed before
+	// it. The functions without options read the local state, which may be
+	// stale on a follower. A consistent read costs a round trip: the leader
+	// verifies its leadership with a quorum, and a follower forwards the read
+	// to the leader.
+	Consistent bool
+}
+
+// verifyLeader verifies the current node is still the leader.
+func (s *service) verifyLeader() error {
+	if err := s.raft.VerifyLeader().Error(); err != nil {
+		return xerrors.Wrap(err, http.StatusServiceUnavailable)
+	}
+	return nil
+}
+
+// readFromLeader prepares a consistent read, it returns true if the read
+// can be served from the local state, otherwise, the read has been forwarded
+// to the leader with 'pathAndQuery', and the result is decoded to 'result'.
+func readFromLeader(pathAndQuery string, result any) (local bool, err error) {
+	if svcInst.isLeader() {
+		return true, svcInst.verifyLeader()
+	}
+	return false, sendGetRequestToLeader(context.Background(), pathAndQuery, result)
+}
+
+// UsersWithOptions is the same as Users, but with read options 'opts'. Note
+// the passwords are cleared if the read is forwarded to the leader.
+func UsersWithOptions(opts ReadOptions) ([]*User, error) {
+	if !opts.Consistent {
+		return Users(), nil
+	}
+
+	var us []*User
+	if local, err := readFromLeader("/meta/users?consistent=true", &us); err != nil {
+		return nil, err
+	} else if local {
+		return Users(), nil
+	}
+	return us, nil
+}
+
+// DatabasesWithOptions is the same as Databases, but with read options 'opts'.
+func DatabasesWithOptions(opts ReadOptions) ([]*Database, error) {
+	if !opts.Consistent {
+		return Databases(), nil
+	}
+
+	var dbs []*Database
+	if local, err := readFromLeader("/meta/databases?consistent=true", &dbs); err != nil {
+		return nil, err
+	} else if local {
+		return Databases(), nil
+	}
+	return dbs, nil
+}
+
+// prepareRead prepares the read of a list handler, it writes an error
+// response and returns false if the read cannot be served.
+func prepareRead(w http.ResponseWriter, r *http.Request) bool {
+	if r.FormValue("consistent") != "true" {
+		return true
+	}
+
+	// requests are never forwarded again to avoid loops during elections,
+	// the client should retry.
+	if !svcInst.isLeader() {
+		xerrors.WriteJSON(w, xerrors.New(http.StatusServiceUnavailable, "not leader"))
+		return false
+	}
+
+	if err := svcInst.verifyLeader(); err != nil {
+		xerrors.WriteJSON(w, err)
+		return false
+	}
+
+	return true
+}
+
+// writeJSON writes 'v' to 'w' in JSON format.
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Debug("failed to write response", slog.String("error", err.Error()))
+	}
+}
+
+func handleListUsers(w http.ResponseWriter, r *http.Request) {
+	if !prepareRead(w, r) {
+		return
+	}
+
+	// never send the passwords over the network.
+	us := Users()
+	for i, u := range us {
+		u1 := *u
+		u1.Password = ""
+		us[i] = &u1
+	}
+
+	writeJSON(w, us)
+}
+
+func handleListDatabases(w http.ResponseWriter, r *http.Request) {
+	if !prepareRead(w, r) {
+		return
+	}
+	writeJSON(w, Databases())
+}
@@ -0,0 +1,85 @@
+package meta
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConsistentReadForward(t *testing.T) {
+	assert := assert.New(t)
+
+	// the current node is a follower which has a stale state.
+	s := newService()
+	defer func(inst *service) { svcInst = inst }(svcInst)
+	svcInst = s
+	s.md.Users["alice"] = &User{Name: "alice"}
+
+	// the mocked leader.
+	var paths []string
+	leader := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.String())
+		switch r.URL.Path {
+		case "/meta/users":
+			w.Write([]byte(`[{"name":"alice"},{"name":"bob"}]`))
+		case "/meta/databases":
+			w.Write([]byte(`[{"name":"db1","duration":3600000000000}]`))
+		}
+	}))
+	defer leader.Close()
+
+	defer func(fn func() string) { leaderHTTPAddr = fn }(leaderHTTPAddr)
+	leaderHTTPAddr = func() string { return strings.TrimPrefix(leader.URL, "http://") }
+
+	// a normal read is served locally.
+	us, err := UsersWithOptions(ReadOptions{})
+	assert.NoError(err)
+	assert.Len(us, 1)
+	assert.Empty(paths)
+
+	us, err = UsersWithOptions(ReadOptions{Consistent: true})
+	assert.NoError(err)
+	assert.Equal([]string{"alice", "bob"}, []string{us[0].Name, us[1].Name})
+
+	dbs, err := DatabasesWithOptions(ReadOptions{Consistent: true})
+	assert.NoError(err)
+	assert.Equal("db1", dbs[0].Name)
+
+	assert.Equal([]string{"/meta/users?consistent=true", "/meta/databases?consistent=true"}, paths)
+
+	// no leader.
+	leaderHTTPAddr = func() string { return "" }
+	_, err = UsersWithOptions(ReadOptions{Consistent: true})
+	assert.Equal(ErrMetaServiceUnavailable, err)
+
+	// a follower refuses to serve a consistent read.
+	w := httptest.NewRecorder()
+	handleListUsers(w, httptest.NewRequest(http.MethodGet, "/meta/users?consistent=true", nil))
+	assert.Equal(http.StatusServiceUnavailable, w.Code)
+}
+
+func TestConsistentReadLeader(t *testing.T) {
+	assert := assert.New(t)
+
+	s := newService()
+	defer func(inst *service) { svcInst = inst }(svcInst)
+	svcInst = s
+	startTestRaft(t, s, "1")
+
+	assert.NoError(CreateUser(&User{Name: "admin", Password: "pwd"}, false))
+
+	us, err := UsersWithOptions(ReadOptions{Consistent: true})
+	assert.NoError(err)
+	assert.Equal("pwd", us[0].Password)
+
+	// passwords are not sent over the network.
+	w := httptest.NewRecorder()
+	handleListUsers(w, httptest.NewRequest(http.MethodGet, "/meta/users?consistent=true", nil))
+	assert.Equal(http.StatusOK, w.Code)
+	assert.Contains(w.Body.String(), `"name":"admin"`)
+	assert.Contains(w.Body.String(), `"password":""`)
+	assert.Equal("pwd", UserByName("admin").Password)
+}
@@ -2,13 +2,15 @@ package meta
 
 import (
 	"log/slog"
-	"path/filepath"
+	"regexp"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/raft"
 	raftboltdb "github.com/hashicorp/raft-boltdb/v2"
+	"github.com/localvar/xuandb/pkg/cache"
 	"github.com/localvar/xuandb/pkg/config"
 	"github.com/localvar/xuandb/pkg/logger"
 )
@@ -16,12 +18,57 @@ import (
 // service represents the meta service.
 type service struct {
 	raft *raft.Raft
+	logs raft.LogStore // log store of raft, for debugging
+
+	// raftStorePath is the path of the boltdb raft store, empty if the
+	// store is not boltdb.
+	raftStorePath string
 
 	md *Data // metadata
 
+	// the NodeInfos in 'nodes' are shared with readers and raft commands,
+	// they must be replaced instead of being updated in place.
 	nodesLock sync.Mutex
 	nodes     map[string]*NodeInfo
 
+	applyFailuresLock sync.Mutex
+	applyFailures     ApplyFailureStats
+	maxApplyFailures  int
+
+	maxCommandSize int
+
+	// heartbeat interval of the current node and the thresholds to report
+	// a node as unknown or down.
+	heartbeatInterval time.Duration
+	nodeUnknownAfter  time.Duration
+	nodeDeadAfter     time.Duration
+
+	// nodes dropped by this node while it is the leader, they can rejoin the
+	// cluster automatically within rejoinGraceWindow.
+	departedLock      sync.Mutex
+	departed          map[string]departedNode
+	rejoinGraceWindow time.Duration
+
+	// passwords which are not allowed to be used, nil if no deny list, it
+	// can be reloaded by flushing the cache, see reloadPasswordDenyList. And
+	// the strength requirements of passwords.
+	pwdDenyList atomic.Pointer[passwordDenyList]
+	pwdPolicy   config.PasswordPolicy
+
+	// the time since when the meta data of this node diverges from the
+	// leader, and how long to wait before resynchronizing from the leader.
+	// divergedSince is only accessed when applying raft logs.
+	divergedSince   time.Time
+	readRepairAfter time.Duration
+
+	// the raft observer, and the signal that this node becomes the leader,
+	// which makes it announce the node list right away.
+	observer       *raft.Observer
+	leaderAcquired chan struct{}
+
+	// the signal that a user exists, see bindWhenUserExists.
+	userExists chan struct{}
+
 	stop chan struct{}
 	wg   sync.WaitGroup
 }
@@ -31,7 +78,15 @@ func newService() *service {
 	svc := &service{}
 	svc.md = newData()
 	svc.nodes = make(map[string]*NodeInfo)
+	svc.departed = make(map[string]departedNode)
 	svc.stop = make(chan struct{})
+	svc.leaderAcquired = make(chan struct{}, 1)
+	svc.userExists = make(chan struct{}, 1)
+	svc.heartbeatInterval = time.Second
+	svc.nodeUnknownAfter = 10 * time.Second
+	svc.nodeDeadAfter = 30 * time.Second
+	svc.rejoinGraceWindow = 5 * time.Minute
+	svc.readRepairAfter = time.Minute
 	return svc
 }
 
@@ -61,7 +116,7 @@ func createRaftSnapshotStore(logger hclog.Logger) (raft.SnapshotStore, error) {
 	case "memory":
 		return raft.NewInmemSnapshotStore(), nil
 	case "file":
-		return raft.NewFileSnapshotStoreWithLogger(mc.DataDir, 1, logger)
+		return raft.NewFileSnapshotStoreWithLogger(dataLayout{dir: mc.DataDir}.snapshotBase(), 1, logger)
 	default:
 		panic("should not reach here")
 	}
@@ -76,7 +131,7 @@ func createRaftStore() (raft.LogStore, raft.StableStore, error) {
 		db := raft.NewInmemStore()
 		return db, db, nil
 	case "boltdb":
-		opt := raftboltdb.Options{Path: filepath.Join(mc.DataDir, "raft.db")}
+		opt := raftboltdb.Options{Path: dataLayout{dir: mc.DataDir}.raftStorePath()}
 		db, err := raftboltdb.New(opt)
 		if err != nil {
 			return nil, nil, err
@@ -90,6 +145,9 @@ func createRaftStore() (raft.LogStore, raft.StableStore, error) {
 // start start meta service by creating raft and its dependencies.
 func (s *service) start() (bool, error) {
 	mc := config.CurrentNode().Meta
+	initClient(mc.RequestTimeout)
+	retryAttempts, retryBaseDelay = mc.LeaderRetryAttempts, mc.LeaderRetryDelay
+	namePattern = regexp.MustCompile(mc.NamePattern)
 
 	logger := logger.HashiCorp(nil)
 	trans, err := raft.NewTCPTransportWithLogger(mc.RaftAddr, nil, 3, 10*time.Second, logger)
@@ -98,6 +156,13 @@ func (s *service) start() (bool, error) {
 		return false, err
 	}
 
+	if mc.DataDir != "" {
+		if err = (dataLayout{dir: mc.DataDir}).prepare(); err != nil {
+			slog.Error("failed to prepare data directory", slog.String("error", err.Error()))
+			return false, err
+		}
+	}
+
 	snapshot, err := createRaftSnapshotStore(logger)
 	if err != nil {
 		slog.Error("failed to create snapshot store", slog.String("error", err.Error()))
@@ -116,6 +181,26 @@ func (s *service) start() (bool, error) {
 		return false, err
 	}
 
+	dl, err := loadPasswordDenyList(mc.PasswordDenyList, mc.PasswordDenyListIgnoreCase)
+	if err != nil {
+		slog.Error("failed to load password deny list", slog.String("error", err.Error()))
+		return false, err
+	}
+	s.pwdDenyList.Store(dl)
+
+	s.pwdPolicy = mc.PasswordPolicy
+	s.logs = ls
+	if mc.RaftStore == "boltdb" {
+		s.raftStorePath = dataLayout{dir: mc.DataDir}.raftStorePath()
+	}
+	s.maxApplyFailures = mc.MaxApplyFailures
+	s.maxCommandSize = mc.MaxCommandSize
+	s.heartbeatInterval = mc.HeartbeatInterval
+	s.nodeUnknownAfter = mc.NodeUnknownAfter
+	s.nodeDeadAfter = mc.NodeDeadAfter
+	s.rejoinGraceWindow = mc.RejoinGraceWindow
+	s.readRepairAfter = mc.ReadRepairAfter
+
 	cfg := raft.DefaultConfig()
 	cfg.LocalID = raft.ServerID(config.NodeID())
 	cfg.Logger = logger
@@ -126,6 +211,8 @@ func (s *service) start() (bool, error) {
 		return false, err
 	}
 	s.raft = ra
+	s.observeRaft()
+	s.bindWhenUserExists()
 
 	return hasState, nil
 }
@@ -175,6 +262,9 @@ func (s *service) joinOrBootstrap() {
 
 // shutdown shuts down the meta service.
 func (s *service) shutdown() {
+	if s.observer != nil {
+		s.raft.DeregisterObserver(s.observer)
+	}
 	close(s.stop)
 
 	if err := s.raft.Shutdown().Error(); err != nil {
@@ -203,12 +293,21 @@ func StartService() error {
 	svcInst = inst
 	if !hasState {
 		svcInst.joinOrBootstrap()
+	} else {
+		// the node is restarted, the cluster already knows it, but its info,
+		// e.g. the HTTP address, may have changed. 'updateNodeInfo' below
+		// re-announces the info to the leader immediately.
+		slog.Info("existing raft state found, rejoin the cluster")
 	}
 
 	// API handlers must be registered after raft is created.
 	nodeRegisterAPIHandlers()
 	userRegisterAPIHandlers()
 	databaseRegisterAPIHandlers()
+	resyncRegisterAPIHandlers()
+	versionRegisterAPIHandlers()
+
+	cache.Register(passwordDenyListCache, svcInst.reloadPasswordDenyList)
 
 	svcInst.updateNodeInfo()
 	return nil
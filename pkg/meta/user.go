@@ -1,8 +1,10 @@
 package meta
 
 import (
+	"context"
 	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"math"
@@ -11,6 +13,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/hashicorp/raft"
@@ -19,6 +22,34 @@ import (
 	"github.com/localvar/xuandb/pkg/xerrors"
 )
 
+// bindPublic makes the HTTP service listen on the configured address, see
+// bindWhenUserExists.
+var bindPublic = httpserver.BindPublic
+
+// notifyUserExists signals that a user exists. It is called when applying
+// raft logs, so it does not bind the listener itself, which may block or
+// exit the process, see bindWhenUserExists.
+func (s *service) notifyUserExists() {
+	select {
+	case s.userExists <- struct{}{}:
+	default:
+	}
+}
+
+// bindWhenUserExists starts a goroutine which makes the HTTP service listen
+// on the configured address once a user exists.
+func (s *service) bindWhenUserExists() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		select {
+		case <-s.stop:
+		case <-s.userExists:
+			bindPublic()
+		}
+	}()
+}
+
 // Errors for user operations.
 var (
 	ErrUserExists    = xerrors.New(http.StatusConflict, "user already exists")
@@ -27,14 +58,17 @@ var (
 
 	ErrAuthRequired           = xerrors.New(http.StatusUnauthorized, "authorization required")
 	ErrPasswordMismatch       = xerrors.New(http.StatusUnauthorized, "password mismatch or user not exists")
+	ErrUserDisabled           = xerrors.New(http.StatusUnauthorized, "user is disabled")
 	ErrInsufficientPrivileges = xerrors.New(http.StatusForbidden, "insufficient privileges")
 )
 
 // raft operation names for users.
 const (
-	opCreateUser  = "create-user"
-	opDropUser    = "drop-user"
-	opSetPassword = "set-password"
+	opCreateUser     = "create-user"
+	opBulkCreateUser = "bulk-create-user"
+	opDropUser       = "drop-user"
+	opSetPassword    = "set-password"
+	opSetUserEnabled = "set-user-enabled"
 )
 
 // userRegisterAPIHandlers registers API handlers for user operations.
@@ -43,9 +77,12 @@ func userRegisterAPIHandlers() {
 	if !config.CurrentNode().Meta.RaftVoter {
 		return
 	}
-	httpserver.HandleFunc("POST /meta/users", handleCreateUser)
-	httpserver.HandleFunc("PUT /meta/users", handleSetPassword)
-	httpserver.HandleFunc("DELETE /meta/users", handleDropUser)
+	handleFunc("GET /meta/users", handleListUsers)
+	handleFunc("POST /meta/users", handleCreateUser)
+	handleFunc("POST /meta/users/bulk", handleBulkCreateUser)
+	handleFunc("PUT /meta/users", handleSetPassword)
+	handleFunc("PUT /meta/users/enabled", handleSetUserEnabled)
+	handleFunc("DELETE /meta/users", handleDropUser)
 }
 
 // Privilege represents the privilege of a user.
@@ -62,8 +99,11 @@ const (
 	PrivilegeRead Privilege = 2
 	// PrivilegeWrite allows the user to write data to a database.
 	PrivilegeWrite Privilege = 4
+	// PrivilegeDDL allows the user to change the schema, like creating and
+	// dropping databases, it does not allow the user to write data.
+	PrivilegeDDL Privilege = 8
 	// PrivilegeMask is a mask that used to check if a privilege is valid.
-	PrivilegeMask Privilege = 7
+	PrivilegeMask Privilege = 15
 
 	// PrivilegeAdmin is a special privilege that has all common privileges,
 	// including the privileges we may add in the future.
@@ -91,6 +131,8 @@ func (p *Privilege) parse(str string) error {
 			v |= PrivilegeRead
 		case "WRITE":
 			v |= PrivilegeWrite
+		case "DDL":
+			v |= PrivilegeDDL
 		case "ADMIN":
 			v |= PrivilegeAdmin
 		default:
@@ -130,6 +172,13 @@ func (p Privilege) String() string {
 			s = "WRITE"
 		}
 	}
+	if p&PrivilegeDDL == PrivilegeDDL {
+		if s != "" {
+			s += ",DDL"
+		} else {
+			s = "DDL"
+		}
+	}
 
 	return s
 }
@@ -168,6 +217,11 @@ type User struct {
 	// privileges cannot be changed. The first user created is a system user.
 	System bool `json:"system"`
 
+	// Disabled marks a suspended user, a disabled user cannot be
+	// authenticated until it is enabled again. System users cannot be
+	// disabled.
+	Disabled bool `json:"disabled"`
+
 	// Priv is the global privileges of a user, when checking if a user has
 	// the privilege to perform an operation on a database, this field is
 	// checked first, which means if the user has this privilege, he has this
@@ -203,18 +257,41 @@ func applyCreateUser(l *raft.Log) any {
 			slog.Info("system admin created", slog.String("name", cmd.User.Name))
 		}
 		md.Users[key] = cmd.User
+		informer.publish(UserCreated{User: cmd.User})
+
+		// a user exists now, the HTTP service can listen on the configured
+		// address if it was withheld.
+		svcInst.notifyUserExists()
 		return nil
 	}
 
 	return ErrUserExists
 }
 
-func leaderCreateUser(u *User) error {
+// leaderCreateUser creates user 'u', if 'ifNotExists' is true, it is not an
+// error if the user already exists.
+func leaderCreateUser(u *User, ifNotExists bool) error {
+	if err := validateNewUser(u); err != nil {
+		return xerrors.New(http.StatusBadRequest, err.Error())
+	}
+
 	if UserByName(u.Name) != nil {
 		slog.Debug("user already exists", slog.String("name", u.Name))
+		if ifNotExists {
+			return nil
+		}
 		return ErrUserExists
 	}
 
+	if err := checkPassword(u.Password); err != nil {
+		return err
+	}
+
+	m := &Mutation{Op: MutationCreateUser, Name: u.Name, Object: u}
+	if err := admit(m); err != nil {
+		return err
+	}
+
 	u.CreatedAt = time.Now()
 	cmd := createUserCommand{
 		baseCommand: baseCommand{Op: opCreateUser},
@@ -226,45 +303,191 @@ func leaderCreateUser(u *User) error {
 		return nil
 	}
 
+	if err == ErrUserExists && ifNotExists {
+		slog.Debug("user already exists", slog.String("name", u.Name))
+		return nil
+	}
+
 	slog.Debug("create user failed", slog.String("error", err.Error()))
 	return err
 }
 
+// validateNewUser validates user 'u' which is going to be created.
+func validateNewUser(u *User) error {
+	if u.Name == "" || u.Password == "" {
+		return errors.New("name and password are required")
+	}
+	if !namePattern.MatchString(u.Name) {
+		return fmt.Errorf("invalid user name: %q", u.Name)
+	}
+	if (u.Priv != PrivilegeAdmin) && (u.Priv&^PrivilegeMask != 0) {
+		return errors.New("invalid privilege")
+	}
+	return nil
+}
+
 func handleCreateUser(w http.ResponseWriter, r *http.Request) {
 	u := &User{}
 
 	if err := json.NewDecoder(r.Body).Decode(u); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		xerrors.WriteJSON(w, xerrors.New(http.StatusBadRequest, err.Error()))
 		return
 	}
 
-	if u.Name == "" || u.Password == "" {
-		http.Error(w, "name and password are required", http.StatusBadRequest)
+	ifNotExists := r.FormValue("ifNotExists") == "true"
+
+	slog.Debug("create user command received", slog.String("name", u.Name))
+	if err := leaderCreateUser(u, ifNotExists); err != nil {
+		xerrors.WriteJSON(w, err)
 		return
 	}
 
-	if (u.Priv != PrivilegeAdmin) && (u.Priv&^PrivilegeMask != 0) {
-		http.Error(w, "invalid privilege", http.StatusBadRequest)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CreateUser creates a user, if 'ifNotExists' is true, it is not an error if
+// the user already exists.
+func CreateUser(u *User, ifNotExists bool) error {
+	u.System = false // clear the system flag
+	if svcInst.isLeader() {
+		return leaderCreateUser(u, ifNotExists)
+	}
+	pathAndQuery := "/meta/users"
+	if ifNotExists {
+		pathAndQuery += "?ifNotExists=true"
+	}
+	return sendPostRequestToLeader(context.Background(), pathAndQuery, u)
+}
+
+// handlers for the bulk create user command.
+type bulkCreateUserCommand struct {
+	baseCommand
+	Users []*User `json:"users"`
+}
+
+func (cmd *bulkCreateUserCommand) batchSize() int {
+	return len(cmd.Users)
+}
+
+// errUserExists returns the error for an existing user 'name' in a batch.
+func errUserExists(name string) error {
+	return xerrors.New(http.StatusConflict, "user already exists: "+name)
+}
+
+func applyBulkCreateUser(l *raft.Log) any {
+	cmd := &bulkCreateUserCommand{}
+	if err := json.Unmarshal(l.Data, cmd); err != nil {
+		return err
+	}
+
+	md := svcInst.md
+
+	md.lock()
+	defer md.unlock()
+
+	// the batch is all-or-nothing, check all users before creating any.
+	keys := make(map[string]struct{}, len(cmd.Users))
+	for _, u := range cmd.Users {
+		key := strings.ToLower(u.Name)
+		if _, ok := keys[key]; ok || md.Users[key] != nil {
+			return errUserExists(u.Name)
+		}
+		keys[key] = struct{}{}
+	}
+
+	for _, u := range cmd.Users {
+		if len(md.Users) == 0 {
+			u.Priv = PrivilegeAdmin
+			u.System = true
+			slog.Info("system admin created", slog.String("name", u.Name))
+		}
+		md.Users[strings.ToLower(u.Name)] = u
+		informer.publish(UserCreated{User: u})
+	}
+
+	if len(cmd.Users) > 0 {
+		svcInst.notifyUserExists()
+	}
+	return nil
+}
+
+// leaderCreateUsers creates users 'us' in a single raft log, no user is
+// created if any of them already exists.
+func leaderCreateUsers(us []*User) error {
+	keys := make(map[string]struct{}, len(us))
+	for _, u := range us {
+		if err := validateNewUser(u); err != nil {
+			return xerrors.New(http.StatusBadRequest, err.Error())
+		}
+
+		key := strings.ToLower(u.Name)
+		if _, ok := keys[key]; ok || UserByName(u.Name) != nil {
+			slog.Debug("user already exists", slog.String("name", u.Name))
+			return errUserExists(u.Name)
+		}
+		keys[key] = struct{}{}
+
+		if err := checkPassword(u.Password); err != nil {
+			return err
+		}
+
+		m := &Mutation{Op: MutationCreateUser, Name: u.Name, Object: u}
+		if err := admit(m); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	for _, u := range us {
+		u.CreatedAt = now
+	}
+
+	cmd := bulkCreateUserCommand{
+		baseCommand: baseCommand{Op: opBulkCreateUser},
+		Users:       us,
+	}
+	err := svcInst.raftApply(&cmd)
+	if err == nil {
+		slog.Info("users created", slog.Int("count", len(us)))
+		return nil
+	}
+
+	slog.Debug("bulk create user failed", slog.String("error", err.Error()))
+	return err
+}
+
+func handleBulkCreateUser(w http.ResponseWriter, r *http.Request) {
+	var us []*User
+
+	if err := json.NewDecoder(r.Body).Decode(&us); err != nil {
+		xerrors.WriteJSON(w, xerrors.New(http.StatusBadRequest, err.Error()))
 		return
 	}
 
-	slog.Debug("create user command received", slog.String("name", u.Name))
-	if err := leaderCreateUser(u); err != nil {
-		se := err.(*xerrors.StatusError)
-		http.Error(w, se.Msg, se.StatusCode)
+	if len(us) == 0 {
+		xerrors.WriteJSON(w, xerrors.New(http.StatusBadRequest, "no user to create"))
+		return
+	}
+
+	slog.Debug("bulk create user command received", slog.Int("count", len(us)))
+	if err := leaderCreateUsers(us); err != nil {
+		xerrors.WriteJSON(w, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
-// CreateUser creates a user.
-func CreateUser(u *User) error {
-	u.System = false // clear the system flag
+// CreateUsers creates users 'us' in a single raft log, it is all-or-nothing,
+// that is, no user is created if any of them already exists.
+func CreateUsers(us []*User) error {
+	for _, u := range us {
+		u.System = false // clear the system flag
+	}
 	if svcInst.isLeader() {
-		return leaderCreateUser(u)
+		return leaderCreateUsers(us)
 	}
-	return sendPostRequestToLeader("/meta/users", u)
+	return sendPostRequestToLeader(context.Background(), "/meta/users/bulk", us)
 }
 
 // handlers for the drop user command.
@@ -282,25 +505,37 @@ func applyDropUser(l *raft.Log) any {
 	md := svcInst.md
 	key := strings.ToLower(cmd.Name)
 
-	// we have checked that the user is not the admin in leaderDropUser, and we
-	// don't care if the user exists or not, so simply delete the user here.
+	// we have checked that the user is not a system user in leaderDropUser.
+	// The user may have been dropped since then, which is not an error, see
+	// leaderDropUser, so simply delete the user here.
 	md.lock()
+	_, existed := md.Users[key]
 	delete(md.Users, key)
 	md.unlock()
 
+	if existed {
+		informer.publish(UserDropped{Name: cmd.Name})
+	}
 	return nil
 }
 
-func leaderDropUser(name string) error {
+// leaderDropUser drops user 'name'. Dropping a user which does not exist has
+// always been a successful operation, clients rely on this, so it is not an
+// error even if 'ifExists' is false, which is accepted for symmetry with
+// leaderDropDatabase.
+func leaderDropUser(name string, ifExists bool) error {
 	if u := UserByName(name); u == nil {
-		// treat the user does not exist as a successful operation.
-		slog.Debug("user does not exist", slog.String("name", name))
+		slog.Debug("user does not exist", slog.String("name", name), slog.Bool("ifExists", ifExists))
 		return nil
 	} else if u.System {
 		slog.Debug("cannot drop system user", slog.String("name", name))
 		return ErrSystemUser
 	}
 
+	if err := admit(&Mutation{Op: MutationDropUser, Name: name}); err != nil {
+		return err
+	}
+
 	cmd := dropUserCommand{
 		baseCommand: baseCommand{Op: opDropUser},
 		Name:        name,
@@ -323,26 +558,32 @@ func leaderDropUser(name string) error {
 func handleDropUser(w http.ResponseWriter, r *http.Request) {
 	name := r.FormValue("name")
 	if name == "" {
-		http.Error(w, "name is required", http.StatusBadRequest)
+		xerrors.WriteJSON(w, xerrors.New(http.StatusBadRequest, "name is required"))
 		return
 	}
 
+	ifExists := r.FormValue("ifExists") == "true"
+
 	slog.Debug("drop user command received", slog.String("name", name))
-	if err := leaderDropUser(name); err != nil {
-		se := err.(*xerrors.StatusError)
-		http.Error(w, se.Msg, se.StatusCode)
+	if err := leaderDropUser(name, ifExists); err != nil {
+		xerrors.WriteJSON(w, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
-// DropUser drops a user.
-func DropUser(name string) error {
+// DropUser drops a user, it is not an error if the user does not exist,
+// whether 'ifExists' is true or not, see leaderDropUser.
+func DropUser(name string, ifExists bool) error {
 	if svcInst.isLeader() {
-		return leaderDropUser(name)
+		return leaderDropUser(name, ifExists)
 	}
-	return sendDeleteRequestToLeader("/meta/users?name=" + url.QueryEscape(name))
+	pathAndQuery := "/meta/users?name=" + url.QueryEscape(name)
+	if ifExists {
+		pathAndQuery += "&ifExists=true"
+	}
+	return sendDeleteRequestToLeader(context.Background(), pathAndQuery)
 }
 
 // handlers for the set password command.
@@ -381,6 +622,15 @@ func leaderSetPassword(u *User) error {
 		return ErrUserNotExists
 	}
 
+	if err := checkPassword(u.Password); err != nil {
+		return err
+	}
+
+	m := &Mutation{Op: MutationSetPassword, Name: u.Name, Object: u}
+	if err := admit(m); err != nil {
+		return err
+	}
+
 	cmd := &setPasswordCommand{
 		baseCommand: baseCommand{Op: opSetPassword},
 		Name:        u.Name,
@@ -401,19 +651,18 @@ func handleSetPassword(w http.ResponseWriter, r *http.Request) {
 
 	err := json.NewDecoder(r.Body).Decode(u)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		xerrors.WriteJSON(w, xerrors.New(http.StatusBadRequest, err.Error()))
 		return
 	}
 
 	if u.Name == "" || u.Password == "" {
-		http.Error(w, "name and password are required", http.StatusBadRequest)
+		xerrors.WriteJSON(w, xerrors.New(http.StatusBadRequest, "name and password are required"))
 		return
 	}
 
 	slog.Debug("set password command received", slog.String("name", u.Name))
 	if err = leaderSetPassword(u); err != nil {
-		se := err.(*xerrors.StatusError)
-		http.Error(w, se.Msg, se.StatusCode)
+		xerrors.WriteJSON(w, err)
 		return
 	}
 
@@ -426,10 +675,112 @@ func SetPassword(name, password string) error {
 	if svcInst.isLeader() {
 		return leaderSetPassword(u)
 	}
-	return sendPutRequestToLeader("/meta/users", u)
+	return sendPutRequestToLeader(context.Background(), "/meta/users", u)
+}
+
+// handlers for the set user enabled command.
+type setUserEnabledCommand struct {
+	baseCommand
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+}
+
+func applySetUserEnabled(l *raft.Log) any {
+	cmd := &setUserEnabledCommand{}
+	if err := json.Unmarshal(l.Data, cmd); err != nil {
+		return err
+	}
+
+	md := svcInst.md
+	key := strings.ToLower(cmd.Name)
+
+	md.lock()
+	defer md.unlock()
+
+	if u := md.Users[key]; u != nil {
+		u1 := *u
+		u1.Disabled = !cmd.Enabled
+		md.Users[key] = &u1
+		return nil
+	}
+
+	return ErrUserNotExists
+}
+
+// leaderSetUserEnabled enables or disables user 'name'.
+func leaderSetUserEnabled(name string, enabled bool) error {
+	if u := UserByName(name); u == nil {
+		slog.Debug("user does not exist", slog.String("name", name))
+		return ErrUserNotExists
+	} else if u.System && !enabled {
+		slog.Debug("cannot disable system user", slog.String("name", name))
+		return ErrSystemUser
+	}
+
+	if err := admit(&Mutation{Op: MutationSetUserEnabled, Name: name}); err != nil {
+		return err
+	}
+
+	cmd := &setUserEnabledCommand{
+		baseCommand: baseCommand{Op: opSetUserEnabled},
+		Name:        name,
+		Enabled:     enabled,
+	}
+	err := svcInst.raftApply(cmd)
+	if err == nil {
+		slog.Info(
+			"set user enabled succeeded",
+			slog.String("name", name),
+			slog.Bool("enabled", enabled),
+		)
+		return nil
+	}
+
+	slog.Debug("set user enabled failed", slog.String("error", err.Error()))
+	return err
 }
 
-// Users returns all users. The result is sorted by name.
+func handleSetUserEnabled(w http.ResponseWriter, r *http.Request) {
+	name := r.FormValue("name")
+	if name == "" {
+		xerrors.WriteJSON(w, xerrors.New(http.StatusBadRequest, "name is required"))
+		return
+	}
+
+	enabled, err := strconv.ParseBool(r.FormValue("enabled"))
+	if err != nil {
+		xerrors.WriteJSON(w, xerrors.New(http.StatusBadRequest, "invalid value of enabled"))
+		return
+	}
+
+	slog.Debug(
+		"set user enabled command received",
+		slog.String("name", name),
+		slog.Bool("enabled", enabled),
+	)
+	if err = leaderSetUserEnabled(name, enabled); err != nil {
+		xerrors.WriteJSON(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SetUserEnabled enables or disables a user, a disabled user cannot be
+// authenticated, but is kept with its privileges so that it can be enabled
+// again later.
+func SetUserEnabled(name string, enabled bool) error {
+	if svcInst.isLeader() {
+		return leaderSetUserEnabled(name, enabled)
+	}
+	pathAndQuery := "/meta/users/enabled?name=" + url.QueryEscape(name) +
+		"&enabled=" + strconv.FormatBool(enabled)
+	return sendPutRequestToLeader(context.Background(), pathAndQuery, nil)
+}
+
+// Users returns all users. The result is sorted by name. It reads the local
+// state, which may be stale on a follower, use UsersWithOptions for a
+// consistent read.
 func Users() []*User {
 	md := svcInst.md
 
@@ -474,39 +825,96 @@ type RequiredPrivileges struct {
 	Databases map[string]Privilege
 }
 
-// Auth does authentication and authorization.
-func Auth(name, pwd string, rp RequiredPrivileges) error {
+// Authenticator verifies the credentials of users.
+type Authenticator interface {
+	// Authenticate returns the user named 'name' if 'pwd' is its password.
+	// It returns nil and no error if authentication is disabled, e.g. no
+	// user has been created.
+	Authenticate(name, pwd string) (*User, error)
+}
+
+// localAuthenticator authenticates users against the users in the meta data.
+type localAuthenticator struct{}
+
+// Authenticate implements [Authenticator].
+func (localAuthenticator) Authenticate(name, pwd string) (*User, error) {
 	u, noUser := getUser(name)
 	if noUser {
-		return nil
+		return nil, nil
 	}
 
 	if len(name) == 0 {
-		return ErrAuthRequired
+		return nil, ErrAuthRequired
 	}
 
 	if u == nil {
-		return ErrPasswordMismatch
+		return nil, ErrPasswordMismatch
 	}
 
 	if subtle.ConstantTimeCompare([]byte(pwd), []byte(u.Password)) == 0 {
-		return ErrPasswordMismatch
+		return nil, ErrPasswordMismatch
+	}
+
+	return u, nil
+}
+
+// authenticator is the authenticator installed by SetAuthenticator, the
+// local one is used if it is nil.
+var authenticator atomic.Pointer[Authenticator]
+
+// SetAuthenticator replaces the authenticator used by Auth with 'a', e.g. to
+// authenticate users with an external service. A nil 'a' restores the local
+// authenticator.
+func SetAuthenticator(a Authenticator) {
+	if a == nil {
+		authenticator.Store(nil)
+	} else {
+		authenticator.Store(&a)
+	}
+}
+
+// Auth does authentication and authorization, the authentication is done by
+// the current authenticator. Disabled users are rejected with ErrUserDisabled.
+func Auth(name, pwd string, rp RequiredPrivileges) error {
+	_, err := AuthUser(name, pwd, rp)
+	return err
+}
+
+// AuthUser is the same as Auth, but also returns the authenticated user. The
+// user is nil if authentication is disabled, e.g. no user has been created,
+// in which case all operations are allowed.
+func AuthUser(name, pwd string, rp RequiredPrivileges) (*User, error) {
+	var a Authenticator = localAuthenticator{}
+	if p := authenticator.Load(); p != nil {
+		a = *p
+	}
+
+	u, err := a.Authenticate(name, pwd)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, nil
+	}
+
+	if u.Disabled {
+		return nil, ErrUserDisabled
 	}
 
 	if u.Priv == PrivilegeAdmin {
-		return nil
+		return u, nil
 	}
 
 	// don't use 'u.Priv&rp.Global != 0' because 'rp.Global' may be 0.
 	if u.Priv&rp.Global != rp.Global {
-		return ErrInsufficientPrivileges
+		return nil, ErrInsufficientPrivileges
 	}
 
 	for db, priv := range rp.Databases {
 		if (u.Priv|u.DbPriv[db])&priv != priv {
-			return ErrInsufficientPrivileges
+			return nil, ErrInsufficientPrivileges
 		}
 	}
 
-	return nil
+	return u, nil
 }
@@ -0,0 +1,389 @@
+package meta
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/localvar/xuandb/pkg/xerrors"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestUserIfExists(t *testing.T) {
+	assert := assert.New(t)
+
+	s := newService()
+	defer func(inst *service) { svcInst = inst }(svcInst)
+	svcInst = s
+	startTestRaft(t, s, "1")
+
+	// the first user is the system user.
+	assert.NoError(CreateUser(&User{Name: "admin", Password: "pwd"}, false))
+
+	assert.NoError(CreateUser(&User{Name: "alice", Password: "pwd"}, true))
+	assert.NoError(CreateUser(&User{Name: "alice", Password: "pwd"}, true))
+	assert.Equal(ErrUserExists, CreateUser(&User{Name: "alice", Password: "pwd"}, false))
+
+	assert.NoError(DropUser("bob", true))
+	// dropping a user is idempotent even without IF EXISTS.
+	assert.NoError(DropUser("bob", false))
+
+	assert.NoError(DropUser("alice", false))
+	assert.Nil(UserByName("alice"))
+	assert.Equal(ErrSystemUser, DropUser("admin", true))
+
+	// users are validated by the leader, whatever the entry point is.
+	err := CreateUser(&User{Name: "1bob", Password: "pwd"}, true)
+	if se, ok := err.(*xerrors.StatusError); assert.True(ok) {
+		assert.Equal(http.StatusBadRequest, se.StatusCode)
+	}
+	err = CreateUsers([]*User{{Name: "bob", Password: "pwd"}, {Name: "carol"}})
+	if se, ok := err.(*xerrors.StatusError); assert.True(ok) {
+		assert.Equal(http.StatusBadRequest, se.StatusCode)
+	}
+	assert.Nil(UserByName("bob"))
+}
+
+func TestHandleCreateUserError(t *testing.T) {
+	assert := assert.New(t)
+
+	s := newService()
+	defer func(inst *service) { svcInst = inst }(svcInst)
+	svcInst = s
+	startTestRaft(t, s, "1")
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/meta/users", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		handleCreateUser(w, req)
+		return w
+	}
+
+	assert.Equal(http.StatusNoContent, post(`{"name":"admin","password":"pwd"}`).Code)
+
+	w := post(`{"name":"admin","password":"pwd"}`)
+	assert.Equal(http.StatusConflict, w.Code)
+	assert.Equal("application/json", w.Header().Get("Content-Type"))
+	assert.JSONEq(`{"error":"user already exists","code":409}`, w.Body.String())
+
+	// the client gets the original error back.
+	assert.Equal(ErrUserExists, xerrors.FromHTTPResponse(w.Result()))
+
+	w = post(`{"name":"bob"}`)
+	assert.Equal(http.StatusBadRequest, w.Code)
+	assert.JSONEq(`{"error":"name and password are required","code":400}`, w.Body.String())
+
+	w = post(`{"name":"bob\nalice","password":"pwd"}`)
+	assert.Equal(http.StatusBadRequest, w.Code)
+	assert.JSONEq(`{"error":"invalid user name: \"bob\\nalice\"","code":400}`, w.Body.String())
+	assert.Nil(UserByName("bob\nalice"))
+
+	assert.Equal(http.StatusNoContent, post(`{"name":"bob-1","password":"pwd"}`).Code)
+}
+
+func TestNamePattern(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.NoError(validateNewUser(&User{Name: "_bob-1", Password: "pwd"}))
+	assert.EqualError(validateNewUser(&User{Name: "1bob", Password: "pwd"}), `invalid user name: "1bob"`)
+	assert.EqualError(validateNewUser(&User{Name: "bob\x01", Password: "pwd"}), `invalid user name: "bob\x01"`)
+	assert.NoError(ValidateDatabase(&Database{Name: "my-db"}))
+
+	defer func(re *regexp.Regexp) { namePattern = re }(namePattern)
+	namePattern = regexp.MustCompile(`^[a-z]+$`)
+
+	assert.NoError(validateNewUser(&User{Name: "bob", Password: "pwd"}))
+	assert.EqualError(validateNewUser(&User{Name: "Bob", Password: "pwd"}), `invalid user name: "Bob"`)
+	assert.NoError(ValidateDatabase(&Database{Name: "foo"}))
+	assert.EqualError(ValidateDatabase(&Database{Name: "foo_1"}), "invalid database name: foo_1")
+}
+
+func TestAuthBootstrap(t *testing.T) {
+	assert := assert.New(t)
+
+	s := newService()
+	defer func(inst *service) { svcInst = inst }(svcInst)
+	svcInst = s
+	startTestRaft(t, s, "1")
+
+	var called atomic.Int32
+	defer func(fn func()) { bindPublic = fn }(bindPublic)
+	bindPublic = func() { called.Add(1) }
+	s.bindWhenUserExists()
+	defer close(s.stop)
+
+	// everyone is allowed before the first user is created, the public
+	// listener is still withheld.
+	assert.NoError(Auth("", "", RequiredPrivileges{Global: PrivilegeAdmin}))
+	assert.Zero(called.Load())
+
+	// the listener is bound out of raft apply.
+	assert.NoError(CreateUser(&User{Name: "admin", Password: "pwd"}, false))
+	assert.Eventually(func() bool { return called.Load() == 1 }, time.Second, time.Millisecond)
+	assert.NoError(CreateUser(&User{Name: "alice", Password: "pwd"}, false))
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(int32(1), called.Load())
+	assert.Equal(ErrAuthRequired, Auth("", "", RequiredPrivileges{Global: PrivilegeAdmin}))
+}
+
+func TestCreateUsers(t *testing.T) {
+	assert := assert.New(t)
+
+	s := newService()
+	defer func(inst *service) { svcInst = inst }(svcInst)
+	svcInst = s
+	startTestRaft(t, s, "1")
+
+	lastIndex := s.raft.LastIndex()
+	assert.NoError(CreateUsers([]*User{
+		{Name: "admin", Password: "pwd"},
+		{Name: "alice", Password: "pwd", Priv: PrivilegeRead},
+		{Name: "bob", Password: "pwd", System: true},
+	}))
+	assert.Equal(lastIndex+1, s.raft.LastIndex(), "a batch should be a single log entry")
+
+	// the first user of the batch is the system user.
+	assert.True(UserByName("admin").System)
+	assert.Equal(PrivilegeAdmin, UserByName("admin").Priv)
+	assert.Equal(PrivilegeRead, UserByName("alice").Priv)
+	assert.False(UserByName("bob").System)
+	assert.False(UserByName("bob").CreatedAt.IsZero())
+
+	// 'Alice' conflicts with an existing user, nothing is created.
+	err := CreateUsers([]*User{
+		{Name: "carol", Password: "pwd"},
+		{Name: "Alice", Password: "pwd"},
+	})
+	assert.EqualError(err, "user already exists: Alice")
+	assert.Nil(UserByName("carol"))
+
+	// duplicated users in a batch.
+	err = CreateUsers([]*User{
+		{Name: "dave", Password: "pwd"},
+		{Name: "dave", Password: "pwd"},
+	})
+	assert.EqualError(err, "user already exists: dave")
+	assert.Nil(UserByName("dave"))
+
+	// the apply function rejects a conflicting batch even if the leader
+	// check is bypassed.
+	cmd := bulkCreateUserCommand{
+		baseCommand: baseCommand{Op: opBulkCreateUser},
+		Users:       []*User{{Name: "erin", Password: "pwd"}, {Name: "bob", Password: "pwd"}},
+	}
+	assert.EqualError(s.raftApply(&cmd), "user already exists: bob")
+	assert.Nil(UserByName("erin"))
+	assert.Len(Users(), 3)
+}
+
+func TestHandleBulkCreateUser(t *testing.T) {
+	assert := assert.New(t)
+
+	s := newService()
+	defer func(inst *service) { svcInst = inst }(svcInst)
+	svcInst = s
+	startTestRaft(t, s, "1")
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/meta/users/bulk", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		handleBulkCreateUser(w, req)
+		return w
+	}
+
+	w := post(`[{"name":"admin","password":"pwd"},{"name":"alice","password":"pwd"}]`)
+	assert.Equal(http.StatusNoContent, w.Code)
+	assert.NotNil(UserByName("alice"))
+
+	w = post(`[{"name":"bob","password":"pwd"},{"name":"alice","password":"pwd"}]`)
+	assert.Equal(http.StatusConflict, w.Code)
+	assert.Contains(w.Body.String(), "user already exists: alice")
+	assert.Nil(UserByName("bob"))
+
+	assert.Equal(http.StatusBadRequest, post(`[]`).Code)
+	assert.Equal(http.StatusBadRequest, post(`[{"name":"bob"}]`).Code)
+	assert.Equal(http.StatusBadRequest, post(`{"name":"bob","password":"pwd"}`).Code)
+}
+
+func TestPrivilegeYAML(t *testing.T) {
+	assert := assert.New(t)
+
+	in := map[string]Privilege{"db1": PrivilegeRead | PrivilegeDDL, "db2": PrivilegeNone}
+	data, err := yaml.Marshal(in)
+	assert.NoError(err)
+	assert.Equal("db1: READ,DDL\ndb2: \"\"\n", string(data))
+
+	var out map[string]Privilege
+	assert.NoError(yaml.Unmarshal(data, &out))
+	assert.Equal(in, out)
+
+	assert.Error(yaml.Unmarshal([]byte("db1: bogus\n"), &out))
+}
+
+func TestPrivilegeDDL(t *testing.T) {
+	assert := assert.New(t)
+
+	var p Privilege
+	assert.NoError(p.parse("read, ddl"))
+	assert.Equal(PrivilegeRead|PrivilegeDDL, p)
+	assert.Equal("READ,DDL", p.String())
+
+	s := newService()
+	defer func(inst *service) { svcInst = inst }(svcInst)
+	svcInst = s
+	startTestRaft(t, s, "1")
+
+	assert.NoError(CreateUser(&User{Name: "admin", Password: "pwd"}, false))
+	assert.NoError(CreateUser(&User{Name: "dba", Password: "pwd", Priv: PrivilegeDDL}, false))
+	assert.NoError(CreateUser(&User{Name: "writer", Password: "pwd", Priv: PrivilegeWrite}, false))
+
+	ddl := RequiredPrivileges{Global: PrivilegeDDL}
+	admin := RequiredPrivileges{Global: PrivilegeAdmin}
+
+	// a DDL user can create databases, but cannot manage users.
+	assert.NoError(Auth("dba", "pwd", ddl))
+	assert.Equal(ErrInsufficientPrivileges, Auth("dba", "pwd", admin))
+	assert.Equal(ErrInsufficientPrivileges, Auth("dba", "pwd", RequiredPrivileges{Global: PrivilegeWrite}))
+
+	// write no longer implies DDL, while admin includes it.
+	assert.Equal(ErrInsufficientPrivileges, Auth("writer", "pwd", ddl))
+	assert.NoError(Auth("admin", "pwd", ddl))
+
+	// DDL can also be granted on a database, which allows dropping it.
+	u := &User{Name: "owner", Password: "pwd", DbPriv: map[string]Privilege{"db1": PrivilegeDDL}}
+	assert.NoError(CreateUser(u, false))
+	drop := func(db string) RequiredPrivileges {
+		return RequiredPrivileges{Databases: map[string]Privilege{db: PrivilegeDDL}}
+	}
+	assert.NoError(Auth("owner", "pwd", drop("db1")))
+	assert.Equal(ErrInsufficientPrivileges, Auth("owner", "pwd", drop("db2")))
+	assert.Equal(ErrInsufficientPrivileges, Auth("owner", "pwd", ddl))
+	assert.NoError(Auth("dba", "pwd", drop("db2")))
+}
+
+// fakeAuthenticator authenticates users against a fixed list of users.
+type fakeAuthenticator struct {
+	users map[string]*User
+	calls int
+}
+
+func (fa *fakeAuthenticator) Authenticate(name, pwd string) (*User, error) {
+	fa.calls++
+	u := fa.users[name]
+	if u == nil || u.Password != pwd {
+		return nil, ErrPasswordMismatch
+	}
+	return u, nil
+}
+
+func TestSetAuthenticator(t *testing.T) {
+	assert := assert.New(t)
+
+	fa := &fakeAuthenticator{users: map[string]*User{
+		"alice": {Name: "alice", Password: "ldap-pwd", Priv: PrivilegeRead},
+		"root":  {Name: "root", Password: "oidc-pwd", Priv: PrivilegeAdmin},
+	}}
+	SetAuthenticator(fa)
+	defer SetAuthenticator(nil)
+
+	read := RequiredPrivileges{Databases: map[string]Privilege{"db1": PrivilegeRead}}
+	write := RequiredPrivileges{Databases: map[string]Privilege{"db1": PrivilegeWrite}}
+
+	// the password is verified by the authenticator.
+	assert.NoError(Auth("alice", "ldap-pwd", read))
+	assert.Equal(ErrPasswordMismatch, Auth("alice", "bad", read))
+	assert.Equal(ErrPasswordMismatch, Auth("bob", "ldap-pwd", read))
+	assert.Equal(3, fa.calls)
+
+	// the privileges are still checked.
+	assert.Equal(ErrInsufficientPrivileges, Auth("alice", "ldap-pwd", write))
+	assert.Equal(ErrInsufficientPrivileges, Auth("alice", "ldap-pwd", RequiredPrivileges{Global: PrivilegeAdmin}))
+	assert.NoError(Auth("root", "oidc-pwd", write))
+
+	// the authenticated user is returned on success only.
+	u, err := AuthUser("alice", "ldap-pwd", read)
+	assert.NoError(err)
+	assert.Equal("alice", u.Name)
+	u, err = AuthUser("alice", "ldap-pwd", write)
+	assert.Equal(ErrInsufficientPrivileges, err)
+	assert.Nil(u)
+
+	// the local authenticator is restored, it does not know the users.
+	s := newService()
+	defer func(inst *service) { svcInst = inst }(svcInst)
+	svcInst = s
+	startTestRaft(t, s, "1")
+	assert.NoError(CreateUser(&User{Name: "admin", Password: "pwd"}, false))
+
+	SetAuthenticator(nil)
+	calls := fa.calls
+	assert.Equal(ErrPasswordMismatch, Auth("alice", "ldap-pwd", read))
+	assert.NoError(Auth("admin", "pwd", write))
+	assert.Equal(calls, fa.calls)
+}
+
+func TestSetUserEnabled(t *testing.T) {
+	assert := assert.New(t)
+
+	s := newService()
+	defer func(inst *service) { svcInst = inst }(svcInst)
+	svcInst = s
+	startTestRaft(t, s, "1")
+
+	assert.NoError(CreateUser(&User{Name: "admin", Password: "pwd"}, false))
+	assert.NoError(CreateUser(&User{Name: "alice", Password: "pwd", Priv: PrivilegeRead}, false))
+	read := RequiredPrivileges{Global: PrivilegeRead}
+
+	// a disabled user cannot be authenticated, but is kept.
+	assert.NoError(SetUserEnabled("Alice", false))
+	assert.True(UserByName("alice").Disabled)
+	assert.Equal(ErrUserDisabled, Auth("alice", "pwd", read))
+	assert.Equal(ErrPasswordMismatch, Auth("alice", "bad", read))
+	assert.Equal(PrivilegeRead, UserByName("alice").Priv)
+
+	// re-enabling restores access.
+	assert.NoError(SetUserEnabled("alice", true))
+	assert.False(UserByName("alice").Disabled)
+	assert.NoError(Auth("alice", "pwd", read))
+
+	// system users cannot be disabled.
+	assert.Equal(ErrSystemUser, SetUserEnabled("admin", false))
+	assert.NoError(SetUserEnabled("admin", true))
+	assert.NoError(Auth("admin", "pwd", read))
+
+	assert.Equal(ErrUserNotExists, SetUserEnabled("bob", false))
+}
+
+func TestHandleSetUserEnabled(t *testing.T) {
+	assert := assert.New(t)
+
+	s := newService()
+	defer func(inst *service) { svcInst = inst }(svcInst)
+	svcInst = s
+	startTestRaft(t, s, "1")
+
+	assert.NoError(CreateUser(&User{Name: "admin", Password: "pwd"}, false))
+	assert.NoError(CreateUser(&User{Name: "alice", Password: "pwd"}, false))
+
+	put := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/meta/users/enabled?"+query, nil)
+		w := httptest.NewRecorder()
+		handleSetUserEnabled(w, req)
+		return w
+	}
+
+	assert.Equal(http.StatusNoContent, put("name=alice&enabled=false").Code)
+	assert.True(UserByName("alice").Disabled)
+	assert.Equal(http.StatusNoContent, put("name=alice&enabled=true").Code)
+	assert.False(UserByName("alice").Disabled)
+
+	assert.Equal(http.StatusBadRequest, put("enabled=true").Code)
+	assert.Equal(http.StatusBadRequest, put("name=alice&enabled=maybe").Code)
+	assert.Equal(http.StatusForbidden, put("name=admin&enabled=false").Code)
+	assert.Equal(http.StatusNotFound, put("name=bob&enabled=false").Code)
+}
@@ -0,0 +1,82 @@
+package meta
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/localvar/xuandb/pkg/config"
+	"github.com/localvar/xuandb/pkg/httpserver"
+	"github.com/localvar/xuandb/pkg/version"
+	"github.com/localvar/xuandb/pkg/xerrors"
+)
+
+// NodeVersion is the version and build information reported by a node, Err
+// is the reason if the information could not be retrieved.
+type NodeVersion struct {
+	version.Info
+	Addr string
+	Err  error
+}
+
+// versionRegisterAPIHandlers registers the API handlers for version checks.
+func versionRegisterAPIHandlers() {
+	// the same as 'GET /debug/version', but for inter-node requests, which
+	// are authenticated by the cluster name instead of a user.
+	handleFunc("GET /meta/version", version.HandleGet)
+}
+
+// nodeVersion gets the version and build information of the node at 'addr'.
+func nodeVersion(addr string) (version.Info, error) {
+	var vi version.Info
+
+	req, err := http.NewRequest(http.MethodGet, httpserver.URL(addr, "/meta/version"), nil)
+	if err != nil {
+		return vi, xerrors.Wrap(err, http.StatusInternalServerError)
+	}
+	req.Header.Set(ClusterNameHeader, config.ClusterName())
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return vi, xerrors.Wrap(err, http.StatusInternalServerError)
+	}
+	defer func() {
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}()
+
+	if resp.StatusCode >= 300 {
+		return vi, xerrors.FromHTTPResponse(resp)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&vi); err != nil {
+		return vi, xerrors.Wrap(err, http.StatusInternalServerError)
+	}
+	return vi, nil
+}
+
+// ClusterVersions gets the version and build information of all nodes in
+// the cluster concurrently, the result is keyed by node ID. Nodes which
+// could not be reached are also included, with Err set.
+func ClusterVersions() map[string]NodeVersion {
+	nodes := Nodes()
+
+	result := make(map[string]NodeVersion, len(nodes))
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	for _, ni := range nodes {
+		wg.Add(1)
+		go func(id, addr string) {
+			defer wg.Done()
+			nv := NodeVersion{Addr: addr}
+			nv.Info, nv.Err = nodeVersion(addr)
+			lock.Lock()
+			result[id] = nv
+			lock.Unlock()
+		}(ni.ID, ni.Addr)
+	}
+	wg.Wait()
+
+	return result
+}
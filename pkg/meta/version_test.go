@@ -0,0 +1,53 @@
+package meta
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/localvar/xuandb/pkg/version"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClusterVersions(t *testing.T) {
+	assert := assert.New(t)
+
+	newNode := func(body string) string {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal("/meta/version", r.URL.Path)
+			w.Write([]byte(body))
+		}))
+		t.Cleanup(srv.Close)
+		return strings.TrimPrefix(srv.URL, "http://")
+	}
+
+	// node 3 runs a different build and node 4 is unreachable.
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	addrs := map[string]string{
+		"1": newNode(`{"version":"1.2.0","goVersion":"go1.23.0","revision":"abc","localModified":false}`),
+		"2": newNode(`{"version":"1.2.0","goVersion":"go1.23.0","revision":"abc","localModified":false}`),
+		"3": newNode(`{"version":"1.1.0","goVersion":"go1.22.0","revision":"def","localModified":true}`),
+		"4": strings.TrimPrefix(down.URL, "http://"),
+	}
+
+	s := newService()
+	defer func(inst *service) { svcInst = inst }(svcInst)
+	svcInst = s
+	for id, addr := range addrs {
+		s.nodes[id] = &NodeInfo{ID: id, Addr: addr}
+	}
+
+	vers := ClusterVersions()
+	assert.Len(vers, 4)
+	assert.Equal(version.Info{Version: "1.2.0", GoVersion: "go1.23.0", Revision: "abc"}, vers["1"].Info)
+	assert.Equal(vers["1"].Info, vers["2"].Info)
+	assert.Equal(version.Info{Version: "1.1.0", GoVersion: "go1.22.0", Revision: "def", LocalModified: true}, vers["3"].Info)
+	assert.NotEqual(vers["1"].Info, vers["3"].Info)
+	for id, addr := range addrs {
+		assert.Equal(addr, vers[id].Addr)
+	}
+	assert.NoError(vers["1"].Err)
+	assert.Error(vers["4"].Err)
+}
@@ -0,0 +1,130 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/localvar/xuandb/pkg/config"
+	"github.com/localvar/xuandb/pkg/httpserver"
+	"github.com/localvar/xuandb/pkg/meta"
+)
+
+// Counter is a monotonically increasing counter which is safe for concurrent
+// use.
+type Counter struct {
+	v atomic.Uint64
+}
+
+// Inc increases the counter by 1.
+func (c *Counter) Inc() {
+	c.v.Add(1)
+}
+
+// Value returns the current value of the counter.
+func (c *Counter) Value() uint64 {
+	return c.v.Load()
+}
+
+// Counters of the query service.
+var (
+	QueryRequests Counter // number of query requests received
+	QueryErrors   Counter // number of query requests that failed
+)
+
+// sources of the metrics, the current node and the meta data.
+var (
+	currentNodeID = config.NodeID
+	leaderNode    = meta.LeaderNode
+	nodeStatuses  = meta.NodeStatuses
+	applyFailures = meta.ApplyFailures
+	snapshotPeak  = meta.SnapshotPeakMemory
+)
+
+// exposition writes metrics in the Prometheus text exposition format.
+type exposition struct {
+	w *bufio.Writer
+}
+
+// header writes the HELP and TYPE lines of a metric.
+func (e *exposition) header(name, typ, help string) {
+	fmt.Fprintf(e.w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+// sample writes a sample of a metric, 'labels' are name/value pairs.
+func (e *exposition) sample(name string, value any, labels ...string) {
+	e.w.WriteString(name)
+	for i := 0; i+1 < len(labels); i += 2 {
+		if i == 0 {
+			e.w.WriteByte('{')
+		} else {
+			e.w.WriteByte(',')
+		}
+		fmt.Fprintf(e.w, "%s=\"%s\"", labels[i], escapeLabelValue(labels[i+1]))
+	}
+	if len(labels) > 1 {
+		e.w.WriteByte('}')
+	}
+	fmt.Fprintf(e.w, " %v\n", value)
+}
+
+// labelValueEscaper escapes label values as required by the text format.
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(v string) string {
+	return labelValueEscaper.Replace(v)
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// handleMetrics is an http handler that exposes the metrics in the Prometheus
+// text exposition format.
+func handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	e := &exposition{w: bufio.NewWriter(w)}
+
+	leader := leaderNode()
+	e.header("xuandb_raft_has_leader", "gauge", "Whether the raft cluster has a leader.")
+	e.sample("xuandb_raft_has_leader", boolToInt(leader != nil))
+	e.header("xuandb_raft_is_leader", "gauge", "Whether the current node is the raft leader.")
+	e.sample("xuandb_raft_is_leader", boolToInt(leader != nil && leader.ID == currentNodeID()))
+
+	af := applyFailures()
+	e.header("xuandb_raft_apply_failures_total", "counter", "Number of raft log apply failures.")
+	e.sample("xuandb_raft_apply_failures_total", af.Count)
+	e.header("xuandb_raft_apply_consecutive_failures", "gauge", "Number of consecutive raft log apply failures.")
+	e.sample("xuandb_raft_apply_consecutive_failures", af.Consecutive)
+	e.header("xuandb_raft_apply_last_failed_index", "gauge", "Index of the last raft log failed to apply.")
+	e.sample("xuandb_raft_apply_last_failed_index", af.LastIndex)
+
+	e.header("xuandb_meta_snapshot_peak_memory_bytes", "gauge", "Peak memory used to buffer the data when persisting the last meta snapshot.")
+	e.sample("xuandb_meta_snapshot_peak_memory_bytes", snapshotPeak())
+
+	nss := nodeStatuses()
+	e.header("xuandb_nodes_total", "gauge", "Number of nodes in the cluster.")
+	e.sample("xuandb_nodes_total", len(nss))
+	e.header("xuandb_node_state", "gauge", "State of the nodes, the value is always 1.")
+	for _, ns := range nss {
+		e.sample("xuandb_node_state", 1, "id", ns.ID, "role", ns.Role.String(), "state", ns.State)
+	}
+
+	e.header("xuandb_query_requests_total", "counter", "Number of query requests received.")
+	e.sample("xuandb_query_requests_total", QueryRequests.Value())
+	e.header("xuandb_query_errors_total", "counter", "Number of query requests that failed.")
+	e.sample("xuandb_query_errors_total", QueryErrors.Value())
+
+	e.w.Flush()
+}
+
+// Init initializes the metrics package, it must be called after the meta
+// service was started.
+func Init() {
+	httpserver.HandleFunc("GET /metrics", handleMetrics)
+}
@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/localvar/xuandb/pkg/meta"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandleMetrics(t *testing.T) {
+	assert := assert.New(t)
+
+	defer func(fn func() string) { currentNodeID = fn }(currentNodeID)
+	defer func(fn func() *meta.NodeInfo) { leaderNode = fn }(leaderNode)
+	defer func(fn func() []meta.NodeStatus) { nodeStatuses = fn }(nodeStatuses)
+	defer func(fn func() meta.ApplyFailureStats) { applyFailures = fn }(applyFailures)
+	defer func(fn func() uint64) { snapshotPeak = fn }(snapshotPeak)
+	defer func(v uint64) { QueryRequests.v.Store(v) }(QueryRequests.Value())
+
+	currentNodeID = func() string { return "1" }
+	leaderNode = func() *meta.NodeInfo { return &meta.NodeInfo{ID: "1"} }
+	nodeStatuses = func() []meta.NodeStatus {
+		return []meta.NodeStatus{
+			{
+				NodeInfo: meta.NodeInfo{ID: "1", Role: meta.NodeRoleMeta, LastHeartbeatTime: time.Now()},
+				Leader:   true,
+				State:    "up",
+			},
+			{
+				NodeInfo: meta.NodeInfo{ID: "2", Role: meta.NodeRoleData},
+				State:    "down",
+			},
+		}
+	}
+	applyFailures = func() meta.ApplyFailureStats {
+		return meta.ApplyFailureStats{Count: 3, Consecutive: 1, LastIndex: 42}
+	}
+	snapshotPeak = func() uint64 { return 4096 }
+	QueryRequests.v.Store(0)
+	QueryRequests.Inc()
+
+	w := httptest.NewRecorder()
+	handleMetrics(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Equal(http.StatusOK, w.Code)
+	body := w.Body.String()
+	assert.Contains(body, "# TYPE xuandb_raft_is_leader gauge\n")
+	assert.Contains(body, "\nxuandb_raft_is_leader 1\n")
+	assert.Contains(body, "\nxuandb_nodes_total 2\n")
+	assert.Contains(body, `xuandb_node_state{id="1",role="meta",state="up"} 1`)
+	assert.Contains(body, `xuandb_node_state{id="2",role="data",state="down"} 1`)
+	assert.Contains(body, "\nxuandb_query_requests_total 1\n")
+	assert.Contains(body, "\nxuandb_raft_apply_failures_total 3\n")
+	assert.Contains(body, "\nxuandb_raft_apply_consecutive_failures 1\n")
+	assert.Contains(body, "\nxuandb_raft_apply_last_failed_index 42\n")
+	assert.Contains(body, "\nxuandb_meta_snapshot_peak_memory_bytes 4096\n")
+
+	// the current node is not the leader.
+	currentNodeID = func() string { return "2" }
+	w = httptest.NewRecorder()
+	handleMetrics(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Contains(w.Body.String(), "\nxuandb_raft_is_leader 0\n")
+}
+
+func TestEscapeLabelValue(t *testing.T) {
+	assert.Equal(t, `a\\b\"c\nd`, escapeLabelValue("a\\b\"c\nd"))
+}
@@ -1,20 +1,153 @@
 package ast
 
 import (
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/localvar/xuandb/pkg/cache"
+	"github.com/localvar/xuandb/pkg/config"
 	"github.com/localvar/xuandb/pkg/meta"
+	"github.com/localvar/xuandb/pkg/query/registry"
+	"github.com/localvar/xuandb/pkg/utils"
+	"github.com/localvar/xuandb/pkg/version"
+	"github.com/localvar/xuandb/pkg/xerrors"
 )
 
+// ResultSet is the interface for statements to output their results.
+//
+// Misuse of a ResultSet, like adding rows before setting columns or adding a
+// row whose value count does not match the column count, is a bug of the
+// statement implementation. In this case, the ResultSet records an error
+// which is returned from AddRow and all subsequent calls, but implementations
+// may also panic in development mode to catch such bugs early.
 type ResultSet interface {
+	// SetError sets the error of the result set, only the first error is
+	// recorded.
 	SetError(error)
+
+	// SetColumns sets the columns of the result set, it must be called once
+	// and only once before adding rows.
 	SetColumns(...string)
+
+	// SetColumnsWithTypes is like SetColumns, but also sets the types of the
+	// columns, which are sent to clients so that they don't need to guess.
+	SetColumnsWithTypes(...Column)
+
+	// AddRow adds a row to the result set, the number of values must match
+	// the number of columns.
 	AddRow(...any) error
+
+	// AddRowMap adds a row to the result set, the values are keyed by column
+	// names and are ordered by the columns, every column must have a value
+	// and every key must be a column.
+	AddRowMap(map[string]any) error
+
+	// SetNextCursor marks that there are more rows beyond the returned ones,
+	// and 'cursor' is the key to continue from.
+	SetNextCursor(cursor string)
+}
+
+// Types of the columns of result sets.
+const (
+	ColumnTypeInt      = "int"
+	ColumnTypeFloat    = "float"
+	ColumnTypeString   = "string"
+	ColumnTypeBool     = "bool"
+	ColumnTypeTime     = "time"
+	ColumnTypeDuration = "duration"
+)
+
+// Column is a column of a result set with its type.
+type Column struct {
+	Name string
+	Type string
+}
+
+// SplitColumns returns the names and the types of 'columns', it is a helper
+// for ResultSet implementations to implement SetColumnsWithTypes.
+func SplitColumns(columns []Column) (names, types []string) {
+	names = make([]string, len(columns))
+	types = make([]string, len(columns))
+	for i, c := range columns {
+		names[i], types[i] = c.Name, c.Type
+	}
+	return names, types
+}
+
+// Duration is a value wrapper of [time.Duration] for result sets, it is
+// formatted in the human readable form like '2w3d' by default, while a
+// bare [time.Duration] is formatted as nanoseconds.
+type Duration time.Duration
+
+// RowValues returns the values of 'row' ordered by 'columns', it is a helper
+// for ResultSet implementations to implement AddRowMap.
+func RowValues(columns []string, row map[string]any) ([]any, error) {
+	for _, k := range slices.Sorted(maps.Keys(row)) {
+		if !slices.Contains(columns, k) {
+			return nil, fmt.Errorf("unknown column: %s", k)
+		}
+	}
+
+	vals := make([]any, len(columns))
+	for i, c := range columns {
+		v, ok := row[c]
+		if !ok {
+			return nil, fmt.Errorf("missing value of column: %s", c)
+		}
+		vals[i] = v
+	}
+
+	return vals, nil
 }
 
 type Statement interface {
 	Auth(name, pwd string) error
-	Execute(rs ResultSet) error
+	Execute(ctx context.Context, rs ResultSet) error
 }
 
+// DeprecatedStatement is implemented by statements which are deprecated, the
+// query service attaches the deprecation message to the response as a warning.
+type DeprecatedStatement interface {
+	Statement
+	Deprecation() string
+}
+
+// ReadOnlyStatement is implemented by statements which only read data.
+type ReadOnlyStatement interface {
+	Statement
+	ReadOnly() bool
+}
+
+// TypeName returns the name of the type of 'stmt', like 'ShowUserStatement'.
+func TypeName(stmt Statement) string {
+	return reflect.Indirect(reflect.ValueOf(stmt)).Type().Name()
+}
+
+// functions to access the meta data, the version and build information of
+// all nodes, the configuration of the current node, and the in-flight queries
+// of the current node.
+var (
+	userByName      = meta.UserByName
+	users           = meta.Users
+	databases       = meta.Databases
+	nodeStatuses    = meta.NodeStatuses
+	raftStats       = meta.RaftStats
+	raftLogs        = meta.RaftLogs
+	clusterVersions = meta.ClusterVersions
+
+	currentNodeConfig = config.CurrentNode
+
+	activeQueries = registry.List
+	killQuery     = registry.Kill
+)
+
 // adminStatement represents a statement which requires the global admin
 // privilege.
 type adminStatement struct {
@@ -25,6 +158,20 @@ func (stmt *adminStatement) Auth(name, pwd string) error {
 	return meta.Auth(name, pwd, rp)
 }
 
+// debugStatement represents a statement which requires the global debug
+// privilege.
+type debugStatement struct {
+}
+
+func (stmt *debugStatement) Auth(name, pwd string) error {
+	rp := meta.RequiredPrivileges{Global: meta.PrivilegeDebug}
+	return meta.Auth(name, pwd, rp)
+}
+
+func (stmt *debugStatement) ReadOnly() bool {
+	return true
+}
+
 // readStatement represents a statement which requires the global read
 // privilege.
 type readStatement struct {
@@ -35,24 +182,40 @@ func (stmt *readStatement) Auth(name, pwd string) error {
 	return meta.Auth(name, pwd, rp)
 }
 
+func (stmt *readStatement) ReadOnly() bool {
+	return true
+}
+
+// ddlStatement represents a statement which requires the global DDL
+// privilege.
+type ddlStatement struct {
+}
+
+func (stmt *ddlStatement) Auth(name, pwd string) error {
+	rp := meta.RequiredPrivileges{Global: meta.PrivilegeDDL}
+	return meta.Auth(name, pwd, rp)
+}
+
 // CreateUserStatement represents a command for creating a new user.
 type CreateUserStatement struct {
 	adminStatement
 	meta.User
+	IfNotExists bool
 }
 
-func (stmt *CreateUserStatement) Execute(rs ResultSet) error {
-	return meta.CreateUser(&stmt.User)
+func (stmt *CreateUserStatement) Execute(ctx context.Context, rs ResultSet) error {
+	return meta.CreateUser(&stmt.User, stmt.IfNotExists)
 }
 
 // DropUserStatement represents a command for dropping a user.
 type DropUserStatement struct {
 	adminStatement
-	Name string
+	Name     string
+	IfExists bool
 }
 
-func (stmt *DropUserStatement) Execute(rs ResultSet) error {
-	return meta.DropUser(stmt.Name)
+func (stmt *DropUserStatement) Execute(ctx context.Context, rs ResultSet) error {
+	return meta.DropUser(stmt.Name, stmt.IfExists)
 }
 
 // SetPasswordStatement represents a command for setting a user's password.
@@ -71,20 +234,144 @@ func (stmt *SetPasswordStatement) Auth(name, pwd string) error {
 	return meta.Auth(name, pwd, rp)
 }
 
-func (stmt *SetPasswordStatement) Execute(rs ResultSet) error {
+func (stmt *SetPasswordStatement) Execute(ctx context.Context, rs ResultSet) error {
 	return meta.SetPassword(stmt.Name, stmt.Password)
 }
 
+// SetUserEnabledStatement represents a command for enabling or disabling a
+// user.
+type SetUserEnabledStatement struct {
+	adminStatement
+	Name    string
+	Enabled bool
+}
+
+func (stmt *SetUserEnabledStatement) Execute(ctx context.Context, rs ResultSet) error {
+	return meta.SetUserEnabled(stmt.Name, stmt.Enabled)
+}
+
 // ShowUserStatement represents a command for showing all users.
 type ShowUserStatement struct {
 	readStatement
+	Projection
+	Pagination
+}
+
+func (stmt *ShowUserStatement) Execute(ctx context.Context, rs ResultSet) error {
+	rs = stmt.project(rs)
+	rs.SetColumnsWithTypes(
+		Column{"name", ColumnTypeString},
+		Column{"isSystem", ColumnTypeBool},
+		Column{"privileges", ColumnTypeString},
+		Column{"isDisabled", ColumnTypeBool},
+	)
+	us := paginate(&stmt.Pagination, rs, users(), func(u *meta.User) string {
+		return u.Name
+	})
+	for _, u := range us {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		err := rs.AddRow(u.Name, u.System, u.Priv.String(), u.Disabled)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func (stmt *ShowUserStatement) Execute(rs ResultSet) error {
-	rs.SetColumns("name", "isSystem", "privileges")
-	for _, u := range meta.Users() {
-		err := rs.AddRow(u.Name, u.System, u.Priv.String())
-		if err != nil {
+// ShowCurrentUserStatement represents a command for showing the current
+// authenticated user and its privileges.
+type ShowCurrentUserStatement struct {
+	name string // name of the authenticated user
+}
+
+func (stmt *ShowCurrentUserStatement) Auth(name, pwd string) error {
+	// authentication is required, but no privilege.
+	rp := meta.RequiredPrivileges{Global: meta.PrivilegeNone}
+	if err := meta.Auth(name, pwd, rp); err != nil {
+		return err
+	}
+	stmt.name = name
+	return nil
+}
+
+func (stmt *ShowCurrentUserStatement) ReadOnly() bool {
+	return true
+}
+
+// formatDbPrivileges formats database privileges to a string like
+// 'db1:READ,WRITE;db2:READ', databases are sorted by name.
+func formatDbPrivileges(dbPriv map[string]meta.Privilege) string {
+	dbs := make([]string, 0, len(dbPriv))
+	for db := range dbPriv {
+		dbs = append(dbs, db)
+	}
+	sort.Strings(dbs)
+
+	var sb strings.Builder
+	for _, db := range dbs {
+		if sb.Len() > 0 {
+			sb.WriteByte(';')
+		}
+		sb.WriteString(db)
+		sb.WriteByte(':')
+		sb.WriteString(dbPriv[db].String())
+	}
+	return sb.String()
+}
+
+func (stmt *ShowCurrentUserStatement) Execute(ctx context.Context, rs ResultSet) error {
+	rs.SetColumns("name", "isSystem", "privileges", "dbPrivileges", "isAnonymous")
+
+	// 'Auth' succeeds without a user only if no user has been created, that
+	// is, the cluster is in the open access bootstrap window, and everyone
+	// has all privileges.
+	u := userByName(stmt.name)
+	if u == nil {
+		return rs.AddRow("", false, meta.PrivilegeAdmin.String(), "", true)
+	}
+
+	return rs.AddRow(u.Name, u.System, u.Priv.String(), formatDbPrivileges(u.DbPriv), false)
+}
+
+// ShowGrantsStatement represents a command for showing the privileges of a
+// user, the global privileges are shown as the privileges of database '*'.
+type ShowGrantsStatement struct {
+	Name string
+}
+
+func (stmt *ShowGrantsStatement) Auth(name, pwd string) error {
+	rp := meta.RequiredPrivileges{Global: meta.PrivilegeAdmin}
+	// a user can show its own privileges.
+	if strings.EqualFold(name, stmt.Name) {
+		rp.Global = meta.PrivilegeNone
+	}
+	return meta.Auth(name, pwd, rp)
+}
+
+func (stmt *ShowGrantsStatement) ReadOnly() bool {
+	return true
+}
+
+func (stmt *ShowGrantsStatement) Execute(ctx context.Context, rs ResultSet) error {
+	u := userByName(stmt.Name)
+	if u == nil {
+		return meta.ErrUserNotExists
+	}
+
+	rs.SetColumns("database", "privileges")
+
+	// an administrator has all privileges on all databases.
+	if u.System || u.Priv == meta.PrivilegeAdmin {
+		return rs.AddRow("*", meta.PrivilegeAdmin.String())
+	}
+
+	if err := rs.AddRow("*", u.Priv.String()); err != nil {
+		return err
+	}
+	for _, db := range slices.Sorted(maps.Keys(u.DbPriv)) {
+		if err := rs.AddRow(db, u.DbPriv[db].String()); err != nil {
 			return err
 		}
 	}
@@ -99,7 +386,7 @@ type JoinNodeStatement struct {
 	Voter bool
 }
 
-func (stmt *JoinNodeStatement) Execute(rs ResultSet) error {
+func (stmt *JoinNodeStatement) Execute(ctx context.Context, rs ResultSet) error {
 	return meta.AddNode(stmt.ID, stmt.Addr, stmt.Voter)
 }
 
@@ -109,27 +396,243 @@ type DropNodeStatement struct {
 	ID string
 }
 
-func (stmt *DropNodeStatement) Execute(rs ResultSet) error {
+func (stmt *DropNodeStatement) Execute(ctx context.Context, rs ResultSet) error {
 	return meta.DropNode(stmt.ID)
 }
 
+// DrainNodeStatement represents a command for marking a node as draining.
+type DrainNodeStatement struct {
+	adminStatement
+	ID string
+}
+
+func (stmt *DrainNodeStatement) Execute(ctx context.Context, rs ResultSet) error {
+	return meta.DrainNode(stmt.ID)
+}
+
+// FlushCacheStatement represents a command for flushing cache 'Name', or all
+// caches if 'Name' is empty. Caches are node-local, only the caches of the
+// node which executes the statement are flushed.
+type FlushCacheStatement struct {
+	adminStatement
+	Name string
+}
+
+func (stmt *FlushCacheStatement) Execute(ctx context.Context, rs ResultSet) error {
+	names, err := cache.Flush(stmt.Name)
+	if err != nil {
+		return err
+	}
+
+	rs.SetColumns("cache")
+	for _, name := range names {
+		if err := rs.AddRow(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // ShowNodeStatement represents a command for showing all nodes in the cluster.
 type ShowNodeStatement struct {
 	readStatement
-}
-
-func (stmt *ShowNodeStatement) Execute(rs ResultSet) error {
-	rs.SetColumns("id", "addr", "role", "heartbeatTime", "isLeader", "state")
-	for _, n := range meta.NodeStatuses() {
-		err := rs.AddRow(
+	Projection
+	Pagination
+}
+
+func (stmt *ShowNodeStatement) Execute(ctx context.Context, rs ResultSet) error {
+	// labels are only output if they are selected explicitly.
+	withLabels := stmt.selects("labels")
+
+	rs = stmt.project(rs)
+	columns := []Column{
+		{"id", ColumnTypeString},
+		{"addr", ColumnTypeString},
+		{"role", ColumnTypeString},
+		{"heartbeatTime", ColumnTypeTime},
+		{"isLeader", ColumnTypeBool},
+		{"state", ColumnTypeString},
+	}
+	if withLabels {
+		columns = append(columns, Column{"labels", ColumnTypeString})
+	}
+	rs.SetColumnsWithTypes(columns...)
+	nss := paginate(&stmt.Pagination, rs, nodeStatuses(), func(ns meta.NodeStatus) string {
+		return ns.ID
+	})
+	for _, n := range nss {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		row := []any{
 			n.ID,
 			n.Addr,
 			n.Role.String(),
 			n.LastHeartbeatTime,
 			n.Leader,
 			n.State,
-		)
-		if err != nil {
+		}
+		if withLabels {
+			row = append(row, formatLabels(n.Labels))
+		}
+		if err := rs.AddRow(row...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// formatLabels formats node labels to a string like 'rack=r1,zone=z1', keys
+// are sorted.
+func formatLabels(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	for _, k := range keys {
+		if sb.Len() > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(k)
+		sb.WriteByte('=')
+		sb.WriteString(labels[k])
+	}
+	return sb.String()
+}
+
+// ShowRaftStatsStatement represents a command for showing the statistics of
+// the raft instance of the current node.
+type ShowRaftStatsStatement struct {
+	readStatement
+}
+
+func (stmt *ShowRaftStatsStatement) Execute(ctx context.Context, rs ResultSet) error {
+	rs.SetColumns("key", "value")
+	stats := raftStats()
+	keys := make([]string, 0, len(stats))
+	for k := range stats {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := rs.AddRow(k, stats[k]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ShowConfigStatement represents a command for showing the effective
+// configuration of the current node, sensitive items are redacted.
+type ShowConfigStatement struct {
+	debugStatement
+}
+
+func (stmt *ShowConfigStatement) Execute(ctx context.Context, rs ResultSet) error {
+	rs.SetColumns("key", "value")
+	for _, item := range currentNodeConfig().Items() {
+		if err := rs.AddRow(item.Key, item.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ShowVersionStatement represents a command for showing the version and
+// build information of the current node.
+type ShowVersionStatement struct {
+	debugStatement
+}
+
+func (stmt *ShowVersionStatement) Execute(ctx context.Context, rs ResultSet) error {
+	rs.SetColumns("version", "goVersion", "revision", "localModified")
+	vi := version.Get()
+	return rs.AddRow(vi.Version, vi.GoVersion, vi.Revision, vi.LocalModified)
+}
+
+// ShowClusterVersionsStatement represents a command for showing the version
+// and build information of all nodes in the cluster. A node is consistent if
+// it runs the same version and revision as the current node.
+type ShowClusterVersionsStatement struct {
+	adminStatement
+}
+
+func (stmt *ShowClusterVersionsStatement) Execute(ctx context.Context, rs ResultSet) error {
+	vers := clusterVersions()
+	ids := slices.Sorted(maps.Keys(vers))
+	local := version.Get()
+
+	rs.SetColumns("id", "addr", "version", "revision", "consistent", "error")
+	for _, id := range ids {
+		nv := vers[id]
+		msg := ""
+		if nv.Err != nil {
+			msg = nv.Err.Error()
+		}
+		consistent := nv.Err == nil && nv.Version == local.Version && nv.Revision == local.Revision
+		if err := rs.AddRow(id, nv.Addr, nv.Version, nv.Revision, consistent, msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ShowQueriesStatement represents a command for showing the in-flight
+// queries of the current node.
+type ShowQueriesStatement struct {
+	readStatement
+}
+
+func (stmt *ShowQueriesStatement) Execute(ctx context.Context, rs ResultSet) error {
+	rs.SetColumns("id", "user", "client", "start", "duration", "sql")
+	now := time.Now()
+	for _, q := range activeQueries() {
+		d := Duration(now.Sub(q.Start))
+		if err := rs.AddRow(q.ID, q.User, q.Client, q.Start, d, q.SQL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// KillQueryStatement represents a command for canceling an in-flight query
+// of the current node.
+type KillQueryStatement struct {
+	adminStatement
+	ID uint64
+}
+
+func (stmt *KillQueryStatement) Execute(ctx context.Context, rs ResultSet) error {
+	if !killQuery(stmt.ID) {
+		msg := fmt.Sprintf("query not found: %d", stmt.ID)
+		return xerrors.New(http.StatusNotFound, msg)
+	}
+	return nil
+}
+
+// ShowRaftLogStatement represents a command for showing the metadata of the
+// raft log entries of the current node, for debugging.
+type ShowRaftLogStatement struct {
+	adminStatement
+	From uint64 // 0 means the first index
+	To   uint64 // 0 means the last index
+}
+
+func (stmt *ShowRaftLogStatement) Execute(ctx context.Context, rs ResultSet) error {
+	entries, err := raftLogs(stmt.From, stmt.To, true)
+	if err != nil {
+		return err
+	}
+
+	rs.SetColumns("index", "term", "type", "size", "op")
+	for _, e := range entries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := rs.AddRow(e.Index, e.Term, e.Type, e.Size, e.Op); err != nil {
 			return err
 		}
 	}
@@ -138,40 +641,186 @@ func (stmt *ShowNodeStatement) Execute(rs ResultSet) error {
 
 // CreateDatabaseStatement represents a command for creating a new database.
 type CreateDatabaseStatement struct {
-	adminStatement
+	ddlStatement
 	meta.Database
+	IfNotExists bool
 }
 
-func (stmt *CreateDatabaseStatement) Execute(rs ResultSet) error {
-	return meta.CreateDatabase(&stmt.Database)
+func (stmt *CreateDatabaseStatement) Execute(ctx context.Context, rs ResultSet) error {
+	if err := meta.ValidateDatabase(&stmt.Database); err != nil {
+		return err
+	}
+	return meta.CreateDatabase(&stmt.Database, stmt.IfNotExists)
 }
 
 // DropDatabaseStatement represents a command for dropping a database.
 type DropDatabaseStatement struct {
-	adminStatement
-	Name string
+	Name     string
+	IfExists bool
 }
 
-func (stmt *DropDatabaseStatement) Execute(rs ResultSet) error {
-	return meta.DropDatabase(stmt.Name)
+func (stmt *DropDatabaseStatement) Auth(name, pwd string) error {
+	// the DDL privilege can be granted globally or on the database.
+	rp := meta.RequiredPrivileges{
+		Databases: map[string]meta.Privilege{stmt.Name: meta.PrivilegeDDL},
+	}
+	return meta.Auth(name, pwd, rp)
+}
+
+func (stmt *DropDatabaseStatement) Execute(ctx context.Context, rs ResultSet) error {
+	return meta.DropDatabase(stmt.Name, stmt.IfExists)
 }
 
 // ShowDatabaseStatement represents a command for showing all databases.
 type ShowDatabaseStatement struct {
 	readStatement
+	Projection
+	Pagination
+}
+
+func (stmt *ShowDatabaseStatement) Execute(ctx context.Context, rs ResultSet) error {
+	rs = stmt.project(rs)
+	rs.SetColumnsWithTypes(
+		Column{"name", ColumnTypeString},
+		Column{"duration", ColumnTypeDuration},
+		Column{"createdAt", ColumnTypeTime},
+		Column{"updatedAt", ColumnTypeTime},
+	)
+	dbs := paginate(&stmt.Pagination, rs, databases(), func(db *meta.Database) string {
+		return db.Name
+	})
+	for _, db := range dbs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		err := rs.AddRow(db.Name, Duration(db.Duration), db.CreatedAt, db.UpdatedAt)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func (stmt *ShowDatabaseStatement) Execute(rs ResultSet) error {
-	rs.SetColumns("name", "duration")
-	for _, db := range meta.Databases() {
-		err := rs.AddRow(db.Name, db.Duration)
-		if err != nil {
+// ExplainStatement represents a command for showing how a statement was
+// parsed, the inner statement is described but not executed.
+type ExplainStatement struct {
+	Statement Statement
+}
+
+func (stmt *ExplainStatement) Auth(name, pwd string) error {
+	return stmt.Statement.Auth(name, pwd)
+}
+
+func (stmt *ExplainStatement) ReadOnly() bool {
+	return true
+}
+
+func (stmt *ExplainStatement) Execute(ctx context.Context, rs ResultSet) error {
+	rs.SetColumns("plan")
+	for _, line := range explain(stmt.Statement, "") {
+		if err := rs.AddRow(line); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+// explain describes 'stmt' with the type of the node and its key fields, one
+// line for each, the lines are prefixed with 'indent'.
+func explain(stmt Statement, indent string) []string {
+	lines := []string{indent + TypeName(stmt)}
+	indent += "  "
+	field := func(name string, value any) {
+		lines = append(lines, fmt.Sprintf("%s%s: %v", indent, name, value))
+	}
+	show := func(proj *Projection, page *Pagination) {
+		if len(proj.Columns) > 0 {
+			cols := make([]string, 0, len(proj.Columns))
+			for _, ca := range proj.Columns {
+				if ca.Alias == "" {
+					cols = append(cols, ca.Column)
+				} else {
+					cols = append(cols, ca.Column+" AS "+ca.Alias)
+				}
+			}
+			field("columns", strings.Join(cols, ", "))
+		}
+		if page.After != "" {
+			field("after", page.After)
+		}
+		if page.Limit > 0 {
+			field("limit", page.Limit)
+		}
+		if page.Offset > 0 {
+			field("offset", page.Offset)
+		}
+	}
+
+	switch s := stmt.(type) {
+	case *CreateUserStatement:
+		field("name", s.Name)
+		field("privileges", s.Priv.String())
+		field("ifNotExists", s.IfNotExists)
+	case *DropUserStatement:
+		field("name", s.Name)
+		field("ifExists", s.IfExists)
+	case *SetPasswordStatement:
+		field("name", s.Name)
+	case *SetUserEnabledStatement:
+		field("name", s.Name)
+		field("enabled", s.Enabled)
+	case *ShowUserStatement:
+		show(&s.Projection, &s.Pagination)
+	case *JoinNodeStatement:
+		field("id", s.ID)
+		field("addr", s.Addr)
+		field("voter", s.Voter)
+	case *DropNodeStatement:
+		field("id", s.ID)
+	case *DrainNodeStatement:
+		field("id", s.ID)
+	case *KillQueryStatement:
+		field("id", s.ID)
+	case *FlushCacheStatement:
+		if s.Name != "" {
+			field("name", s.Name)
+		}
+	case *SetClusterLogLevelStatement:
+		field("level", s.Level)
+	case *ShowRaftLogStatement:
+		if s.From > 0 {
+			field("from", s.From)
+		}
+		if s.To > 0 {
+			field("to", s.To)
+		}
+	case *ShowNodeStatement:
+		show(&s.Projection, &s.Pagination)
+	case *CreateDatabaseStatement:
+		field("name", s.Name)
+		field("duration", utils.FormatDuration(s.Duration))
+		field("ifNotExists", s.IfNotExists)
+	case *DropDatabaseStatement:
+		field("name", s.Name)
+		field("ifExists", s.IfExists)
+	case *ShowDatabaseStatement:
+		show(&s.Projection, &s.Pagination)
+	case *SelectStatement:
+		fields := make([]string, 0, len(s.Fields))
+		for _, f := range s.Fields {
+			fields = append(fields, f.String())
+		}
+		field("fields", strings.Join(fields, ", "))
+		if len(s.GroupBy) > 0 {
+			field("groupBy", strings.Join(s.GroupBy, ", "))
+		}
+		lines = append(lines, indent+"source:")
+		lines = append(lines, explain(s.Source, indent+"  ")...)
+	}
+
+	return lines
+}
+
 type Expr interface {
 }
 
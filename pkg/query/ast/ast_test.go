@@ -0,0 +1,673 @@
+package ast
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/localvar/xuandb/pkg/cache"
+	"github.com/localvar/xuandb/pkg/config"
+	"github.com/localvar/xuandb/pkg/meta"
+	"github.com/localvar/xuandb/pkg/query/registry"
+	"github.com/localvar/xuandb/pkg/version"
+	"github.com/localvar/xuandb/pkg/xerrors"
+	"github.com/stretchr/testify/assert"
+)
+
+// testResultSet is an in-memory ResultSet for test cases.
+type testResultSet struct {
+	err        error
+	columns    []string
+	types      []string
+	rows       [][]any
+	nextCursor string
+}
+
+func (rs *testResultSet) SetError(err error) {
+	if rs.err == nil {
+		rs.err = err
+	}
+}
+
+func (rs *testResultSet) SetColumns(columns ...string) {
+	rs.columns = columns
+}
+
+func (rs *testResultSet) SetColumnsWithTypes(columns ...Column) {
+	rs.columns, rs.types = SplitColumns(columns)
+}
+
+func (rs *testResultSet) AddRow(vals ...any) error {
+	rs.rows = append(rs.rows, vals)
+	return nil
+}
+
+func (rs *testResultSet) AddRowMap(row map[string]any) error {
+	vals, err := RowValues(rs.columns, row)
+	if err != nil {
+		rs.SetError(err)
+		return err
+	}
+	return rs.AddRow(vals...)
+}
+
+func (rs *testResultSet) SetNextCursor(cursor string) {
+	rs.nextCursor = cursor
+}
+
+func TestShowCurrentUser(t *testing.T) {
+	assert := assert.New(t)
+
+	users := map[string]*meta.User{
+		"alice": {
+			Name: "alice",
+			Priv: meta.PrivilegeDebug,
+			DbPriv: map[string]meta.Privilege{
+				"db2": meta.PrivilegeRead,
+				"db1": meta.PrivilegeRead | meta.PrivilegeWrite,
+			},
+		},
+	}
+	defer func(fn func(string) *meta.User) { userByName = fn }(userByName)
+	userByName = func(name string) *meta.User { return users[name] }
+
+	// an authenticated user.
+	rs := &testResultSet{}
+	stmt := &ShowCurrentUserStatement{name: "alice"}
+	assert.NoError(stmt.Execute(context.Background(), rs))
+	assert.Equal([]string{"name", "isSystem", "privileges", "dbPrivileges", "isAnonymous"}, rs.columns)
+	assert.Equal([][]any{{"alice", false, "DEBUG", "db1:READ,WRITE;db2:READ", false}}, rs.rows)
+
+	// no user has been created.
+	users = map[string]*meta.User{}
+	rs = &testResultSet{}
+	stmt = &ShowCurrentUserStatement{}
+	assert.NoError(stmt.Execute(context.Background(), rs))
+	assert.Equal([][]any{{"", false, "ADMIN", "", true}}, rs.rows)
+}
+
+func TestShowGrants(t *testing.T) {
+	assert := assert.New(t)
+
+	users := map[string]*meta.User{
+		"alice": {
+			Name: "alice",
+			Priv: meta.PrivilegeDebug | meta.PrivilegeRead,
+			DbPriv: map[string]meta.Privilege{
+				"db2": meta.PrivilegeRead,
+				"db1": meta.PrivilegeRead | meta.PrivilegeWrite | meta.PrivilegeDDL,
+			},
+		},
+		"bob":  {Name: "bob"},
+		"root": {Name: "root", System: true, Priv: meta.PrivilegeAdmin},
+	}
+	defer func(fn func(string) *meta.User) { userByName = fn }(userByName)
+	userByName = func(name string) *meta.User { return users[name] }
+
+	show := func(name string) (*testResultSet, error) {
+		rs := &testResultSet{}
+		err := (&ShowGrantsStatement{Name: name}).Execute(context.Background(), rs)
+		return rs, err
+	}
+
+	// mixed global and database privileges.
+	rs, err := show("alice")
+	assert.NoError(err)
+	assert.Equal([]string{"database", "privileges"}, rs.columns)
+	assert.Equal([][]any{
+		{"*", "DEBUG,READ"},
+		{"db1", "READ,WRITE,DDL"},
+		{"db2", "READ"},
+	}, rs.rows)
+
+	// no privileges.
+	rs, err = show("bob")
+	assert.NoError(err)
+	assert.Equal([][]any{{"*", ""}}, rs.rows)
+
+	// the system administrator.
+	rs, err = show("root")
+	assert.NoError(err)
+	assert.Equal([][]any{{"*", "ADMIN"}}, rs.rows)
+
+	_, err = show("carol")
+	assert.Equal(meta.ErrUserNotExists, err)
+}
+
+func TestShowUserPagination(t *testing.T) {
+	assert := assert.New(t)
+
+	defer func(fn func() []*meta.User) { users = fn }(users)
+	users = func() []*meta.User {
+		return []*meta.User{{Name: "a"}, {Name: "b"}, {Name: "c"}, {Name: "d"}, {Name: "e", Disabled: true}}
+	}
+
+	var names []string
+	stmt := &ShowUserStatement{Pagination: Pagination{Limit: 2}}
+	for i := 0; i < 10; i++ {
+		rs := &testResultSet{}
+		assert.NoError(stmt.Execute(context.Background(), rs))
+		for _, row := range rs.rows {
+			names = append(names, row[0].(string))
+		}
+		if rs.nextCursor == "" {
+			// the final page.
+			assert.Len(rs.rows, 1)
+			break
+		}
+		assert.Len(rs.rows, 2)
+		assert.Equal(rs.rows[1][0], rs.nextCursor)
+		stmt.After = rs.nextCursor
+	}
+	assert.Equal([]string{"a", "b", "c", "d", "e"}, names)
+
+	// exactly fits the limit, no more rows.
+	rs := &testResultSet{}
+	stmt = &ShowUserStatement{Pagination: Pagination{After: "b", Limit: 3}}
+	assert.NoError(stmt.Execute(context.Background(), rs))
+	assert.Len(rs.rows, 3)
+	assert.Empty(rs.nextCursor)
+
+	// no limit.
+	rs = &testResultSet{}
+	stmt = &ShowUserStatement{}
+	assert.NoError(stmt.Execute(context.Background(), rs))
+	assert.Len(rs.rows, 5)
+	assert.Empty(rs.nextCursor)
+	assert.Equal([]string{"name", "isSystem", "privileges", "isDisabled"}, rs.columns)
+	assert.Equal([]any{"e", false, "", true}, rs.rows[4])
+}
+
+func TestShowUserOffset(t *testing.T) {
+	assert := assert.New(t)
+
+	defer func(fn func() []*meta.User) { users = fn }(users)
+	users = func() []*meta.User {
+		return []*meta.User{{Name: "a"}, {Name: "b"}, {Name: "c"}, {Name: "d"}, {Name: "e"}}
+	}
+
+	window := func(p Pagination) ([]string, string) {
+		rs := &testResultSet{}
+		stmt := &ShowUserStatement{Pagination: p}
+		assert.NoError(stmt.Execute(context.Background(), rs))
+		names := []string{}
+		for _, row := range rs.rows {
+			names = append(names, row[0].(string))
+		}
+		return names, rs.nextCursor
+	}
+
+	names, cursor := window(Pagination{Limit: 2, Offset: 1})
+	assert.Equal([]string{"b", "c"}, names)
+	assert.Equal("c", cursor)
+
+	names, cursor = window(Pagination{Limit: 10, Offset: 3})
+	assert.Equal([]string{"d", "e"}, names)
+	assert.Empty(cursor)
+
+	names, cursor = window(Pagination{Limit: 2})
+	assert.Equal([]string{"a", "b"}, names)
+	assert.Equal("b", cursor)
+
+	// offset is applied after 'After'.
+	names, _ = window(Pagination{After: "a", Limit: 2, Offset: 2})
+	assert.Equal([]string{"d", "e"}, names)
+
+	// offset beyond the end.
+	names, cursor = window(Pagination{Limit: 2, Offset: 5})
+	assert.Empty(names)
+	assert.Empty(cursor)
+}
+
+func TestShowDatabase(t *testing.T) {
+	assert := assert.New(t)
+
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	updated := created.Add(time.Hour)
+	defer func(fn func() []*meta.Database) { databases = fn }(databases)
+	databases = func() []*meta.Database {
+		return []*meta.Database{
+			{Name: "db1", Duration: 14 * 24 * time.Hour, CreatedAt: created, UpdatedAt: updated},
+		}
+	}
+
+	rs := &testResultSet{}
+	assert.NoError((&ShowDatabaseStatement{}).Execute(context.Background(), rs))
+	assert.Equal([]string{"name", "duration", "createdAt", "updatedAt"}, rs.columns)
+	assert.Equal([]string{ColumnTypeString, ColumnTypeDuration, ColumnTypeTime, ColumnTypeTime}, rs.types)
+	assert.Equal([][]any{{"db1", Duration(14 * 24 * time.Hour), created, updated}}, rs.rows)
+}
+
+func TestSelectGroupBy(t *testing.T) {
+	assert := assert.New(t)
+
+	defer func(fn func() []meta.NodeStatus) { nodeStatuses = fn }(nodeStatuses)
+	nodeStatuses = func() []meta.NodeStatus {
+		return []meta.NodeStatus{
+			{NodeInfo: meta.NodeInfo{ID: "1", Role: meta.NodeRoleMeta}, State: "up"},
+			{NodeInfo: meta.NodeInfo{ID: "2", Role: meta.NodeRoleData}, State: "up"},
+			{NodeInfo: meta.NodeInfo{ID: "3", Role: meta.NodeRoleMeta}, State: "down"},
+			{NodeInfo: meta.NodeInfo{ID: "4", Role: meta.NodeRoleData}, State: "up"},
+			{NodeInfo: meta.NodeInfo{ID: "5", Role: meta.NodeRoleMeta}, State: "up"},
+		}
+	}
+
+	stmt := &SelectStatement{
+		Source: &ShowNodeStatement{},
+		Fields: []*Field{
+			{Column: "role"},
+			{Func: "count", Column: "*"},
+			{Func: "min", Column: "id"},
+			{Func: "max", Column: "id"},
+		},
+		GroupBy: []string{"role"},
+	}
+	assert.NoError(stmt.Validate())
+
+	rs := &testResultSet{}
+	assert.NoError(stmt.Execute(context.Background(), rs))
+	assert.Equal([]string{"role", "count(*)", "min(id)", "max(id)"}, rs.columns)
+	assert.Equal([][]any{
+		{"meta", int64(3), "1", "5"},
+		{"data", int64(2), "2", "4"},
+	}, rs.rows)
+
+	// group by multiple columns.
+	stmt = &SelectStatement{
+		Source:  &ShowNodeStatement{},
+		Fields:  []*Field{{Column: "role"}, {Column: "state"}, {Func: "count", Column: "id"}},
+		GroupBy: []string{"role", "state"},
+	}
+	rs = &testResultSet{}
+	assert.NoError(stmt.Execute(context.Background(), rs))
+	assert.Equal([][]any{
+		{"meta", "up", int64(2)},
+		{"data", "up", int64(2)},
+		{"meta", "down", int64(1)},
+	}, rs.rows)
+
+	// aggregation without GROUP BY.
+	stmt = &SelectStatement{
+		Source: &ShowNodeStatement{},
+		Fields: []*Field{{Func: "count", Column: "*"}},
+	}
+	rs = &testResultSet{}
+	assert.NoError(stmt.Execute(context.Background(), rs))
+	assert.Equal([][]any{{int64(5)}}, rs.rows)
+
+	// projection.
+	stmt = &SelectStatement{
+		Source: &ShowNodeStatement{},
+		Fields: []*Field{{Column: "ID"}, {Column: "state"}},
+	}
+	rs = &testResultSet{}
+	assert.NoError(stmt.Execute(context.Background(), rs))
+	assert.Equal([]string{"ID", "state"}, rs.columns)
+	assert.Len(rs.rows, 5)
+	assert.Equal([]any{"3", "down"}, rs.rows[2])
+
+	// unknown column.
+	stmt = &SelectStatement{
+		Source: &ShowNodeStatement{},
+		Fields: []*Field{{Column: "unknown"}},
+	}
+	assert.EqualError(stmt.Execute(context.Background(), &testResultSet{}), "unknown column: unknown")
+
+	// durations are compared by their values, not their text.
+	assert.Negative(compareValues(Duration(9*time.Second), Duration(10*time.Second)))
+	assert.Zero(compareValues(Duration(time.Hour), Duration(time.Hour)))
+}
+
+func TestShowProjection(t *testing.T) {
+	assert := assert.New(t)
+
+	defer func(fn func() []meta.NodeStatus) { nodeStatuses = fn }(nodeStatuses)
+	now := time.Now()
+	nodeStatuses = func() []meta.NodeStatus {
+		return []meta.NodeStatus{
+			{NodeInfo: meta.NodeInfo{ID: "1", Addr: "10.0.0.1:8086", LastHeartbeatTime: now}, State: "up"},
+			{NodeInfo: meta.NodeInfo{ID: "2", Addr: "10.0.0.2:8086", LastHeartbeatTime: now}, State: "down"},
+			{NodeInfo: meta.NodeInfo{ID: "3", Addr: "10.0.0.3:8086", LastHeartbeatTime: now}, State: "up"},
+		}
+	}
+
+	stmt := &ShowNodeStatement{
+		Projection: Projection{Columns: []ColumnAlias{
+			{Column: "id"},
+			{Column: "heartbeatTime", Alias: "last_seen"},
+			{Column: "ADDR", Alias: "address"},
+		}},
+		Pagination: Pagination{Limit: 2},
+	}
+	rs := &testResultSet{}
+	assert.NoError(stmt.Execute(context.Background(), rs))
+	assert.Equal([]string{"id", "last_seen", "address"}, rs.columns)
+	assert.Equal([]string{ColumnTypeString, ColumnTypeTime, ColumnTypeString}, rs.types)
+	assert.Equal([][]any{
+		{"1", now, "10.0.0.1:8086"},
+		{"2", now, "10.0.0.2:8086"},
+	}, rs.rows)
+	assert.Equal("2", rs.nextCursor)
+
+	// unknown column.
+	stmt = &ShowNodeStatement{
+		Projection: Projection{Columns: []ColumnAlias{{Column: "id"}, {Column: "unknown"}}},
+	}
+	rs = &testResultSet{}
+	assert.EqualError(stmt.Execute(context.Background(), rs), "unknown column: unknown")
+	assert.EqualError(rs.err, "unknown column: unknown")
+	assert.Empty(rs.rows)
+}
+
+func TestAddRowMap(t *testing.T) {
+	assert := assert.New(t)
+
+	rs := &rowSet{}
+	rs.SetColumns("id", "name", "age")
+	assert.NoError(rs.AddRowMap(map[string]any{"name": "alice", "age": 18, "id": 1}))
+	assert.Equal([][]any{{1, "alice", 18}}, rs.rows)
+
+	assert.EqualError(rs.AddRowMap(map[string]any{"id": 2, "name": "bob", "age": 20, "sex": "m"}), "unknown column: sex")
+	assert.EqualError(rs.err, "unknown column: sex")
+	assert.Len(rs.rows, 1)
+
+	rs = &rowSet{}
+	rs.SetColumns("id", "name")
+	assert.EqualError(rs.AddRowMap(map[string]any{"id": 2}), "missing value of column: name")
+
+	// only the selected columns are output by a projected result set.
+	trs := &testResultSet{}
+	p := &Projection{Columns: []ColumnAlias{{Column: "name"}, {Column: "id", Alias: "ID"}}}
+	prs := p.project(trs)
+	prs.SetColumns("id", "name", "age")
+	assert.NoError(prs.AddRowMap(map[string]any{"age": 18, "name": "alice", "id": 1}))
+	assert.Equal([]string{"name", "ID"}, trs.columns)
+	assert.Equal([][]any{{"alice", 1}}, trs.rows)
+	assert.EqualError(prs.AddRowMap(map[string]any{"ID": 2}), "unknown column: ID")
+	assert.EqualError(trs.err, "unknown column: ID")
+}
+
+func TestShowNodeLabels(t *testing.T) {
+	assert := assert.New(t)
+
+	defer func(fn func() []meta.NodeStatus) { nodeStatuses = fn }(nodeStatuses)
+	nodeStatuses = func() []meta.NodeStatus {
+		return []meta.NodeStatus{
+			{NodeInfo: meta.NodeInfo{ID: "1", Labels: map[string]string{"zone": "z1", "rack": "r1"}}},
+			{NodeInfo: meta.NodeInfo{ID: "2"}},
+		}
+	}
+
+	// labels are not output by default.
+	rs := &testResultSet{}
+	assert.NoError((&ShowNodeStatement{}).Execute(context.Background(), rs))
+	assert.NotContains(rs.columns, "labels")
+
+	stmt := &ShowNodeStatement{
+		Projection: Projection{Columns: []ColumnAlias{{Column: "id"}, {Column: "LABELS"}}},
+	}
+	rs = &testResultSet{}
+	assert.NoError(stmt.Execute(context.Background(), rs))
+	assert.Equal([]string{"id", "labels"}, rs.columns)
+	assert.Equal([][]any{{"1", "rack=r1,zone=z1"}, {"2", ""}}, rs.rows)
+}
+
+// cancelResultSet is a testResultSet which cancels the context after the
+// first row is added.
+type cancelResultSet struct {
+	testResultSet
+	cancel context.CancelFunc
+}
+
+func (rs *cancelResultSet) AddRow(vals ...any) error {
+	rs.cancel()
+	return rs.testResultSet.AddRow(vals...)
+}
+
+func TestExecuteCanceled(t *testing.T) {
+	assert := assert.New(t)
+
+	defer func(fn func() []meta.NodeStatus) { nodeStatuses = fn }(nodeStatuses)
+	nodeStatuses = func() []meta.NodeStatus {
+		return []meta.NodeStatus{
+			{NodeInfo: meta.NodeInfo{ID: "1"}},
+			{NodeInfo: meta.NodeInfo{ID: "2"}},
+			{NodeInfo: meta.NodeInfo{ID: "3"}},
+		}
+	}
+
+	// canceled before execution.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	rs := &testResultSet{}
+	assert.Equal(context.Canceled, (&ShowNodeStatement{}).Execute(ctx, rs))
+	assert.Empty(rs.rows)
+
+	// canceled between rows.
+	ctx, cancel = context.WithCancel(context.Background())
+	crs := &cancelResultSet{cancel: cancel}
+	assert.Equal(context.Canceled, (&ShowNodeStatement{}).Execute(ctx, crs))
+	assert.Len(crs.rows, 1)
+
+	// the source of a select statement is canceled.
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	stmt := &SelectStatement{
+		Fields: []*Field{{Column: "id"}},
+		Source: &ShowNodeStatement{},
+	}
+	assert.Equal(context.Canceled, stmt.Execute(ctx, &testResultSet{}))
+}
+
+func TestExplain(t *testing.T) {
+	assert := assert.New(t)
+
+	defer func(fn func() []*meta.User) { users = fn }(users)
+	users = func() []*meta.User {
+		t.Fatal("the user store should not be accessed")
+		return nil
+	}
+
+	stmt := &ExplainStatement{Statement: &ShowUserStatement{
+		Projection: Projection{Columns: []ColumnAlias{{Column: "name", Alias: "user"}}},
+		Pagination: Pagination{Limit: 10},
+	}}
+	rs := &testResultSet{}
+	assert.NoError(stmt.Execute(context.Background(), rs))
+	assert.Equal([]string{"plan"}, rs.columns)
+	assert.Equal([][]any{
+		{"ShowUserStatement"},
+		{"  columns: name AS user"},
+		{"  limit: 10"},
+	}, rs.rows)
+
+	stmt = &ExplainStatement{Statement: &CreateUserStatement{
+		User: meta.User{Name: "alice", Password: "secret", Priv: meta.PrivilegeRead},
+	}}
+	rs = &testResultSet{}
+	assert.NoError(stmt.Execute(context.Background(), rs))
+	assert.Equal([][]any{
+		{"CreateUserStatement"},
+		{"  name: alice"},
+		{"  privileges: READ"},
+		{"  ifNotExists: false"},
+	}, rs.rows)
+
+	stmt = &ExplainStatement{Statement: &SelectStatement{
+		Source:  &ShowNodeStatement{},
+		Fields:  []*Field{{Column: "role"}, {Func: "count", Column: "*"}},
+		GroupBy: []string{"role"},
+	}}
+	rs = &testResultSet{}
+	assert.NoError(stmt.Execute(context.Background(), rs))
+	assert.Equal([][]any{
+		{"SelectStatement"},
+		{"  fields: role, count(*)"},
+		{"  groupBy: role"},
+		{"  source:"},
+		{"    ShowNodeStatement"},
+	}, rs.rows)
+}
+
+func TestShowRaftStats(t *testing.T) {
+	assert := assert.New(t)
+
+	defer func(fn func() map[string]string) { raftStats = fn }(raftStats)
+	raftStats = func() map[string]string {
+		return map[string]string{"term": "2", "state": "Leader", "last_log_index": "10"}
+	}
+
+	rs := &testResultSet{}
+	assert.NoError((&ShowRaftStatsStatement{}).Execute(context.Background(), rs))
+	assert.Equal([]string{"key", "value"}, rs.columns)
+	assert.Equal([][]any{
+		{"last_log_index", "10"},
+		{"state", "Leader"},
+		{"term", "2"},
+	}, rs.rows)
+}
+
+func TestShowConfig(t *testing.T) {
+	assert := assert.New(t)
+
+	defer func(fn func() *config.NodeConfig) { currentNodeConfig = fn }(currentNodeConfig)
+	currentNodeConfig = func() *config.NodeConfig {
+		return &config.NodeConfig{
+			ID:         "1",
+			TLSKeyFile: "/etc/xuandb/node.key",
+			Logger:     &config.LoggerConfig{Level: slog.LevelWarn},
+			Meta:       &config.MetaConfig{RaftAddr: "127.0.0.1:8001"},
+		}
+	}
+
+	rs := &testResultSet{}
+	assert.NoError((&ShowConfigStatement{}).Execute(context.Background(), rs))
+	assert.Equal([]string{"key", "value"}, rs.columns)
+	assert.Contains(rs.rows, []any{"id", "1"})
+	assert.Contains(rs.rows, []any{"tls-key-file", "***"})
+	assert.Contains(rs.rows, []any{"logger.level", "WARN"})
+	assert.Contains(rs.rows, []any{"meta.raft-addr", "127.0.0.1:8001"})
+	assert.True((&ShowConfigStatement{}).ReadOnly())
+}
+
+func TestShowVersion(t *testing.T) {
+	assert := assert.New(t)
+
+	rs := &testResultSet{}
+	assert.NoError((&ShowVersionStatement{}).Execute(context.Background(), rs))
+	assert.Equal([]string{"version", "goVersion", "revision", "localModified"}, rs.columns)
+	if assert.Len(rs.rows, 1) {
+		assert.Equal(runtime.Version(), rs.rows[0][1])
+	}
+}
+
+func TestShowClusterVersions(t *testing.T) {
+	assert := assert.New(t)
+
+	local := version.Get()
+	other := local
+	other.Version, other.Revision = "0.0.1-other", "other"
+
+	defer func(fn func() map[string]meta.NodeVersion) { clusterVersions = fn }(clusterVersions)
+	clusterVersions = func() map[string]meta.NodeVersion {
+		return map[string]meta.NodeVersion{
+			"2": {Info: other, Addr: "127.0.0.1:7002"},
+			"1": {Info: local, Addr: "127.0.0.1:7001"},
+			"3": {Addr: "127.0.0.1:7003", Err: xerrors.New(http.StatusInternalServerError, "unreachable")},
+		}
+	}
+
+	rs := &testResultSet{}
+	assert.NoError((&ShowClusterVersionsStatement{}).Execute(context.Background(), rs))
+	assert.Equal([]string{"id", "addr", "version", "revision", "consistent", "error"}, rs.columns)
+	assert.Equal([][]any{
+		{"1", "127.0.0.1:7001", local.Version, local.Revision, true, ""},
+		{"2", "127.0.0.1:7002", "0.0.1-other", "other", false, ""},
+		{"3", "127.0.0.1:7003", "", "", false, "unreachable"},
+	}, rs.rows)
+}
+
+func TestShowQueries(t *testing.T) {
+	assert := assert.New(t)
+
+	start := time.Now().Add(-time.Minute)
+	defer func(fn func() []registry.Query) { activeQueries = fn }(activeQueries)
+	activeQueries = func() []registry.Query {
+		return []registry.Query{
+			{ID: 1, SQL: "SHOW NODE", User: "alice", Client: "127.0.0.1:1234", Start: start},
+		}
+	}
+
+	rs := &testResultSet{}
+	assert.NoError((&ShowQueriesStatement{}).Execute(context.Background(), rs))
+	assert.Equal([]string{"id", "user", "client", "start", "duration", "sql"}, rs.columns)
+	if assert.Len(rs.rows, 1) {
+		row := rs.rows[0]
+		assert.Equal([]any{uint64(1), "alice", "127.0.0.1:1234", start}, row[:4])
+		assert.GreaterOrEqual(row[4].(Duration), Duration(time.Minute))
+		assert.Equal("SHOW NODE", row[5])
+	}
+}
+
+func TestKillQuery(t *testing.T) {
+	assert := assert.New(t)
+
+	var killed []uint64
+	defer func(fn func(uint64) bool) { killQuery = fn }(killQuery)
+	killQuery = func(id uint64) bool {
+		killed = append(killed, id)
+		return id == 1
+	}
+
+	assert.NoError((&KillQueryStatement{ID: 1}).Execute(context.Background(), &testResultSet{}))
+	err := (&KillQueryStatement{ID: 2}).Execute(context.Background(), &testResultSet{})
+	assert.EqualError(err, "query not found: 2")
+	assert.Equal(http.StatusNotFound, err.(*xerrors.StatusError).StatusCode)
+	assert.Equal([]uint64{1, 2}, killed)
+}
+
+func TestFlushCache(t *testing.T) {
+	assert := assert.New(t)
+
+	flushed := 0
+	cache.Register("ast-test", func() { flushed++ })
+
+	rs := &testResultSet{}
+	assert.NoError((&FlushCacheStatement{Name: "AST-TEST"}).Execute(context.Background(), rs))
+	assert.Equal(1, flushed)
+	assert.Equal([]string{"cache"}, rs.columns)
+	assert.Equal([][]any{{"ast-test"}}, rs.rows)
+
+	rs = &testResultSet{}
+	assert.NoError((&FlushCacheStatement{}).Execute(context.Background(), rs))
+	assert.Equal(2, flushed)
+	assert.Contains(rs.rows, []any{"ast-test"})
+
+	assert.ErrorContains((&FlushCacheStatement{Name: "unknown"}).Execute(context.Background(), &testResultSet{}), "unknown cache: unknown")
+}
+
+func TestShowRaftLog(t *testing.T) {
+	assert := assert.New(t)
+
+	defer func(fn func(uint64, uint64, bool) ([]meta.RaftLogEntry, error)) { raftLogs = fn }(raftLogs)
+	raftLogs = func(from, to uint64, withOp bool) ([]meta.RaftLogEntry, error) {
+		assert.EqualValues(2, from)
+		assert.EqualValues(3, to)
+		assert.True(withOp)
+		return []meta.RaftLogEntry{
+			{Index: 2, Term: 1, Type: "LogConfiguration", Size: 10},
+			{Index: 3, Term: 1, Type: "LogCommand", Size: 20, Op: "create-user"},
+		}, nil
+	}
+
+	rs := &testResultSet{}
+	assert.NoError((&ShowRaftLogStatement{From: 2, To: 3}).Execute(context.Background(), rs))
+	assert.Equal([]string{"index", "term", "type", "size", "op"}, rs.columns)
+	assert.Equal([][]any{
+		{uint64(2), uint64(1), "LogConfiguration", 10, ""},
+		{uint64(3), uint64(1), "LogCommand", 20, "create-user"},
+	}, rs.rows)
+}
@@ -0,0 +1,162 @@
+package ast
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/localvar/xuandb/pkg/httpserver"
+	"github.com/localvar/xuandb/pkg/meta"
+	"github.com/localvar/xuandb/pkg/xerrors"
+)
+
+// the nodes of the cluster, and the timeout of the log level request to a
+// single node.
+var (
+	nodes           = meta.Nodes
+	logLevelTimeout = 5 * time.Second
+)
+
+// nodeLogLevel gets the log level of the node at 'addr' if 'level' is empty,
+// or sets it to 'level' if not. 'name' and 'pwd' are the credentials to
+// access the debug endpoint of the node. It returns the log level of the
+// node after the request.
+func nodeLogLevel(ctx context.Context, addr, level, name, pwd string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, logLevelTimeout)
+	defer cancel()
+
+	var req *http.Request
+	var err error
+	u := httpserver.URL(addr, "/debug/logger/level")
+	if level == "" {
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	} else {
+		body := strings.NewReader(url.Values{"value": {level}}.Encode())
+		req, err = http.NewRequestWithContext(ctx, http.MethodPost, u, body)
+		if err == nil {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+	if name != "" {
+		req.SetBasicAuth(name, pwd)
+	}
+
+	resp, err := httpserver.Client().Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		se := xerrors.FromHTTPResponse(resp).(*xerrors.StatusError)
+		se.Msg = strings.TrimSpace(se.Msg)
+		return "", se
+	}
+
+	if level != "" {
+		io.Copy(io.Discard, resp.Body)
+		return level, nil
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// clusterLogLevel gets or sets the log level of all nodes concurrently, see
+// nodeLogLevel for the details. The result of every node, including the
+// failures, is added to 'rs' as a row.
+func clusterLogLevel(ctx context.Context, rs ResultSet, level, name, pwd string) error {
+	nis := nodes()
+
+	levels := make([]string, len(nis))
+	errs := make([]error, len(nis))
+	var wg sync.WaitGroup
+	for i := range nis {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			levels[i], errs[i] = nodeLogLevel(ctx, nis[i].Addr, level, name, pwd)
+		}(i)
+	}
+	wg.Wait()
+
+	rs.SetColumns("id", "addr", "level", "error")
+	for i, ni := range nis {
+		msg := ""
+		if err := errs[i]; err != nil {
+			msg = err.Error()
+			slog.Warn(
+				"failed to access log level of node",
+				slog.String("nodeId", ni.ID),
+				slog.String("error", msg),
+			)
+		}
+		if err := rs.AddRow(ni.ID, ni.Addr, levels[i], msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// credentials records the credentials used to authenticate a statement, so
+// that the statement can use them to access other nodes on behalf of the
+// user.
+type credentials struct {
+	name string
+	pwd  string
+}
+
+// ShowClusterLogLevelStatement represents a command for showing the log level
+// of all nodes in the cluster.
+type ShowClusterLogLevelStatement struct {
+	adminStatement
+	credentials
+}
+
+func (stmt *ShowClusterLogLevelStatement) Auth(name, pwd string) error {
+	if err := stmt.adminStatement.Auth(name, pwd); err != nil {
+		return err
+	}
+	stmt.credentials = credentials{name: name, pwd: pwd}
+	return nil
+}
+
+func (stmt *ShowClusterLogLevelStatement) Execute(ctx context.Context, rs ResultSet) error {
+	return clusterLogLevel(ctx, rs, "", stmt.name, stmt.pwd)
+}
+
+// SetClusterLogLevelStatement represents a command for setting the log level
+// of all nodes in the cluster. Unreachable nodes do not fail the statement,
+// the result of every node is reported instead.
+type SetClusterLogLevelStatement struct {
+	adminStatement
+	credentials
+	Level string
+}
+
+func (stmt *SetClusterLogLevelStatement) Auth(name, pwd string) error {
+	if err := stmt.adminStatement.Auth(name, pwd); err != nil {
+		return err
+	}
+	stmt.credentials = credentials{name: name, pwd: pwd}
+	return nil
+}
+
+func (stmt *SetClusterLogLevelStatement) Execute(ctx context.Context, rs ResultSet) error {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(stmt.Level)); err != nil {
+		return xerrors.New(http.StatusBadRequest, "invalid log level: "+stmt.Level)
+	}
+	return clusterLogLevel(ctx, rs, lvl.String(), stmt.name, stmt.pwd)
+}
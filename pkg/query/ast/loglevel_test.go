@@ -0,0 +1,105 @@
+package ast
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/localvar/xuandb/pkg/meta"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubLogLevelNode is a stub node which serves the log level endpoint.
+type stubLogLevelNode struct {
+	*httptest.Server
+	mu    sync.Mutex
+	level string
+	user  string
+}
+
+func newStubLogLevelNode(t *testing.T) *stubLogLevelNode {
+	n := &stubLogLevelNode{level: "INFO"}
+	n.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n.mu.Lock()
+		defer n.mu.Unlock()
+		n.user, _, _ = r.BasicAuth()
+		if r.Method == http.MethodPost {
+			n.level = r.FormValue("value")
+			return
+		}
+		w.Write([]byte(n.level))
+	}))
+	t.Cleanup(n.Close)
+	return n
+}
+
+// state returns the log level of the node and the user of the last request.
+func (n *stubLogLevelNode) state() (string, string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.level, n.user
+}
+
+// addr returns the address of the node without the scheme.
+func (n *stubLogLevelNode) addr() string {
+	return strings.TrimPrefix(n.URL, "http://")
+}
+
+func TestClusterLogLevel(t *testing.T) {
+	assert := assert.New(t)
+
+	n1, n2 := newStubLogLevelNode(t), newStubLogLevelNode(t)
+
+	// node 3 is unreachable and node 4 refuses the request.
+	n3 := httptest.NewServer(http.NotFoundHandler())
+	n3.Close()
+	n4 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "insufficient privileges", http.StatusForbidden)
+	}))
+	defer n4.Close()
+
+	defer func(fn func() []meta.NodeInfo) { nodes = fn }(nodes)
+	nodes = func() []meta.NodeInfo {
+		return []meta.NodeInfo{
+			{ID: "1", Addr: n1.addr()},
+			{ID: "2", Addr: n2.addr()},
+			{ID: "3", Addr: strings.TrimPrefix(n3.URL, "http://")},
+			{ID: "4", Addr: strings.TrimPrefix(n4.URL, "http://")},
+		}
+	}
+
+	stmt := &SetClusterLogLevelStatement{Level: "debug"}
+	stmt.credentials = credentials{name: "admin", pwd: "pwd"}
+	rs := &testResultSet{}
+	assert.NoError(stmt.Execute(context.Background(), rs))
+	assert.Equal([]string{"id", "addr", "level", "error"}, rs.columns)
+	assert.Len(rs.rows, 4)
+	assert.Equal([]any{"1", n1.addr(), "DEBUG", ""}, rs.rows[0])
+	assert.Equal([]any{"2", n2.addr(), "DEBUG", ""}, rs.rows[1])
+	assert.Equal("", rs.rows[2][2])
+	assert.NotEmpty(rs.rows[2][3])
+	assert.Equal([]any{"4", strings.TrimPrefix(n4.URL, "http://"), "", "insufficient privileges"}, rs.rows[3])
+
+	// the level is set on each reachable node with the user's credentials.
+	level, user := n1.state()
+	assert.Equal("DEBUG", level)
+	assert.Equal("admin", user)
+	level, _ = n2.state()
+	assert.Equal("DEBUG", level)
+
+	n2.mu.Lock()
+	n2.level = "WARN"
+	n2.mu.Unlock()
+	show := &ShowClusterLogLevelStatement{}
+	rs = &testResultSet{}
+	assert.NoError(show.Execute(context.Background(), rs))
+	assert.Equal([]any{"1", n1.addr(), "DEBUG", ""}, rs.rows[0])
+	assert.Equal([]any{"2", n2.addr(), "WARN", ""}, rs.rows[1])
+	assert.NotEmpty(rs.rows[2][3])
+
+	err := (&SetClusterLogLevelStatement{Level: "verbose"}).Execute(context.Background(), &testResultSet{})
+	assert.EqualError(err, "invalid log level: verbose")
+}
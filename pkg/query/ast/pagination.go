@@ -0,0 +1,43 @@
+package ast
+
+// Pagination represents the pagination of a SHOW statement, it supports both
+// keyset pagination by 'After' and offset pagination by 'Offset'.
+type Pagination struct {
+	// After is the cursor to continue from, only rows whose key is greater
+	// than it are returned. Empty means from the first row.
+	After string
+
+	// Offset is the number of rows to skip, after applying 'After'.
+	Offset uint64
+
+	// Limit is the maximum number of rows to return, 0 means no limit.
+	Limit uint64
+}
+
+// paginate returns the rows of 'items' that belong to the page specified by
+// 'p', that is, at most 'p.Limit' rows after 'p.After', with the first
+// 'p.Offset' rows skipped. 'items' must be sorted by 'key' in ascending order.
+// If there are more rows after the page, the key of the last row of the page
+// is set to 'rs' as the next cursor.
+func paginate[T any](p *Pagination, rs ResultSet, items []T, key func(T) string) []T {
+	if p.After != "" {
+		i := 0
+		for i < len(items) && key(items[i]) <= p.After {
+			i++
+		}
+		items = items[i:]
+	}
+
+	if p.Offset >= uint64(len(items)) {
+		return nil
+	}
+	items = items[p.Offset:]
+
+	if p.Limit == 0 || uint64(len(items)) <= p.Limit {
+		return items
+	}
+
+	items = items[:p.Limit]
+	rs.SetNextCursor(key(items[len(items)-1]))
+	return items
+}
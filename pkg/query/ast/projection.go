@@ -0,0 +1,153 @@
+package ast
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/localvar/xuandb/pkg/xerrors"
+)
+
+// ColumnAlias selects a column from the output of a SHOW statement and
+// optionally renames it.
+type ColumnAlias struct {
+	// Column is the name of the column to select, case-insensitive.
+	Column string
+
+	// Alias is the new name of the column, empty means not renamed.
+	Alias string
+}
+
+// Projection selects and renames a subset of the output columns of a SHOW
+// statement, e.g. 'SHOW NODE (id, addr AS address)'.
+type Projection struct {
+	// Columns are the selected columns in output order, empty means all
+	// columns are selected and none is renamed.
+	Columns []ColumnAlias
+}
+
+// project wraps 'rs' so that only the columns selected by 'p' are output.
+// It returns 'rs' itself if 'p' selects all columns.
+func (p *Projection) project(rs ResultSet) ResultSet {
+	if len(p.Columns) == 0 {
+		return rs
+	}
+	return &projectedResultSet{ResultSet: rs, p: p}
+}
+
+// selects returns whether column 'name' is explicitly selected by 'p'.
+func (p *Projection) selects(name string) bool {
+	for _, c := range p.Columns {
+		if strings.EqualFold(c.Column, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// projectedResultSet is a ResultSet which only outputs the selected columns
+// to the underlying ResultSet.
+type projectedResultSet struct {
+	ResultSet
+	p       *Projection
+	idx     []int    // indexes of the selected columns in the source columns
+	columns []string // the source columns
+	err     error
+}
+
+// SetColumns validates the selected columns exist in 'columns', and sets the
+// selected columns with their aliases to the underlying ResultSet.
+func (prs *projectedResultSet) SetColumns(columns ...string) {
+	if names := prs.selectColumns(columns); names != nil {
+		prs.ResultSet.SetColumns(names...)
+	}
+}
+
+// SetColumnsWithTypes is like SetColumns, but also sets the types of the
+// selected columns.
+func (prs *projectedResultSet) SetColumnsWithTypes(columns ...Column) {
+	names, types := SplitColumns(columns)
+	names = prs.selectColumns(names)
+	if names == nil {
+		return
+	}
+
+	selected := make([]Column, len(names))
+	for i, idx := range prs.idx {
+		selected[i] = Column{Name: names[i], Type: types[idx]}
+	}
+	prs.ResultSet.SetColumnsWithTypes(selected...)
+}
+
+// selectColumns validates the selected columns exist in 'columns', and
+// returns the names of the selected columns with their aliases. It returns
+// nil on error, which is also set to the underlying ResultSet.
+func (prs *projectedResultSet) selectColumns(columns []string) []string {
+	names := make([]string, len(prs.p.Columns))
+	prs.idx = make([]int, len(prs.p.Columns))
+	for i, c := range prs.p.Columns {
+		idx := indexFold(columns, c.Column)
+		if idx < 0 {
+			prs.err = xerrors.New(http.StatusBadRequest, "unknown column: "+c.Column)
+			prs.ResultSet.SetError(prs.err)
+			return nil
+		}
+
+		prs.idx[i] = idx
+		if c.Alias != "" {
+			names[i] = c.Alias
+		} else {
+			names[i] = columns[idx]
+		}
+	}
+
+	prs.columns = columns
+	return names
+}
+
+// AddRow adds the values of the selected columns to the underlying ResultSet.
+func (prs *projectedResultSet) AddRow(vals ...any) error {
+	if prs.err != nil {
+		return prs.err
+	}
+
+	// let the underlying ResultSet report the misuse.
+	if prs.idx == nil {
+		return prs.ResultSet.AddRow(vals...)
+	}
+
+	if len(vals) != len(prs.columns) {
+		prs.err = errors.New("column count mismatch")
+		prs.ResultSet.SetError(prs.err)
+		return prs.err
+	}
+
+	selected := make([]any, len(prs.idx))
+	for i, idx := range prs.idx {
+		selected[i] = vals[idx]
+	}
+
+	return prs.ResultSet.AddRow(selected...)
+}
+
+// AddRowMap orders the values of 'row' by the source columns and adds the
+// values of the selected columns to the underlying ResultSet.
+func (prs *projectedResultSet) AddRowMap(row map[string]any) error {
+	if prs.err != nil {
+		return prs.err
+	}
+
+	// let the underlying ResultSet report the misuse.
+	if prs.idx == nil {
+		return prs.ResultSet.AddRowMap(row)
+	}
+
+	vals, err := RowValues(prs.columns, row)
+	if err != nil {
+		prs.err = err
+		prs.ResultSet.SetError(prs.err)
+		return prs.err
+	}
+
+	return prs.AddRow(vals...)
+}
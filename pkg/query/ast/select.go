@@ -0,0 +1,344 @@
+package ast
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// rowSet is an in-memory ResultSet, it is used to collect the result of a
+// statement for further processing.
+type rowSet struct {
+	err     error
+	columns []string
+	rows    [][]any
+}
+
+func (rs *rowSet) SetError(err error) {
+	if rs.err == nil {
+		rs.err = err
+	}
+}
+
+func (rs *rowSet) SetColumns(columns ...string) {
+	if rs.columns != nil {
+		rs.SetError(errors.New("columns has already been set"))
+		return
+	}
+	rs.columns = columns
+}
+
+// SetColumnsWithTypes sets the columns, the types are dropped because they
+// are not needed for further processing.
+func (rs *rowSet) SetColumnsWithTypes(columns ...Column) {
+	names, _ := SplitColumns(columns)
+	rs.SetColumns(names...)
+}
+
+func (rs *rowSet) AddRow(vals ...any) error {
+	if rs.err != nil {
+		return rs.err
+	}
+	if len(vals) != len(rs.columns) {
+		rs.SetError(errors.New("column count mismatch"))
+		return rs.err
+	}
+	rs.rows = append(rs.rows, vals)
+	return nil
+}
+
+func (rs *rowSet) AddRowMap(row map[string]any) error {
+	if rs.err != nil {
+		return rs.err
+	}
+	vals, err := RowValues(rs.columns, row)
+	if err != nil {
+		rs.SetError(err)
+		return rs.err
+	}
+	return rs.AddRow(vals...)
+}
+
+func (rs *rowSet) SetNextCursor(cursor string) {
+	// pagination is not supported by rowSet, rows are always collected in
+	// a single page.
+}
+
+// Field is a field in the select list of a SelectStatement.
+type Field struct {
+	// Func is the name of the aggregate function in lower case, or empty if
+	// the field is not an aggregate.
+	Func string
+
+	// Column is the name of the column, '*' is only valid for 'count'.
+	Column string
+}
+
+// String returns the name of the field in the result set.
+func (f *Field) String() string {
+	if f.Func == "" {
+		return f.Column
+	}
+	return f.Func + "(" + f.Column + ")"
+}
+
+// SelectStatement represents a command for selecting fields from the result
+// of a SHOW statement, it supports grouping the rows and aggregating each
+// group with the 'count', 'min' and 'max' functions.
+type SelectStatement struct {
+	Source  Statement
+	Fields  []*Field
+	GroupBy []string
+}
+
+func (stmt *SelectStatement) Auth(name, pwd string) error {
+	return stmt.Source.Auth(name, pwd)
+}
+
+func (stmt *SelectStatement) ReadOnly() bool {
+	return true
+}
+
+// Validate validates the statement: aggregate functions must be known, and
+// non-aggregated fields must appear in the GROUP BY clause if the statement
+// is an aggregation.
+func (stmt *SelectStatement) Validate() error {
+	aggregate := len(stmt.GroupBy) > 0
+	for _, f := range stmt.Fields {
+		switch f.Func {
+		case "":
+			if f.Column == "*" {
+				return errors.New("'*' can only be used in 'count'")
+			}
+		case "count":
+			aggregate = true
+		case "min", "max":
+			if f.Column == "*" {
+				return fmt.Errorf("'*' cannot be used in '%s'", f.Func)
+			}
+			aggregate = true
+		default:
+			return fmt.Errorf("unknown aggregate function: %s", f.Func)
+		}
+	}
+
+	if !aggregate {
+		return nil
+	}
+
+	for _, f := range stmt.Fields {
+		if f.Func != "" {
+			continue
+		}
+		if indexFold(stmt.GroupBy, f.Column) < 0 {
+			return fmt.Errorf("column '%s' must appear in the GROUP BY clause or be used in an aggregate function", f.Column)
+		}
+	}
+
+	return nil
+}
+
+// indexFold returns the index of 'name' in 'names', the match is
+// case-insensitive. It returns -1 if not found.
+func indexFold(names []string, name string) int {
+	for i, n := range names {
+		if strings.EqualFold(n, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// columnIndex returns the index of column 'name' in 'columns'.
+func columnIndex(columns []string, name string) (int, error) {
+	if idx := indexFold(columns, name); idx >= 0 {
+		return idx, nil
+	}
+	return -1, fmt.Errorf("unknown column: %s", name)
+}
+
+// compareValues compares two values of the same type, nil is less than any
+// other value.
+func compareValues(a, b any) int {
+	if a == nil || b == nil {
+		switch {
+		case a == b:
+			return 0
+		case a == nil:
+			return -1
+		default:
+			return 1
+		}
+	}
+
+	switch va := a.(type) {
+	case string:
+		return cmp.Compare(va, b.(string))
+	case int:
+		return cmp.Compare(va, b.(int))
+	case int64:
+		return cmp.Compare(va, b.(int64))
+	case uint64:
+		return cmp.Compare(va, b.(uint64))
+	case float64:
+		return cmp.Compare(va, b.(float64))
+	case time.Duration:
+		return cmp.Compare(va, b.(time.Duration))
+	case Duration:
+		return cmp.Compare(va, b.(Duration))
+	case time.Time:
+		return va.Compare(b.(time.Time))
+	case bool:
+		vb := b.(bool)
+		switch {
+		case va == vb:
+			return 0
+		case !va:
+			return -1
+		default:
+			return 1
+		}
+	default:
+		return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+	}
+}
+
+// group is a group of rows which have the same values of the group key.
+type group struct {
+	key  []any // values of the GROUP BY columns
+	aggs []any // aggregated values of the fields
+}
+
+func (stmt *SelectStatement) Execute(ctx context.Context, rs ResultSet) error {
+	src := &rowSet{}
+	if err := stmt.Source.Execute(ctx, src); err != nil {
+		return err
+	}
+	if src.err != nil {
+		return src.err
+	}
+
+	// resolve column indexes.
+	fieldIdx := make([]int, len(stmt.Fields))
+	for i, f := range stmt.Fields {
+		if f.Column == "*" {
+			continue
+		}
+		idx, err := columnIndex(src.columns, f.Column)
+		if err != nil {
+			return err
+		}
+		fieldIdx[i] = idx
+	}
+
+	groupIdx := make([]int, len(stmt.GroupBy))
+	for i, name := range stmt.GroupBy {
+		idx, err := columnIndex(src.columns, name)
+		if err != nil {
+			return err
+		}
+		groupIdx[i] = idx
+	}
+
+	columns := make([]string, len(stmt.Fields))
+	aggregate := len(stmt.GroupBy) > 0
+	for i, f := range stmt.Fields {
+		columns[i] = f.String()
+		aggregate = aggregate || f.Func != ""
+	}
+	rs.SetColumns(columns...)
+
+	// no aggregation, just a projection.
+	if !aggregate {
+		for _, row := range src.rows {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			vals := make([]any, len(fieldIdx))
+			for i, idx := range fieldIdx {
+				vals[i] = row[idx]
+			}
+			if err := rs.AddRow(vals...); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// group the rows in the order of their first appearance.
+	var groups []*group
+	index := make(map[string]*group)
+	for _, row := range src.rows {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		var sb strings.Builder
+		key := make([]any, len(groupIdx))
+		for i, idx := range groupIdx {
+			key[i] = row[idx]
+			fmt.Fprintf(&sb, "%T:%v\x00", row[idx], row[idx])
+		}
+
+		g := index[sb.String()]
+		if g == nil {
+			g = &group{key: key, aggs: make([]any, len(stmt.Fields))}
+			index[sb.String()] = g
+			groups = append(groups, g)
+		}
+
+		for i, f := range stmt.Fields {
+			var v any
+			if f.Column != "*" {
+				v = row[fieldIdx[i]]
+			}
+
+			switch f.Func {
+			case "count":
+				n, _ := g.aggs[i].(int64)
+				if f.Column == "*" || v != nil {
+					n++
+				}
+				g.aggs[i] = n
+			case "min":
+				if g.aggs[i] == nil || (v != nil && compareValues(v, g.aggs[i]) < 0) {
+					g.aggs[i] = v
+				}
+			case "max":
+				if g.aggs[i] == nil || (v != nil && compareValues(v, g.aggs[i]) > 0) {
+					g.aggs[i] = v
+				}
+			}
+		}
+	}
+
+	// aggregation without GROUP BY always outputs a row.
+	if len(groups) == 0 && len(stmt.GroupBy) == 0 {
+		groups = append(groups, &group{aggs: make([]any, len(stmt.Fields))})
+	}
+
+	for _, g := range groups {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		vals := make([]any, len(stmt.Fields))
+		for i, f := range stmt.Fields {
+			switch f.Func {
+			case "":
+				vals[i] = g.key[indexFold(stmt.GroupBy, f.Column)]
+			case "count":
+				n, _ := g.aggs[i].(int64)
+				vals[i] = n
+			default:
+				vals[i] = g.aggs[i]
+			}
+		}
+		if err := rs.AddRow(vals...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
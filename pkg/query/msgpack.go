@@ -0,0 +1,230 @@
+package query
+
+import (
+	"encoding/binary"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/localvar/xuandb/pkg/query/ast"
+)
+
+// msgpackContentType is the media type of the result sets in the msgpack
+// format.
+const msgpackContentType = "application/msgpack"
+
+// The appendMsgpack* functions append a value in the msgpack format to 'b'
+// and return the extended buffer, the smallest representation is used.
+
+func appendMsgpackNil(b []byte) []byte {
+	return append(b, 0xc0)
+}
+
+func appendMsgpackBool(b []byte, v bool) []byte {
+	if v {
+		return append(b, 0xc3)
+	}
+	return append(b, 0xc2)
+}
+
+func appendMsgpackUint(b []byte, v uint64) []byte {
+	switch {
+	case v <= math.MaxInt8:
+		return append(b, byte(v))
+	case v <= math.MaxUint8:
+		return append(b, 0xcc, byte(v))
+	case v <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, 0xcd), uint16(v))
+	case v <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(b, 0xce), uint32(v))
+	default:
+		return binary.BigEndian.AppendUint64(append(b, 0xcf), v)
+	}
+}
+
+func appendMsgpackInt(b []byte, v int64) []byte {
+	switch {
+	case v >= 0:
+		return appendMsgpackUint(b, uint64(v))
+	case v >= -32:
+		return append(b, byte(v))
+	case v >= math.MinInt8:
+		return append(b, 0xd0, byte(v))
+	case v >= math.MinInt16:
+		return binary.BigEndian.AppendUint16(append(b, 0xd1), uint16(v))
+	case v >= math.MinInt32:
+		return binary.BigEndian.AppendUint32(append(b, 0xd2), uint32(v))
+	default:
+		return binary.BigEndian.AppendUint64(append(b, 0xd3), uint64(v))
+	}
+}
+
+func appendMsgpackFloat32(b []byte, v float32) []byte {
+	return binary.BigEndian.AppendUint32(append(b, 0xca), math.Float32bits(v))
+}
+
+func appendMsgpackFloat64(b []byte, v float64) []byte {
+	return binary.BigEndian.AppendUint64(append(b, 0xcb), math.Float64bits(v))
+}
+
+func appendMsgpackString(b []byte, s string) []byte {
+	switch n := len(s); {
+	case n < 32:
+		b = append(b, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		b = append(b, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		b = binary.BigEndian.AppendUint16(append(b, 0xda), uint16(n))
+	default:
+		b = binary.BigEndian.AppendUint32(append(b, 0xdb), uint32(n))
+	}
+	return append(b, s...)
+}
+
+func appendMsgpackArrayHeader(b []byte, n int) []byte {
+	switch {
+	case n < 16:
+		return append(b, 0x90|byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, 0xdc), uint16(n))
+	default:
+		return binary.BigEndian.AppendUint32(append(b, 0xdd), uint32(n))
+	}
+}
+
+func appendMsgpackMapHeader(b []byte, n int) []byte {
+	switch {
+	case n < 16:
+		return append(b, 0x80|byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, 0xde), uint16(n))
+	default:
+		return binary.BigEndian.AppendUint32(append(b, 0xdf), uint32(n))
+	}
+}
+
+// appendMsgpackValue appends value 'v' to 'b' in the msgpack format, the
+// values are encoded the same as writeValue except that the integers keep
+// their exact values and floats keep NaN and infinities.
+func appendMsgpackValue(b []byte, v any) []byte {
+	switch t := v.(type) {
+	case nil:
+		return appendMsgpackNil(b)
+	case int8:
+		return appendMsgpackInt(b, int64(t))
+	case int16:
+		return appendMsgpackInt(b, int64(t))
+	case int32:
+		return appendMsgpackInt(b, int64(t))
+	case int64:
+		return appendMsgpackInt(b, t)
+	case int:
+		return appendMsgpackInt(b, int64(t))
+	case uint8:
+		return appendMsgpackUint(b, uint64(t))
+	case uint16:
+		return appendMsgpackUint(b, uint64(t))
+	case uint32:
+		return appendMsgpackUint(b, uint64(t))
+	case uint64:
+		return appendMsgpackUint(b, t)
+	case uint:
+		return appendMsgpackUint(b, uint64(t))
+	case float32:
+		return appendMsgpackFloat32(b, t)
+	case float64:
+		return appendMsgpackFloat64(b, t)
+	case string:
+		return appendMsgpackString(b, t)
+	case time.Time:
+		return appendMsgpackInt(b, t.UnixNano())
+	case time.Duration:
+		return appendMsgpackInt(b, t.Nanoseconds())
+	case ast.Duration:
+		return appendMsgpackString(b, formatDuration(t))
+	case bool:
+		return appendMsgpackBool(b, t)
+	default:
+		panic("unexpected")
+	}
+}
+
+// addMsgpackRow adds a row to the result set in the msgpack format.
+func (rsw *resultSetWriter) addMsgpackRow(vals []any) {
+	b := appendMsgpackArrayHeader(rsw.buf.AvailableBuffer(), len(vals))
+	for _, v := range vals {
+		if d, ok := v.(ast.Duration); ok && rsw.rawDurations {
+			v = time.Duration(d)
+		}
+		b = appendMsgpackValue(b, v)
+	}
+	rsw.buf.Write(b)
+	rsw.numRow++
+}
+
+// flushMsgpack writes the result set to 'w' in the msgpack format. The
+// layout is the same as the JSON one, but the number of rows is unknown
+// until now, so only the rows are buffered and the rest is written here.
+func (rsw *resultSetWriter) flushMsgpack(w http.ResponseWriter) error {
+	n := 0
+	if rsw.columns != nil {
+		n += 2
+	}
+	if rsw.types != nil {
+		n++
+	}
+	if rsw.stats != nil {
+		n++
+	}
+
+	b := appendMsgpackMapHeader(nil, n)
+	if rsw.columns != nil {
+		b = appendMsgpackString(b, "columns")
+		b = appendMsgpackArrayHeader(b, len(rsw.columns))
+		for _, c := range rsw.columns {
+			b = appendMsgpackString(b, c)
+		}
+		if rsw.types != nil {
+			b = appendMsgpackString(b, "types")
+			b = appendMsgpackArrayHeader(b, len(rsw.types))
+			for _, t := range rsw.types {
+				b = appendMsgpackString(b, t)
+			}
+		}
+		b = appendMsgpackString(b, "values")
+		b = appendMsgpackArrayHeader(b, rsw.numRow)
+	}
+
+	// the rows are between the values header and the stats.
+	var tail []byte
+	if st := rsw.stats; st != nil {
+		st.Rows = rsw.numRow
+		tail = appendMsgpackString(tail, "stats")
+		tail = appendMsgpackMapHeader(tail, 4)
+		tail = appendMsgpackString(tail, "parseTime")
+		tail = appendMsgpackInt(tail, int64(st.ParseTime))
+		tail = appendMsgpackString(tail, "executeTime")
+		tail = appendMsgpackInt(tail, int64(st.ExecuteTime))
+		tail = appendMsgpackString(tail, "rows")
+		tail = appendMsgpackInt(tail, int64(st.Rows))
+		tail = appendMsgpackString(tail, "servedBy")
+		tail = appendMsgpackString(tail, st.ServedBy)
+	}
+
+	if rsw.nextCursor != "" {
+		w.Header().Set("X-Has-More", "true")
+		w.Header().Set("X-Next-Cursor", rsw.nextCursor)
+	}
+	w.Header().Set("Content-Type", msgpackContentType)
+
+	// we can do nothing to the errors because data may already been
+	// written to [w]
+	if _, err := w.Write(b); err != nil {
+		return err
+	}
+	if _, err := w.Write(rsw.buf.Bytes()); err != nil {
+		return err
+	}
+	_, err := w.Write(tail)
+	return err
+}
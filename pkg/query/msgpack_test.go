@@ -0,0 +1,197 @@
+package query
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-msgpack/v2/codec"
+	"github.com/localvar/xuandb/pkg/query/ast"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// decodeMsgpack decodes msgpack 'data' into 'v'.
+func decodeMsgpack(data []byte, v any) error {
+	mh := &codec.MsgpackHandle{}
+	mh.RawToString = true
+	return codec.NewDecoderBytes(data, mh).Decode(v)
+}
+
+func TestAppendMsgpackValue(t *testing.T) {
+	assert := assert.New(t)
+
+	ints := []int64{
+		0, 1, math.MaxInt8, math.MaxInt8 + 1, math.MaxUint8, math.MaxUint8 + 1,
+		math.MaxUint16, math.MaxUint16 + 1, math.MaxUint32, math.MaxUint32 + 1,
+		math.MaxInt64, -1, -32, -33, math.MinInt8, math.MinInt8 - 1,
+		math.MinInt16, math.MinInt16 - 1, math.MinInt32, math.MinInt32 - 1,
+		math.MinInt64,
+	}
+	for _, v := range ints {
+		var got int64
+		assert.NoError(decodeMsgpack(appendMsgpackValue(nil, v), &got))
+		assert.Equal(v, got)
+	}
+
+	uints := []uint64{math.MaxUint32 + 1, math.MaxInt64 + 1, math.MaxUint64}
+	for _, v := range uints {
+		var got uint64
+		assert.NoError(decodeMsgpack(appendMsgpackValue(nil, v), &got))
+		assert.Equal(v, got)
+	}
+
+	for _, n := range []int{0, 31, 32, 255, 256, 65535, 65536} {
+		var got string
+		s := strings.Repeat("x", n)
+		assert.NoError(decodeMsgpack(appendMsgpackValue(nil, s), &got))
+		assert.Equal(s, got)
+	}
+
+	for _, n := range []int{0, 15, 16, 65535, 65536} {
+		var got []int
+		b := appendMsgpackArrayHeader(nil, n)
+		b = append(b, bytes.Repeat([]byte{1}, n)...)
+		assert.NoError(decodeMsgpack(b, &got))
+		assert.Len(got, n)
+
+		var m map[int]int
+		b = appendMsgpackMapHeader(nil, n)
+		for i := range n {
+			b = appendMsgpackInt(b, int64(i))
+			b = appendMsgpackInt(b, 1)
+		}
+		assert.NoError(decodeMsgpack(b, &m))
+		assert.Len(m, n)
+	}
+
+	var f float64
+	assert.NoError(decodeMsgpack(appendMsgpackValue(nil, math.Inf(-1)), &f))
+	assert.True(math.IsInf(f, -1))
+	assert.NoError(decodeMsgpack(appendMsgpackValue(nil, float32(1.5)), &f))
+	assert.Equal(1.5, f)
+}
+
+func TestMsgpackResultSet(t *testing.T) {
+	assert := assert.New(t)
+
+	rows := [][]any{
+		{int64(math.MaxInt64), uint64(math.MaxUint64), "a", true},
+		{int64(math.MinInt64), uint64(0), strings.Repeat("b", 100), false},
+		{int8(-5), uint16(300), "", nil},
+		{1.5, float32(-2.25), ast.Duration(-90 * time.Second), time.Duration(0)},
+	}
+
+	flush := func(msgpack bool, stats bool) *httptest.ResponseRecorder {
+		rsw := &resultSetWriter{msgpack: msgpack}
+		if stats {
+			rsw.stats = &queryStats{ParseTime: time.Millisecond, ServedBy: "local"}
+		}
+		rsw.SetColumns("c1", "c2", "c3", "c4")
+		for _, row := range rows {
+			require.NoError(t, rsw.AddRow(row...))
+		}
+		rsw.SetNextCursor("next")
+		w := httptest.NewRecorder()
+		require.NoError(t, rsw.Flush(w))
+		return w
+	}
+
+	type resultSet struct {
+		Columns []string `json:"columns" codec:"columns"`
+		Values  [][]any  `json:"values" codec:"values"`
+		Stats   *struct {
+			ParseTime int64  `json:"parseTime" codec:"parseTime"`
+			Rows      int    `json:"rows" codec:"rows"`
+			ServedBy  string `json:"servedBy" codec:"servedBy"`
+		} `json:"stats" codec:"stats"`
+	}
+
+	for _, stats := range []bool{false, true} {
+		w := flush(false, stats)
+		var fromJSON resultSet
+		dec := json.NewDecoder(w.Body)
+		dec.UseNumber()
+		require.NoError(t, dec.Decode(&fromJSON))
+
+		w = flush(true, stats)
+		assert.Equal(msgpackContentType, w.Header().Get("Content-Type"))
+		assert.Equal("next", w.Header().Get("X-Next-Cursor"))
+		var fromMsgpack resultSet
+		require.NoError(t, decodeMsgpack(w.Body.Bytes(), &fromMsgpack))
+
+		// the values are the same as the JSON ones in text form.
+		assert.Equal(fromJSON.Columns, fromMsgpack.Columns)
+		require.Len(t, fromMsgpack.Values, len(rows))
+		for i, row := range fromMsgpack.Values {
+			for j, v := range row {
+				assert.Equal(fmt.Sprint(fromJSON.Values[i][j]), fmt.Sprint(v), "row %d, column %d", i, j)
+			}
+		}
+		assert.Equal(fromJSON.Stats, fromMsgpack.Stats)
+	}
+
+	// the integers round trip exactly.
+	w := flush(true, false)
+	var rs resultSet
+	require.NoError(t, decodeMsgpack(w.Body.Bytes(), &rs))
+	assert.EqualValues(math.MaxInt64, rs.Values[0][0])
+	assert.EqualValues(uint64(math.MaxUint64), rs.Values[0][1])
+	assert.EqualValues(math.MinInt64, rs.Values[1][0])
+
+	// the column types.
+	rsw := &resultSetWriter{msgpack: true}
+	rsw.SetColumnsWithTypes(ast.Column{Name: "c1", Type: ast.ColumnTypeInt})
+	require.NoError(t, rsw.AddRow(1))
+	w = httptest.NewRecorder()
+	require.NoError(t, rsw.Flush(w))
+	var trs struct {
+		Columns []string `codec:"columns"`
+		Types   []string `codec:"types"`
+		Values  [][]any  `codec:"values"`
+	}
+	require.NoError(t, decodeMsgpack(w.Body.Bytes(), &trs))
+	assert.Equal([]string{"c1"}, trs.Columns)
+	assert.Equal([]string{ast.ColumnTypeInt}, trs.Types)
+	assert.Len(trs.Values, 1)
+}
+
+func TestMsgpackQuery(t *testing.T) {
+	assert := assert.New(t)
+
+	stmt := &rowsStatement{n: 3}
+	defer func(f func(string) (ast.Statement, error)) { parse = f }(parse)
+	parse = func(q string) (ast.Statement, error) { return stmt, nil }
+
+	query := func(accept, params string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/query?q=rows"+params, nil)
+		r.Header.Set("Accept", accept)
+		w := httptest.NewRecorder()
+		queryHandler(w, r)
+		return w
+	}
+
+	w := query("application/msgpack", "")
+	assert.Equal(http.StatusOK, w.Code)
+	assert.Equal(msgpackContentType, w.Header().Get("Content-Type"))
+	var rs struct {
+		Columns []string `codec:"columns"`
+		Values  [][]any  `codec:"values"`
+	}
+	require.NoError(t, decodeMsgpack(w.Body.Bytes(), &rs))
+	assert.Equal([]string{"id", "value"}, rs.Columns)
+	assert.Len(rs.Values, 3)
+
+	w = query("application/json", "")
+	assert.Equal(http.StatusOK, w.Code)
+	assert.True(json.Valid(w.Body.Bytes()))
+
+	w = query("application/msgpack", "&stream=true")
+	assert.Equal(http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,78 @@
+package parser
+
+import "fmt"
+
+// ErrorCode identifies the kind of an error found when scanning or parsing a
+// statement, it allows clients to handle errors or render localized messages
+// without matching the English text.
+type ErrorCode int
+
+// error codes, the comment of each code is its format string for English
+// messages, the arguments are listed in parentheses if any.
+const (
+	ErrSyntax                    ErrorCode = iota + 1 // %s (message from the parser)
+	ErrRead                                           // %s (error from the reader)
+	ErrInvalidUTF8                                    // invalid UTF-8 encoding
+	ErrInvalidNUL                                     // invalid character NUL
+	ErrInvalidDurationUnit                            // invalid duration unit
+	ErrDurationNotDecimal                             // duration requires decimal integer
+	ErrInvalidRadixPoint                              // invalid radix point in %s (literal name)
+	ErrNoDigits                                       // %s has no digits (literal name)
+	ErrExponentNeedsDecimal                           // %q exponent requires decimal mantissa (exponent)
+	ErrExponentNeedsHex                               // %q exponent requires hexadecimal mantissa (exponent)
+	ErrExponentNoDigits                               // exponent has no digits
+	ErrMantissaNeedsExponent                          // hexadecimal mantissa requires a 'p' exponent
+	ErrInvalidDigit                                   // invalid digit %q in %s (digit, literal name)
+	ErrExtraCharacter                                 // extra character after %s (token name)
+	ErrInvalidCharEscape                              // invalid char escape
+	ErrLiteralNotTerminated                           // literal not terminated
+	ErrInvalidDollarQuoteTag                          // invalid dollar-quote tag
+	ErrDollarStringNotTerminated                      // dollar-quoted string not terminated
+	ErrCommentNotTerminated                           // comment not terminated
+	ErrValueOutOfRange                                // value out of range
+	ErrInvalidNumber                                  // invalid syntax
+	ErrInvalidDuration                                // %s (error from duration parsing)
+	ErrInvalidParamIndex                              // invalid parameter index
+	ErrTrailingToken                                  // unexpected token after statement: %s (token)
+)
+
+// errorFormats are the format strings of the English error messages.
+var errorFormats = map[ErrorCode]string{
+	ErrSyntax:                    "%s",
+	ErrRead:                      "%s",
+	ErrInvalidUTF8:               "invalid UTF-8 encoding",
+	ErrInvalidNUL:                "invalid character NUL",
+	ErrInvalidDurationUnit:       "invalid duration unit",
+	ErrDurationNotDecimal:        "duration requires decimal integer",
+	ErrInvalidRadixPoint:         "invalid radix point in %s",
+	ErrNoDigits:                  "%s has no digits",
+	ErrExponentNeedsDecimal:      "%q exponent requires decimal mantissa",
+	ErrExponentNeedsHex:          "%q exponent requires hexadecimal mantissa",
+	ErrExponentNoDigits:          "exponent has no digits",
+	ErrMantissaNeedsExponent:     "hexadecimal mantissa requires a 'p' exponent",
+	ErrInvalidDigit:              "invalid digit %q in %s",
+	ErrExtraCharacter:            "extra character after %s",
+	ErrInvalidCharEscape:         "invalid char escape",
+	ErrLiteralNotTerminated:      "literal not terminated",
+	ErrInvalidDollarQuoteTag:     "invalid dollar-quote tag",
+	ErrDollarStringNotTerminated: "dollar-quoted string not terminated",
+	ErrCommentNotTerminated:      "comment not terminated",
+	ErrValueOutOfRange:           "value out of range",
+	ErrInvalidNumber:             "invalid syntax",
+	ErrInvalidDuration:           "%s",
+	ErrInvalidParamIndex:         "invalid parameter index",
+	ErrTrailingToken:             "unexpected token after statement: %s",
+}
+
+// MessageFormatter renders the message of error 'code' with arguments 'args',
+// it can be used to localize the error messages.
+type MessageFormatter func(code ErrorCode, args ...any) string
+
+// DefaultMessage renders the English message of error 'code' with arguments
+// 'args', it is the default MessageFormatter.
+func DefaultMessage(code ErrorCode, args ...any) string {
+	if f, ok := errorFormats[code]; ok {
+		return fmt.Sprintf(f, args...)
+	}
+	return fmt.Sprintf("error %d", code)
+}
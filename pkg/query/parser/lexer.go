@@ -1,11 +1,15 @@
 package parser
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"io"
+	"maps"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 	"unicode/utf8"
 
 	"github.com/localvar/xuandb/pkg/query/ast"
@@ -16,50 +20,114 @@ import (
 type Lexer struct {
 	Scanner
 	Result      ast.Statement
-	ReportError func(msg string)
+	ReportError func(e ErrorEntry)
+
+	// Params are the values bound to the positional parameters, Params[0]
+	// is the value of '$1'.
+	Params []any
+
+	// keyword is the text of the last token if it is a keyword, or empty.
+	keyword string
+
+	// scanResult is the result of the last scan, see [Scanner.Scan].
+	scanResult rune
+
+	// comments makes Lex return comments as COMMENT tokens instead of
+	// skipping them.
+	comments bool
 }
 
 // NewLexer creates and returns a new lexer with source 'src'.
 func NewLexer(src io.Reader) *Lexer {
 	l := &Lexer{}
 	l.Scanner.Error = func(s *Scanner, msg string) {
-		l.Error(msg)
+		l.report(s.ErrorCode, msg)
 	}
 	l.Scanner.Init(src)
 	return l
 }
 
+// Keywords returns all keywords in upper case, sorted. Keywords are reserved
+// words, they must be quoted to be used as identifiers.
+func Keywords() []string {
+	return slices.Sorted(maps.Keys(keywords))
+}
+
+// IsKeyword returns whether 's' is a keyword, case-insensitively.
+func IsKeyword(s string) bool {
+	_, ok := keywords[strings.ToUpper(s)]
+	return ok
+}
+
+// upperASCII writes the upper case of 's' to 'buf' and returns it, it
+// returns nil if 's' is longer than 'buf' or contains non-ASCII characters.
+func upperASCII(buf, s []byte) []byte {
+	if len(s) > len(buf) {
+		return nil
+	}
+	for i, c := range s {
+		if c >= utf8.RuneSelf {
+			return nil
+		}
+		if 'a' <= c && c <= 'z' {
+			c -= 'a' - 'A'
+		}
+		buf[i] = c
+	}
+	return buf[:len(s)]
+}
+
 func (l *Lexer) parseIdent(lval *yySymType) int {
-	tt := l.TokenText()
-	if utt := strings.ToUpper(tt); utt == "TRUE" {
+	// the token bytes are converted to upper case in a buffer on the stack,
+	// so that the common cases do not allocate, keywords are ASCII and are
+	// at most 32 bytes.
+	var buf [32]byte
+	tb := l.TokenBytes()
+	utt := upperASCII(buf[:], tb)
+	if utt == nil {
+		utt = bytes.ToUpper(tb)
+	}
+
+	if string(utt) == "TRUE" {
 		lval.bool = true
 		return VAL_BOOL
-	} else if utt == "FALSE" {
+	} else if string(utt) == "FALSE" {
 		lval.bool = false
 		return VAL_BOOL
-	} else if id, ok := keywords[utt]; ok {
+	} else if id, ok := keywords[string(utt)]; ok {
+		l.keyword = string(tb)
 		return id
 	} else {
-		lval.str = tt
+		lval.str = string(tb)
 		return IDENT
 	}
 }
 
+// numErrorCode returns the error code of error 'err' returned by the number
+// parsing functions of package strconv.
+func numErrorCode(err error) ErrorCode {
+	if errors.Is(err, strconv.ErrRange) {
+		return ErrValueOutOfRange
+	}
+	return ErrInvalidNumber
+}
+
 func (l *Lexer) parseInt(lval *yySymType, hasScanErr bool) int {
-	tt := l.TokenText()
+	tb := l.TokenBytes()
 	if hasScanErr {
-		lval.str = tt
+		lval.str = string(tb)
 		return ERR_TOKEN
 	}
 
-	v, err := strconv.ParseUint(tt, 0, 64)
+	// the conversion to string does not allocate for short integers.
+	v, err := strconv.ParseUint(string(tb), 0, 64)
 	if err == nil {
 		lval.int = v
 		return VAL_INT
 	}
 
-	l.Error(errors.Unwrap(err).Error())
-	lval.str = tt
+	l.lexError(numErrorCode(err))
+	lval.str = string(tb)
 	return ERR_TOKEN
 }
 
@@ -76,7 +144,7 @@ func (l *Lexer) parseFloat(lval *yySymType, hasScanErr bool) int {
 		return VAL_FLT
 	}
 
-	l.Error(errors.Unwrap(err).Error())
+	l.lexError(numErrorCode(err))
 	lval.str = tt
 	return ERR_TOKEN
 }
@@ -94,23 +162,133 @@ func (l *Lexer) parseDuration(lval *yySymType, hasScanErr bool) int {
 		return VAL_DURATION
 	}
 
-	l.Error(err.Error())
+	l.lexError(ErrInvalidDuration, err.Error())
+	lval.str = tt
+	return ERR_TOKEN
+}
+
+func (l *Lexer) parseDollarString(lval *yySymType, hasScanErr bool) int {
+	tt := l.TokenText()
+	if hasScanErr {
+		lval.str = tt
+		return ERR_TOKEN
+	}
+
+	// the opening and closing delimiters are the same, '$tag$'.
+	n := strings.IndexByte(tt[1:], '$') + 2
+	lval.str = tt[n : len(tt)-n]
+	return VAL_STR
+}
+
+func (l *Lexer) parseParam(lval *yySymType) int {
+	tt := l.TokenText()
+	v, err := strconv.ParseUint(tt[1:], 10, 64)
+	if err == nil && v > 0 {
+		lval.int = v
+		return PARAM
+	}
+
+	l.lexError(ErrInvalidParamIndex)
 	lval.str = tt
 	return ERR_TOKEN
 }
 
+// param returns the value bound to parameter '$idx'.
+func (l *Lexer) param(idx uint64) (any, error) {
+	if idx > uint64(len(l.Params)) {
+		return nil, fmt.Errorf("parameter $%d is not bound", idx)
+	}
+	return l.Params[idx-1], nil
+}
+
+// paramString returns the value bound to parameter '$idx' as a string.
+func (l *Lexer) paramString(idx uint64) (string, error) {
+	v, err := l.param(idx)
+	if err != nil {
+		return "", err
+	}
+	if s, ok := v.(string); ok {
+		return s, nil
+	}
+	return "", fmt.Errorf("parameter $%d must be a string, got %T", idx, v)
+}
+
+// paramInt returns the value bound to parameter '$idx' as an unsigned
+// integer.
+func (l *Lexer) paramInt(idx uint64) (uint64, error) {
+	v, err := l.param(idx)
+	if err != nil {
+		return 0, err
+	}
+
+	var i int64
+	switch t := v.(type) {
+	case int:
+		i = int64(t)
+	case int8:
+		i = int64(t)
+	case int16:
+		i = int64(t)
+	case int32:
+		i = int64(t)
+	case int64:
+		i = t
+	case uint:
+		return uint64(t), nil
+	case uint8:
+		return uint64(t), nil
+	case uint16:
+		return uint64(t), nil
+	case uint32:
+		return uint64(t), nil
+	case uint64:
+		return t, nil
+	default:
+		return 0, fmt.Errorf("parameter $%d must be an integer, got %T", idx, v)
+	}
+
+	if i < 0 {
+		return 0, fmt.Errorf("parameter $%d must not be negative", idx)
+	}
+	return uint64(i), nil
+}
+
+// paramDuration returns the value bound to parameter '$idx' as a duration,
+// the value can be a [time.Duration] or a string like '1d2h'.
+func (l *Lexer) paramDuration(idx uint64) (time.Duration, error) {
+	v, err := l.param(idx)
+	if err != nil {
+		return 0, err
+	}
+
+	switch t := v.(type) {
+	case time.Duration:
+		return t, nil
+	case string:
+		return utils.ParseDuration(t)
+	default:
+		return 0, fmt.Errorf("parameter $%d must be a duration, got %T", idx, v)
+	}
+}
+
 // Lex implements method Lex of interface yyLexer.
 func (l *Lexer) Lex(lval *yySymType) int {
+	l.keyword = ""
 	for {
 		errCount := l.ErrorCount
 		sr := l.Scan()
 		errCount = l.ErrorCount - errCount
+		l.scanResult = sr
 
 		switch sr {
 		case ScanResultEOF:
 			return 0
 
 		case ScanResultComment:
+			if l.comments {
+				lval.str = l.TokenText()
+				return COMMENT
+			}
 			continue
 
 		case ScanResultIdent:
@@ -133,7 +311,13 @@ func (l *Lexer) Lex(lval *yySymType) int {
 			lval.str = unescape(l.TokenText(), '\'')
 			return VAL_STR
 
+		case ScanResultParam:
+			return l.parseParam(lval)
+
 		case ScanResultRawString:
+			if l.TokenText()[0] == '$' {
+				return l.parseDollarString(lval, errCount > 0)
+			}
 			tt := l.TokenText()[1:]
 			if tt[len(tt)-1] == '`' {
 				tt = tt[:len(tt)-1]
@@ -141,6 +325,15 @@ func (l *Lexer) Lex(lval *yySymType) int {
 			lval.str = tt
 			return VAL_STR
 
+		case 0:
+			// NUL is only allowed inside literals, the scanner has reported
+			// the error if it is not allowed at all.
+			if errCount == 0 {
+				l.lexError(ErrInvalidNUL)
+			}
+			lval.str = l.TokenText()
+			return ERR_TOKEN
+
 		case '+':
 			return OP_ADD
 
@@ -222,8 +415,42 @@ func (l *Lexer) Lex(lval *yySymType) int {
 	}
 }
 
-// Error implements method Error of interface yyLexer.
+// expectsIdent returns whether syntax error message 'msg' reports that an
+// identifier is expected.
+func expectsIdent(msg string) bool {
+	_, expected, ok := strings.Cut(msg, ", expecting ")
+	if !ok {
+		return false
+	}
+	for _, tok := range strings.Split(expected, " or ") {
+		if tok == "IDENT" {
+			return true
+		}
+	}
+	return false
+}
+
+// Error implements method Error of interface yyLexer, it reports a syntax
+// error found by the parser.
 func (l *Lexer) Error(msg string) {
+	// a reserved word is used where an identifier is expected, suggest
+	// quoting it.
+	if l.keyword != "" && expectsIdent(msg) {
+		msg += fmt.Sprintf(`, use "%s" to use reserved word as identifier`, l.keyword)
+	}
+
+	l.report(ErrSyntax, l.format(ErrSyntax, msg))
+}
+
+// lexError reports error 'code' found by the lexer, the message is rendered
+// with the formatter.
+func (l *Lexer) lexError(code ErrorCode, args ...any) {
+	l.ErrorCode = code
+	l.report(code, l.format(code, args...))
+}
+
+// report reports error 'code' with message 'msg' at the current token.
+func (l *Lexer) report(code ErrorCode, msg string) {
 	if l.ReportError == nil {
 		return
 	}
@@ -234,7 +461,12 @@ func (l *Lexer) Error(msg string) {
 		pos = s.Pos()
 	}
 
-	l.ReportError(fmt.Sprintf("%s: %s: %s", pos, s.TokenText(), msg))
+	// string literals may contain secrets like passwords, never echo them.
+	tt := s.TokenText()
+	if isStringLiteral(tt) {
+		tt = redacted
+	}
+	l.ReportError(ErrorEntry{Pos: pos, Token: tt, Msg: msg, Code: code})
 }
 
 // decodeDigits decodes the first 'n' digits of 'str' to a rune, it returns
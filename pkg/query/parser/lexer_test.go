@@ -2,6 +2,7 @@ package parser
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -166,19 +167,109 @@ TRUE FALSE CREATE IDENT
 	checkToken(t, l, 0)
 
 	l = NewLexer(strings.NewReader("9dw"))
-	l.ReportError = func(msg string) {
+	l.ReportError = func(e ErrorEntry) {
 		const want = "<input>:1:1: : abc"
-		if msg != want {
+		if msg := e.String(); msg != want {
 			t.Errorf("error msg = %q, want = %q", msg, want)
 		}
 	}
 	l.Error("abc")
 
-	l.ReportError = func(msg string) {
+	l.ReportError = func(e ErrorEntry) {
 		const want = "<input>:1:1: 9dw: extra character after duration"
-		if msg != want {
+		if msg := e.String(); msg != want {
 			t.Errorf("error msg = %q, want = %q", msg, want)
 		}
 	}
 	l.Lex(&yySymType{})
 }
+
+func TestLexerParam(t *testing.T) {
+	l := NewLexer(strings.NewReader("$1 $23 $0 $ a $a b"))
+
+	var lval yySymType
+	if id := l.Lex(&lval); id != PARAM || lval.int != 1 {
+		t.Errorf("got token %q with index %d, want PARAM with index 1", tokenName(id), lval.int)
+	}
+	if id := l.Lex(&lval); id != PARAM || lval.int != 23 {
+		t.Errorf("got token %q with index %d, want PARAM with index 23", tokenName(id), lval.int)
+	}
+	checkText(t, l, ERR_TOKEN, "$0")
+	checkToken(t, l, '$')
+	checkText(t, l, IDENT, "a")
+	checkText(t, l, ERR_TOKEN, "$a")
+	checkText(t, l, IDENT, "b")
+	checkToken(t, l, 0)
+}
+
+func TestLexerDollarString(t *testing.T) {
+	l := NewLexer(strings.NewReader(`$$hello$$ $q$a'b"c$q$ $$$$ $q$abc`))
+	checkText(t, l, VAL_STR, "hello")
+	checkText(t, l, VAL_STR, `a'b"c`)
+	checkText(t, l, VAL_STR, "")
+	checkText(t, l, ERR_TOKEN, "$q$abc")
+	checkToken(t, l, 0)
+}
+
+func TestLexerErrorCode(t *testing.T) {
+	cases := []struct {
+		src  string
+		code ErrorCode
+	}{
+		{"SHOW USER LIMIT 99999999999999999999999", ErrValueOutOfRange},
+		{"CREATE DATABASE db WITH DURATION 99999999999999999999999w", ErrInvalidDuration},
+		{"SHOW USER LIMIT $0", ErrInvalidParamIndex},
+		{"SHOW USER \x00", ErrInvalidNUL},
+		{"SHOW USER LIMIT 'a'", ErrSyntax},
+		{"SHOW USER garbage", ErrTrailingToken},
+		{"SHOW USER 'abc", ErrLiteralNotTerminated},
+	}
+
+	for _, c := range cases {
+		_, err := Parse(c.src)
+		pe, ok := err.(*ParseError)
+		if !ok || len(pe.Entries) == 0 {
+			t.Errorf("got error %v, want a parse error for %q", err, c.src)
+			continue
+		}
+		if code := pe.Entries[0].Code; code != c.code {
+			t.Errorf("code = %d, want %d for %q", code, c.code, c.src)
+		}
+	}
+
+	l := NewLexer(strings.NewReader("$0"))
+	l.Formatter = func(code ErrorCode, args ...any) string {
+		return fmt.Sprintf("E%d", code)
+	}
+	var entries []ErrorEntry
+	l.ReportError = func(e ErrorEntry) { entries = append(entries, e) }
+	checkText(t, l, ERR_TOKEN, "$0")
+	l.Error("syntax error")
+	want := []ErrorEntry{
+		{Pos: Position{Offset: 0, Line: 1, Column: 1}, Token: "$0", Msg: fmt.Sprintf("E%d", ErrInvalidParamIndex), Code: ErrInvalidParamIndex},
+		{Pos: Position{Offset: 0, Line: 1, Column: 1}, Token: "$0", Msg: fmt.Sprintf("E%d", ErrSyntax), Code: ErrSyntax},
+	}
+	if fmt.Sprint(entries) != fmt.Sprint(want) {
+		t.Errorf("entries = %v, want %v", entries, want)
+	}
+}
+
+func TestKeywords(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True(IsKeyword("create"))
+	assert.True(IsKeyword("CREATE"))
+	assert.True(IsKeyword("Like"))
+	assert.False(IsKeyword("foobar"))
+	assert.False(IsKeyword(""))
+
+	kws := Keywords()
+	assert.NotEmpty(kws)
+	assert.True(slices.IsSorted(kws))
+	assert.Contains(kws, "SELECT")
+	assert.Contains(kws, "AND")
+
+	// the result is a copy.
+	kws[0] = "FOOBAR"
+	assert.False(IsKeyword("foobar"))
+}
@@ -1,27 +1,86 @@
 package parser
 
 import (
-	"errors"
+	"fmt"
 	"log/slog"
 	"strings"
 
 	"github.com/localvar/xuandb/pkg/query/ast"
 )
 
+// ErrorEntry is an error found when parsing a statement.
+type ErrorEntry struct {
+	Pos   Position  // position of the token
+	Token string    // text of the token
+	Msg   string    // error message, may change between versions
+	Code  ErrorCode // error code
+}
+
+func (e ErrorEntry) String() string {
+	return fmt.Sprintf("%s: %s: %s", e.Pos, e.Token, e.Msg)
+}
+
+// ParseError is the error returned by the parse functions, it holds all the
+// errors found when parsing a statement.
+type ParseError struct {
+	Entries []ErrorEntry
+}
+
+// Error implements interface error, the errors are joined by newlines.
+func (e *ParseError) Error() string {
+	var sb strings.Builder
+	for i, ee := range e.Entries {
+		if i > 0 {
+			sb.WriteByte('\n')
+		}
+		sb.WriteString(ee.String())
+	}
+	return sb.String()
+}
+
+// parsable returns whether 'input' can be parsed to a statement with 'args'
+// bound to the positional parameters, errors are not reported.
+func parsable(input string, args []any) bool {
+	l := NewLexer(strings.NewReader(input))
+	l.Params = args
+	return yyParse(l) == 0
+}
+
+// Parse parses 'input' to a statement.
 func Parse(input string) (ast.Statement, error) {
-	slog.Debug("parse query", slog.String("input", input))
+	return ParseWithParams(input)
+}
+
+// ParseWithParams parses 'input' to a statement, with 'args' bound to the
+// positional parameters '$1', '$2', ... in 'input'. A parameter can be used
+// wherever a value is expected, and the bound value is used as is, for
+// example, a string is never interpreted as part of the query even if it
+// contains quotes. Parameters cannot be used as identifiers.
+func ParseWithParams(input string, args ...any) (ast.Statement, error) {
+	slog.Debug("parse query", slog.String("input", Redact(input)))
 
-	errs := make([]string, 0)
+	pe := &ParseError{}
 	l := NewLexer(strings.NewReader(input))
-	l.ReportError = func(msg string) {
-		errs = append(errs, msg)
+	l.Params = args
+	l.ReportError = func(e ErrorEntry) {
+		pe.Entries = append(pe.Entries, e)
 	}
 
 	if yyParse(l) == 0 {
 		return l.Result, nil
 	}
 
-	msg := strings.Join(errs, "\n")
-	slog.Debug("parse error", slog.String("error", msg))
-	return nil, errors.New(msg)
+	// if the input before the first syntax error is a complete statement,
+	// the error is caused by trailing tokens, report it clearly.
+	if len(pe.Entries) > 0 {
+		e := pe.Entries[0]
+		if e.Code == ErrSyntax && e.Pos.Offset > 0 && strings.HasPrefix(e.Msg, "syntax error") && parsable(input[:e.Pos.Offset], args) {
+			e.Code = ErrTrailingToken
+			e.Msg = l.format(ErrTrailingToken, e.Token)
+			pe.Entries = []ErrorEntry{e}
+		}
+	}
+
+	slog.Debug("parse error", slog.String("error", pe.Error()))
+	return nil, pe
 }
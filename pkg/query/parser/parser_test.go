@@ -3,6 +3,11 @@ package parser
 import (
 	"fmt"
 	"testing"
+	"time"
+
+	"github.com/localvar/xuandb/pkg/meta"
+	"github.com/localvar/xuandb/pkg/query/ast"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestParse(t *testing.T) {
@@ -12,3 +17,437 @@ func TestParse(t *testing.T) {
 	stmt, err = Parse("128")
 	fmt.Println(stmt, err)
 }
+
+func TestParseShowCurrentUser(t *testing.T) {
+	stmt, err := Parse("SHOW CURRENT USER")
+	assert.NoError(t, err)
+	assert.IsType(t, &ast.ShowCurrentUserStatement{}, stmt)
+}
+
+func TestParseShowRaftStats(t *testing.T) {
+	stmt, err := Parse("SHOW RAFT STATS")
+	assert.NoError(t, err)
+	assert.IsType(t, &ast.ShowRaftStatsStatement{}, stmt)
+}
+
+func TestParseDrainNode(t *testing.T) {
+	stmt, err := Parse("DRAIN NODE n2")
+	assert.NoError(t, err)
+	assert.Equal(t, &ast.DrainNodeStatement{ID: "n2"}, stmt)
+}
+
+func TestParseAlterUser(t *testing.T) {
+	assert := assert.New(t)
+
+	stmt, err := Parse("ALTER USER alice DISABLE")
+	assert.NoError(err)
+	assert.Equal(&ast.SetUserEnabledStatement{Name: "alice"}, stmt)
+
+	stmt, err = Parse("alter user alice enable")
+	assert.NoError(err)
+	assert.Equal(&ast.SetUserEnabledStatement{Name: "alice", Enabled: true}, stmt)
+
+	_, err = Parse("ALTER USER alice")
+	assert.Error(err)
+}
+
+func TestParseFlushCache(t *testing.T) {
+	assert := assert.New(t)
+
+	stmt, err := Parse("FLUSH CACHE")
+	assert.NoError(err)
+	assert.Equal(&ast.FlushCacheStatement{}, stmt)
+
+	stmt, err = Parse("flush cache auth")
+	assert.NoError(err)
+	assert.Equal(&ast.FlushCacheStatement{Name: "auth"}, stmt)
+}
+
+func TestParseShowQueries(t *testing.T) {
+	stmt, err := Parse("SHOW QUERIES")
+	assert.NoError(t, err)
+	assert.IsType(t, &ast.ShowQueriesStatement{}, stmt)
+}
+
+func TestParseShowGrants(t *testing.T) {
+	assert := assert.New(t)
+
+	stmt, err := Parse("SHOW GRANTS FOR alice")
+	assert.NoError(err)
+	assert.Equal(&ast.ShowGrantsStatement{Name: "alice"}, stmt)
+
+	_, err = Parse("SHOW GRANTS")
+	assert.Error(err)
+}
+
+func TestParseShowConfig(t *testing.T) {
+	stmt, err := Parse("show config")
+	assert.NoError(t, err)
+	assert.Equal(t, &ast.ShowConfigStatement{}, stmt)
+}
+
+func TestParseShowVersion(t *testing.T) {
+	stmt, err := Parse("SHOW VERSION")
+	assert.NoError(t, err)
+	assert.Equal(t, &ast.ShowVersionStatement{}, stmt)
+}
+
+func TestParseKillQuery(t *testing.T) {
+	assert := assert.New(t)
+
+	stmt, err := Parse("KILL QUERY 12")
+	assert.NoError(err)
+	assert.Equal(&ast.KillQueryStatement{ID: 12}, stmt)
+
+	_, err = Parse("KILL QUERY")
+	assert.Error(err)
+}
+
+func TestParseClusterLogLevel(t *testing.T) {
+	assert := assert.New(t)
+
+	stmt, err := Parse("SHOW CLUSTER LOG LEVEL")
+	assert.NoError(err)
+	assert.Equal(&ast.ShowClusterLogLevelStatement{}, stmt)
+
+	stmt, err = Parse("set cluster log level debug")
+	assert.NoError(err)
+	assert.Equal(&ast.SetClusterLogLevelStatement{Level: "debug"}, stmt)
+
+	stmt, err = Parse("SET CLUSTER LOG LEVEL 'INFO+2'")
+	assert.NoError(err)
+	assert.Equal(&ast.SetClusterLogLevelStatement{Level: "INFO+2"}, stmt)
+
+	_, err = Parse("SET CLUSTER LOG LEVEL")
+	assert.Error(err)
+}
+
+func TestParseShowClusterVersions(t *testing.T) {
+	stmt, err := Parse("show cluster versions")
+	assert.NoError(t, err)
+	assert.Equal(t, &ast.ShowClusterVersionsStatement{}, stmt)
+}
+
+func TestParseShowRaftLog(t *testing.T) {
+	assert := assert.New(t)
+
+	stmt, err := Parse("SHOW RAFT LOG")
+	assert.NoError(err)
+	assert.Equal(&ast.ShowRaftLogStatement{}, stmt)
+
+	stmt, err = Parse("SHOW RAFT LOG FROM 10 TO 20")
+	assert.NoError(err)
+	assert.Equal(&ast.ShowRaftLogStatement{From: 10, To: 20}, stmt)
+
+	_, err = Parse("SHOW RAFT LOG FROM 10")
+	assert.Error(err)
+}
+
+func TestParsePagination(t *testing.T) {
+	assert := assert.New(t)
+
+	stmt, err := Parse("SHOW USER")
+	assert.NoError(err)
+	assert.Equal(&ast.ShowUserStatement{}, stmt)
+
+	stmt, err = Parse("SHOW DATABASE LIMIT 10")
+	assert.NoError(err)
+	assert.Equal(&ast.ShowDatabaseStatement{Pagination: ast.Pagination{Limit: 10}}, stmt)
+
+	stmt, err = Parse("SHOW NODE AFTER '2'")
+	assert.NoError(err)
+	assert.Equal(&ast.ShowNodeStatement{Pagination: ast.Pagination{After: "2"}}, stmt)
+
+	stmt, err = Parse("SHOW USER AFTER 'alice' LIMIT 5")
+	assert.NoError(err)
+	assert.Equal(&ast.ShowUserStatement{Pagination: ast.Pagination{After: "alice", Limit: 5}}, stmt)
+
+	_, err = Parse("SHOW USER LIMIT 5 AFTER 'alice'")
+	assert.Error(err)
+
+	stmt, err = Parse("SHOW USER LIMIT 10 OFFSET 20")
+	assert.NoError(err)
+	assert.Equal(&ast.ShowUserStatement{Pagination: ast.Pagination{Limit: 10, Offset: 20}}, stmt)
+
+	stmt, err = Parse("SHOW NODE AFTER '2' LIMIT 10 OFFSET 5")
+	assert.NoError(err)
+	assert.Equal(&ast.ShowNodeStatement{Pagination: ast.Pagination{After: "2", Limit: 10, Offset: 5}}, stmt)
+
+	_, err = Parse("SHOW USER LIMIT 10 OFFSET -1")
+	assert.Error(err)
+
+	_, err = Parse("SHOW USER LIMIT -10")
+	assert.Error(err)
+
+	_, err = Parse("SHOW USER OFFSET 10")
+	assert.Error(err)
+}
+
+func TestParseSelect(t *testing.T) {
+	assert := assert.New(t)
+
+	stmt, err := Parse("SELECT role, COUNT(*), min(id), max(id) FROM NODE GROUP BY role")
+	assert.NoError(err)
+	assert.Equal(&ast.SelectStatement{
+		Source: &ast.ShowNodeStatement{},
+		Fields: []*ast.Field{
+			{Column: "role"},
+			{Func: "count", Column: "*"},
+			{Func: "min", Column: "id"},
+			{Func: "max", Column: "id"},
+		},
+		GroupBy: []string{"role"},
+	}, stmt)
+
+	stmt, err = Parse("SELECT name FROM USER")
+	assert.NoError(err)
+	assert.Equal(&ast.SelectStatement{
+		Source: &ast.ShowUserStatement{},
+		Fields: []*ast.Field{{Column: "name"}},
+	}, stmt)
+
+	stmt, err = Parse(`SELECT count(name), max("duration") FROM DATABASE`)
+	assert.NoError(err)
+	assert.IsType(&ast.SelectStatement{}, stmt)
+
+	// non-aggregated column not in GROUP BY.
+	_, err = Parse("SELECT role, id FROM NODE GROUP BY role")
+	assert.ErrorContains(err, "column 'id' must appear in the GROUP BY clause")
+
+	_, err = Parse("SELECT id, count(*) FROM NODE")
+	assert.ErrorContains(err, "column 'id' must appear in the GROUP BY clause")
+
+	// unknown function.
+	_, err = Parse("SELECT role, sum(id) FROM NODE GROUP BY role")
+	assert.ErrorContains(err, "unknown aggregate function: sum")
+
+	_, err = Parse("SELECT max(*) FROM NODE")
+	assert.Error(err)
+}
+
+func TestParseIfExists(t *testing.T) {
+	assert := assert.New(t)
+
+	stmt, err := Parse("CREATE DATABASE foo IF NOT EXISTS")
+	assert.NoError(err)
+	assert.Equal(&ast.CreateDatabaseStatement{Database: meta.Database{Name: "foo"}, IfNotExists: true}, stmt)
+
+	stmt, err = Parse("CREATE DATABASE foo IF NOT EXISTS WITH DURATION 1d")
+	assert.NoError(err)
+	assert.Equal(&ast.CreateDatabaseStatement{Database: meta.Database{Name: "foo", Duration: 24 * time.Hour}, IfNotExists: true}, stmt)
+
+	stmt, err = Parse("CREATE DATABASE foo WITH DURATION 1d")
+	assert.NoError(err)
+	assert.Equal(&ast.CreateDatabaseStatement{Database: meta.Database{Name: "foo", Duration: 24 * time.Hour}}, stmt)
+
+	stmt, err = Parse("DROP DATABASE bar IF EXISTS")
+	assert.NoError(err)
+	assert.Equal(&ast.DropDatabaseStatement{Name: "bar", IfExists: true}, stmt)
+
+	stmt, err = Parse("DROP DATABASE bar")
+	assert.NoError(err)
+	assert.Equal(&ast.DropDatabaseStatement{Name: "bar"}, stmt)
+
+	stmt, err = Parse("CREATE USER alice IF NOT EXISTS WITH PASSWORD 'pwd' WITH READ PRIVILEGE")
+	assert.NoError(err)
+	assert.Equal(&ast.CreateUserStatement{
+		User:        meta.User{Name: "alice", Password: "pwd", Priv: meta.PrivilegeRead},
+		IfNotExists: true,
+	}, stmt)
+
+	stmt, err = Parse("DROP USER alice IF EXISTS")
+	assert.NoError(err)
+	assert.Equal(&ast.DropUserStatement{Name: "alice", IfExists: true}, stmt)
+
+	_, err = Parse("DROP DATABASE bar IF NOT EXISTS")
+	assert.Error(err)
+
+	_, err = Parse("CREATE DATABASE foo IF EXISTS")
+	assert.Error(err)
+}
+
+func TestParseProjection(t *testing.T) {
+	assert := assert.New(t)
+
+	stmt, err := Parse("SHOW NODE (id, addr AS address)")
+	assert.NoError(err)
+	assert.Equal(&ast.ShowNodeStatement{
+		Projection: ast.Projection{Columns: []ast.ColumnAlias{
+			{Column: "id"},
+			{Column: "addr", Alias: "address"},
+		}},
+	}, stmt)
+
+	stmt, err = Parse("SHOW USER (name AS user_name) AFTER 'alice' LIMIT 5")
+	assert.NoError(err)
+	assert.Equal(&ast.ShowUserStatement{
+		Projection: ast.Projection{Columns: []ast.ColumnAlias{{Column: "name", Alias: "user_name"}}},
+		Pagination: ast.Pagination{After: "alice", Limit: 5},
+	}, stmt)
+
+	stmt, err = Parse(`SHOW DATABASE ("duration" AS retention)`)
+	assert.NoError(err)
+	assert.Equal(&ast.ShowDatabaseStatement{
+		Projection: ast.Projection{Columns: []ast.ColumnAlias{{Column: "duration", Alias: "retention"}}},
+	}, stmt)
+
+	_, err = Parse("SHOW NODE ()")
+	assert.Error(err)
+
+	_, err = Parse("SHOW NODE (id AS)")
+	assert.Error(err)
+
+	_, err = Parse("SHOW NODE LIMIT 5 (id)")
+	assert.Error(err)
+}
+
+func TestParseWithParams(t *testing.T) {
+	assert := assert.New(t)
+
+	// the quote in the bound value is not interpreted as a quote.
+	pwd := "abc' WITH ADMIN PRIVILEGE --"
+	stmt, err := ParseWithParams("CREATE USER alice WITH PASSWORD $1", pwd)
+	assert.NoError(err)
+	assert.Equal(&ast.CreateUserStatement{User: meta.User{Name: "alice", Password: pwd}}, stmt)
+
+	stmt, err = ParseWithParams("SET PASSWORD FOR alice = $1", `it's "quoted"`)
+	assert.NoError(err)
+	assert.Equal(&ast.SetPasswordStatement{Name: "alice", Password: `it's "quoted"`}, stmt)
+
+	stmt, err = ParseWithParams("SHOW USER AFTER $2 LIMIT $1", 10, "bob")
+	assert.NoError(err)
+	assert.Equal(&ast.ShowUserStatement{Pagination: ast.Pagination{After: "bob", Limit: 10}}, stmt)
+
+	stmt, err = ParseWithParams("CREATE DATABASE foo WITH DURATION $1", 2*time.Hour)
+	assert.NoError(err)
+	assert.Equal(&ast.CreateDatabaseStatement{Database: meta.Database{Name: "foo", Duration: 2 * time.Hour}}, stmt)
+
+	stmt, err = ParseWithParams("CREATE DATABASE foo WITH DURATION $1", "1d")
+	assert.NoError(err)
+	assert.Equal(&ast.CreateDatabaseStatement{Database: meta.Database{Name: "foo", Duration: 24 * time.Hour}}, stmt)
+
+	_, err = ParseWithParams("SHOW USER LIMIT $1")
+	assert.ErrorContains(err, "parameter $1 is not bound")
+
+	_, err = ParseWithParams("SHOW USER LIMIT $1", "10")
+	assert.ErrorContains(err, "parameter $1 must be an integer, got string")
+
+	_, err = ParseWithParams("SHOW USER LIMIT $1", -1)
+	assert.ErrorContains(err, "parameter $1 must not be negative")
+
+	_, err = ParseWithParams("SHOW USER AFTER $1", 1)
+	assert.ErrorContains(err, "parameter $1 must be a string, got int")
+
+	// parameters cannot be used as identifiers.
+	_, err = ParseWithParams("DROP USER $1", "alice")
+	assert.Error(err)
+}
+
+func TestParseReservedWordHint(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := Parse("CREATE DATABASE node")
+	assert.ErrorContains(err, `use "node" to use reserved word as identifier`)
+
+	_, err = Parse("DROP USER Database")
+	assert.ErrorContains(err, `use "Database" to use reserved word as identifier`)
+
+	stmt, err := Parse(`CREATE DATABASE "node"`)
+	assert.NoError(err)
+	assert.Equal(&ast.CreateDatabaseStatement{Database: meta.Database{Name: "node"}}, stmt)
+
+	// no hint if an identifier is not expected.
+	_, err = Parse("CREATE node")
+	assert.Error(err)
+	assert.NotContains(err.Error(), "reserved word")
+
+	// no hint if the unexpected token is not a keyword.
+	_, err = Parse("CREATE DATABASE 'node'")
+	assert.Error(err)
+	assert.NotContains(err.Error(), "reserved word")
+}
+
+func TestParseError(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := Parse("CREATE USER alice WITH PASSWORD '\\q'\n 123")
+	pe, ok := err.(*ParseError)
+	if !assert.True(ok) || !assert.Len(pe.Entries, 2) {
+		return
+	}
+
+	assert.Equal(Position{Offset: 32, Line: 1, Column: 33}, pe.Entries[0].Pos)
+	assert.Equal(`'***'`, pe.Entries[0].Token)
+	assert.Equal("invalid char escape", pe.Entries[0].Msg)
+
+	assert.Equal(Position{Offset: 38, Line: 2, Column: 2}, pe.Entries[1].Pos)
+	assert.Equal("123", pe.Entries[1].Token)
+	assert.Equal("syntax error: unexpected VAL_INT", pe.Entries[1].Msg)
+
+	assert.Equal("<input>:1:33: '***': invalid char escape\n<input>:2:2: 123: syntax error: unexpected VAL_INT", err.Error())
+}
+
+func TestParseTrailing(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := Parse("SHOW USER garbage")
+	assert.EqualError(err, "<input>:1:11: garbage: unexpected token after statement: garbage")
+
+	_, err = Parse("SHOW USER LIMIT 5 6")
+	assert.EqualError(err, "<input>:1:19: 6: unexpected token after statement: 6")
+
+	_, err = Parse("SHOW USER; DROP USER alice")
+	assert.EqualError(err, "<input>:1:12: DROP: unexpected token after statement: DROP")
+
+	stmt, err := Parse("SHOW USER -- comment")
+	assert.NoError(err)
+	assert.Equal(&ast.ShowUserStatement{}, stmt)
+
+	stmt, err = Parse("SHOW USER /* comment */ ;")
+	assert.NoError(err)
+	assert.Equal(&ast.ShowUserStatement{}, stmt)
+
+	// an incomplete statement is still a syntax error.
+	_, err = Parse("SHOW USER LIMIT garbage")
+	assert.ErrorContains(err, "syntax error")
+}
+
+func TestParseExplain(t *testing.T) {
+	assert := assert.New(t)
+
+	stmt, err := Parse("EXPLAIN SHOW USER LIMIT 10")
+	assert.NoError(err)
+	assert.Equal(&ast.ExplainStatement{
+		Statement: &ast.ShowUserStatement{Pagination: ast.Pagination{Limit: 10}},
+	}, stmt)
+
+	stmt, err = Parse("explain DROP USER alice")
+	assert.NoError(err)
+	assert.Equal(&ast.ExplainStatement{Statement: &ast.DropUserStatement{Name: "alice"}}, stmt)
+
+	_, err = Parse("EXPLAIN EXPLAIN SHOW USER")
+	assert.ErrorContains(err, "cannot explain an EXPLAIN statement")
+
+	_, err = Parse("EXPLAIN")
+	assert.Error(err)
+}
+
+func TestParseErrorRedacted(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, q := range []string{
+		"CREATE USER 'bob' 'secret",
+		"SET PASSWORD FOR bob 'secret'",
+		"CREATE USER bob WITH PASSWORD 'pwd' 'secret'",
+		"CREATE USER bob WITH PASSWORD $$secret",
+	} {
+		_, err := Parse(q)
+		if assert.Error(err, q) {
+			assert.NotContains(err.Error(), "secret", q)
+		}
+	}
+
+	// other tokens are still echoed.
+	_, err := Parse("SHOW USER foo")
+	assert.ErrorContains(err, "foo")
+}
@@ -7,6 +7,7 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"sort"
 	"unicode"
 	"unicode/utf8"
 )
@@ -45,6 +46,7 @@ const (
 	ScanResultDuration
 	ScanResultString
 	ScanResultRawString
+	ScanResultParam
 )
 
 var tokenString = map[rune]string{
@@ -57,6 +59,7 @@ var tokenString = map[rune]string{
 	ScanResultDuration:    "duration",
 	ScanResultString:      "string",
 	ScanResultRawString:   "raw string",
+	ScanResultParam:       "parameter",
 }
 
 // TokenString returns a printable string for a token or Unicode character.
@@ -67,12 +70,18 @@ func TokenString(tok rune) string {
 	return fmt.Sprintf("%q", string(tok))
 }
 
+// Predefined mode bits to control the behavior of the scanner.
+const (
+	AllowNUL = 1 << iota // allow the NUL character in the source
+)
+
 const bufLen = 1024 // at least utf8.UTFMax
 
 // Scanner implements a scanner and tokenizer for UTF-8-encoded text.
 // It takes an io.Reader providing the source, which then can be tokenized
 // through repeated calls to the Scan function. For compatibility with
-// existing tools, the NUL character is not allowed. If the first character
+// existing tools, the NUL character is not allowed unless [AllowNUL] is set
+// in [Scanner.Mode]. If the first character
 // in the source is a UTF-8 encoded byte order mark (BOM), it is discarded.
 type Scanner struct {
 	// Input
@@ -90,6 +99,10 @@ type Scanner struct {
 	lastLineLen  int // length of last line in characters (for correct column reporting)
 	lastCharLen  int // length of last character in bytes
 
+	// Line starts and multi-byte characters, for PositionForOffset
+	lineStarts []int      // byte offsets of the start of lines
+	wideRunes  []wideRune // multi-byte characters in offset order
+
 	// Token text buffer
 	// Typically, token text is stored completely in srcBuf, but in general
 	// the token text's head may be buffered in tokBuf while the token text's
@@ -108,6 +121,18 @@ type Scanner struct {
 	// ErrorCount is incremented by one for each error encountered.
 	ErrorCount int
 
+	// ErrorCode is the code of the most recently encountered error, it is
+	// set before Error is called.
+	ErrorCode ErrorCode
+
+	// Formatter renders the messages passed to Error, [DefaultMessage] is
+	// used if it is nil.
+	Formatter MessageFormatter
+
+	// The Mode field controls the behavior of the scanner, it is a set of
+	// the predefined mode bits like [AllowNUL].
+	Mode uint
+
 	// Start position of most recently scanned token; set by Scan.
 	// Calling Init invalidates the position (Line == 0).
 	// The Filename field is always left untouched by the Scanner.
@@ -135,6 +160,8 @@ func (s *Scanner) Init(src io.Reader) *Scanner {
 	s.column = 0
 	s.lastLineLen = 0
 	s.lastCharLen = 0
+	s.lineStarts = append(s.lineStarts[:0], 0)
+	s.wideRunes = s.wideRunes[:0]
 
 	// initialize token text buffer
 	// (required for first call to next()).
@@ -182,7 +209,7 @@ func (s *Scanner) next() rune {
 			s.srcBuf[s.srcEnd] = utf8.RuneSelf // sentinel
 			if err != nil {
 				if err != io.EOF {
-					s.error(err.Error())
+					s.error(ErrRead, err.Error())
 				}
 				if s.srcEnd == 0 {
 					if s.lastCharLen > 0 {
@@ -209,9 +236,12 @@ func (s *Scanner) next() rune {
 				s.srcPos += width
 				s.lastCharLen = width
 				s.column++
-				s.error("invalid UTF-8 encoding")
+				s.error(ErrInvalidUTF8)
 				return ch
 			}
+			if width > 1 {
+				s.addWideRune(s.srcBufOffset+s.srcPos, width)
+			}
 		}
 	}
 
@@ -224,16 +254,66 @@ func (s *Scanner) next() rune {
 	switch ch {
 	case 0:
 		// for compatibility with other tools
-		s.error("invalid character NUL")
+		if s.Mode&AllowNUL == 0 {
+			s.error(ErrInvalidNUL)
+		}
 	case '\n':
 		s.line++
 		s.lastLineLen = s.column
 		s.column = 0
+		s.lineStarts = append(s.lineStarts, s.srcBufOffset+s.srcPos)
 	}
 
 	return ch
 }
 
+// wideRune records a multi-byte character in the source.
+type wideRune struct {
+	offset int // byte offset of the character
+	extra  int // total extra bytes of multi-byte characters up to this one
+}
+
+// addWideRune records a multi-byte character of 'width' bytes at 'offset'.
+func (s *Scanner) addWideRune(offset, width int) {
+	extra := width - 1
+	if n := len(s.wideRunes); n > 0 {
+		extra += s.wideRunes[n-1].extra
+	}
+	s.wideRunes = append(s.wideRunes, wideRune{offset: offset, extra: extra})
+}
+
+// extraBytes returns the total extra bytes of multi-byte characters before
+// 'offset'.
+func (s *Scanner) extraBytes(offset int) int {
+	i := sort.Search(len(s.wideRunes), func(i int) bool {
+		return s.wideRunes[i].offset >= offset
+	})
+	if i == 0 {
+		return 0
+	}
+	return s.wideRunes[i-1].extra
+}
+
+// PositionForOffset returns the position of byte 'offset' in the source. It
+// is only valid for offsets which have already been consumed by the scanner,
+// and the result is invalid (Line == 0) if 'offset' is negative.
+func (s *Scanner) PositionForOffset(offset int) Position {
+	pos := Position{Filename: s.Filename, Offset: offset}
+	if offset < 0 {
+		return pos
+	}
+
+	// the number of lines which start at or before 'offset'.
+	line := sort.Search(len(s.lineStarts), func(i int) bool {
+		return s.lineStarts[i] > offset
+	})
+	start := s.lineStarts[line-1]
+
+	pos.Line = line
+	pos.Column = offset - start - (s.extraBytes(offset) - s.extraBytes(start)) + 1
+	return pos
+}
+
 // Next reads and returns the next Unicode character. It returns [ScanResultEOF]
 // at the end of the source. It reports a read error by calling s.Error. Next
 // does not update the [Scanner.Position] field; use [Scanner.Pos]() to get the
@@ -262,17 +342,22 @@ func (s *Scanner) Peek() rune {
 	return s.ch
 }
 
-func (s *Scanner) error(msg string) {
+func (s *Scanner) error(code ErrorCode, args ...any) {
 	s.tokEnd = s.srcPos - s.lastCharLen // make sure token text is terminated
 	s.ErrorCount++
+	s.ErrorCode = code
 	if s.Error == nil {
 		return
 	}
-	s.Error(s, msg)
+	s.Error(s, s.format(code, args...))
 }
 
-func (s *Scanner) errorf(format string, args ...any) {
-	s.error(fmt.Sprintf(format, args...))
+// format renders the message of error 'code' with the formatter.
+func (s *Scanner) format(code ErrorCode, args ...any) string {
+	if s.Formatter != nil {
+		return s.Formatter(code, args...)
+	}
+	return DefaultMessage(code, args...)
 }
 
 func lower(ch rune) rune        { return ('a' - 'A') | ch } // returns lower-case ch iff ch is ASCII letter
@@ -281,6 +366,15 @@ func isDecimal(ch rune) bool    { return '0' <= ch && ch <= '9' }
 func isHex(ch rune) bool        { return '0' <= ch && ch <= '9' || 'a' <= lower(ch) && lower(ch) <= 'f' }
 func isWhitespace(ch rune) bool { return (1<<'\t'|1<<'\n'|1<<'\r'|1<<' ')&(1<<uint(ch)) != 0 }
 
+// scanParam scans the digits of a positional parameter like '$1', the '$' has
+// been consumed and 'ch' is the first digit.
+func (s *Scanner) scanParam(ch rune) rune {
+	for isDecimal(ch) {
+		ch = s.next()
+	}
+	return ch
+}
+
 func (s *Scanner) scanIdentifier() rune {
 	// we know the zero'th rune is OK; start scanning at the next one
 	ch := s.next()
@@ -347,7 +441,7 @@ func (s *Scanner) duration(ch rune, notDec bool) (rune, rune) {
 			// this is an invalid duration if we already saw a unit, and an
 			// integer otherwise
 			if sawUnit {
-				s.errorf("invalid duration unit")
+				s.error(ErrInvalidDurationUnit)
 				return ScanResultDuration, ch
 			}
 			return ScanResultInt, ch
@@ -366,7 +460,7 @@ func (s *Scanner) duration(ch rune, notDec bool) (rune, rune) {
 	}
 
 	if notDec {
-		s.error("duration requires decimal integer")
+		s.error(ErrDurationNotDecimal)
 	}
 
 	return ScanResultDuration, ch
@@ -416,7 +510,7 @@ func (s *Scanner) doScanNumberOrDuration(ch rune, sawDot bool) (rune, rune) {
 	if sawDot {
 		tok = ScanResultFloat
 		if prefix == 'o' || prefix == 'b' {
-			s.error("invalid radix point in " + litname(prefix))
+			s.error(ErrInvalidRadixPoint, litname(prefix))
 		}
 		var n int
 		ch, n = s.digits(ch, base, &invalid)
@@ -424,16 +518,16 @@ func (s *Scanner) doScanNumberOrDuration(ch rune, sawDot bool) (rune, rune) {
 	}
 
 	if !hasDigit {
-		s.error(litname(prefix) + " has no digits")
+		s.error(ErrNoDigits, litname(prefix))
 	}
 
 	// exponent
 	if e := lower(ch); e == 'e' || e == 'p' {
 		switch {
 		case e == 'e' && prefix != 0 && prefix != '0':
-			s.errorf("%q exponent requires decimal mantissa", ch)
+			s.error(ErrExponentNeedsDecimal, ch)
 		case e == 'p' && prefix != 'x':
-			s.errorf("%q exponent requires hexadecimal mantissa", ch)
+			s.error(ErrExponentNeedsHex, ch)
 		}
 		ch = s.next()
 		tok = ScanResultFloat
@@ -442,10 +536,10 @@ func (s *Scanner) doScanNumberOrDuration(ch rune, sawDot bool) (rune, rune) {
 		}
 		var n int
 		if ch, n = s.digits(ch, 10, nil); n == 0 {
-			s.error("exponent has no digits")
+			s.error(ErrExponentNoDigits)
 		}
 	} else if prefix == 'x' && tok == ScanResultFloat {
-		s.error("hexadecimal mantissa requires a 'p' exponent")
+		s.error(ErrMantissaNeedsExponent)
 	}
 
 	if tok != ScanResultInt {
@@ -453,7 +547,7 @@ func (s *Scanner) doScanNumberOrDuration(ch rune, sawDot bool) (rune, rune) {
 	}
 
 	if invalid != 0 {
-		s.errorf("invalid digit %q in %s", invalid, litname(prefix))
+		s.error(ErrInvalidDigit, invalid, litname(prefix))
 	}
 
 	// it can also be a duration, so make a try.
@@ -475,7 +569,7 @@ func (s *Scanner) scanNumberOrDuration(ch rune, sawDot bool) (rune, rune) {
 		ch = s.next()
 	}
 	if hasExtra {
-		s.errorf("extra character after %s", TokenString(tok))
+		s.error(ErrExtraCharacter, TokenString(tok))
 	}
 
 	return tok, ch
@@ -510,7 +604,7 @@ func (s *Scanner) scanDigits(ch rune, base, n int) rune {
 		n--
 	}
 	if n > 0 {
-		s.error("invalid char escape")
+		s.error(ErrInvalidCharEscape)
 	}
 	return ch
 }
@@ -530,7 +624,7 @@ func (s *Scanner) scanEscape(quote byte) rune {
 	case 'U':
 		ch = s.scanDigits(s.next(), 16, 8)
 	default:
-		s.error("invalid char escape")
+		s.error(ErrInvalidCharEscape)
 	}
 	return ch
 }
@@ -539,7 +633,7 @@ func (s *Scanner) scanString(quote byte) rune {
 	ch := s.next() // read character after quote
 	for ch != rune(quote) {
 		if ch == '\n' || ch < 0 {
-			s.error("literal not terminated")
+			s.error(ErrLiteralNotTerminated)
 			break
 		}
 		if ch == '\\' {
@@ -555,7 +649,7 @@ func (s *Scanner) scanRawString() rune {
 	ch := s.next() // read character after '`'
 	for ch != '`' {
 		if ch < 0 {
-			s.error("literal not terminated")
+			s.error(ErrLiteralNotTerminated)
 			break
 		}
 		ch = s.next()
@@ -563,6 +657,38 @@ func (s *Scanner) scanRawString() rune {
 	return s.next()
 }
 
+// scanDollarString scans a dollar-quoted string like '$tag$...$tag$', the
+// leading '$' has been consumed and 'ch' is the character after it. The tag
+// is optional and follows the rules of identifiers.
+func (s *Scanner) scanDollarString(ch rune) rune {
+	delim := []rune{'$'}
+	for ch != '$' {
+		if !isIdentRune(ch) {
+			s.error(ErrInvalidDollarQuoteTag)
+			return ch
+		}
+		delim = append(delim, ch)
+		ch = s.next()
+	}
+	delim = append(delim, '$')
+
+	// the tag cannot contain '$', so a mismatch can only restart at a '$'.
+	for matched := 0; matched < len(delim); {
+		if ch = s.next(); ch < 0 {
+			s.error(ErrDollarStringNotTerminated)
+			return ch
+		}
+		if ch == delim[matched] {
+			matched++
+		} else if ch == '$' {
+			matched = 1
+		} else {
+			matched = 0
+		}
+	}
+	return s.next()
+}
+
 func (s *Scanner) scanLineComment() rune {
 	ch := s.next() // read character after "--"
 	for ch != '\n' && ch >= 0 {
@@ -576,7 +702,7 @@ func (s *Scanner) scanBlockComment() rune {
 	ch := s.next() // read character after "/*"
 	for {
 		if ch < 0 {
-			s.error("comment not terminated")
+			s.error(ErrCommentNotTerminated)
 			break
 		}
 		ch0 := ch
@@ -667,6 +793,15 @@ func (s *Scanner) Scan() rune {
 			ch = s.scanRawString()
 			tok = ScanResultRawString
 
+		case '$':
+			if ch = s.next(); isDecimal(ch) {
+				ch = s.scanParam(ch)
+				tok = ScanResultParam
+			} else if ch == '$' || ch == '_' || unicode.IsLetter(ch) {
+				ch = s.scanDollarString(ch)
+				tok = ScanResultRawString
+			}
+
 		default:
 			ch = s.next()
 		}
@@ -703,12 +838,12 @@ func (s *Scanner) Pos() (pos Position) {
 	return
 }
 
-// TokenText returns the string corresponding to the most recently scanned token.
-// Valid after calling [Scanner.Scan] and in calls of [Scanner.Error].
-func (s *Scanner) TokenText() string {
+// tokenBytes returns the bytes of the most recently scanned token, 'spilled'
+// is false if the bytes are in srcBuf, or true if they are in tokBuf.
+func (s *Scanner) tokenBytes() (b []byte, spilled bool) {
 	if s.tokPos < 0 {
 		// no token text
-		return ""
+		return nil, false
 	}
 
 	if s.tokEnd < s.tokPos {
@@ -719,12 +854,31 @@ func (s *Scanner) TokenText() string {
 
 	if s.tokBuf.Len() == 0 {
 		// common case: the entire token text is still in srcBuf
-		return string(s.srcBuf[s.tokPos:s.tokEnd])
+		return s.srcBuf[s.tokPos:s.tokEnd:s.tokEnd], false
 	}
 
 	// part of the token text was saved in tokBuf: save the rest in
 	// tokBuf as well and return its content
 	s.tokBuf.Write(s.srcBuf[s.tokPos:s.tokEnd])
-	s.tokPos = s.tokEnd // ensure idempotency of TokenText() call
-	return s.tokBuf.String()
+	s.tokPos = s.tokEnd // ensure idempotency of tokenBytes() call
+	return s.tokBuf.Bytes(), true
+}
+
+// TokenBytes is like [Scanner.TokenText], but returns the bytes of the token.
+// The bytes are not copied if the whole token text is still buffered, which
+// is the common case, so they are only valid until the next call to
+// [Scanner.Scan] and must not be modified.
+func (s *Scanner) TokenBytes() []byte {
+	b, spilled := s.tokenBytes()
+	if spilled {
+		return bytes.Clone(b)
+	}
+	return b
+}
+
+// TokenText returns the string corresponding to the most recently scanned token.
+// Valid after calling [Scanner.Scan] and in calls of [Scanner.Error].
+func (s *Scanner) TokenText() string {
+	b, _ := s.tokenBytes()
+	return string(b)
 }
@@ -252,6 +252,10 @@ var tokenList = []token{
 	{ScanResultRawString, "`\\`"},
 	{ScanResultRawString, "`" + "\n\n/* foobar */\n\n" + "`"},
 	{ScanResultRawString, "`" + f100 + "`"},
+	{ScanResultRawString, "$$hello$$"},
+	{ScanResultRawString, `$q$a'b"c$q$`},
+	{ScanResultRawString, "$q$$$q$"},
+	{ScanResultRawString, "$_1$\n$_$_1$"},
 
 	{ScanResultComment, "-- individual characters"},
 	// NUL character is not allowed
@@ -426,6 +430,25 @@ func testError(t *testing.T, src, pos, msg string, tok rune) {
 	}
 }
 
+func TestAllowNUL(t *testing.T) {
+	const src = "`a\x00b` \"a\x00b\" \x00a"
+
+	s := new(Scanner).Init(strings.NewReader(src))
+	s.Error = func(s *Scanner, msg string) {}
+	checkTokErr(t, s, 1, ScanResultRawString, "`a\x00b`")
+
+	s = new(Scanner).Init(strings.NewReader(src))
+	s.Mode = AllowNUL
+	checkTok(t, s, 1, s.Scan(), ScanResultRawString, "`a\x00b`")
+	checkTok(t, s, 1, s.Scan(), ScanResultQuotedIdent, "\"a\x00b\"")
+	// NUL cannot begin an identifier
+	checkTok(t, s, 1, s.Scan(), 0, "\x00")
+	checkTok(t, s, 1, s.Scan(), ScanResultIdent, "a")
+	if s.ErrorCount != 0 {
+		t.Errorf("%d errors reported, want 0", s.ErrorCount)
+	}
+}
+
 func TestError(t *testing.T) {
 	s := new(Scanner).Init(strings.NewReader("1d3"))
 	checkTokErr(t, s, 1, ScanResultDuration, "1d3")
@@ -460,6 +483,9 @@ func TestError(t *testing.T) {
 	testError(t, `"abc`, "<input>:1:5", "literal not terminated", ScanResultQuotedIdent)
 	testError(t, `"abc`+"\n", "<input>:1:5", "literal not terminated", ScanResultQuotedIdent)
 	testError(t, "`abc\n", "<input>:2:1", "literal not terminated", ScanResultRawString)
+	testError(t, "$q$abc$q", "<input>:1:9", "dollar-quoted string not terminated", ScanResultRawString)
+	testError(t, "$$abc", "<input>:1:6", "dollar-quoted string not terminated", ScanResultRawString)
+	testError(t, "$q abc", "<input>:1:3", "invalid dollar-quote tag", ScanResultRawString)
 	testError(t, `/*/`, "<input>:1:4", "comment not terminated", ScanResultComment)
 	testError(t, `'`, "<input>:1:2", "literal not terminated", ScanResultString)
 	testError(t, `'`+"\n", "<input>:1:2", "literal not terminated", ScanResultString)
@@ -474,6 +500,71 @@ func TestError(t *testing.T) {
 	testError(t, `'abc\02m'`, "<input>:1:8", "invalid char escape", ScanResultString)
 }
 
+func TestErrorCode(t *testing.T) {
+	cases := []struct {
+		src  string
+		code ErrorCode
+	}{
+		{"\x80", ErrInvalidUTF8},
+		{"\x00", ErrInvalidNUL},
+		{"1d2x", ErrInvalidDurationUnit},
+		{"1d03m", ErrDurationNotDecimal},
+		{"0b1.1", ErrInvalidRadixPoint},
+		{"0x", ErrNoDigits},
+		{"0b1e1", ErrExponentNeedsDecimal},
+		{"1p1", ErrExponentNeedsHex},
+		{"1.5e", ErrExponentNoDigits},
+		{"0x1.8", ErrMantissaNeedsExponent},
+		{"01238", ErrInvalidDigit},
+		{"12abc", ErrExtraCharacter},
+		{`'\q'`, ErrInvalidCharEscape},
+		{`'abc`, ErrLiteralNotTerminated},
+		{"$q abc", ErrInvalidDollarQuoteTag},
+		{"$$abc", ErrDollarStringNotTerminated},
+		{"/*", ErrCommentNotTerminated},
+	}
+
+	for _, c := range cases {
+		s := new(Scanner).Init(strings.NewReader(c.src))
+		var codes []ErrorCode
+		s.Error = func(s *Scanner, msg string) {
+			codes = append(codes, s.ErrorCode)
+			if want := DefaultMessage(s.ErrorCode); !strings.Contains(errorFormats[s.ErrorCode], "%") && msg != want {
+				t.Errorf("msg = %q, want %q for %q", msg, want, c.src)
+			}
+		}
+		s.Scan()
+		if len(codes) == 0 || codes[0] != c.code {
+			t.Errorf("codes = %v, want %d first for %q", codes, c.code, c.src)
+		}
+	}
+
+	s := new(Scanner).Init(errReader{})
+	s.Error = func(s *Scanner, msg string) {}
+	s.Scan()
+	if s.ErrorCode != ErrRead {
+		t.Errorf("code = %d, want %d for read error", s.ErrorCode, ErrRead)
+	}
+}
+
+func TestErrorFormatter(t *testing.T) {
+	s := new(Scanner).Init(strings.NewReader("01238"))
+	s.Formatter = func(code ErrorCode, args ...any) string {
+		return fmt.Sprintf("E%d: %q", code, args)
+	}
+	var msg string
+	s.Error = func(s *Scanner, m string) { msg = m }
+	s.Scan()
+
+	want := fmt.Sprintf(`E%d: ['8' "octal literal"]`, ErrInvalidDigit)
+	if msg != want {
+		t.Errorf("msg = %q, want %q", msg, want)
+	}
+	if got := DefaultMessage(ErrInvalidDigit, '8', "octal literal"); got != "invalid digit '8' in octal literal" {
+		t.Errorf("default msg = %q", got)
+	}
+}
+
 // An errReader returns (n, err) where err is not io.EOF.
 type errReader struct{ n int }
 
@@ -619,6 +710,59 @@ func TestPos(t *testing.T) {
 	}
 }
 
+func TestPositionForOffset(t *testing.T) {
+	// positions are the same as those in TestPos.
+	s := new(Scanner).Init(strings.NewReader("  foo६४  \n\n本語\n"))
+	for s.Scan() != ScanResultEOF {
+	}
+	for _, want := range []Position{
+		{Offset: 0, Line: 1, Column: 1},
+		{Offset: 1, Line: 1, Column: 2},
+		{Offset: 5, Line: 1, Column: 6},
+		{Offset: 8, Line: 1, Column: 7},
+		{Offset: 11, Line: 1, Column: 8},
+		{Offset: 13, Line: 1, Column: 10},
+		{Offset: 14, Line: 2, Column: 1},
+		{Offset: 15, Line: 3, Column: 1},
+		{Offset: 18, Line: 3, Column: 2},
+		{Offset: 21, Line: 3, Column: 3},
+		{Offset: 22, Line: 4, Column: 1},
+	} {
+		checkPos(t, s.PositionForOffset(want.Offset), want)
+	}
+
+	s = new(Scanner).Init(strings.NewReader("abc\n本語\n\nx"))
+	for s.Scan() != ScanResultEOF {
+	}
+	for _, want := range []Position{
+		{Offset: 3, Line: 1, Column: 4},
+		{Offset: 4, Line: 2, Column: 1},
+		{Offset: 10, Line: 2, Column: 3},
+		{Offset: 12, Line: 4, Column: 1},
+		{Offset: 13, Line: 4, Column: 2},
+	} {
+		checkPos(t, s.PositionForOffset(want.Offset), want)
+	}
+
+	if pos := s.PositionForOffset(-1); pos.IsValid() {
+		t.Errorf("position for negative offset should be invalid, got %s", pos)
+	}
+
+	// a source which is larger than the source buffer, the position of each
+	// token should match the one reported by the scanner.
+	src := strings.Repeat("本 x\n", bufLen)
+	s = new(Scanner).Init(strings.NewReader(src))
+	for tok := s.Scan(); tok != ScanResultEOF; tok = s.Scan() {
+		checkPos(t, s.PositionForOffset(s.Offset), s.Position)
+	}
+
+	// Init resets the recorded positions.
+	s.Init(strings.NewReader("本\nx"))
+	for s.Scan() != ScanResultEOF {
+	}
+	checkPos(t, s.PositionForOffset(4), Position{Offset: 4, Line: 2, Column: 1})
+}
+
 type countReader int
 
 func (r *countReader) Read([]byte) (int, error) {
@@ -896,3 +1040,61 @@ func TestTokenText(t *testing.T) {
 		t.Errorf("error handler not called")
 	}
 }
+
+func TestTokenBytes(t *testing.T) {
+	long := strings.Repeat("x", 3*bufLen)
+	src := "select abc_123 from " + long + " where 42"
+
+	zeroCopy := map[string]bool{}
+	s := new(Scanner).Init(strings.NewReader(src))
+	for tok := s.Scan(); tok != ScanResultEOF; tok = s.Scan() {
+		spilled := s.tokBuf.Len() > 0
+		tb := s.TokenBytes()
+		zeroCopy[string(tb)] = len(tb) > 0 && &tb[0] == &s.srcBuf[s.tokPos]
+		if zeroCopy[string(tb)] == spilled {
+			t.Errorf("token %.16q: zero copy = %v, spilled = %v", tb, !spilled, spilled)
+		}
+
+		if tt := s.TokenText(); string(tb) != tt {
+			t.Errorf("token bytes = %q, want %q", tb, tt)
+		}
+	}
+
+	if !zeroCopy["abc_123"] {
+		t.Errorf("short token is copied")
+	}
+	if zeroCopy[long] {
+		t.Errorf("long token is not copied")
+	}
+
+	if tb := s.TokenBytes(); len(tb) != 0 {
+		t.Errorf("token bytes at EOF = %q, want empty", tb)
+	}
+}
+
+// sinkText and sinkBytes prevent the compiler from optimizing away the token
+// text in the benchmarks.
+var (
+	sinkText  string
+	sinkBytes []byte
+)
+
+func BenchmarkTokenText(b *testing.B) {
+	src := strings.Repeat("select a, b from tbl where c > 100 ", 100)
+	for range b.N {
+		s := new(Scanner).Init(strings.NewReader(src))
+		for tok := s.Scan(); tok != ScanResultEOF; tok = s.Scan() {
+			sinkText = s.TokenText()
+		}
+	}
+}
+
+func BenchmarkTokenBytes(b *testing.B) {
+	src := strings.Repeat("select a, b from tbl where c > 100 ", 100)
+	for range b.N {
+		s := new(Scanner).Init(strings.NewReader(src))
+		for tok := s.Scan(); tok != ScanResultEOF; tok = s.Scan() {
+			sinkBytes = s.TokenBytes()
+		}
+	}
+}
@@ -2,6 +2,7 @@
 package parser
 
 import "net/netip"
+import "strings"
 import "time"
 
 import "github.com/localvar/xuandb/pkg/meta"
@@ -15,6 +16,12 @@ import "github.com/localvar/xuandb/pkg/query/ast"
     int     uint64
     float   float64
     bool    bool
+    page    ast.Pagination
+    proj    ast.Projection
+    alias   ast.ColumnAlias
+    field   *ast.Field
+    fields  []*ast.Field
+    strs    []string
 }
 
 // Identifiers
@@ -26,7 +33,9 @@ import "github.com/localvar/xuandb/pkg/query/ast"
        USER   DATABASE   NODE   CLUSTER   VOTER   NONVOTER
        AS   AT   BY   FOR   IN   ON   WHERE   WITH
        GROUP   LIMIT   OFFSET   JOIN   BETWEEN   DURATION   PASSWORD
-       PRIVILEGE
+       PRIVILEGE   CURRENT   AFTER   FROM   IF   EXISTS   EXPLAIN
+       RAFT   STATS   DRAIN   LOG   TO   FLUSH   CACHE   LEVEL   QUERIES
+       KILL   QUERY   GRANTS   ENABLE   DISABLE   CONFIG   VERSION   VERSIONS
 
 // comments
 %token<str>    COMMENT
@@ -37,6 +46,9 @@ import "github.com/localvar/xuandb/pkg/query/ast"
 %token<float>  VAL_FLT
 %token<bool>   VAL_BOOL
 
+// Positional parameters like '$1', the value is the index
+%token<int>    PARAM
+
 // Operators
 %left  OP_ASSIGN
 %left  OP_OR
@@ -57,17 +69,37 @@ import "github.com/localvar/xuandb/pkg/query/ast"
 // the last token also.
 %token<str> ERR_TOKEN
 
-%type<str>  ADDR_PORT
+%type<str>  ADDR_PORT STR_VALUE
+%type<int>  INT_VALUE DURATION_VALUE
+%type<page> PAGINATION
+%type<proj> PROJECTION COLUMN_ALIASES
+%type<alias> COLUMN_ALIAS
+%type<bool> IF_EXISTS IF_NOT_EXISTS
+%type<field> FIELD
+%type<fields> FIELDS
+%type<strs> IDENTS GROUP_BY
 
 // Statements
 %type<stmt> STATEMENT
             CREATE_USER_STATEMENT SHOW_USER_STATEMENT DROP_USER_STATEMENT SET_PASSWORD_STATEMENT
+            ALTER_USER_STATEMENT
+            SHOW_CURRENT_USER_STATEMENT SHOW_GRANTS_STATEMENT
             CREATE_DATABASE_STATEMENT DROP_DATABASE_STATEMENT SHOW_DATABASE_STATEMENT
-            JOIN_NODE_STATEMENT DROP_NODE_STATEMENT SHOW_NODE_STATEMENT
+            JOIN_NODE_STATEMENT DROP_NODE_STATEMENT DRAIN_NODE_STATEMENT SHOW_NODE_STATEMENT
+            SHOW_RAFT_STATS_STATEMENT SHOW_RAFT_LOG_STATEMENT FLUSH_CACHE_STATEMENT
+            SHOW_CLUSTER_LOG_LEVEL_STATEMENT SET_CLUSTER_LOG_LEVEL_STATEMENT
+            SHOW_QUERIES_STATEMENT KILL_QUERY_STATEMENT SHOW_CONFIG_STATEMENT
+            SHOW_VERSION_STATEMENT SHOW_CLUSTER_VERSIONS_STATEMENT
+            SELECT_STATEMENT SELECT_SOURCE
 
 
 %%
 
+// INPUT is a single statement with an optional terminator.
+INPUT:
+    STATEMENT
+    | STATEMENT ';'
+
 STATEMENT:
     CREATE_USER_STATEMENT
 	{
@@ -84,11 +116,26 @@ STATEMENT:
         yylex.(*Lexer).Result = $1
 		$$ = $1
     }
+    | ALTER_USER_STATEMENT
+    {
+        yylex.(*Lexer).Result = $1
+        $$ = $1
+    }
     | SHOW_USER_STATEMENT
     {
         yylex.(*Lexer).Result = $1
         $$ = $1
     }
+    | SHOW_CURRENT_USER_STATEMENT
+    {
+        yylex.(*Lexer).Result = $1
+        $$ = $1
+    }
+    | SHOW_GRANTS_STATEMENT
+    {
+        yylex.(*Lexer).Result = $1
+        $$ = $1
+    }
     | CREATE_DATABASE_STATEMENT
     {
         yylex.(*Lexer).Result = $1
@@ -114,14 +161,120 @@ STATEMENT:
         yylex.(*Lexer).Result = $1
         $$ = $1
     }
+    | DRAIN_NODE_STATEMENT
+    {
+        yylex.(*Lexer).Result = $1
+        $$ = $1
+    }
     | SHOW_NODE_STATEMENT
     {
         yylex.(*Lexer).Result = $1
         $$ = $1
     }
+    | SHOW_RAFT_STATS_STATEMENT
+    {
+        yylex.(*Lexer).Result = $1
+        $$ = $1
+    }
+    | SHOW_RAFT_LOG_STATEMENT
+    {
+        yylex.(*Lexer).Result = $1
+        $$ = $1
+    }
+    | FLUSH_CACHE_STATEMENT
+    {
+        yylex.(*Lexer).Result = $1
+        $$ = $1
+    }
+    | SHOW_CLUSTER_LOG_LEVEL_STATEMENT
+    {
+        yylex.(*Lexer).Result = $1
+        $$ = $1
+    }
+    | SET_CLUSTER_LOG_LEVEL_STATEMENT
+    {
+        yylex.(*Lexer).Result = $1
+        $$ = $1
+    }
+    | SHOW_QUERIES_STATEMENT
+    {
+        yylex.(*Lexer).Result = $1
+        $$ = $1
+    }
+    | SHOW_CONFIG_STATEMENT
+    {
+        yylex.(*Lexer).Result = $1
+        $$ = $1
+    }
+    | SHOW_VERSION_STATEMENT
+    {
+        yylex.(*Lexer).Result = $1
+        $$ = $1
+    }
+    | SHOW_CLUSTER_VERSIONS_STATEMENT
+    {
+        yylex.(*Lexer).Result = $1
+        $$ = $1
+    }
+    | KILL_QUERY_STATEMENT
+    {
+        yylex.(*Lexer).Result = $1
+        $$ = $1
+    }
+    | SELECT_STATEMENT
+    {
+        yylex.(*Lexer).Result = $1
+        $$ = $1
+    }
+    | EXPLAIN STATEMENT
+    {
+        if _, ok := $2.(*ast.ExplainStatement); ok {
+            yylex.Error("cannot explain an EXPLAIN statement")
+            goto ret1
+        }
+        stmt := &ast.ExplainStatement{Statement: $2}
+        yylex.(*Lexer).Result = stmt
+        $$ = stmt
+    }
 
-ADDR_PORT:
+STR_VALUE:
     VAL_STR
+    | PARAM
+    {
+        v, err := yylex.(*Lexer).paramString($1)
+        if err != nil {
+            yylex.Error(err.Error())
+            goto ret1
+        }
+        $$ = v
+    }
+
+INT_VALUE:
+    VAL_INT
+    | PARAM
+    {
+        v, err := yylex.(*Lexer).paramInt($1)
+        if err != nil {
+            yylex.Error(err.Error())
+            goto ret1
+        }
+        $$ = v
+    }
+
+DURATION_VALUE:
+    VAL_DURATION
+    | PARAM
+    {
+        v, err := yylex.(*Lexer).paramDuration($1)
+        if err != nil {
+            yylex.Error(err.Error())
+            goto ret1
+        }
+        $$ = uint64(v)
+    }
+
+ADDR_PORT:
+    STR_VALUE
     {
         if _, err := netip.ParseAddrPort($1); err != nil {
             yylex.Error("invalid network address")
@@ -130,15 +283,91 @@ ADDR_PORT:
         $$ = $1
     }
 
+IF_EXISTS:
+    /* empty */
+    {
+        $$ = false
+    }
+    | IF EXISTS
+    {
+        $$ = true
+    }
+
+IF_NOT_EXISTS:
+    /* empty */
+    {
+        $$ = false
+    }
+    | IF OP_NOT EXISTS
+    {
+        $$ = true
+    }
+
+COLUMN_ALIAS:
+    IDENT
+    {
+        $$ = ast.ColumnAlias{Column: $1}
+    }
+    | IDENT AS IDENT
+    {
+        $$ = ast.ColumnAlias{Column: $1, Alias: $3}
+    }
+
+COLUMN_ALIASES:
+    COLUMN_ALIAS
+    {
+        $$ = ast.Projection{Columns: []ast.ColumnAlias{$1}}
+    }
+    | COLUMN_ALIASES ',' COLUMN_ALIAS
+    {
+        $$ = ast.Projection{Columns: append($1.Columns, $3)}
+    }
+
+PROJECTION:
+    /* empty */
+    {
+        $$ = ast.Projection{}
+    }
+    | '(' COLUMN_ALIASES ')'
+    {
+        $$ = $2
+    }
+
+PAGINATION:
+    /* empty */
+    {
+        $$ = ast.Pagination{}
+    }
+    | LIMIT INT_VALUE
+    {
+        $$ = ast.Pagination{Limit: $2}
+    }
+    | LIMIT INT_VALUE OFFSET INT_VALUE
+    {
+        $$ = ast.Pagination{Limit: $2, Offset: $4}
+    }
+    | AFTER STR_VALUE
+    {
+        $$ = ast.Pagination{After: $2}
+    }
+    | AFTER STR_VALUE LIMIT INT_VALUE
+    {
+        $$ = ast.Pagination{After: $2, Limit: $4}
+    }
+    | AFTER STR_VALUE LIMIT INT_VALUE OFFSET INT_VALUE
+    {
+        $$ = ast.Pagination{After: $2, Limit: $4, Offset: $6}
+    }
+
 CREATE_USER_STATEMENT:
-    CREATE USER IDENT WITH PASSWORD VAL_STR
+    CREATE USER IDENT IF_NOT_EXISTS WITH PASSWORD STR_VALUE
     {
-        $$ = &ast.CreateUserStatement{User: meta.User{Name: $3, Password: $6}}
+        $$ = &ast.CreateUserStatement{User: meta.User{Name: $3, Password: $7}, IfNotExists: $4}
     }
-    | CREATE USER IDENT WITH PASSWORD VAL_STR WITH IDENT PRIVILEGE
+    | CREATE USER IDENT IF_NOT_EXISTS WITH PASSWORD STR_VALUE WITH IDENT PRIVILEGE
     {
-        stmt := &ast.CreateUserStatement{User: meta.User{Name: $3, Password: $6}}
-        if err := stmt.User.Priv.UnmarshalText([]byte($8)); err != nil {
+        stmt := &ast.CreateUserStatement{User: meta.User{Name: $3, Password: $7}, IfNotExists: $4}
+        if err := stmt.User.Priv.UnmarshalText([]byte($9)); err != nil {
             yylex.Error(err.Error())
             goto ret1
         }
@@ -146,39 +375,65 @@ CREATE_USER_STATEMENT:
     }
 
 DROP_USER_STATEMENT:
-    DROP USER IDENT
+    DROP USER IDENT IF_EXISTS
     {
-        $$ = &ast.DropUserStatement{Name: $3}
+        $$ = &ast.DropUserStatement{Name: $3, IfExists: $4}
     }
 
 SET_PASSWORD_STATEMENT:
-    SET PASSWORD FOR IDENT OP_EQU VAL_STR
+    SET PASSWORD FOR IDENT OP_EQU STR_VALUE
     {
         $$ = &ast.SetPasswordStatement{Name: $4, Password: $6}
     }
 
+SHOW_GRANTS_STATEMENT:
+    SHOW GRANTS FOR IDENT
+    {
+        $$ = &ast.ShowGrantsStatement{Name: $4}
+    }
+
+ALTER_USER_STATEMENT:
+    ALTER USER IDENT ENABLE
+    {
+        $$ = &ast.SetUserEnabledStatement{Name: $3, Enabled: true}
+    }
+    | ALTER USER IDENT DISABLE
+    {
+        $$ = &ast.SetUserEnabledStatement{Name: $3, Enabled: false}
+    }
+
 SHOW_USER_STATEMENT:
-    SHOW USER
+    SHOW USER PROJECTION PAGINATION
     {
-        $$ = &ast.ShowUserStatement{}
+        $$ = &ast.ShowUserStatement{Projection: $3, Pagination: $4}
+    }
+
+SHOW_CURRENT_USER_STATEMENT:
+    SHOW CURRENT USER
+    {
+        $$ = &ast.ShowCurrentUserStatement{}
     }
 
 CREATE_DATABASE_STATEMENT:
-    CREATE DATABASE IDENT WITH DURATION VAL_DURATION
+    CREATE DATABASE IDENT IF_NOT_EXISTS
     {
-        $$ = &ast.CreateDatabaseStatement{Database: meta.Database{Name: $3, Duration: time.Duration($6)}}
+        $$ = &ast.CreateDatabaseStatement{Database: meta.Database{Name: $3}, IfNotExists: $4}
+    }
+    | CREATE DATABASE IDENT IF_NOT_EXISTS WITH DURATION DURATION_VALUE
+    {
+        $$ = &ast.CreateDatabaseStatement{Database: meta.Database{Name: $3, Duration: time.Duration($7)}, IfNotExists: $4}
     }
 
 DROP_DATABASE_STATEMENT:
-    DROP DATABASE IDENT
+    DROP DATABASE IDENT IF_EXISTS
     {
-        $$ = &ast.DropDatabaseStatement{Name: $3}
+        $$ = &ast.DropDatabaseStatement{Name: $3, IfExists: $4}
     }
 
 SHOW_DATABASE_STATEMENT:
-    SHOW DATABASE
+    SHOW DATABASE PROJECTION PAGINATION
     {
-        $$ = &ast.ShowDatabaseStatement{}
+        $$ = &ast.ShowDatabaseStatement{Projection: $3, Pagination: $4}
     }
 
 JOIN_NODE_STATEMENT:
@@ -197,11 +452,158 @@ DROP_NODE_STATEMENT:
         $$ = &ast.DropNodeStatement{ID: $3}
     }
 
+DRAIN_NODE_STATEMENT:
+    DRAIN NODE IDENT
+    {
+        $$ = &ast.DrainNodeStatement{ID: $3}
+    }
+
 SHOW_NODE_STATEMENT:
-    SHOW NODE
+    SHOW NODE PROJECTION PAGINATION
+    {
+        $$ = &ast.ShowNodeStatement{Projection: $3, Pagination: $4}
+    }
+
+SHOW_RAFT_STATS_STATEMENT:
+    SHOW RAFT STATS
+    {
+        $$ = &ast.ShowRaftStatsStatement{}
+    }
+
+SHOW_RAFT_LOG_STATEMENT:
+    SHOW RAFT LOG
+    {
+        $$ = &ast.ShowRaftLogStatement{}
+    }
+    | SHOW RAFT LOG FROM INT_VALUE TO INT_VALUE
+    {
+        $$ = &ast.ShowRaftLogStatement{From: $5, To: $7}
+    }
+
+SHOW_QUERIES_STATEMENT:
+    SHOW QUERIES
+    {
+        $$ = &ast.ShowQueriesStatement{}
+    }
+
+SHOW_CONFIG_STATEMENT:
+    SHOW CONFIG
+    {
+        $$ = &ast.ShowConfigStatement{}
+    }
+
+SHOW_VERSION_STATEMENT:
+    SHOW VERSION
+    {
+        $$ = &ast.ShowVersionStatement{}
+    }
+
+KILL_QUERY_STATEMENT:
+    KILL QUERY INT_VALUE
+    {
+        $$ = &ast.KillQueryStatement{ID: $3}
+    }
+
+FLUSH_CACHE_STATEMENT:
+    FLUSH CACHE
+    {
+        $$ = &ast.FlushCacheStatement{}
+    }
+    | FLUSH CACHE IDENT
+    {
+        $$ = &ast.FlushCacheStatement{Name: $3}
+    }
+
+SHOW_CLUSTER_LOG_LEVEL_STATEMENT:
+    SHOW CLUSTER LOG LEVEL
+    {
+        $$ = &ast.ShowClusterLogLevelStatement{}
+    }
+
+SHOW_CLUSTER_VERSIONS_STATEMENT:
+    SHOW CLUSTER VERSIONS
+    {
+        $$ = &ast.ShowClusterVersionsStatement{}
+    }
+
+SET_CLUSTER_LOG_LEVEL_STATEMENT:
+    SET CLUSTER LOG LEVEL IDENT
+    {
+        $$ = &ast.SetClusterLogLevelStatement{Level: $5}
+    }
+    | SET CLUSTER LOG LEVEL STR_VALUE
+    {
+        $$ = &ast.SetClusterLogLevelStatement{Level: $5}
+    }
+
+IDENTS:
+    IDENT
+    {
+        $$ = []string{$1}
+    }
+    | IDENTS ',' IDENT
+    {
+        $$ = append($1, $3)
+    }
+
+FIELD:
+    IDENT
+    {
+        $$ = &ast.Field{Column: $1}
+    }
+    | IDENT '(' IDENT ')'
+    {
+        $$ = &ast.Field{Func: strings.ToLower($1), Column: $3}
+    }
+    | IDENT '(' OP_MUL ')'
+    {
+        $$ = &ast.Field{Func: strings.ToLower($1), Column: "*"}
+    }
+
+FIELDS:
+    FIELD
+    {
+        $$ = []*ast.Field{$1}
+    }
+    | FIELDS ',' FIELD
+    {
+        $$ = append($1, $3)
+    }
+
+GROUP_BY:
+    /* empty */
+    {
+        $$ = nil
+    }
+    | GROUP BY IDENTS
+    {
+        $$ = $3
+    }
+
+SELECT_SOURCE:
+    USER
+    {
+        $$ = &ast.ShowUserStatement{}
+    }
+    | DATABASE
+    {
+        $$ = &ast.ShowDatabaseStatement{}
+    }
+    | NODE
     {
         $$ = &ast.ShowNodeStatement{}
     }
+
+SELECT_STATEMENT:
+    SELECT FIELDS FROM SELECT_SOURCE GROUP_BY
+    {
+        stmt := &ast.SelectStatement{Source: $4, Fields: $2, GroupBy: $5}
+        if err := stmt.Validate(); err != nil {
+            yylex.Error(err.Error())
+            goto ret1
+        }
+        $$ = stmt
+    }
         
 %%
 
@@ -221,6 +623,13 @@ var keywords = map[string]int {
 // must be defined as the last token before the keyword tokens, and COMMENT
 // must be defined as the first token after the keyword tokens.
 func init() {
+    // make syntax errors report the unexpected and expected tokens, which
+    // are also used to give hints for reserved words. Note this applies to
+    // all syntax errors, their messages are like 'syntax error: unexpected
+    // VAL_INT, expecting IDENT' instead of a bare 'syntax error', clients
+    // should use the error code of ErrorEntry instead of matching messages.
+    yyErrorVerbose = true
+
     id, idx := 0, 0
 
     // Find the index of the IDENT token.
@@ -0,0 +1,108 @@
+package parser
+
+import (
+	"slices"
+	"strings"
+)
+
+// Token is a token of the input, it is returned by Tokenize.
+type Token struct {
+	// Kind is the scan result of the token, like [ScanResultIdent], or the
+	// first character of the token for operators and punctuations.
+	Kind rune
+
+	// ID is the token ID used by the parser, like IDENT, COMMENT, VAL_STR,
+	// OP_ADD or the ID of a keyword.
+	ID int
+
+	// Keyword is true if the token is a keyword.
+	Keyword bool
+
+	Text  string   // text of the token
+	Start Position // position of the first character of the token
+	End   Position // position immediately after the token
+}
+
+// redacted is the replacement of the redacted string literals.
+const redacted = "'***'"
+
+// isStringLiteral returns whether token text 'tt' is a string literal, which
+// may be incomplete.
+func isStringLiteral(tt string) bool {
+	if tt == "" {
+		return false
+	}
+	switch tt[0] {
+	case '\'', '`':
+		return true
+	case '$':
+		// '$1' is a parameter, others are dollar-quoted strings.
+		return len(tt) > 1 && (tt[1] < '0' || tt[1] > '9')
+	}
+	return false
+}
+
+// Redact returns 'input' with the string literals replaced by '***' if it
+// contains the PASSWORD keyword, like 'CREATE USER' and 'SET PASSWORD', so
+// that it can be logged without leaking the passwords. 'input' need not be
+// a valid statement.
+func Redact(input string) string {
+	tokens, _ := Tokenize(input)
+	if !slices.ContainsFunc(tokens, func(tok Token) bool {
+		return tok.ID == PASSWORD
+	}) {
+		return input
+	}
+
+	var sb strings.Builder
+	last := 0
+	for _, tok := range tokens {
+		if !isStringLiteral(tok.Text) {
+			continue
+		}
+		sb.WriteString(input[last:tok.Start.Offset])
+		sb.WriteString(redacted)
+		last = tok.End.Offset
+	}
+	sb.WriteString(input[last:])
+	return sb.String()
+}
+
+// Tokenize splits 'input' into tokens without parsing it, comments are also
+// included. Tokenizing does not stop at errors, the invalid tokens have an ID
+// of ERR_TOKEN, and the errors are returned as a ParseError.
+func Tokenize(input string) ([]Token, error) {
+	pe := &ParseError{}
+	l := NewLexer(strings.NewReader(input))
+	l.comments = true
+	l.ReportError = func(e ErrorEntry) {
+		pe.Entries = append(pe.Entries, e)
+	}
+
+	var tokens []Token
+	for {
+		var lval yySymType
+		id := l.Lex(&lval)
+		if id == 0 {
+			break
+		}
+
+		start, end := l.Position, l.Pos()
+		tok := Token{
+			Kind:  l.scanResult,
+			ID:    id,
+			Text:  input[start.Offset:end.Offset],
+			Start: start,
+			End:   end,
+		}
+		if tok.Kind == ScanResultIdent {
+			_, tok.Keyword = keywords[strings.ToUpper(tok.Text)]
+		}
+		tokens = append(tokens, tok)
+	}
+
+	if len(pe.Entries) > 0 {
+		return tokens, pe
+	}
+	return tokens, nil
+}
@@ -0,0 +1,78 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenize(t *testing.T) {
+	assert := assert.New(t)
+
+	tokens, err := Tokenize("CREATE USER 'x' -- c")
+	assert.NoError(err)
+
+	pos := func(offset int) Position {
+		return Position{Offset: offset, Line: 1, Column: offset + 1}
+	}
+	want := []Token{
+		{Kind: ScanResultIdent, ID: CREATE, Keyword: true, Text: "CREATE", Start: pos(0), End: pos(6)},
+		{Kind: ScanResultIdent, ID: USER, Keyword: true, Text: "USER", Start: pos(7), End: pos(11)},
+		{Kind: ScanResultString, ID: VAL_STR, Text: "'x'", Start: pos(12), End: pos(15)},
+		{Kind: ScanResultComment, ID: COMMENT, Text: "-- c", Start: pos(16), End: pos(20)},
+	}
+	assert.Equal(want, tokens)
+
+	// multiple lines, multi-character operators and identifiers.
+	tokens, err = Tokenize("show\n  name >= $1")
+	assert.NoError(err)
+	if assert.Len(tokens, 4) {
+		assert.Equal(Position{Offset: 7, Line: 2, Column: 3}, tokens[1].Start)
+		assert.Equal(rune(ScanResultIdent), tokens[1].Kind)
+		assert.Equal(IDENT, tokens[1].ID)
+		assert.False(tokens[1].Keyword)
+		assert.Equal(OP_GTE, tokens[2].ID)
+		assert.Equal(">=", tokens[2].Text)
+		assert.Equal(rune('>'), tokens[2].Kind)
+		assert.Equal(PARAM, tokens[3].ID)
+		assert.Equal(rune(ScanResultParam), tokens[3].Kind)
+	}
+
+	// tokenizing goes on after errors.
+	tokens, err = Tokenize("$0 abc")
+	assert.Len(tokens, 2)
+	assert.Equal(ERR_TOKEN, tokens[0].ID)
+	assert.Equal(IDENT, tokens[1].ID)
+	if assert.IsType(&ParseError{}, err) {
+		assert.Equal(ErrInvalidParamIndex, err.(*ParseError).Entries[0].Code)
+	}
+
+	tokens, err = Tokenize("")
+	assert.NoError(err)
+	assert.Empty(tokens)
+}
+
+func TestRedact(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(
+		"CREATE USER alice WITH PASSWORD '***' WITH READ PRIVILEGE",
+		Redact("CREATE USER alice WITH PASSWORD 's3cret' WITH READ PRIVILEGE"),
+	)
+	assert.Equal(
+		"set password for alice = '***'",
+		Redact(`set password for alice = 'it\'s a secret'`),
+	)
+	assert.Equal("CREATE USER alice WITH PASSWORD '***'", Redact("CREATE USER alice WITH PASSWORD $$s3cret$$"))
+	assert.Equal("CREATE USER alice WITH PASSWORD '***'", Redact("CREATE USER alice WITH PASSWORD `s3cret`"))
+
+	// parameters are not literals.
+	assert.Equal("CREATE USER alice WITH PASSWORD $1", Redact("CREATE USER alice WITH PASSWORD $1"))
+
+	// invalid statements are redacted too.
+	assert.Equal("CREATE USER '***' PASSWORD '***'", Redact("CREATE USER 'bob' PASSWORD 'secret"))
+
+	// other statements are not redacted.
+	q := "SELECT 'a' AS x FROM foo"
+	assert.Equal(q, Redact(q))
+}
@@ -0,0 +1,182 @@
+package query
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// tokenBucket is the token bucket of a client.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimiter limits the rate of requests of each client with a token
+// bucket, the buckets of idle clients are garbage collected.
+type rateLimiter struct {
+	lock    sync.Mutex
+	rate    float64
+	burst   float64
+	buckets map[string]*tokenBucket
+	lastGC  time.Time
+}
+
+// rateLimiterGCInterval is the interval to remove the buckets of idle clients.
+const rateLimiterGCInterval = time.Minute
+
+// newRateLimiter creates a rate limiter which allows 'rate' requests per
+// second with bursts of at most 'burst' requests for each client.
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	return &rateLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// gc removes the buckets which are full at 'now', they are the same as the
+// buckets of new clients. It must be called with the lock held.
+func (rl *rateLimiter) gc(now time.Time) {
+	rl.lastGC = now
+	for client, b := range rl.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*rl.rate >= rl.burst {
+			delete(rl.buckets, client)
+		}
+	}
+}
+
+// bucket returns the bucket of 'client' refilled to 'now', it creates the
+// bucket if 'create' is true, or returns nil otherwise. It must be called with
+// the lock held.
+func (rl *rateLimiter) bucket(client string, now time.Time, create bool) *tokenBucket {
+	if now.Sub(rl.lastGC) >= rateLimiterGCInterval {
+		rl.gc(now)
+	}
+
+	b := rl.buckets[client]
+	if b == nil {
+		if create {
+			b = &tokenBucket{tokens: rl.burst, last: now}
+			rl.buckets[client] = b
+		}
+	} else if now.After(b.last) {
+		b.tokens = min(rl.burst, b.tokens+now.Sub(b.last).Seconds()*rl.rate)
+		b.last = now
+	}
+	return b
+}
+
+// wait returns zero if a token is available in 'b', or the time to wait for
+// the next token otherwise.
+func (rl *rateLimiter) wait(b *tokenBucket) time.Duration {
+	if b == nil || b.tokens >= 1 {
+		return 0
+	}
+	wait := time.Duration((1 - b.tokens) / rl.rate * float64(time.Second))
+	return max(wait, time.Nanosecond)
+}
+
+// allow takes a token from the bucket of 'client' at 'now'. It returns zero
+// if a token is available, or the time to wait for the next token otherwise.
+func (rl *rateLimiter) allow(client string, now time.Time) time.Duration {
+	rl.lock.Lock()
+	defer rl.lock.Unlock()
+
+	b := rl.bucket(client, now, true)
+	if wait := rl.wait(b); wait > 0 {
+		return wait
+	}
+	b.tokens--
+	return 0
+}
+
+// peek is the same as allow, but it does not take the token.
+func (rl *rateLimiter) peek(client string, now time.Time) time.Duration {
+	rl.lock.Lock()
+	defer rl.lock.Unlock()
+	return rl.wait(rl.bucket(client, now, false))
+}
+
+// limiter is the rate limiter of the query requests, nil means no limit.
+var limiter *rateLimiter
+
+// ipRateLimitKey returns the key of the remote IP of 'r' for rate limiting.
+func ipRateLimitKey(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// userRateLimitKey returns the key of user 'name' for rate limiting.
+func userRateLimitKey(name string) string {
+	return "user:" + name
+}
+
+// rejectRateLimit responds 429 with a 'Retry-After' header of 'wait'.
+func rejectRateLimit(w http.ResponseWriter, wait time.Duration) {
+	secs := max(1, int64(math.Ceil(wait.Seconds())))
+	w.Header().Set("Retry-After", strconv.FormatInt(secs, 10))
+	http.Error(w, "too many requests", http.StatusTooManyRequests)
+}
+
+// checkRateLimit is called before the credentials of 'r' are verified, it
+// returns whether the client of 'r' may be within the rate limit without
+// taking a token, and responds 429 if not. That is, the request is rejected
+// if the bucket of its remote IP, or of its user, is exhausted. A throttled
+// client is rejected here, so its requests never reach the authenticator,
+// which may be an external service.
+func checkRateLimit(w http.ResponseWriter, r *http.Request) bool {
+	if limiter == nil {
+		return true
+	}
+
+	now := time.Now()
+	wait := limiter.peek(ipRateLimitKey(r), now)
+	if name, _, ok := r.BasicAuth(); ok && name != "" && wait == 0 {
+		wait = limiter.peek(userRateLimitKey(name), now)
+	}
+	if wait == 0 {
+		return true
+	}
+
+	rejectRateLimit(w, wait)
+	return false
+}
+
+// chargeRemoteIP takes a token from the bucket of the remote IP of 'r' if one
+// is available, it is for the requests which have already failed.
+func chargeRemoteIP(r *http.Request) {
+	if limiter != nil {
+		limiter.allow(ipRateLimitKey(r), time.Now())
+	}
+}
+
+// takeRateLimit is called after the credentials of 'r' are verified, or
+// failed to verify, it takes a token for the client of 'r' and responds 429
+// if none is available. The client is user 'name' if 'verified' is true, or
+// the remote IP otherwise. A client cannot use the name of another user to
+// exhaust that user's limit or to bypass its own.
+func takeRateLimit(w http.ResponseWriter, r *http.Request, name string, verified bool) bool {
+	if limiter == nil {
+		return true
+	}
+
+	key := ipRateLimitKey(r)
+	if verified && name != "" {
+		key = userRateLimitKey(name)
+	}
+
+	wait := limiter.allow(key, time.Now())
+	if wait == 0 {
+		return true
+	}
+
+	rejectRateLimit(w, wait)
+	return false
+}
@@ -0,0 +1,140 @@
+package query
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/localvar/xuandb/pkg/meta"
+	"github.com/localvar/xuandb/pkg/query/ast"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiter(t *testing.T) {
+	assert := assert.New(t)
+
+	rl := newRateLimiter(2, 3)
+	now := time.Now()
+
+	// a burst within the limit passes.
+	for range 3 {
+		assert.Zero(rl.allow("alice", now))
+	}
+
+	// the bucket is exhausted, a token is available after 500ms.
+	assert.Equal(500*time.Millisecond, rl.allow("alice", now))
+	assert.Equal(250*time.Millisecond, rl.allow("alice", now.Add(250*time.Millisecond)))
+	assert.Zero(rl.allow("alice", now.Add(500*time.Millisecond)))
+
+	// other clients are not affected.
+	assert.Zero(rl.allow("bob", now))
+
+	// peeking does not take a token, nor creates a bucket.
+	assert.Equal(500*time.Millisecond, rl.peek("alice", now))
+	assert.Zero(rl.peek("bob", now))
+	assert.Zero(rl.peek("dave", now))
+	assert.NotContains(rl.buckets, "dave")
+
+	// the bucket is refilled up to the burst only.
+	later := now.Add(10 * time.Second)
+	for range 3 {
+		assert.Zero(rl.allow("alice", later))
+	}
+	assert.NotZero(rl.allow("alice", later))
+
+	// the buckets of idle clients are removed.
+	assert.Len(rl.buckets, 2)
+	rl.allow("carol", later.Add(rateLimiterGCInterval))
+	assert.Len(rl.buckets, 1)
+	assert.Contains(rl.buckets, "carol")
+}
+
+// authStatement is a statement which only requires the credentials of the
+// client to be valid.
+type authStatement struct{}
+
+func (stmt *authStatement) Auth(name, pwd string) error {
+	return meta.Auth(name, pwd, meta.RequiredPrivileges{})
+}
+
+func (stmt *authStatement) Execute(ctx context.Context, rs ast.ResultSet) error {
+	return nil
+}
+
+// countingAuthenticator counts the calls to the authenticator.
+type countingAuthenticator struct {
+	fakeAuthenticator
+	calls int
+}
+
+func (ca *countingAuthenticator) Authenticate(name, pwd string) (*meta.User, error) {
+	ca.calls++
+	return ca.fakeAuthenticator.Authenticate(name, pwd)
+}
+
+func TestQueryRateLimit(t *testing.T) {
+	assert := assert.New(t)
+
+	defer func(l *rateLimiter) { limiter = l }(limiter)
+	limiter = newRateLimiter(0.5, 2)
+	defer func(f func(string) (ast.Statement, error)) { parse = f }(parse)
+	parse = func(q string) (ast.Statement, error) {
+		if q == "bad" {
+			return nil, errors.New("syntax error")
+		}
+		return &authStatement{}, nil
+	}
+	ca := &countingAuthenticator{fakeAuthenticator: fakeAuthenticator{
+		"alice": {Name: "alice", Priv: meta.PrivilegeRead},
+	}}
+	meta.SetAuthenticator(ca)
+	defer meta.SetAuthenticator(nil)
+
+	get := func(q, user, remoteAddr string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/query?q="+q, nil)
+		r.RemoteAddr = remoteAddr
+		if user != "" {
+			r.SetBasicAuth(user, "pwd")
+		}
+		w := httptest.NewRecorder()
+		queryHandler(w, r)
+		return w
+	}
+
+	// a burst within the limit passes.
+	assert.Equal(http.StatusNoContent, get("q", "alice", "10.0.0.1:1234").Code)
+	assert.Equal(http.StatusNoContent, get("q", "alice", "10.0.0.2:1234").Code)
+
+	// exceeding it is rejected, before the credentials are verified.
+	calls := ca.calls
+	w := get("q", "alice", "10.0.0.3:1234")
+	assert.Equal(http.StatusTooManyRequests, w.Code)
+	assert.Equal("2", w.Header().Get("Retry-After"))
+	assert.Equal(calls, ca.calls)
+
+	// clients without a user name are limited by the remote IP.
+	assert.Equal(http.StatusUnauthorized, get("q", "", "10.0.0.1:1234").Code)
+	assert.Equal(http.StatusUnauthorized, get("q", "", "10.0.0.1:5678").Code)
+	assert.Equal(http.StatusTooManyRequests, get("q", "", "10.0.0.1:1234").Code)
+	assert.Equal(http.StatusUnauthorized, get("q", "", "10.0.0.2:1234").Code)
+
+	// so are the clients whose credentials are not verified, and the flood
+	// of them never reaches the authenticator once they are throttled.
+	assert.Equal(http.StatusUnauthorized, get("q", "mallory", "10.0.0.4:1234").Code)
+	assert.Equal(http.StatusUnauthorized, get("q", "mallory", "10.0.0.5:1234").Code)
+	assert.Equal(http.StatusUnauthorized, get("q", "mallory", "10.0.0.4:1234").Code)
+	calls = ca.calls
+	for range 10 {
+		assert.Equal(http.StatusTooManyRequests, get("q", "mallory", "10.0.0.4:1234").Code)
+	}
+	assert.Equal(calls, ca.calls)
+
+	// the requests failing before their credentials are verified are
+	// charged to the remote IP.
+	assert.Equal(http.StatusBadRequest, get("bad", "bob", "10.0.0.6:1234").Code)
+	assert.Equal(http.StatusBadRequest, get("bad", "bob", "10.0.0.6:1234").Code)
+	assert.Equal(http.StatusTooManyRequests, get("bad", "bob", "10.0.0.6:1234").Code)
+}
@@ -0,0 +1,82 @@
+// Package registry records the in-flight queries of the current node.
+package registry
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Query is an in-flight query.
+type Query struct {
+	ID     uint64    `json:"id"`
+	SQL    string    `json:"sql"`
+	User   string    `json:"user"`
+	Client string    `json:"client"` // remote address of the client
+	Start  time.Time `json:"start"`
+
+	cancel context.CancelFunc
+}
+
+var (
+	lock    sync.Mutex
+	lastID  uint64
+	queries = make(map[uint64]*Query)
+)
+
+// Add adds a query to the registry and returns its id, the query must be
+// removed by Remove when it completes. 'cancel' cancels the execution of the
+// query, see Kill.
+func Add(sql, user, client string, cancel context.CancelFunc) uint64 {
+	lock.Lock()
+	defer lock.Unlock()
+
+	lastID++
+	queries[lastID] = &Query{
+		ID:     lastID,
+		SQL:    sql,
+		User:   user,
+		Client: client,
+		Start:  time.Now(),
+		cancel: cancel,
+	}
+	return lastID
+}
+
+// Remove removes query 'id' from the registry.
+func Remove(id uint64) {
+	lock.Lock()
+	defer lock.Unlock()
+	delete(queries, id)
+}
+
+// Kill cancels the execution of query 'id', it returns false if the query
+// is not in the registry.
+func Kill(id uint64) bool {
+	lock.Lock()
+	q := queries[id]
+	lock.Unlock()
+
+	if q == nil {
+		return false
+	}
+	q.cancel()
+	return true
+}
+
+// List returns the in-flight queries, ordered by id.
+func List() []Query {
+	lock.Lock()
+	result := make([]Query, 0, len(queries))
+	for _, q := range queries {
+		result = append(result, *q)
+	}
+	lock.Unlock()
+
+	slices.SortFunc(result, func(a, b Query) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return result
+}
@@ -0,0 +1,51 @@
+package registry
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry(t *testing.T) {
+	assert := assert.New(t)
+
+	id1 := Add("SHOW USER", "alice", "127.0.0.1:1234", func() {})
+	id2 := Add("SHOW NODE", "bob", "127.0.0.1:5678", func() {})
+	assert.Greater(id2, id1)
+
+	qs := List()
+	if assert.Len(qs, 2) {
+		assert.Equal(id1, qs[0].ID)
+		assert.Equal("SHOW USER", qs[0].SQL)
+		assert.Equal("alice", qs[0].User)
+		assert.Equal("127.0.0.1:1234", qs[0].Client)
+		assert.False(qs[0].Start.IsZero())
+		assert.Equal(id2, qs[1].ID)
+	}
+
+	Remove(id1)
+	qs = List()
+	if assert.Len(qs, 1) {
+		assert.Equal(id2, qs[0].ID)
+	}
+
+	Remove(id2)
+	assert.Empty(List())
+}
+
+func TestKill(t *testing.T) {
+	assert := assert.New(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	id := Add("SELECT 1", "alice", "127.0.0.1:1234", cancel)
+	defer Remove(id)
+
+	assert.False(Kill(id + 1))
+	assert.NoError(ctx.Err())
+
+	assert.True(Kill(id))
+	assert.Equal(context.Canceled, ctx.Err())
+}
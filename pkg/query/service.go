@@ -2,14 +2,29 @@ package query
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"log/slog"
+	"math"
+	"mime"
 	"net/http"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/localvar/xuandb/pkg/config"
+	"github.com/localvar/xuandb/pkg/httpauth"
 	"github.com/localvar/xuandb/pkg/httpserver"
+	"github.com/localvar/xuandb/pkg/meta"
+	"github.com/localvar/xuandb/pkg/metrics"
+	"github.com/localvar/xuandb/pkg/query/ast"
 	"github.com/localvar/xuandb/pkg/query/parser"
+	"github.com/localvar/xuandb/pkg/query/registry"
+	"github.com/localvar/xuandb/pkg/utils"
 	"github.com/localvar/xuandb/pkg/xerrors"
 )
 
@@ -17,17 +32,124 @@ import (
 //
 //	{
 //	  "columns": ["col1", "col2", ...],
+//	  "types": ["string", "int", ...], // only if known
 //	  "values": [ [val1, val2, ...],    ... ],
+//	  "stats": { "parseTime": 123, ... }, // only if requested
+//	  "error": "...", // only if an error occurs after streaming started
 //	}
 //
 // TODO: this is a temporary implmentation which will be refactored later.
 // the buffer size should be limited and data should be written to temporary
 // file when exceeds the limit.
 type resultSetWriter struct {
-	buf     bytes.Buffer
-	err     error
-	columns []string
-	numRow  int
+	buf        bytes.Buffer
+	err        error
+	columns    []string
+	types      []string
+	numRow     int
+	nextCursor string
+
+	// stats is the execution statistics written to the result set, it is
+	// nil if the client does not request it.
+	stats *queryStats
+
+	// rawDurations makes [ast.Duration] values be written as nanoseconds
+	// instead of the human readable form, for machine clients.
+	rawDurations bool
+
+	// stream is the response writer to stream the result set to in chunks,
+	// the result set is buffered and written by Flush if it is nil. The
+	// status code is sent with the first chunk and cannot be changed after
+	// that, so later errors are reported by the 'error' field at the end of
+	// the result set. 'streaming' is true once the first chunk is sent.
+	stream    http.ResponseWriter
+	streaming bool
+
+	// msgpack makes the result set be written in the msgpack format, the
+	// rows are buffered and the rest is written by Flush. It does not
+	// support streaming.
+	msgpack bool
+}
+
+// streamChunkSize is the size of the buffered data to trigger sending a
+// chunk in streaming mode.
+const streamChunkSize = 32 << 10
+
+// writeChunk sends the buffered data to the client as a chunk, the response
+// header is sent with the first chunk.
+func (rsw *resultSetWriter) writeChunk() error {
+	if !rsw.streaming {
+		h := rsw.stream.Header()
+		h.Set("Content-Type", "application/json")
+		h.Set("Trailer", "X-Has-More, X-Next-Cursor")
+		rsw.streaming = true
+	}
+
+	_, err := rsw.stream.Write(rsw.buf.Bytes())
+	rsw.buf.Reset()
+	if f, ok := rsw.stream.(http.Flusher); ok {
+		f.Flush()
+	}
+	return err
+}
+
+// writeErrorMarker ends a streamed result set with an 'error' field.
+func (rsw *resultSetWriter) writeErrorMarker(err error) error {
+	msg := err.Error()
+	if se, ok := err.(*xerrors.StatusError); ok {
+		msg = se.Msg
+	}
+
+	if rsw.numRow > 0 {
+		rsw.buf.WriteByte(']')
+	}
+	rsw.buf.WriteString(`,"error":`)
+	rsw.buf.Write(strconv.AppendQuote(nil, msg))
+	rsw.buf.WriteByte('}')
+	return rsw.writeChunk()
+}
+
+// rswPool is a pool of result set writers, it reduces the allocations of
+// the writers and their buffers.
+var rswPool = sync.Pool{
+	New: func() any { return &resultSetWriter{} },
+}
+
+// maxPooledBufSize is the maximum capacity of the buffer of a result set
+// writer to be put back to the pool, larger buffers are left to the garbage
+// collector to avoid holding too much memory.
+const maxPooledBufSize = 1 << 20
+
+// getResultSetWriter gets a result set writer from the pool.
+func getResultSetWriter() *resultSetWriter {
+	return rswPool.Get().(*resultSetWriter)
+}
+
+// putResultSetWriter resets 'rsw' and puts it back to the pool, 'rsw' must
+// not be used after that.
+func putResultSetWriter(rsw *resultSetWriter) {
+	if rsw.buf.Cap() > maxPooledBufSize {
+		return
+	}
+	rsw.reset()
+	rswPool.Put(rsw)
+}
+
+// reset resets the result set writer to its initial state but keeps the
+// memory of the buffer. The writer holds no resources other than memory, so
+// nothing needs to be released.
+func (rsw *resultSetWriter) reset() {
+	rsw.buf.Reset()
+	rsw.err = nil
+	rsw.columns = nil
+	rsw.types = nil
+	rsw.numRow = 0
+	rsw.nextCursor = ""
+	rsw.stats = nil
+	rsw.rawDurations = false
+	rsw.stream = nil
+	rsw.streaming = false
+	rsw.msgpack = false
 }
 
 func (rsw *resultSetWriter) SetError(err error) {
@@ -36,25 +158,105 @@ func (rsw *resultSetWriter) SetError(err error) {
 	}
 }
 
+// Errors of the result set writer, they are caused by bugs in statement
+// implementations.
+var (
+	ErrColumnsAlreadySet   = xerrors.New(http.StatusInternalServerError, "columns has already been set")
+	ErrColumnsNotSet       = xerrors.New(http.StatusInternalServerError, "columns must be set before add rows")
+	ErrColumnCountMismatch = xerrors.New(http.StatusInternalServerError, "column count mismatch")
+)
+
+// devMode makes the result set writer panic on statement implementation
+// bugs instead of returning errors, to catch the bugs early in tests.
+var devMode bool
+
+// bugError sets 'err' as the error of the result set writer and returns it,
+// or panics if in development mode.
+func (rsw *resultSetWriter) bugError(err error) error {
+	if devMode {
+		panic(err.Error())
+	}
+	rsw.SetError(err)
+	return err
+}
+
 func (rsw *resultSetWriter) SetColumns(columns ...string) {
+	rsw.setColumns(columns, nil)
+}
+
+// SetColumnsWithTypes implements [ast.ResultSet], the types are written as
+// the 'types' field after the columns.
+func (rsw *resultSetWriter) SetColumnsWithTypes(columns ...ast.Column) {
+	rsw.setColumns(ast.SplitColumns(columns))
+}
+
+// writeStrings writes 'ss' as a JSON array.
+func (rsw *resultSetWriter) writeStrings(ss []string) {
+	rsw.buf.WriteByte('[')
+	for i, s := range ss {
+		if i > 0 {
+			rsw.buf.WriteByte(',')
+		}
+		rsw.buf.Write(strconv.AppendQuote(nil, s))
+	}
+	rsw.buf.WriteByte(']')
+}
+
+// setColumns sets the columns and their types, 'types' is nil if unknown.
+func (rsw *resultSetWriter) setColumns(columns, types []string) {
 	if rsw.err != nil {
 		return
 	}
 
 	if rsw.columns != nil {
-		panic("columns has already been set.")
+		rsw.bugError(ErrColumnsAlreadySet)
+		return
 	}
 
-	rsw.columns = columns
+	rsw.columns, rsw.types = columns, types
+	if rsw.msgpack {
+		return
+	}
 
-	rsw.buf.WriteString(`{"columns":[`)
-	for i, c := range columns {
-		if i > 0 {
-			rsw.buf.WriteByte(',')
+	rsw.buf.WriteString(`{"columns":`)
+	rsw.writeStrings(columns)
+	if types != nil {
+		rsw.buf.WriteString(`,"types":`)
+		rsw.writeStrings(types)
+	}
+
+	// the columns are sent immediately in streaming mode.
+	if rsw.stream != nil {
+		if err := rsw.writeChunk(); err != nil {
+			rsw.SetError(err)
 		}
-		rsw.buf.Write(strconv.AppendQuote(nil, c))
 	}
-	rsw.buf.WriteByte(']')
+}
+
+// SetNextCursor implements [ast.ResultSet], the cursor is sent to the client
+// via the 'X-Next-Cursor' response header.
+func (rsw *resultSetWriter) SetNextCursor(cursor string) {
+	rsw.nextCursor = cursor
+}
+
+// writeFloat writes float 'f' of 'bitSize' bits to 'w'. NaN and infinities
+// have no JSON representation, they are written as null so that the result
+// set is always valid JSON.
+func writeFloat(w io.Writer, f float64, bitSize int) error {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		_, err := w.Write([]byte(`null`))
+		return err
+	}
+	_, err := w.Write([]byte(strconv.FormatFloat(f, 'g', -1, bitSize)))
+	return err
+}
+
+// formatDuration formats 'd' in the human readable form.
+func formatDuration(d ast.Duration) string {
+	if d < 0 {
+		return "-" + utils.FormatDuration(-time.Duration(d))
+	}
+	return utils.FormatDuration(time.Duration(d))
 }
 
 func writeValue(w io.Writer, v any) error {
@@ -83,15 +285,17 @@ func writeValue(w io.Writer, v any) error {
 	case uint:
 		_, err = w.Write([]byte(strconv.FormatUint(uint64(t), 10)))
 	case float32:
-		_, err = w.Write([]byte(strconv.FormatFloat(float64(t), 'g', -1, 32)))
+		err = writeFloat(w, float64(t), 32)
 	case float64:
-		_, err = w.Write([]byte(strconv.FormatFloat(t, 'g', -1, 64)))
+		err = writeFloat(w, t, 64)
 	case string:
 		_, err = w.Write(strconv.AppendQuote(nil, t))
 	case time.Time:
 		_, err = w.Write([]byte(strconv.FormatInt(t.UnixNano(), 10)))
 	case time.Duration:
 		_, err = w.Write([]byte(strconv.FormatInt(t.Nanoseconds(), 10)))
+	case ast.Duration:
+		_, err = w.Write(strconv.AppendQuote(nil, formatDuration(t)))
 	case bool:
 		_, err = w.Write([]byte(strconv.FormatBool(t)))
 	default:
@@ -106,11 +310,16 @@ func (rsw *resultSetWriter) AddRow(vals ...any) error {
 	}
 
 	if rsw.columns == nil {
-		panic("columns must be set before add rows.")
+		return rsw.bugError(ErrColumnsNotSet)
 	}
 
 	if len(vals) != len(rsw.columns) {
-		panic("column count mismatch.")
+		return rsw.bugError(ErrColumnCountMismatch)
+	}
+
+	if rsw.msgpack {
+		rsw.addMsgpackRow(vals)
+		return nil
 	}
 
 	var err error
@@ -135,6 +344,9 @@ func (rsw *resultSetWriter) AddRow(vals ...any) error {
 				return err
 			}
 		}
+		if d, ok := v.(ast.Duration); ok && rsw.rawDurations {
+			v = time.Duration(d)
+		}
 		if err = writeValue(&rsw.buf, v); err != nil {
 			return err
 		}
@@ -145,23 +357,70 @@ func (rsw *resultSetWriter) AddRow(vals ...any) error {
 	}
 
 	rsw.numRow++
+
+	if rsw.stream != nil && rsw.buf.Len() >= streamChunkSize {
+		if err := rsw.writeChunk(); err != nil {
+			rsw.SetError(err)
+			return err
+		}
+	}
 	return nil
 }
 
-func (rsw *resultSetWriter) Flush(w http.ResponseWriter) error {
+// AddRowMap implements [ast.ResultSet], the values of 'row' are ordered by
+// the columns.
+func (rsw *resultSetWriter) AddRowMap(row map[string]any) error {
 	if rsw.err != nil {
 		return rsw.err
 	}
 
 	if rsw.columns == nil {
+		return rsw.bugError(ErrColumnsNotSet)
+	}
+
+	vals, err := ast.RowValues(rsw.columns, row)
+	if err != nil {
+		return rsw.bugError(xerrors.New(http.StatusInternalServerError, err.Error()))
+	}
+
+	return rsw.AddRow(vals...)
+}
+
+// Flush writes the result set to 'w', or sends the rest of the result set
+// in streaming mode.
+func (rsw *resultSetWriter) Flush(w http.ResponseWriter) error {
+	if err := rsw.err; err != nil {
+		if rsw.streaming {
+			rsw.writeErrorMarker(err)
+			return err
+		}
+		if se, ok := err.(*xerrors.StatusError); ok {
+			http.Error(w, se.Msg, se.StatusCode)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return err
+	}
+
+	if rsw.columns == nil && rsw.stats == nil {
 		w.WriteHeader(http.StatusNoContent)
 		return nil
 	}
 
+	if rsw.msgpack {
+		return rsw.flushMsgpack(w)
+	}
+
 	var err error
-	if rsw.numRow > 0 {
-		_, err = rsw.buf.WriteString(`]}`)
-	} else {
+	if rsw.columns == nil {
+		err = rsw.buf.WriteByte('{')
+	} else if rsw.numRow > 0 {
+		err = rsw.buf.WriteByte(']')
+	}
+	if err == nil && rsw.stats != nil {
+		err = rsw.writeStats()
+	}
+	if err == nil {
 		err = rsw.buf.WriteByte('}')
 	}
 	if err != nil {
@@ -169,26 +428,277 @@ func (rsw *resultSetWriter) Flush(w http.ResponseWriter) error {
 		return err
 	}
 
+	if rsw.nextCursor != "" {
+		w.Header().Set("X-Has-More", "true")
+		w.Header().Set("X-Next-Cursor", rsw.nextCursor)
+	}
+
+	// the cursor headers are sent as trailers in streaming mode.
+	if rsw.stream != nil {
+		return rsw.writeChunk()
+	}
+
 	// we can do nothing to this error because data may already been written
 	// to [w]
 	_, err = w.Write(rsw.buf.Bytes())
 	return err
 }
 
+// queryStats is the execution statistics of a query.
+type queryStats struct {
+	ParseTime   time.Duration `json:"parseTime"`
+	ExecuteTime time.Duration `json:"executeTime"`
+	Rows        int           `json:"rows"`
+
+	// ServedBy is 'leader' if the query is executed on the leader of the
+	// meta service, or 'local' if on other nodes.
+	ServedBy string `json:"servedBy"`
+}
+
+// parse parses query 'q' into a statement.
+var parse = parser.Parse
+
+// isMetaLeader returns whether the current node is the meta service leader.
+var isMetaLeader = meta.IsLeader
+
+// writeStats writes the execution statistics as the 'stats' field of the
+// result set, the durations are in nanoseconds.
+func (rsw *resultSetWriter) writeStats() error {
+	rsw.stats.Rows = rsw.numRow
+	data, err := json.Marshal(rsw.stats)
+	if err != nil {
+		return err
+	}
+	if rsw.columns != nil {
+		rsw.buf.WriteByte(',')
+	}
+	rsw.buf.WriteString(`"stats":`)
+	_, err = rsw.buf.Write(data)
+	return err
+}
+
+// parseBool parses the boolean parameter 'name' of value 'v', an empty value
+// is false.
+func parseBool(name, v string) (bool, error) {
+	if v == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("invalid value of '%s': %s", name, v)
+	}
+	return b, nil
+}
+
+// notice is the message sent to clients in the 'X-Server-Notice' header.
+var notice string
+
+// auditReads enables auditing of the read statements.
+var auditReads bool
+
+// auditRead logs an audit record for read statement 'stmt' executed by user
+// 'name', which returned 'rows' rows. It does nothing if 'stmt' is not a read
+// statement or read auditing is disabled.
+func auditRead(name string, stmt ast.Statement, rows int) {
+	if !auditReads {
+		return
+	}
+	if ro, ok := stmt.(ast.ReadOnlyStatement); !ok || !ro.ReadOnly() {
+		return
+	}
+
+	slog.Info(
+		"read statement executed",
+		slog.Bool("audit", true),
+		slog.String("actor", name),
+		slog.String("statement", ast.TypeName(stmt)),
+		slog.Int("rows", rows),
+	)
+}
+
+// slowThreshold is the execution time above which a query is logged as a
+// slow query, 0 disables slow query logging.
+var slowThreshold time.Duration
+
+// logSlowQuery logs query 'q' as a slow query if its execution time 'd'
+// exceeds the threshold.
+func logSlowQuery(q string, d time.Duration, client string) {
+	if slowThreshold <= 0 || d <= slowThreshold {
+		return
+	}
+	slog.Warn(
+		"slow query",
+		slog.String("query", parser.Redact(q)),
+		slog.Duration("duration", d),
+		slog.String("client", client),
+	)
+}
+
+// addWarnings adds the warnings of 'stmt' to the response headers.
+func addWarnings(h http.Header, stmt ast.Statement) {
+	if ds, ok := stmt.(ast.DeprecatedStatement); ok {
+		if msg := ds.Deprecation(); msg != "" {
+			h.Add("Warning", "299 - "+strconv.Quote(msg))
+		}
+	}
+}
+
+// maxQuerySize is the maximum size in bytes of a query and of a query request
+// body, it is applied to all the supported content types and to the query
+// in the URL.
+var maxQuerySize int64 = 1 << 20
+
+// errQueryTooLarge returns the error of a query exceeding 'limit' bytes.
+func errQueryTooLarge(limit int64) error {
+	msg := fmt.Sprintf("query is too large, the limit is %d bytes", limit)
+	return xerrors.New(http.StatusRequestEntityTooLarge, msg)
+}
+
+// queryReadError converts error 'err' of reading a query to a StatusError.
+func queryReadError(err error) error {
+	var mbe *http.MaxBytesError
+	if errors.As(err, &mbe) {
+		return errQueryTooLarge(mbe.Limit)
+	}
+	return xerrors.Wrap(err, http.StatusBadRequest)
+}
+
+// readQuery reads the query statement from request 'r' according to its
+// content type:
+//   - multipart/form-data: the 'q' field, or the content of the uploaded
+//     file 'q'.
+//   - application/sql: the raw request body.
+//   - others: the 'q' parameter of the URL query or the URL-encoded form.
+func readQuery(w http.ResponseWriter, r *http.Request) (string, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxQuerySize)
+
+	ct, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch ct {
+	case "multipart/form-data":
+		if err := r.ParseMultipartForm(maxQuerySize); err != nil {
+			return "", queryReadError(err)
+		}
+		if q := r.FormValue("q"); q != "" {
+			return q, nil
+		}
+		f, _, err := r.FormFile("q")
+		if errors.Is(err, http.ErrMissingFile) {
+			return "", nil
+		} else if err != nil {
+			return "", queryReadError(err)
+		}
+		defer f.Close()
+		data, err := io.ReadAll(f)
+		if err != nil {
+			return "", queryReadError(err)
+		}
+		return string(data), nil
+
+	case "application/sql":
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			return "", queryReadError(err)
+		}
+		return string(data), nil
+
+	default:
+		if err := r.ParseForm(); err != nil {
+			return "", queryReadError(err)
+		}
+		return r.FormValue("q"), nil
+	}
+}
+
+// parseDurationFormat parses the 'durations' parameter of a query request,
+// it returns true if durations should be written as raw nanoseconds.
+func parseDurationFormat(format string) (bool, error) {
+	switch format {
+	case "", "human":
+		return false, nil
+	case "ns":
+		return true, nil
+	default:
+		return false, errors.New("invalid duration format: " + format)
+	}
+}
+
 func queryHandler(w http.ResponseWriter, r *http.Request) {
-	q := r.FormValue("q")
+	metrics.QueryRequests.Inc()
+
+	if notice != "" {
+		w.Header().Set("X-Server-Notice", notice)
+	}
+
+	if !checkRateLimit(w, r) {
+		metrics.QueryErrors.Inc()
+		return
+	}
+
+	// the requests which fail before their credentials are verified are
+	// charged to the remote IP.
+	charged := false
+	defer func() {
+		if !charged {
+			chargeRemoteIP(r)
+		}
+	}()
+
+	q, err := readQuery(w, r)
+	if err == nil && int64(len(q)) > maxQuerySize {
+		// the query in the URL is not limited by the body size limit.
+		err = errQueryTooLarge(maxQuerySize)
+	}
+	if err != nil {
+		metrics.QueryErrors.Inc()
+		se := err.(*xerrors.StatusError)
+		http.Error(w, se.Msg, se.StatusCode)
+		return
+	}
 	if q == "" {
+		metrics.QueryErrors.Inc()
 		http.Error(w, "query statement is required", http.StatusBadRequest)
 		return
 	}
 
-	stmt, err := parser.Parse(q)
+	rawDurations, err := parseDurationFormat(r.FormValue("durations"))
 	if err != nil {
+		metrics.QueryErrors.Inc()
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	slog.Debug("query received", slog.String("query", q))
+	withStats, err := parseBool("stats", r.FormValue("stats"))
+	if err != nil {
+		metrics.QueryErrors.Inc()
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	stream, err := parseBool("stream", r.FormValue("stream"))
+	if err != nil {
+		metrics.QueryErrors.Inc()
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	useMsgpack := strings.EqualFold(r.Header.Get("Accept"), msgpackContentType)
+	if useMsgpack && stream {
+		metrics.QueryErrors.Inc()
+		http.Error(w, "streaming is not supported in msgpack format", http.StatusBadRequest)
+		return
+	}
+
+	start := time.Now()
+	stmt, err := parse(q)
+	parseTime := time.Since(start)
+	if err != nil {
+		metrics.QueryErrors.Inc()
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	slog.Debug("query received", slog.String("query", parser.Redact(q)))
+	addWarnings(w.Header(), stmt)
 	/*
 		if db := r.FormValue("db"); db != "" {
 			stmt.BindDatabase(db)
@@ -196,14 +706,53 @@ func queryHandler(w http.ResponseWriter, r *http.Request) {
 	*/
 
 	name, pwd, _ := r.BasicAuth()
-	if err := stmt.Auth(name, pwd); err != nil {
-		se := err.(*xerrors.StatusError)
-		http.Error(w, se.Msg, se.StatusCode)
+	err = stmt.Auth(name, pwd)
+	charged = true
+	if !takeRateLimit(w, r, name, err == nil) {
+		metrics.QueryErrors.Inc()
+		return
+	}
+	if err != nil {
+		metrics.QueryErrors.Inc()
+		httpauth.Error(w, err)
 		return
 	}
 
-	rsw := &resultSetWriter{}
-	if err := stmt.Execute(rsw); err != nil {
+	rsw := getResultSetWriter()
+	defer putResultSetWriter(rsw)
+	rsw.rawDurations = rawDurations
+	rsw.msgpack = useMsgpack
+	if stream {
+		rsw.stream = w
+	}
+	if withStats {
+		rsw.stats = &queryStats{ParseTime: parseTime, ServedBy: "local"}
+		if isMetaLeader() {
+			rsw.stats.ServedBy = "leader"
+		}
+	}
+
+	// the query is removed from the registry even if 'Execute' panics, and it
+	// is redacted as the registry is visible to other users.
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	id := registry.Add(parser.Redact(q), name, r.RemoteAddr, cancel)
+	defer registry.Remove(id)
+
+	start = time.Now()
+	err = stmt.Execute(ctx, rsw)
+	executeTime := time.Since(start)
+	if rsw.stats != nil {
+		rsw.stats.ExecuteTime = executeTime
+	}
+	logSlowQuery(q, executeTime, r.RemoteAddr)
+	if err != nil {
+		metrics.QueryErrors.Inc()
+		if rsw.streaming {
+			rsw.SetError(err)
+			rsw.Flush(w)
+			return
+		}
 		if se, ok := err.(*xerrors.StatusError); ok {
 			http.Error(w, se.Msg, se.StatusCode)
 		} else {
@@ -212,18 +761,71 @@ func queryHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if rsw.err == nil {
+		auditRead(name, stmt, rsw.numRow)
+	}
+
 	if err := rsw.Flush(w); err != nil {
 		slog.Error(
 			"failed to flush result set",
-			slog.String("query", q),
+			slog.String("query", parser.Redact(q)),
 			slog.String("error", err.Error()),
 		)
 	}
 }
 
+// privileges required by the query management handlers.
+var (
+	activeQueriesPrivileges = meta.RequiredPrivileges{Global: meta.PrivilegeRead}
+	killQueryPrivileges     = meta.RequiredPrivileges{Global: meta.PrivilegeAdmin}
+)
+
+// activeQueriesHandler lists the in-flight queries of the current node, it
+// requires activeQueriesPrivileges.
+func activeQueriesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(registry.List())
+}
+
+// killQueryHandler cancels the in-flight query specified by the 'id'
+// parameter, it requires killQueryPrivileges.
+func killQueryHandler(w http.ResponseWriter, r *http.Request) {
+	v := r.FormValue("id")
+	id, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid query id: "+v, http.StatusBadRequest)
+		return
+	}
+
+	if !registry.Kill(id) {
+		http.Error(w, "query not found: "+v, http.StatusNotFound)
+		return
+	}
+
+	var actor string
+	if u := httpauth.User(r.Context()); u != nil {
+		actor = u.Name
+	}
+	slog.Info("query killed", slog.Uint64("id", id), slog.String("actor", actor))
+	w.WriteHeader(http.StatusNoContent)
+}
+
 // StartService starts the query service.
 func StartService() error {
+	if qc := config.CurrentNode().Query; qc != nil {
+		devMode = qc.DevMode
+		notice = qc.Notice
+		auditReads = qc.AuditReads
+		slowThreshold = qc.SlowThreshold
+		maxQuerySize = int64(qc.MaxQueryBytes)
+		if qc.RateLimitPerSec > 0 {
+			limiter = newRateLimiter(qc.RateLimitPerSec, qc.RateLimitBurst)
+		}
+	}
+
 	httpserver.HandleFunc("/query", queryHandler)
+	httpserver.HandleFunc("GET /query/active", httpauth.Require(activeQueriesPrivileges, activeQueriesHandler))
+	httpserver.HandleFunc("POST /query/kill", httpauth.Require(killQueryPrivileges, killQueryHandler))
 	slog.Info("query service started")
 	return nil
 }
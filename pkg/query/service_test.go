@@ -0,0 +1,780 @@
+package query
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"math"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/localvar/xuandb/pkg/httpauth"
+	"github.com/localvar/xuandb/pkg/meta"
+	"github.com/localvar/xuandb/pkg/query/ast"
+	"github.com/localvar/xuandb/pkg/query/registry"
+	"github.com/localvar/xuandb/pkg/xerrors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlushPaginationHeaders(t *testing.T) {
+	assert := assert.New(t)
+
+	rsw := &resultSetWriter{}
+	rsw.SetColumns("name")
+	rsw.AddRow("a")
+	rsw.SetNextCursor("a")
+	w := httptest.NewRecorder()
+	assert.NoError(rsw.Flush(w))
+	assert.Equal("true", w.Header().Get("X-Has-More"))
+	assert.Equal("a", w.Header().Get("X-Next-Cursor"))
+	assert.Equal(`{"columns":["name"],"values":[["a"]]}`, w.Body.String())
+
+	// the final page.
+	rsw = &resultSetWriter{}
+	rsw.SetColumns("name")
+	rsw.AddRow("b")
+	w = httptest.NewRecorder()
+	assert.NoError(rsw.Flush(w))
+	assert.Empty(w.Header().Get("X-Has-More"))
+	assert.Empty(w.Header().Get("X-Next-Cursor"))
+}
+
+func TestColumnTypes(t *testing.T) {
+	assert := assert.New(t)
+
+	rsw := &resultSetWriter{}
+	rsw.SetColumnsWithTypes(
+		ast.Column{Name: "name", Type: ast.ColumnTypeString},
+		ast.Column{Name: "duration", Type: ast.ColumnTypeDuration},
+	)
+	assert.NoError(rsw.AddRow("db", ast.Duration(time.Hour)))
+	w := httptest.NewRecorder()
+	assert.NoError(rsw.Flush(w))
+	assert.Equal(`{"columns":["name","duration"],"types":["string","duration"],"values":[["db","1h"]]}`, w.Body.String())
+
+	// the types are omitted if not set.
+	rsw = &resultSetWriter{}
+	rsw.SetColumns("name")
+	assert.NoError(rsw.AddRow("db"))
+	w = httptest.NewRecorder()
+	assert.NoError(rsw.Flush(w))
+	assert.Equal(`{"columns":["name"],"values":[["db"]]}`, w.Body.String())
+}
+
+func TestResultSetWriterReuse(t *testing.T) {
+	assert := assert.New(t)
+
+	// the writer is dirty in every way before it is put back.
+	rsw := getResultSetWriter()
+	rsw.rawDurations = true
+	rsw.stats = &queryStats{ServedBy: "local"}
+	rsw.SetColumns("name")
+	rsw.AddRow("a")
+	rsw.SetNextCursor("a")
+	rsw.SetError(ErrColumnCountMismatch)
+	putResultSetWriter(rsw)
+	assert.Equal(&resultSetWriter{buf: rsw.buf}, rsw)
+	assert.Zero(rsw.buf.Len())
+
+	for range 3 {
+		rsw = getResultSetWriter()
+		rsw.SetColumns("value")
+		rsw.AddRow(ast.Duration(time.Second))
+		w := httptest.NewRecorder()
+		assert.NoError(rsw.Flush(w))
+		assert.Equal(`{"columns":["value"],"values":[["1s"]]}`, w.Body.String())
+		assert.Empty(w.Header().Get("X-Next-Cursor"))
+		putResultSetWriter(rsw)
+	}
+
+	// large buffers are not put back.
+	rsw = getResultSetWriter()
+	rsw.buf.Grow(maxPooledBufSize + 1)
+	rsw.SetColumns("name")
+	putResultSetWriter(rsw)
+	assert.Equal([]string{"name"}, rsw.columns)
+}
+
+func benchmarkResultSetWriter(b *testing.B, get func() *resultSetWriter, put func(*resultSetWriter)) {
+	b.ReportAllocs()
+	for range b.N {
+		rsw := get()
+		rsw.SetColumns("name", "value")
+		for i := range 100 {
+			rsw.AddRow("name", i)
+		}
+		rsw.Flush(httptest.NewRecorder())
+		put(rsw)
+	}
+}
+
+func BenchmarkResultSetWriter(b *testing.B) {
+	b.Run("new", func(b *testing.B) {
+		benchmarkResultSetWriter(b, func() *resultSetWriter { return &resultSetWriter{} }, func(*resultSetWriter) {})
+	})
+	b.Run("pooled", func(b *testing.B) {
+		benchmarkResultSetWriter(b, getResultSetWriter, putResultSetWriter)
+	})
+}
+
+func TestResultSetWriterBugs(t *testing.T) {
+	assert := assert.New(t)
+
+	// production mode, errors are returned.
+	rsw := &resultSetWriter{}
+	assert.Equal(ErrColumnsNotSet, rsw.AddRow("a"))
+	assert.Equal(ErrColumnsNotSet, rsw.err)
+
+	rsw = &resultSetWriter{}
+	rsw.SetColumns("name", "value")
+	assert.Equal(ErrColumnCountMismatch, rsw.AddRow("a"))
+	assert.Equal(ErrColumnCountMismatch, rsw.AddRow("a", 1))
+
+	w := httptest.NewRecorder()
+	assert.Equal(ErrColumnCountMismatch, rsw.Flush(w))
+	assert.Equal(http.StatusInternalServerError, w.Code)
+
+	rsw = &resultSetWriter{}
+	rsw.SetColumns("name")
+	rsw.SetColumns("name")
+	assert.Equal(ErrColumnsAlreadySet, rsw.err)
+
+	rsw = &resultSetWriter{}
+	assert.Equal(ErrColumnsNotSet, rsw.AddRowMap(map[string]any{"name": "a"}))
+
+	rsw = &resultSetWriter{}
+	rsw.SetColumns("name", "value")
+	assert.NoError(rsw.AddRowMap(map[string]any{"value": 1, "name": "a"}))
+	assert.Equal(`{"columns":["name","value"],"values":[["a",1]`, rsw.buf.String())
+	assert.EqualError(rsw.AddRowMap(map[string]any{"name": "b", "value": 2, "x": 3}), "unknown column: x")
+	w = httptest.NewRecorder()
+	assert.Error(rsw.Flush(w))
+	assert.Equal(http.StatusInternalServerError, w.Code)
+
+	// development mode, panics.
+	devMode = true
+	defer func() { devMode = false }()
+
+	assert.Panics(func() {
+		rsw := &resultSetWriter{}
+		rsw.AddRow("a")
+	})
+	assert.Panics(func() {
+		rsw := &resultSetWriter{}
+		rsw.SetColumns("name", "value")
+		rsw.AddRow("a")
+	})
+	assert.Panics(func() {
+		rsw := &resultSetWriter{}
+		rsw.SetColumns("name")
+		rsw.SetColumns("name")
+	})
+}
+
+func TestWriteDuration(t *testing.T) {
+	assert := assert.New(t)
+
+	d := ast.Duration(14 * 24 * time.Hour)
+
+	rsw := &resultSetWriter{}
+	rsw.SetColumns("name", "duration")
+	assert.NoError(rsw.AddRow("db", d))
+	w := httptest.NewRecorder()
+	assert.NoError(rsw.Flush(w))
+	assert.Equal(`{"columns":["name","duration"],"values":[["db","2w"]]}`, w.Body.String())
+
+	rsw = &resultSetWriter{rawDurations: true}
+	rsw.SetColumns("name", "duration")
+	assert.NoError(rsw.AddRow("db", d))
+	w = httptest.NewRecorder()
+	assert.NoError(rsw.Flush(w))
+	assert.Equal(`{"columns":["name","duration"],"values":[["db",1209600000000000]]}`, w.Body.String())
+
+	raw, err := parseDurationFormat("")
+	assert.NoError(err)
+	assert.False(raw)
+	raw, err = parseDurationFormat("human")
+	assert.NoError(err)
+	assert.False(raw)
+	raw, err = parseDurationFormat("ns")
+	assert.NoError(err)
+	assert.True(raw)
+	_, err = parseDurationFormat("ms")
+	assert.EqualError(err, "invalid duration format: ms")
+}
+
+func TestWriteNonFiniteFloat(t *testing.T) {
+	assert := assert.New(t)
+
+	rsw := &resultSetWriter{}
+	rsw.SetColumns("f64", "f32")
+	assert.NoError(rsw.AddRow(math.NaN(), float32(math.NaN())))
+	assert.NoError(rsw.AddRow(math.Inf(1), float32(math.Inf(1))))
+	assert.NoError(rsw.AddRow(math.Inf(-1), float32(math.Inf(-1))))
+	assert.NoError(rsw.AddRow(1.5, float32(2.5)))
+	w := httptest.NewRecorder()
+	assert.NoError(rsw.Flush(w))
+
+	var result struct {
+		Columns []string     `json:"columns"`
+		Values  [][]*float64 `json:"values"`
+	}
+	assert.NoError(json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Len(result.Values, 4)
+	for _, row := range result.Values[:3] {
+		assert.Equal([]*float64{nil, nil}, row)
+	}
+	assert.Equal(1.5, *result.Values[3][0])
+	assert.Equal(2.5, *result.Values[3][1])
+}
+
+// testStatement is a statement which requires no authentication and outputs
+// its rows.
+type testStatement struct {
+	rows [][]any
+}
+
+func (stmt *testStatement) Auth(name, pwd string) error {
+	return nil
+}
+
+func (stmt *testStatement) Execute(ctx context.Context, rs ast.ResultSet) error {
+	rs.SetColumns("name")
+	for _, row := range stmt.rows {
+		if err := rs.AddRow(row...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestQueryStats(t *testing.T) {
+	assert := assert.New(t)
+
+	defer func(f func(string) (ast.Statement, error)) { parse = f }(parse)
+	parse = func(q string) (ast.Statement, error) {
+		return &testStatement{rows: [][]any{{"a"}, {"b"}}}, nil
+	}
+	defer func(f func() bool) { isMetaLeader = f }(isMetaLeader)
+	isMetaLeader = func() bool { return true }
+
+	query := func(params string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/query?q=test"+params, nil)
+		queryHandler(w, r)
+		return w
+	}
+
+	w := query("")
+	assert.Equal(http.StatusOK, w.Code)
+	assert.NotContains(w.Body.String(), `"stats"`)
+
+	w = query("&stats=false")
+	assert.Equal(http.StatusOK, w.Code)
+	assert.NotContains(w.Body.String(), `"stats"`)
+
+	w = query("&stats=true")
+	assert.Equal(http.StatusOK, w.Code)
+	var result struct {
+		Columns []string    `json:"columns"`
+		Values  [][]string  `json:"values"`
+		Stats   *queryStats `json:"stats"`
+	}
+	assert.NoError(json.Unmarshal(w.Body.Bytes(), &result))
+	if assert.NotNil(result.Stats) {
+		assert.GreaterOrEqual(result.Stats.ParseTime, time.Duration(0))
+		assert.GreaterOrEqual(result.Stats.ExecuteTime, time.Duration(0))
+		assert.Less(result.Stats.ExecuteTime, time.Minute)
+		assert.Equal(2, result.Stats.Rows)
+		assert.Equal("leader", result.Stats.ServedBy)
+	}
+
+	isMetaLeader = func() bool { return false }
+	w = query("&stats=1")
+	assert.NoError(json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal("local", result.Stats.ServedBy)
+
+	w = query("&stats=maybe")
+	assert.Equal(http.StatusBadRequest, w.Code)
+	assert.Contains(w.Body.String(), "invalid value of 'stats': maybe")
+
+	// stats of a result set without columns.
+	rsw := &resultSetWriter{stats: &queryStats{ServedBy: "local"}}
+	w = httptest.NewRecorder()
+	assert.NoError(rsw.Flush(w))
+	assert.JSONEq(`{"stats":{"parseTime":0,"executeTime":0,"rows":0,"servedBy":"local"}}`, w.Body.String())
+}
+
+func TestServerNotice(t *testing.T) {
+	assert := assert.New(t)
+
+	defer func(n string) { notice = n }(notice)
+
+	notice = ""
+	w := httptest.NewRecorder()
+	queryHandler(w, httptest.NewRequest(http.MethodGet, "/query", nil))
+	assert.Empty(w.Header().Get("X-Server-Notice"))
+
+	notice = "maintenance at 02:00 UTC"
+	w = httptest.NewRecorder()
+	queryHandler(w, httptest.NewRequest(http.MethodGet, "/query", nil))
+	assert.Equal("maintenance at 02:00 UTC", w.Header().Get("X-Server-Notice"))
+}
+
+// errAuthenticator always fails with an error which is not a status error.
+type errAuthenticator struct{}
+
+func (errAuthenticator) Authenticate(name, pwd string) (*meta.User, error) {
+	return nil, errors.New("directory unavailable")
+}
+
+func TestQueryAuthError(t *testing.T) {
+	assert := assert.New(t)
+
+	meta.SetAuthenticator(errAuthenticator{})
+	defer meta.SetAuthenticator(nil)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/query?q=SHOW+USER", nil)
+	r.SetBasicAuth("alice", "pwd")
+	queryHandler(w, r)
+	assert.Equal(http.StatusUnauthorized, w.Code)
+	assert.Contains(w.Body.String(), "directory unavailable")
+}
+
+func TestReadQuery(t *testing.T) {
+	assert := assert.New(t)
+
+	const q = "SHOW USER LIMIT 10"
+	read := func(r *http.Request) string {
+		got, err := readQuery(httptest.NewRecorder(), r)
+		assert.NoError(err)
+		return got
+	}
+
+	// URL query and URL-encoded form.
+	assert.Equal(q, read(httptest.NewRequest(http.MethodGet, "/query?q="+url.QueryEscape(q), nil)))
+	r := httptest.NewRequest(http.MethodPost, "/query", strings.NewReader("q="+url.QueryEscape(q)))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	assert.Equal(q, read(r))
+
+	// raw body.
+	r = httptest.NewRequest(http.MethodPost, "/query", strings.NewReader(q))
+	r.Header.Set("Content-Type", "application/sql; charset=utf-8")
+	assert.Equal(q, read(r))
+
+	// multipart field.
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("q", q)
+	mw.Close()
+	r = httptest.NewRequest(http.MethodPost, "/query", &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	assert.Equal(q, read(r))
+
+	// multipart file upload.
+	body.Reset()
+	mw = multipart.NewWriter(&body)
+	fw, _ := mw.CreateFormFile("q", "script.sql")
+	fw.Write([]byte(q))
+	mw.Close()
+	r = httptest.NewRequest(http.MethodPost, "/query", &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	assert.Equal(q, read(r))
+
+	// no query in multipart.
+	body.Reset()
+	mw = multipart.NewWriter(&body)
+	mw.WriteField("db", "db1")
+	mw.Close()
+	r = httptest.NewRequest(http.MethodPost, "/query", &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	assert.Empty(read(r))
+}
+
+func TestQueryTooLarge(t *testing.T) {
+	assert := assert.New(t)
+
+	defer func(n int64) { maxQuerySize = n }(maxQuerySize)
+	maxQuerySize = 16
+
+	q := "SHOW USER LIMIT 10000"
+	r := httptest.NewRequest(http.MethodPost, "/query", strings.NewReader(q))
+	r.Header.Set("Content-Type", "application/sql")
+	w := httptest.NewRecorder()
+	queryHandler(w, r)
+	assert.Equal(http.StatusRequestEntityTooLarge, w.Code)
+
+	r = httptest.NewRequest(http.MethodPost, "/query", strings.NewReader("q="+url.QueryEscape(q)))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	queryHandler(w, r)
+	assert.Equal(http.StatusRequestEntityTooLarge, w.Code)
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("q", q)
+	mw.Close()
+	r = httptest.NewRequest(http.MethodPost, "/query", &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	w = httptest.NewRecorder()
+	queryHandler(w, r)
+	assert.Equal(http.StatusRequestEntityTooLarge, w.Code)
+
+	// the query in the URL, just over the limit.
+	r = httptest.NewRequest(http.MethodGet, "/query?q="+url.QueryEscape(q[:17]), nil)
+	w = httptest.NewRecorder()
+	queryHandler(w, r)
+	assert.Equal(http.StatusRequestEntityTooLarge, w.Code)
+	assert.Equal("query is too large, the limit is 16 bytes\n", w.Body.String())
+
+	// exactly at the limit is fine, it fails later at parsing.
+	r = httptest.NewRequest(http.MethodGet, "/query?q="+url.QueryEscape(q[:16]), nil)
+	w = httptest.NewRecorder()
+	queryHandler(w, r)
+	assert.Equal(http.StatusBadRequest, w.Code)
+}
+
+// deprecatedStatement is a statement flagged as deprecated.
+type deprecatedStatement struct {
+	ast.ShowCurrentUserStatement
+	msg string
+}
+
+func (stmt *deprecatedStatement) Deprecation() string {
+	return stmt.msg
+}
+
+func TestAddWarnings(t *testing.T) {
+	assert := assert.New(t)
+
+	h := http.Header{}
+	addWarnings(h, &ast.ShowCurrentUserStatement{})
+	assert.Empty(h.Values("Warning"))
+
+	addWarnings(h, &deprecatedStatement{})
+	assert.Empty(h.Values("Warning"))
+
+	addWarnings(h, &deprecatedStatement{msg: `statement "X" is deprecated`})
+	assert.Equal([]string{`299 - "statement \"X\" is deprecated"`}, h.Values("Warning"))
+}
+
+func TestAuditRead(t *testing.T) {
+	assert := assert.New(t)
+
+	var buf bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+	defer func(v bool) { auditReads = v }(auditReads)
+
+	// disabled.
+	auditReads = false
+	auditRead("alice", &ast.ShowUserStatement{}, 3)
+	assert.Empty(buf.String())
+
+	auditReads = true
+	auditRead("alice", &ast.ShowUserStatement{}, 3)
+	assert.Contains(buf.String(), "level=INFO")
+	assert.Contains(buf.String(), "audit=true actor=alice statement=ShowUserStatement rows=3")
+
+	// mutations are not audited as reads.
+	buf.Reset()
+	auditRead("alice", &ast.DropUserStatement{Name: "bob"}, 0)
+	assert.Empty(buf.String())
+}
+
+// blockingStatement is a statement which blocks until it is released, or
+// panics if 'panicking' is true.
+type blockingStatement struct {
+	started   chan struct{}
+	release   chan struct{}
+	panicking bool
+}
+
+func (stmt *blockingStatement) Auth(name, pwd string) error {
+	return nil
+}
+
+func (stmt *blockingStatement) Execute(ctx context.Context, rs ast.ResultSet) error {
+	close(stmt.started)
+	<-stmt.release
+	if stmt.panicking {
+		panic("boom")
+	}
+	return nil
+}
+
+// fakeAuthenticator authenticates the users in it with any password.
+type fakeAuthenticator map[string]*meta.User
+
+func (fa fakeAuthenticator) Authenticate(name, pwd string) (*meta.User, error) {
+	if u := fa[name]; u != nil {
+		return u, nil
+	}
+	return nil, meta.ErrPasswordMismatch
+}
+
+func TestActiveQueries(t *testing.T) {
+	assert := assert.New(t)
+
+	stmt := &blockingStatement{started: make(chan struct{}), release: make(chan struct{})}
+	defer func(f func(string) (ast.Statement, error)) { parse = f }(parse)
+	parse = func(q string) (ast.Statement, error) { return stmt, nil }
+	meta.SetAuthenticator(fakeAuthenticator{
+		"alice": {Name: "alice", Priv: meta.PrivilegeRead},
+	})
+	defer meta.SetAuthenticator(nil)
+
+	query := func() {
+		defer func() { recover() }()
+		q := url.QueryEscape("SET PASSWORD FOR alice = 's3cret'")
+		r := httptest.NewRequest(http.MethodGet, "/query?q="+q, nil)
+		r.SetBasicAuth("alice", "pwd")
+		queryHandler(httptest.NewRecorder(), r)
+	}
+	active := func(name string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/query/active", nil)
+		r.SetBasicAuth(name, "pwd")
+		httpauth.Require(activeQueriesPrivileges, activeQueriesHandler)(w, r)
+		return w
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		query()
+	}()
+	<-stmt.started
+
+	// the slow query is in the registry while it is running.
+	w := active("alice")
+	assert.Equal(http.StatusOK, w.Code)
+	var qs []registry.Query
+	assert.NoError(json.Unmarshal(w.Body.Bytes(), &qs))
+	if assert.Len(qs, 1) {
+		// passwords are redacted.
+		assert.Equal("SET PASSWORD FOR alice = '***'", qs[0].SQL)
+		assert.Equal("alice", qs[0].User)
+		assert.NotEmpty(qs[0].Client)
+		assert.False(qs[0].Start.IsZero())
+	}
+
+	assert.Equal(http.StatusUnauthorized, active("bob").Code)
+
+	// and is removed on completion.
+	close(stmt.release)
+	<-done
+	assert.Empty(registry.List())
+
+	// even if the statement panics.
+	stmt = &blockingStatement{started: make(chan struct{}), release: make(chan struct{}), panicking: true}
+	close(stmt.release)
+	query()
+	assert.Empty(registry.List())
+}
+
+// cancelableStatement is a statement which runs until its context is done.
+type cancelableStatement struct {
+	started chan struct{}
+	err     chan error
+}
+
+func (stmt *cancelableStatement) Auth(name, pwd string) error {
+	return nil
+}
+
+func (stmt *cancelableStatement) Execute(ctx context.Context, rs ast.ResultSet) error {
+	close(stmt.started)
+	<-ctx.Done()
+	stmt.err <- ctx.Err()
+	return ctx.Err()
+}
+
+func TestKillQuery(t *testing.T) {
+	assert := assert.New(t)
+
+	stmt := &cancelableStatement{started: make(chan struct{}), err: make(chan error, 1)}
+	defer func(f func(string) (ast.Statement, error)) { parse = f }(parse)
+	parse = func(q string) (ast.Statement, error) { return stmt, nil }
+	meta.SetAuthenticator(fakeAuthenticator{
+		"alice": {Name: "alice", Priv: meta.PrivilegeRead},
+		"admin": {Name: "admin", Priv: meta.PrivilegeAdmin},
+	})
+	defer meta.SetAuthenticator(nil)
+
+	kill := func(name, id string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/query/kill?id="+id, nil)
+		r.SetBasicAuth(name, "pwd")
+		httpauth.Require(killQueryPrivileges, killQueryHandler)(w, r)
+		return w
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r := httptest.NewRequest(http.MethodGet, "/query?q=forever", nil)
+		queryHandler(httptest.NewRecorder(), r)
+	}()
+	<-stmt.started
+
+	qs := registry.List()
+	if !assert.Len(qs, 1) {
+		return
+	}
+	id := strconv.FormatUint(qs[0].ID, 10)
+
+	assert.Equal(http.StatusForbidden, kill("alice", id).Code)
+	assert.Equal(http.StatusBadRequest, kill("admin", "abc").Code)
+	assert.Equal(http.StatusNotFound, kill("admin", id+"0").Code)
+
+	assert.Equal(http.StatusNoContent, kill("admin", id).Code)
+	assert.Equal(context.Canceled, <-stmt.err)
+	<-done
+	assert.Empty(registry.List())
+	assert.Equal(http.StatusNotFound, kill("admin", id).Code)
+}
+
+// sleepStatement is a statement which sleeps for a while.
+type sleepStatement struct {
+	d time.Duration
+}
+
+func (stmt *sleepStatement) Auth(name, pwd string) error {
+	return nil
+}
+
+func (stmt *sleepStatement) Execute(ctx context.Context, rs ast.ResultSet) error {
+	time.Sleep(stmt.d)
+	return nil
+}
+
+func TestSlowQuery(t *testing.T) {
+	assert := assert.New(t)
+
+	var buf bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+	defer func(d time.Duration) { slowThreshold = d }(slowThreshold)
+
+	stmt := &sleepStatement{d: 20 * time.Millisecond}
+	defer func(f func(string) (ast.Statement, error)) { parse = f }(parse)
+	parse = func(q string) (ast.Statement, error) { return stmt, nil }
+
+	query := func() {
+		r := httptest.NewRequest(http.MethodGet, "/query?q=select+1", nil)
+		r.RemoteAddr = "192.0.2.1:1234"
+		queryHandler(httptest.NewRecorder(), r)
+	}
+
+	// disabled.
+	slowThreshold = 0
+	query()
+	assert.NotContains(buf.String(), "slow query")
+
+	// faster than the threshold.
+	slowThreshold = time.Minute
+	query()
+	assert.NotContains(buf.String(), "slow query")
+
+	slowThreshold = time.Millisecond
+	query()
+	assert.Contains(buf.String(), `level=WARN msg="slow query" query="select 1" duration=`)
+	assert.Contains(buf.String(), "client=192.0.2.1:1234")
+}
+
+// flushRecorder is an [httptest.ResponseRecorder] which records the body at
+// each flush.
+type flushRecorder struct {
+	*httptest.ResponseRecorder
+	chunks []string
+}
+
+func (fr *flushRecorder) Flush() {
+	fr.chunks = append(fr.chunks, fr.Body.String())
+	fr.ResponseRecorder.Flush()
+}
+
+// rowsStatement adds 'n' rows of a large value and then returns 'err'.
+type rowsStatement struct {
+	n   int
+	err error
+}
+
+func (stmt *rowsStatement) Auth(name, pwd string) error {
+	return nil
+}
+
+func (stmt *rowsStatement) Execute(ctx context.Context, rs ast.ResultSet) error {
+	rs.SetColumns("id", "value")
+	value := strings.Repeat("x", 1024)
+	for i := range stmt.n {
+		if err := rs.AddRow(i, value); err != nil {
+			return err
+		}
+	}
+	return stmt.err
+}
+
+func TestStreamQuery(t *testing.T) {
+	assert := assert.New(t)
+
+	stmt := &rowsStatement{n: 100}
+	defer func(f func(string) (ast.Statement, error)) { parse = f }(parse)
+	parse = func(q string) (ast.Statement, error) { return stmt, nil }
+
+	query := func(stream string) *flushRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/query?q=rows&stream="+stream, nil)
+		w := &flushRecorder{ResponseRecorder: httptest.NewRecorder()}
+		queryHandler(w, r)
+		return w
+	}
+
+	// the buffered result set.
+	w := query("false")
+	assert.Equal(http.StatusOK, w.Code)
+	assert.Empty(w.chunks)
+	buffered := w.Body.String()
+
+	// the streamed result set is the same, the columns are sent first, and
+	// the rows are sent in several chunks.
+	w = query("true")
+	assert.Equal(http.StatusOK, w.Code)
+	assert.Equal("application/json", w.Header().Get("Content-Type"))
+	assert.Equal(buffered, w.Body.String())
+	assert.Equal(`{"columns":["id","value"]`, w.chunks[0])
+	assert.Greater(len(w.chunks), 3)
+	assert.Equal(buffered, w.chunks[len(w.chunks)-1])
+
+	// errors after streaming started are reported by the error marker.
+	stmt.err = xerrors.New(http.StatusInternalServerError, "disk failure")
+	w = query("true")
+	assert.Equal(http.StatusOK, w.Code)
+	var rs struct {
+		Columns []string `json:"columns"`
+		Values  [][]any  `json:"values"`
+		Error   string   `json:"error"`
+	}
+	assert.NoError(json.Unmarshal(w.Body.Bytes(), &rs))
+	assert.Equal([]string{"id", "value"}, rs.Columns)
+	assert.Len(rs.Values, 100)
+	assert.Equal("disk failure", rs.Error)
+
+	// errors before streaming started change the status code as usual.
+	stmt.n = 0
+	w = query("true")
+	assert.Equal(http.StatusOK, w.Code)
+	assert.JSONEq(`{"columns":["id","value"],"error":"disk failure"}`, w.Body.String())
+
+	w = query("maybe")
+	assert.Equal(http.StatusBadRequest, w.Code)
+}
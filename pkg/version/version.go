@@ -1,6 +1,8 @@
 package version
 
 import (
+	"encoding/json"
+	"net/http"
 	"runtime"
 	"runtime/debug"
 )
@@ -42,3 +44,28 @@ func Revision() string {
 func LocalModified() bool {
 	return readBuildSetting("vcs.modified") == "true"
 }
+
+// Info is the version and build information of the application.
+type Info struct {
+	Version       string `json:"version"`
+	GoVersion     string `json:"goVersion"`
+	Revision      string `json:"revision"`
+	LocalModified bool   `json:"localModified"`
+}
+
+// Get returns the version and build information of the application.
+func Get() Info {
+	return Info{
+		Version:       Version(),
+		GoVersion:     GoVersion(),
+		Revision:      Revision(),
+		LocalModified: LocalModified(),
+	}
+}
+
+// HandleGet is an http handler that responds the version and build
+// information of the application in JSON.
+func HandleGet(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Get())
+}
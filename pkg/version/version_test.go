@@ -0,0 +1,28 @@
+package version
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleGet(t *testing.T) {
+	assert := assert.New(t)
+
+	w := httptest.NewRecorder()
+	HandleGet(w, httptest.NewRequest(http.MethodGet, "/debug/version", nil))
+	assert.Equal(http.StatusOK, w.Code)
+	assert.Equal("application/json", w.Header().Get("Content-Type"))
+
+	// the values may be empty in test builds, but the fields are present.
+	var fields map[string]any
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &fields))
+	for _, name := range []string{"version", "goVersion", "revision", "localModified"} {
+		assert.Contains(fields, name)
+	}
+	assert.Equal(GoVersion(), fields["goVersion"])
+}
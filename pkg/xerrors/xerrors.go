@@ -1,7 +1,9 @@
 package xerrors
 
 import (
+	"encoding/json"
 	"io"
+	"mime"
 	"net/http"
 )
 
@@ -9,6 +11,9 @@ import (
 type StatusError struct {
 	StatusCode int
 	Msg        string
+
+	// Cause is the underlying error, it is set by Wrap and can be nil.
+	Cause error
 }
 
 // Error implements the error interface.
@@ -16,19 +21,57 @@ func (e *StatusError) Error() string {
 	return e.Msg
 }
 
+// Unwrap returns the underlying error, so that [errors.Is] and [errors.As]
+// can see it through the StatusError.
+func (e *StatusError) Unwrap() error {
+	return e.Cause
+}
+
 // New constructs a StatusError.
 func New(code int, msg string) error {
 	return &StatusError{StatusCode: code, Msg: msg}
 }
 
-// FromHTTPResponse constructs a StatusError from an HTTP response.
+// jsonError is the JSON body of an error response.
+type jsonError struct {
+	Error string `json:"error"`
+	Code  int    `json:"code"`
+}
+
+// FromHTTPResponse constructs a StatusError from an HTTP response, the body
+// can be either a JSON body written by WriteJSON or a plain text message.
 func FromHTTPResponse(resp *http.Response) error {
 	// ignore the read error?
 	msg, _ := io.ReadAll(resp.Body)
+
+	if ct, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); ct == "application/json" {
+		var je jsonError
+		if json.Unmarshal(msg, &je) == nil {
+			return &StatusError{StatusCode: resp.StatusCode, Msg: je.Error}
+		}
+	}
+
 	return &StatusError{StatusCode: resp.StatusCode, Msg: string(msg)}
 }
 
-// Wrap wraps an error with a status code.
+// WriteJSON writes 'err' to 'w' as a JSON body like '{"error":"...","code":N}',
+// the status code is the one of 'err' if it is a StatusError, or 500 if not.
+func WriteJSON(w http.ResponseWriter, err error) {
+	je := jsonError{Error: err.Error(), Code: http.StatusInternalServerError}
+	if se, ok := err.(*StatusError); ok {
+		je.Code = se.StatusCode
+	}
+
+	h := w.Header()
+	h.Del("Content-Length")
+	h.Set("Content-Type", "application/json")
+	h.Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(je.Code)
+	json.NewEncoder(w).Encode(je)
+}
+
+// Wrap wraps an error with a status code, 'err' is kept as the cause. If
+// 'err' is already a StatusError, it is returned as is.
 func Wrap(err error, code int) error {
 	if err == nil {
 		return nil
@@ -38,5 +81,5 @@ func Wrap(err error, code int) error {
 		return se
 	}
 
-	return &StatusError{StatusCode: code, Msg: err.Error()}
+	return &StatusError{StatusCode: code, Msg: err.Error(), Cause: err}
 }
@@ -0,0 +1,67 @@
+package xerrors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteJSON(t *testing.T) {
+	assert := assert.New(t)
+
+	w := httptest.NewRecorder()
+	WriteJSON(w, New(http.StatusNotFound, `user "alice" does not exist`))
+	assert.Equal(http.StatusNotFound, w.Code)
+	assert.Equal("application/json", w.Header().Get("Content-Type"))
+	assert.JSONEq(`{"error":"user \"alice\" does not exist","code":404}`, w.Body.String())
+	assert.Equal(New(http.StatusNotFound, `user "alice" does not exist`), FromHTTPResponse(w.Result()))
+
+	// a plain error is an internal server error.
+	w = httptest.NewRecorder()
+	WriteJSON(w, errors.New("oops"))
+	assert.Equal(http.StatusInternalServerError, w.Code)
+	assert.JSONEq(`{"error":"oops","code":500}`, w.Body.String())
+}
+
+func TestFromHTTPResponsePlainText(t *testing.T) {
+	w := httptest.NewRecorder()
+	http.Error(w, "not leader", http.StatusServiceUnavailable)
+	err := FromHTTPResponse(w.Result())
+	assert.Equal(t, New(http.StatusServiceUnavailable, "not leader\n"), err)
+
+	// the message is kept as is if the JSON body is malformed.
+	resp := &http.Response{
+		StatusCode: http.StatusBadRequest,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(`{"error":`)),
+	}
+	assert.Equal(t, New(http.StatusBadRequest, `{"error":`), FromHTTPResponse(resp))
+}
+
+func TestWrap(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Nil(Wrap(nil, http.StatusInternalServerError))
+
+	cause := fmt.Errorf("read meta: %w", context.DeadlineExceeded)
+	err := Wrap(cause, http.StatusGatewayTimeout)
+	assert.EqualError(err, "read meta: context deadline exceeded")
+	assert.ErrorIs(err, context.DeadlineExceeded)
+
+	// a StatusError can be found through other wrappers.
+	var se *StatusError
+	assert.True(errors.As(fmt.Errorf("query: %w", err), &se))
+	assert.Equal(http.StatusGatewayTimeout, se.StatusCode)
+	assert.Same(cause, se.Cause)
+
+	// nested StatusErrors are collapsed.
+	assert.Same(err, Wrap(err, http.StatusBadRequest))
+	assert.Nil(errors.Unwrap(New(http.StatusNotFound, "not found")))
+}